@@ -0,0 +1,108 @@
+package availability
+
+import (
+	"sync"
+
+	"event-api/models"
+)
+
+// Broker fans availability updates out to the streams watching each event
+type Broker struct {
+	mu   sync.Mutex
+	subs map[uint]map[*Subscription]struct{}
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewBroker creates a new Broker
+func NewBroker() *Broker {
+	return &Broker{
+		subs: make(map[uint]map[*Subscription]struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// Close tells every stream to end, so a shutting down server is not held
+// open by them; clients reconnect to another replica. It is safe to call
+// more than once.
+func (b *Broker) Close() {
+	b.closeOnce.Do(func() { close(b.done) })
+}
+
+// Done is closed once the broker is closed
+func (b *Broker) Done() <-chan struct{} {
+	return b.done
+}
+
+// Subscription receives the availability updates for one event
+type Subscription struct {
+	broker  *Broker
+	eventID uint
+	updates chan models.AvailabilityUpdate
+}
+
+// Subscribe starts receiving updates for an event. Callers must Close the
+// subscription when they are done with it.
+func (b *Broker) Subscribe(eventID uint) *Subscription {
+	sub := &Subscription{
+		broker:  b,
+		eventID: eventID,
+		updates: make(chan models.AvailabilityUpdate, 1),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs[eventID] == nil {
+		b.subs[eventID] = make(map[*Subscription]struct{})
+	}
+	b.subs[eventID][sub] = struct{}{}
+	return sub
+}
+
+// Updates returns the channel updates are delivered on
+func (s *Subscription) Updates() <-chan models.AvailabilityUpdate {
+	return s.updates
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subs[s.eventID], s)
+	if len(b.subs[s.eventID]) == 0 {
+		delete(b.subs, s.eventID)
+	}
+}
+
+// Publish delivers an update to every subscriber of its event without
+// blocking. Availability is state, not a log, so a subscriber that has not
+// read its previous update yet gets only the newest one.
+func (b *Broker) Publish(update models.AvailabilityUpdate) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs[update.EventID] {
+		select {
+		case sub.updates <- update:
+		default:
+			// Replace the unread update with this one
+			select {
+			case <-sub.updates:
+			default:
+			}
+			sub.updates <- update
+		}
+	}
+}
+
+// EventIDs returns the events that currently have subscribers
+func (b *Broker) EventIDs() []uint {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ids := make([]uint, 0, len(b.subs))
+	for id := range b.subs {
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,108 @@
+package availability
+
+import (
+	"bytes"
+	"testing"
+
+	"event-api/models"
+)
+
+func TestBrokerFansOutPerEvent(t *testing.T) {
+	b := NewBroker()
+	a1 := b.Subscribe(1)
+	a2 := b.Subscribe(1)
+	other := b.Subscribe(2)
+	defer a1.Close()
+	defer a2.Close()
+	defer other.Close()
+
+	b.Publish(models.AvailabilityUpdate{EventID: 1, AvailableSeats: 9, Version: 1})
+
+	for _, sub := range []*Subscription{a1, a2} {
+		select {
+		case u := <-sub.Updates():
+			if u.AvailableSeats != 9 {
+				t.Errorf("got %d seats, want 9", u.AvailableSeats)
+			}
+		default:
+			t.Fatal("subscriber of event 1 got no update")
+		}
+	}
+	select {
+	case u := <-other.Updates():
+		t.Fatalf("subscriber of event 2 got %+v", u)
+	default:
+	}
+}
+
+func TestBrokerKeepsOnlyNewestForSlowSubscriber(t *testing.T) {
+	b := NewBroker()
+	sub := b.Subscribe(1)
+	defer sub.Close()
+
+	for v := int64(1); v <= 5; v++ {
+		b.Publish(models.AvailabilityUpdate{EventID: 1, AvailableSeats: 10 - int(v), Version: v})
+	}
+
+	u := <-sub.Updates()
+	if u.Version != 5 || u.AvailableSeats != 5 {
+		t.Fatalf("got %+v, want version 5 with 5 seats", u)
+	}
+	select {
+	case u := <-sub.Updates():
+		t.Fatalf("unexpected extra update %+v", u)
+	default:
+	}
+}
+
+func TestBrokerCloseUnsubscribes(t *testing.T) {
+	b := NewBroker()
+	sub := b.Subscribe(1)
+	sub.Close()
+
+	if ids := b.EventIDs(); len(ids) != 0 {
+		t.Fatalf("EventIDs() = %v after close, want none", ids)
+	}
+	b.Publish(models.AvailabilityUpdate{EventID: 1, Version: 1})
+	select {
+	case u := <-sub.Updates():
+		t.Fatalf("closed subscription got %+v", u)
+	default:
+	}
+}
+
+func TestBrokerClose(t *testing.T) {
+	b := NewBroker()
+	select {
+	case <-b.Done():
+		t.Fatal("Done closed before Close")
+	default:
+	}
+	b.Close()
+	b.Close()
+	select {
+	case <-b.Done():
+	default:
+		t.Fatal("Done still open after Close")
+	}
+}
+
+func TestWriteUpdate(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteUpdate(&buf, models.AvailabilityUpdate{EventID: 3, Capacity: 10, AvailableSeats: 4, Version: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "id: 7\nevent: availability\ndata: {\"event_id\":3,\"capacity\":10,\"available_seats\":4,\"version\":7}\n\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestParseLastEventID(t *testing.T) {
+	for header, want := range map[string]int64{"": -1, "abc": -1, "0": 0, "42": 42} {
+		if got := ParseLastEventID(header); got != want {
+			t.Errorf("ParseLastEventID(%q) = %d, want %d", header, got, want)
+		}
+	}
+}
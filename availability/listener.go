@@ -0,0 +1,104 @@
+package availability
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"event-api/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Reconnect backoff for the LISTEN connection
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// Source loads the current availability of an event
+type Source interface {
+	GetAvailability(ctx context.Context, id uint) (*models.AvailabilityUpdate, error)
+}
+
+// Listener feeds the Broker from Postgres notifications, so a stream on any
+// replica sees seat changes committed on every replica
+type Listener struct {
+	dsn    string
+	broker *Broker
+	source Source
+}
+
+// NewListener creates a new Listener
+func NewListener(dsn string, broker *Broker, source Source) *Listener {
+	return &Listener{dsn: dsn, broker: broker, source: source}
+}
+
+// Run listens until ctx is cancelled, reconnecting with backoff when the
+// connection drops
+func (l *Listener) Run(ctx context.Context) {
+	delay := minReconnectDelay
+	for {
+		err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.WarnContext(ctx, "availability listener disconnected", "err", err, "reconnect_in", delay.String())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// listen holds one LISTEN connection until it fails
+func (l *Listener) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, l.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{models.AvailabilityChannel}.Sanitize()); err != nil {
+		return err
+	}
+
+	// Notifications sent while we were disconnected are lost, so catch up
+	// every open stream from the database
+	l.resync(ctx)
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var update models.AvailabilityUpdate
+		if err := json.Unmarshal([]byte(n.Payload), &update); err != nil {
+			slog.WarnContext(ctx, "availability listener: bad payload", "payload", n.Payload, "err", err)
+			continue
+		}
+		l.broker.Publish(update)
+	}
+}
+
+// resync catches up every open stream from the database
+func (l *Listener) resync(ctx context.Context) {
+	publishCurrent(ctx, l.broker, l.source)
+}
+
+// publishCurrent publishes the current availability of every subscribed
+// event. Streams drop versions they have already sent, so this is safe to
+// repeat.
+func publishCurrent(ctx context.Context, broker *Broker, source Source) {
+	for _, id := range broker.EventIDs() {
+		update, err := source.GetAvailability(ctx, id)
+		if err != nil {
+			continue
+		}
+		broker.Publish(*update)
+	}
+}
@@ -0,0 +1,35 @@
+package availability
+
+import (
+	"context"
+	"time"
+)
+
+// Poller feeds the Broker by reading the availability of every streamed
+// event at an interval. It stands in for the Listener on databases without
+// notifications, such as SQLite, where one instance owns all the data.
+type Poller struct {
+	broker   *Broker
+	source   Source
+	interval time.Duration
+}
+
+// NewPoller creates a new Poller
+func NewPoller(broker *Broker, source Source, interval time.Duration) *Poller {
+	return &Poller{broker: broker, source: source, interval: interval}
+}
+
+// Run polls until ctx is cancelled
+func (p *Poller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			publishCurrent(ctx, p.broker, p.source)
+		}
+	}
+}
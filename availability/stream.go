@@ -0,0 +1,41 @@
+package availability
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"event-api/models"
+)
+
+// EventName is the SSE event name of availability updates
+const EventName = "availability"
+
+// WriteUpdate writes an update as a Server-Sent Event whose id is the update's
+// version, so a reconnecting client resumes via Last-Event-ID
+func WriteUpdate(w io.Writer, update models.AvailabilityUpdate) error {
+	data, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", update.Version, EventName, data)
+	return err
+}
+
+// WriteHeartbeat writes an SSE comment that keeps idle connections and
+// proxies from timing out
+func WriteHeartbeat(w io.Writer) error {
+	_, err := io.WriteString(w, ": heartbeat\n\n")
+	return err
+}
+
+// ParseLastEventID parses a Last-Event-ID header. It returns -1 when the
+// header is missing or invalid, so the client gets the current state.
+func ParseLastEventID(header string) int64 {
+	version, err := strconv.ParseInt(header, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return version
+}
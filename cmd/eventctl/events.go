@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"event-api/models"
+)
+
+// runEvents runs the events commands
+func (a *app) runEvents(command string, args []string) error {
+	if command == "list" {
+		flags := flag.NewFlagSet("events list", flag.ContinueOnError)
+		organizerID := flags.Uint("organizer", 0, "only events of this organizer")
+		if err := flags.Parse(args); err != nil {
+			return errUsage
+		}
+		return a.listEvents(*organizerID)
+	}
+	if command == "reconcile" {
+		flags := flag.NewFlagSet("events reconcile", flag.ContinueOnError)
+		fix := flags.Bool("fix", false, "reset drifted counters under the event's lock")
+		if err := flags.Parse(args); err != nil {
+			return errUsage
+		}
+		return a.reconcile(*fix)
+	}
+
+	id, err := idArg(args)
+	if err != nil {
+		return err
+	}
+	switch command {
+	case "show":
+		return a.showEvent(id)
+	case "recount":
+		return a.recountSeats(id)
+	default:
+		return errUsage
+	}
+}
+
+// eventRow is the table row of an event
+func eventRow(e models.Event) []string {
+	return []string{
+		strconv.FormatUint(uint64(e.ID), 10),
+		e.Title,
+		strconv.FormatUint(uint64(e.OrganizerID), 10),
+		strconv.Itoa(e.Capacity),
+		strconv.Itoa(e.AvailableSeats),
+		string(e.Inventory),
+		formatOptionalTime(e.StartsAt),
+		formatOptionalTime(e.PublishedAt),
+	}
+}
+
+// eventHeaders are the table headers of eventRow
+var eventHeaders = []string{"ID", "TITLE", "ORGANIZER", "CAPACITY", "AVAILABLE", "INVENTORY", "STARTS", "PUBLISHED"}
+
+// listEvents prints every event, or an organizer's
+func (a *app) listEvents(organizerID uint) error {
+	var events []models.Event
+	var err error
+	if organizerID != 0 {
+		events, err = a.events.GetEventsByOrganizerID(context.Background(), organizerID)
+	} else {
+		events, err = a.events.GetAllEvents(context.Background())
+	}
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(events))
+	for i, e := range events {
+		rows[i] = eventRow(e)
+	}
+	return a.out.print(events, eventHeaders, rows)
+}
+
+// showEvent prints an event followed by its registrations
+func (a *app) showEvent(id uint) error {
+	event, err := a.events.GetEventByID(context.Background(), id)
+	if err != nil {
+		return notFound(err, "event", id)
+	}
+	regs, err := a.registrations.GetEventRegistrations(context.Background(), id)
+	if err != nil {
+		return err
+	}
+
+	if a.out.format == formatJSON {
+		return a.out.print(struct {
+			Event         *models.Event         `json:"event"`
+			Registrations []models.Registration `json:"registrations"`
+		}{event, regs}, nil, nil)
+	}
+	if err := a.out.print(nil, eventHeaders, [][]string{eventRow(*event)}); err != nil {
+		return err
+	}
+	fmt.Fprintln(a.out.w)
+	return a.printRegistrations(regs)
+}
+
+// recountSeats recomputes an event's available seats and prints the change
+func (a *app) recountSeats(id uint) error {
+	recount, err := a.events.RecountSeats(context.Background(), id)
+	if err != nil {
+		return notFound(err, "event", id)
+	}
+	return a.out.print(recount,
+		[]string{"EVENT", "CAPACITY", "REGISTERED", "AVAILABLE BEFORE", "AVAILABLE AFTER"},
+		[][]string{{
+			strconv.FormatUint(uint64(recount.EventID), 10),
+			strconv.Itoa(recount.Capacity),
+			strconv.Itoa(recount.Registered),
+			strconv.Itoa(recount.Before),
+			strconv.Itoa(recount.After),
+		}})
+}
+
+// reconcile prints every event whose available seats disagree with its
+// registrations, correcting them with fix
+func (a *app) reconcile(fix bool) error {
+	report, err := a.reconciliation.Reconcile(context.Background(), fix)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(report.Drifts))
+	for i, d := range report.Drifts {
+		rows[i] = []string{
+			strconv.FormatUint(uint64(d.EventID), 10),
+			strconv.Itoa(d.Capacity),
+			strconv.Itoa(d.Registered),
+			strconv.Itoa(d.Available),
+			strconv.Itoa(d.Expected),
+			yesNo(d.Corrected),
+		}
+	}
+	if err := a.out.print(report, []string{"EVENT", "CAPACITY", "REGISTERED", "AVAILABLE", "EXPECTED", "CORRECTED"}, rows); err != nil {
+		return err
+	}
+	if a.out.format == formatTable {
+		fmt.Fprintf(a.out.w, "%d events checked, %d drifted\n", report.EventsChecked, len(report.Drifts))
+	}
+	return nil
+}
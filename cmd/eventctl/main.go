@@ -0,0 +1,217 @@
+/*
+Eventctl is the operator's command line for the event API. It works through
+the same services as the server, so domain events, seat accounting and
+locking behave exactly as they do for API requests, and it reads the same
+environment configuration (DB_DRIVER, DB_HOST, ...).
+
+	eventctl [-o table|json|csv] <command>
+
+	events list [-organizer ID]         list events
+	events show ID                      show an event and its registrations
+	events recount ID                   recompute available seats from registrations
+	events reconcile [-fix]             find, and with -fix correct, seat counter drift
+	registrations list -event ID        list an event's registrations
+	registrations list -user ID         list a user's registrations
+	registrations show ID               show a registration
+	registrations cancel ID             cancel a registration, returning its seat
+	attendees export EVENT_ID           export an event's attendees
+	can USER_ID [EVENT_ID]              check what a user may do, optionally on an event
+
+Eventctl does not migrate the database; use "server migrate" for that.
+*/
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"event-api/config"
+	"event-api/models"
+	"event-api/repository"
+	"event-api/service"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// errUsage reports a malformed command line
+var errUsage = errors.New("usage")
+
+// actor is who the audit log credits with changes made here
+var actor = models.Actor{RequestID: "eventctl"}
+
+// app holds the services the commands use
+type app struct {
+	out            *printer
+	events         service.EventService
+	registrations  service.RegistrationService
+	permissions    service.PermissionService
+	reconciliation service.ReconciliationService
+}
+
+func main() {
+	format := flag.String("o", "table", "output format: table, json or csv")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	out, err := newPrinter(os.Stdout, *format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "eventctl: %v\n", err)
+		os.Exit(2)
+	}
+
+	cfg := config.LoadConfig()
+	db, err := cfg.OpenDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "eventctl: failed to connect to database: %v\n", err)
+		os.Exit(1)
+	}
+	// SQL logging would mix with the output
+	db = db.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)})
+
+	a, err := newApp(cfg, db, out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "eventctl: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = a.run(flag.Args())
+	if errors.Is(err, errUsage) {
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "eventctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// usage prints the command summary
+func usage() {
+	fmt.Fprint(os.Stderr, `Usage: eventctl [-o table|json|csv] <command>
+
+Commands:
+  events list [-organizer ID]       list events
+  events show ID                    show an event and its registrations
+  events recount ID                 recompute available seats from registrations
+  events reconcile [-fix]           find, and with -fix correct, seat counter drift
+  registrations list -event ID      list an event's registrations
+  registrations list -user ID       list a user's registrations
+  registrations show ID             show a registration
+  registrations cancel ID           cancel a registration, returning its seat
+  attendees export EVENT_ID         export an event's attendees
+  can USER_ID [EVENT_ID]            check what a user may do, optionally on an event
+`)
+}
+
+// newApp wires the services the same way the server does
+func newApp(cfg *config.Config, db *gorm.DB, out *printer) (*app, error) {
+	transactor := repository.NewTransactor(db)
+	userRepo := repository.NewUserRepository(db)
+	eventRepo := repository.NewEventRepository(db)
+	registrationRepo := repository.NewRegistrationRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	inventoryRepo := repository.NewInventoryRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+
+	notificationService := service.NewNotificationService(repository.NewNotificationRepository(db))
+	reminderService := service.NewReminderService(db, repository.NewReminderRepository(db), notificationService, cfg.ReminderOffsets)
+	eventService := service.NewEventService(transactor, eventRepo, registrationRepo, outboxRepo, inventoryRepo, auditRepo, reminderService)
+	seats, err := service.NewSeatAllocator(service.SeatStrategy(cfg.SeatStrategy), eventRepo, inventoryRepo)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SEAT_STRATEGY: %w", err)
+	}
+
+	return &app{
+		out:            out,
+		events:         eventService,
+		registrations:  service.NewRegistrationService(transactor, registrationRepo, userRepo, eventRepo, outboxRepo, auditRepo, seats, cfg.RegistrationTimeout),
+		permissions:    service.NewPermissionService(userRepo, registrationRepo, eventService),
+		reconciliation: service.NewReconciliationService(eventRepo, eventService, false),
+	}, nil
+}
+
+// run dispatches a command
+func (a *app) run(args []string) error {
+	if len(args) < 2 && (len(args) == 0 || args[0] != "can") {
+		return errUsage
+	}
+	switch args[0] {
+	case "events":
+		return a.runEvents(args[1], args[2:])
+	case "registrations":
+		return a.runRegistrations(args[1], args[2:])
+	case "attendees":
+		if args[1] != "export" {
+			return errUsage
+		}
+		eventID, err := idArg(args[2:])
+		if err != nil {
+			return err
+		}
+		return a.exportAttendees(eventID)
+	case "can":
+		return a.checkPermissions(args[1:])
+	default:
+		return errUsage
+	}
+}
+
+// idArg parses the single ID argument of a command
+func idArg(args []string) (uint, error) {
+	if len(args) != 1 {
+		return 0, errUsage
+	}
+	return parseID(args[0])
+}
+
+// parseID parses a record ID
+func parseID(arg string) (uint, error) {
+	id, err := strconv.ParseUint(arg, 10, 32)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("invalid ID %q", arg)
+	}
+	return uint(id), nil
+}
+
+// notFound turns a missing record into a readable error
+func notFound(err error, what string, id uint) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%s %d not found", what, id)
+	}
+	return err
+}
+
+// checkPermissions prints what a user may do, as if they made the request
+func (a *app) checkPermissions(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errUsage
+	}
+	userID, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+	var eventID uint
+	if len(args) == 2 {
+		if eventID, err = parseID(args[1]); err != nil {
+			return err
+		}
+	}
+
+	permissions, err := a.permissions.CheckPermissions(context.Background(), userID, eventID)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, len(permissions))
+	for i, p := range permissions {
+		rows[i] = []string{string(p.Action), yesNo(p.Allowed), p.Reason}
+	}
+	return a.out.print(permissions, []string{"ACTION", "ALLOWED", "REASON"}, rows)
+}
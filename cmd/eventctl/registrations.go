@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"strconv"
+	"time"
+
+	"event-api/models"
+)
+
+// runRegistrations runs the registrations commands
+func (a *app) runRegistrations(command string, args []string) error {
+	if command == "list" {
+		flags := flag.NewFlagSet("registrations list", flag.ContinueOnError)
+		eventID := flags.Uint("event", 0, "registrations for this event")
+		userID := flags.Uint("user", 0, "registrations of this user")
+		if err := flags.Parse(args); err != nil || (*eventID == 0) == (*userID == 0) {
+			return errUsage
+		}
+		return a.listRegistrations(*eventID, *userID)
+	}
+
+	id, err := idArg(args)
+	if err != nil {
+		return err
+	}
+	switch command {
+	case "show":
+		return a.showRegistration(id)
+	case "cancel":
+		return a.cancelRegistration(id)
+	default:
+		return errUsage
+	}
+}
+
+// registrationHeaders are the table headers of registrationRow
+var registrationHeaders = []string{"ID", "EVENT", "USER", "ATTENDEE", "REGISTERED", "CHECKED IN"}
+
+// registrationRow is the table row of a registration
+func registrationRow(r models.Registration) []string {
+	attendee := "-"
+	if r.User != nil {
+		attendee = r.User.Name + " <" + r.User.Email + ">"
+	}
+	return []string{
+		strconv.FormatUint(uint64(r.ID), 10),
+		strconv.FormatUint(uint64(r.EventID), 10),
+		strconv.FormatUint(uint64(r.UserID), 10),
+		attendee,
+		formatTime(r.CreatedAt),
+		formatOptionalTime(r.CheckedInAt),
+	}
+}
+
+// printRegistrations prints registrations as a list
+func (a *app) printRegistrations(registrations []models.Registration) error {
+	rows := make([][]string, len(registrations))
+	for i, r := range registrations {
+		rows[i] = registrationRow(r)
+	}
+	return a.out.print(registrations, registrationHeaders, rows)
+}
+
+// listRegistrations prints the registrations for an event or of a user
+func (a *app) listRegistrations(eventID, userID uint) error {
+	var registrations []models.Registration
+	var err error
+	if eventID != 0 {
+		registrations, err = a.registrations.GetEventRegistrations(context.Background(), eventID)
+	} else {
+		registrations, err = a.registrations.GetUserRegistrations(context.Background(), userID)
+	}
+	if err != nil {
+		return err
+	}
+	return a.printRegistrations(registrations)
+}
+
+// showRegistration prints a registration with its user and event
+func (a *app) showRegistration(id uint) error {
+	registration, err := a.registrations.GetRegistrationByID(context.Background(), id)
+	if err != nil {
+		return notFound(err, "registration", id)
+	}
+	return a.out.print(registration, registrationHeaders, [][]string{registrationRow(*registration)})
+}
+
+// cancelRegistration cancels a registration on the attendee's behalf and
+// prints what was cancelled. The seat is returned and the attendee notified
+// just as when they cancel themselves.
+func (a *app) cancelRegistration(id uint) error {
+	registration, err := a.registrations.GetRegistrationByID(context.Background(), id)
+	if err != nil {
+		return notFound(err, "registration", id)
+	}
+	if err := a.registrations.CancelRegistration(context.Background(), actor, registration.UserID, registration.EventID); err != nil {
+		return err
+	}
+	return a.out.print(registration, registrationHeaders, [][]string{registrationRow(*registration)})
+}
+
+// attendee is one line of an attendee export
+type attendee struct {
+	RegistrationID uint       `json:"registration_id"`
+	UserID         uint       `json:"user_id"`
+	Name           string     `json:"name"`
+	Email          string     `json:"email"`
+	RegisteredAt   time.Time  `json:"registered_at"`
+	CheckedInAt    *time.Time `json:"checked_in_at,omitempty"`
+}
+
+// exportAttendees prints everyone registered for an event
+func (a *app) exportAttendees(eventID uint) error {
+	if _, err := a.events.GetEventByID(context.Background(), eventID); err != nil {
+		return notFound(err, "event", eventID)
+	}
+	registrations, err := a.registrations.GetEventRegistrations(context.Background(), eventID)
+	if err != nil {
+		return err
+	}
+
+	attendees := make([]attendee, 0, len(registrations))
+	rows := make([][]string, 0, len(registrations))
+	for _, r := range registrations {
+		// A deleted user's registration stays, without name or email
+		entry := attendee{
+			RegistrationID: r.ID,
+			UserID:         r.UserID,
+			RegisteredAt:   r.CreatedAt,
+			CheckedInAt:    r.CheckedInAt,
+		}
+		if r.User != nil {
+			entry.Name, entry.Email = r.User.Name, r.User.Email
+		}
+		attendees = append(attendees, entry)
+		rows = append(rows, []string{
+			strconv.FormatUint(uint64(r.ID), 10),
+			strconv.FormatUint(uint64(r.UserID), 10),
+			entry.Name,
+			entry.Email,
+			formatTime(r.CreatedAt),
+			formatOptionalTime(r.CheckedInAt),
+		})
+	}
+	return a.out.print(attendees, []string{"REGISTRATION", "USER", "NAME", "EMAIL", "REGISTERED", "CHECKED IN"}, rows)
+}
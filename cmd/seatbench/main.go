@@ -0,0 +1,291 @@
+/*
+Seatbench measures registration throughput and latency for each seat
+strategy against the configured database.
+
+For every strategy it creates an event, registers -users distinct users
+with -concurrency workers, and checks that the event was not oversold. The
+"sharded" strategy uses an event with sharded inventory; the others use row
+inventory with that SeatStrategy. Test data is removed afterwards, except
+for its audit entries: the audit log is append-only.
+
+	go run ./cmd/seatbench -users 5000 -capacity 4000 -concurrency 64
+*/
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"event-api/config"
+	"event-api/models"
+	"event-api/repository"
+	"event-api/service"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// emailPrefix marks the users created by a run so they can be cleaned up
+const emailPrefix = "seatbench+"
+
+// actor is who the audit log credits with the run's changes
+var actor = models.Actor{RequestID: "seatbench"}
+
+func main() {
+	users := flag.Int("users", 2000, "registration attempts per mode, one per user")
+	capacity := flag.Int("capacity", 1500, "event capacity")
+	concurrency := flag.Int("concurrency", 32, "concurrent registration workers")
+	shards := flag.Int("shards", models.DefaultInventoryShards, "shards for the sharded mode")
+	maxConns := flag.Int("max-conns", 50, "database connection pool size")
+	strategies := flag.String("strategies", "pessimistic,optimistic,atomic,sharded", "comma-separated seat strategies to run")
+	flag.Parse()
+
+	cfg := config.LoadConfig()
+	db, err := cfg.ConnectDB()
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	db = db.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)})
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(*maxConns)
+	sqlDB.SetMaxIdleConns(*maxConns)
+
+	b := newBench(db)
+	defer b.cleanup()
+
+	userIDs, err := b.createUsers(*users)
+	if err != nil {
+		log.Fatalf("Failed to create users: %v", err)
+	}
+
+	fmt.Printf("%-12s %8s %8s %8s %10s %9s %9s %9s  %s\n",
+		"strategy", "ok", "full", "errors", "reg/s", "p50", "p95", "p99", "check")
+	failed := false
+	for _, name := range strings.Split(*strategies, ",") {
+		name = strings.TrimSpace(name)
+		event := &models.Event{
+			Title:     "seatbench " + name,
+			Capacity:  *capacity,
+			Inventory: models.InventoryRow,
+		}
+		strategy := service.SeatStrategy(name)
+		if name == string(models.InventorySharded) {
+			event.Inventory = models.InventorySharded
+			event.InventoryShards = *shards
+			strategy = service.SeatStrategyPessimistic // unused: sharded events always use their shards
+		}
+
+		result, err := b.run(name, strategy, event, userIDs, *concurrency)
+		if err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+		fmt.Println(result)
+		failed = failed || result.checkErr != nil
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// bench holds the repositories and services under test
+type bench struct {
+	db               *gorm.DB
+	eventRepo        repository.EventRepository
+	registrationRepo repository.RegistrationRepository
+	userRepo         repository.UserRepository
+	outboxRepo       repository.OutboxRepository
+	inventoryRepo    repository.InventoryRepository
+	auditRepo        repository.AuditRepository
+	eventService     service.EventService
+	organizerID      uint
+	eventIDs         []uint
+}
+
+func newBench(db *gorm.DB) *bench {
+	b := &bench{
+		db:               db,
+		eventRepo:        repository.NewEventRepository(db),
+		registrationRepo: repository.NewRegistrationRepository(db),
+		userRepo:         repository.NewUserRepository(db),
+		outboxRepo:       repository.NewOutboxRepository(db),
+		inventoryRepo:    repository.NewInventoryRepository(db),
+		auditRepo:        repository.NewAuditRepository(db),
+	}
+	notificationService := service.NewNotificationService(repository.NewNotificationRepository(db))
+	reminderService := service.NewReminderService(db, repository.NewReminderRepository(db), notificationService, nil)
+	b.eventService = service.NewEventService(repository.NewTransactor(db), b.eventRepo, b.registrationRepo, b.outboxRepo, b.inventoryRepo, b.auditRepo, reminderService)
+	return b
+}
+
+// registrationService builds a RegistrationService using strategy
+func (b *bench) registrationService(strategy service.SeatStrategy) (service.RegistrationService, error) {
+	seats, err := service.NewSeatAllocator(strategy, b.eventRepo, b.inventoryRepo)
+	if err != nil {
+		return nil, err
+	}
+	// No timeout: every attempt should end in a seat or ErrEventFull
+	return service.NewRegistrationService(repository.NewTransactor(b.db), b.registrationRepo, b.userRepo, b.eventRepo, b.outboxRepo, b.auditRepo, seats, 0), nil
+}
+
+// createUsers creates an organizer and n attendees, returning the attendee IDs
+func (b *bench) createUsers(n int) ([]uint, error) {
+	runID := time.Now().UnixNano()
+	organizer := models.User{
+		Name:  "Seatbench Organizer",
+		Email: fmt.Sprintf("%s%d-organizer@example.com", emailPrefix, runID),
+		Role:  models.RoleOrganizer,
+	}
+	if err := b.db.Create(&organizer).Error; err != nil {
+		return nil, err
+	}
+	b.organizerID = organizer.ID
+
+	users := make([]models.User, n)
+	for i := range users {
+		users[i] = models.User{
+			Name:  fmt.Sprintf("Seatbench User %d", i),
+			Email: fmt.Sprintf("%s%d-%d@example.com", emailPrefix, runID, i),
+			Role:  models.RoleAttendee,
+		}
+	}
+	if err := b.db.CreateInBatches(&users, 500).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, n)
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+	return ids, nil
+}
+
+// result summarizes one strategy's run
+type result struct {
+	name     string
+	ok       int
+	full     int
+	errors   int
+	elapsed  time.Duration
+	latency  []time.Duration
+	checkErr error
+}
+
+func (r result) String() string {
+	check := "ok"
+	if r.checkErr != nil {
+		check = "FAIL: " + r.checkErr.Error()
+	}
+	return fmt.Sprintf("%-12s %8d %8d %8d %10.0f %9s %9s %9s  %s",
+		r.name, r.ok, r.full, r.errors,
+		float64(r.ok+r.full)/r.elapsed.Seconds(),
+		r.percentile(0.50), r.percentile(0.95), r.percentile(0.99),
+		check)
+}
+
+// percentile returns the latency below which a fraction p of requests completed
+func (r result) percentile(p float64) time.Duration {
+	if len(r.latency) == 0 {
+		return 0
+	}
+	i := int(float64(len(r.latency)-1) * p)
+	return r.latency[i].Round(10 * time.Microsecond)
+}
+
+// run creates the event and registers every user for it concurrently
+func (b *bench) run(name string, strategy service.SeatStrategy, event *models.Event, userIDs []uint, concurrency int) (result, error) {
+	registrationService, err := b.registrationService(strategy)
+	if err != nil {
+		return result{}, err
+	}
+
+	event.OrganizerID = b.organizerID
+	if err := b.eventService.CreateEvent(context.Background(), actor, event); err != nil {
+		return result{}, err
+	}
+	b.eventIDs = append(b.eventIDs, event.ID)
+
+	res := result{name: name, latency: make([]time.Duration, 0, len(userIDs))}
+	var mu sync.Mutex
+	work := make(chan uint)
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for userID := range work {
+				began := time.Now()
+				_, err := registrationService.RegisterForEvent(context.Background(), actor, userID, event.ID, models.RegistrationDetails{})
+				took := time.Since(began)
+
+				mu.Lock()
+				res.latency = append(res.latency, took)
+				switch {
+				case err == nil:
+					res.ok++
+				case errors.Is(err, models.ErrEventFull):
+					res.full++
+				default:
+					res.errors++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, id := range userIDs {
+		work <- id
+	}
+	close(work)
+	wg.Wait()
+	res.elapsed = time.Since(start)
+
+	sort.Slice(res.latency, func(i, j int) bool { return res.latency[i] < res.latency[j] })
+	res.checkErr = b.check(event, res.ok)
+	return res, nil
+}
+
+// check verifies that the event was not oversold and that its seat count
+// matches its registrations
+func (b *bench) check(event *models.Event, ok int) error {
+	var registered int64
+	if err := b.db.Model(&models.Registration{}).Where("event_id = ?", event.ID).Count(&registered).Error; err != nil {
+		return err
+	}
+	current, err := b.eventService.GetEventByID(context.Background(), event.ID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case registered > int64(event.Capacity):
+		return fmt.Errorf("oversold: %d registrations for %d seats", registered, event.Capacity)
+	case int(registered) != ok:
+		return fmt.Errorf("%d registrations stored, %d reported", registered, ok)
+	case current.AvailableSeats != event.Capacity-int(registered):
+		return fmt.Errorf("%d seats available, want %d", current.AvailableSeats, event.Capacity-int(registered))
+	}
+	return nil
+}
+
+// cleanup removes everything the run created
+func (b *bench) cleanup() {
+	if len(b.eventIDs) > 0 {
+		b.db.Unscoped().Where("event_id IN ?", b.eventIDs).Delete(&models.Registration{})
+		b.db.Where("event_id IN ?", b.eventIDs).Delete(&models.EventInventoryShard{})
+		b.db.Where("aggregate_type = ? AND aggregate_id IN ?", models.AggregateEvent, b.eventIDs).Delete(&models.OutboxEvent{})
+		b.db.Unscoped().Delete(&models.Event{}, b.eventIDs)
+	}
+	b.db.Unscoped().Where("email LIKE ?", emailPrefix+"%").Delete(&models.User{})
+}
@@ -1,65 +1,403 @@
 package main
 
 import (
+	"context"
+	"expvar"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"event-api/availability"
 	"event-api/config"
 	"event-api/handler"
+	"event-api/logging"
+	"event-api/metrics"
+	"event-api/notification"
+	"event-api/outbox"
+	"event-api/ratelimit"
 	"event-api/repository"
 	"event-api/service"
+	"event-api/tracing"
+	"event-api/waitingroom"
+	"event-api/webhook"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
 
+	// "server migrate ..." manages the schema instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(cfg, os.Args[2:]))
+	}
+
+	// Log structured records, carrying request and trace IDs, to stdout
+	logger, err := logging.New(os.Stdout, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		fatal("Invalid logging configuration", err)
+	}
+	slog.SetDefault(logger)
+
+	// Export spans of requests, service calls and queries
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingExporter, cfg.TracingSampleRatio)
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
+
 	// Connect to database
 	db, err := cfg.ConnectDB()
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("Failed to connect to database", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		fatal("Failed to connect to database", err)
+	}
+
+	// Collect metrics on a registry of our own, served at /metrics
+	appMetrics := metrics.New()
+	if err := appMetrics.InstrumentDB(db, metricsDBName(cfg)); err != nil {
+		fatal("Failed to instrument database", err)
+	}
+	if err := tracing.InstrumentDB(db); err != nil {
+		fatal("Failed to trace database", err)
 	}
 
 	// Initialize repositories
+	transactor := repository.NewTransactor(db)
 	userRepo := repository.NewUserRepository(db)
-	eventRepo := repository.NewEventRepository(db)
+	eventRepo := appMetrics.InstrumentEventRepository(repository.NewEventRepository(db))
 	registrationRepo := repository.NewRegistrationRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	reminderRepo := repository.NewReminderRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	waitingRoomRepo := repository.NewWaitingRoomRepository(db)
+	inventoryRepo := repository.NewInventoryRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
 
 	// Initialize services
-	userService := service.NewUserService(userRepo)
-	eventService := service.NewEventService(eventRepo)
-	registrationService := service.NewRegistrationService(db, eventRepo, registrationRepo, userRepo)
+	notificationService := service.NewNotificationService(notificationRepo)
+	webhookService := service.NewWebhookService(webhookRepo)
+	userService := service.NewUserService(transactor, userRepo, tokenRepo, auditRepo)
+	accountService := service.NewAccountService(db, userRepo, tokenRepo, auditRepo, notificationService, service.AccountConfig{
+		VerificationTTL:  cfg.VerificationTokenTTL,
+		PasswordResetTTL: cfg.PasswordResetTokenTTL,
+	})
+	exportService := service.NewExportService(userRepo, eventRepo, repository.NewAttendeeRepository(db))
+	privacyService := service.NewPrivacyService(db, userRepo, repository.NewPrivacyRepository(db), auditRepo)
+	reminderService := service.NewReminderService(db, reminderRepo, notificationService, cfg.ReminderOffsets)
+	eventService := service.NewEventService(transactor, eventRepo, registrationRepo, outboxRepo, inventoryRepo, auditRepo, reminderService)
+	seatAllocator, err := service.NewSeatAllocator(service.SeatStrategy(cfg.SeatStrategy), eventRepo, inventoryRepo)
+	if err != nil {
+		fatal("Invalid SEAT_STRATEGY", err)
+	}
+	registrationService := appMetrics.InstrumentRegistrationService(
+		service.NewRegistrationService(transactor, registrationRepo, userRepo, eventRepo, outboxRepo, auditRepo, seatAllocator, cfg.RegistrationTimeout))
+	waitingRoomService := service.NewWaitingRoomService(db, eventRepo, userRepo, waitingRoomRepo, waitingRoomConfig(cfg))
+	inventoryService := service.NewInventoryService(db, inventoryRepo)
+	reconciliationService := service.NewReconciliationService(eventRepo, eventService, cfg.ReconcileAutoCorrect)
+	auditService := service.NewAuditService(userRepo, auditRepo)
+
+	// Background workers run until workerCtx is cancelled at shutdown
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+
+	// Publish committed domain events to their subscribers
+	relay := outbox.NewRelay(db, outboxRepo, relayConfig(cfg))
+	relay.Subscribe("notifications", notificationService.HandleOutboxEvent)
+	relay.Subscribe("webhooks", webhookService.HandleOutboxEvent)
+	workers.Go(func() { relay.Run(workerCtx) })
+
+	// Start delivering queued notifications in the background
+	notificationWorker := notification.NewWorker(notificationRepo, newNotificationSender(cfg), notificationWorkerConfig(cfg))
+	workers.Go(func() { notificationWorker.Run(workerCtx) })
+
+	// Send event reminders; safe to run on every replica
+	workers.Go(func() { reminderService.Run(workerCtx, cfg.ReminderPollInterval) })
+
+	// Deliver queued webhooks to subscriber endpoints
+	webhookWorker := webhook.NewWorker(webhookRepo, webhookWorkerConfig(cfg))
+	workers.Go(func() { webhookWorker.Run(workerCtx) })
+
+	// Fan committed seat changes from every replica out to availability streams
+	availabilityBroker := availability.NewBroker()
+	if cfg.DBDriver == config.DriverSQLite {
+		poller := availability.NewPoller(availabilityBroker, eventService, cfg.AvailabilityPollInterval)
+		workers.Go(func() { poller.Run(workerCtx) })
+	} else {
+		listener := availability.NewListener(cfg.GetDSN(), availabilityBroker, eventService)
+		workers.Go(func() { listener.Run(workerCtx) })
+	}
+
+	// Admit waiting room batches at the configured rate
+	workers.Go(func() { waitingRoomService.Run(workerCtx) })
+
+	// Spread the remaining seats of sharded events back over empty shards
+	workers.Go(func() { inventoryService.Run(workerCtx, cfg.InventoryRebalanceInterval) })
+
+	// Check available seats against registrations, served at /debug/vars
+	if cfg.ReconcileInterval > 0 {
+		workers.Go(func() { reconciliationService.Run(workerCtx, cfg.ReconcileInterval) })
+	}
+	expvar.Publish("seat_reconciliation", expvar.Func(func() any { return reconciliationService.Stats() }))
+
+	// Erase deleted users and purge deleted rows once past retention
+	if cfg.RetentionDays > 0 {
+		retention := time.Duration(cfg.RetentionDays) * 24 * time.Hour
+		workers.Go(func() { privacyService.Run(workerCtx, cfg.RetentionPurgeInterval, retention) })
+	}
+
+	// Limit how often each client may call the API
+	limiter := ratelimit.NewLimiter(newRateLimitStore(workerCtx, cfg, db, &workers), rateLimits(cfg))
+
+	// Remaining seats are read from the database on every scrape
+	if err := appMetrics.WatchSeats(eventRepo.FindActiveSeatCounts); err != nil {
+		fatal("Failed to register seat metrics", err)
+	}
 
 	// Initialize handlers
-	userHandler := handler.NewUserHandler(userService)
+	userHandler := handler.NewUserHandler(userService, accountService, privacyService)
 	eventHandler := handler.NewEventHandler(eventService)
-	registrationHandler := handler.NewRegistrationHandler(registrationService)
+	registrationHandler := handler.NewRegistrationHandler(registrationService, waitingRoomService)
+	exportHandler := handler.NewExportHandler(exportService)
+	notificationHandler := handler.NewNotificationHandler(notificationService)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	availabilityHandler := handler.NewAvailabilityHandler(eventService, availabilityBroker, cfg.AvailabilityHeartbeatInterval)
+	waitingRoomHandler := handler.NewWaitingRoomHandler(waitingRoomService)
+	reconciliationHandler := handler.NewReconciliationHandler(reconciliationService)
+	auditHandler := handler.NewAuditHandler(auditService)
+	healthHandler := handler.NewHealthHandler(sqlDB.PingContext)
 
 	// Setup router
-	router := setupRouter(userHandler, eventHandler, registrationHandler)
+	router, err := setupRouter(
+		cfg.TrustedProxies,
+		appMetrics,
+		limiter,
+		healthHandler,
+		userHandler,
+		eventHandler,
+		registrationHandler,
+		exportHandler,
+		notificationHandler,
+		webhookHandler,
+		availabilityHandler,
+		waitingRoomHandler,
+		reconciliationHandler,
+		auditHandler,
+		handler.RequireAdmin(userService),
+		handler.RequireOrganizer(userService),
+	)
+	if err != nil {
+		fatal("Invalid TRUSTED_PROXIES", err)
+	}
 
 	// Start server
-	addr := fmt.Sprintf(":%s", cfg.ServerPort)
-	log.Printf("Server starting on %s", addr)
-	if err := router.Run(addr); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%s", cfg.ServerPort),
+		Handler:      router,
+		ReadTimeout:  cfg.ServerReadTimeout,
+		WriteTimeout: cfg.ServerWriteTimeout,
+		IdleTimeout:  cfg.ServerIdleTimeout,
+	}
+	// Streams would otherwise hold Shutdown open until the timeout
+	srv.RegisterOnShutdown(availabilityBroker.Close)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+	slog.Info("Server starting", "addr", srv.Addr)
+
+	select {
+	case err := <-serveErr:
+		fatal("Failed to start server", err)
+	case <-ctx.Done():
+	}
+	// A second signal kills the process right away
+	stop()
+
+	// Shut down in order: fail readiness for ShutdownDelay, so load balancers
+	// stop sending requests; stop accepting requests and wait for those in
+	// flight, so running registrations commit rather than being cut off;
+	// stop the background workers and wait for their current batch; then
+	// flush traces and close the database
+	slog.Info("Shutting down", "delay", cfg.ShutdownDelay.String(), "timeout", cfg.ShutdownTimeout.String())
+	healthHandler.Drain()
+	time.Sleep(cfg.ShutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Requests still in flight were cut off", "err", err)
+		_ = srv.Close()
+	}
+
+	stopWorkers()
+	workersDone := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(workersDone)
+	}()
+	select {
+	case <-workersDone:
+	case <-shutdownCtx.Done():
+		slog.Error("Background workers did not stop in time")
+	}
+
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to flush traces", "err", err)
 	}
+	if err := sqlDB.Close(); err != nil {
+		slog.Error("Failed to close database", "err", err)
+	}
+	slog.Info("Server stopped")
+}
+
+// fatal logs msg with err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
+}
+
+// newNotificationSender returns an SMTP sender, or a log sender when no relay is configured
+func newNotificationSender(cfg *config.Config) notification.Sender {
+	if cfg.SMTPHost == "" {
+		slog.Warn("SMTP_HOST not set, notifications will be logged instead of sent")
+		return notification.NewLogSender()
+	}
+	return notification.NewSMTPSender(notification.SMTPConfig{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+	})
+}
+
+// notificationWorkerConfig applies configured overrides to the worker defaults
+func notificationWorkerConfig(cfg *config.Config) notification.WorkerConfig {
+	workerCfg := notification.DefaultWorkerConfig()
+	workerCfg.PollInterval = cfg.NotificationPollInterval
+	workerCfg.MaxAttempts = cfg.NotificationMaxAttempts
+	return workerCfg
+}
+
+// relayConfig applies configured overrides to the relay defaults
+func relayConfig(cfg *config.Config) outbox.RelayConfig {
+	relayCfg := outbox.DefaultRelayConfig()
+	relayCfg.PollInterval = cfg.OutboxPollInterval
+	relayCfg.MaxAttempts = cfg.OutboxMaxAttempts
+	return relayCfg
+}
+
+// webhookWorkerConfig applies configured overrides to the worker defaults
+func webhookWorkerConfig(cfg *config.Config) webhook.WorkerConfig {
+	workerCfg := webhook.DefaultWorkerConfig()
+	workerCfg.PollInterval = cfg.WebhookPollInterval
+	workerCfg.MaxAttempts = cfg.WebhookMaxAttempts
+	workerCfg.RequestTimeout = cfg.WebhookTimeout
+	workerCfg.AllowPrivateTargets = cfg.WebhookAllowPrivateTargets
+	return workerCfg
+}
+
+// waitingRoomConfig builds the waiting room settings, generating a signing
+// secret when none is configured
+func waitingRoomConfig(cfg *config.Config) service.WaitingRoomConfig {
+	secret := []byte(cfg.WaitingRoomSecret)
+	if len(secret) == 0 {
+		slog.Warn("WAITING_ROOM_SECRET not set, queue tokens will not survive a restart or work across replicas")
+		secret = waitingroom.NewSecret()
+	}
+	return service.WaitingRoomConfig{
+		Secret:        secret,
+		AdmitBatch:    cfg.WaitingRoomAdmitBatch,
+		AdmitInterval: cfg.WaitingRoomAdmitInterval,
+		AdmissionTTL:  cfg.WaitingRoomAdmissionTTL,
+	}
+}
+
+// rateLimits parses the configured limits of each route group
+func rateLimits(cfg *config.Config) map[string]ratelimit.Limit {
+	limits, err := ratelimit.ParseLimits(cfg.RateLimits)
+	if err != nil {
+		fatal("Invalid RATE_LIMITS", err)
+	}
+	return limits
+}
+
+// newRateLimitStore returns the configured rate limit store. The database
+// store is purged of idle clients by a worker that runs until ctx is cancelled.
+func newRateLimitStore(ctx context.Context, cfg *config.Config, db *gorm.DB, workers *sync.WaitGroup) ratelimit.Store {
+	switch cfg.RateLimitStore {
+	case config.RateLimitStoreMemory:
+		return ratelimit.NewMemoryStore()
+	case config.RateLimitStorePostgres:
+		store := ratelimit.NewPostgresStore(db)
+		workers.Go(func() { store.Run(ctx, time.Minute) })
+		return store
+	}
+	fatal("Invalid RATE_LIMIT_STORE", fmt.Errorf("unknown store %q", cfg.RateLimitStore))
+	return nil
+}
+
+// metricsDBName labels the connection pool metrics
+func metricsDBName(cfg *config.Config) string {
+	if cfg.DBDriver == config.DriverSQLite {
+		return cfg.SQLitePath
+	}
+	return cfg.DBName
 }
 
 // setupRouter configures all routes
 func setupRouter(
+	trustedProxies []string,
+	appMetrics *metrics.Metrics,
+	limiter *ratelimit.Limiter,
+	healthHandler *handler.HealthHandler,
 	userHandler *handler.UserHandler,
 	eventHandler *handler.EventHandler,
 	registrationHandler *handler.RegistrationHandler,
-) *gin.Engine {
-	router := gin.Default()
+	exportHandler *handler.ExportHandler,
+	notificationHandler *handler.NotificationHandler,
+	webhookHandler *handler.WebhookHandler,
+	availabilityHandler *handler.AvailabilityHandler,
+	waitingRoomHandler *handler.WaitingRoomHandler,
+	reconciliationHandler *handler.ReconciliationHandler,
+	auditHandler *handler.AuditHandler,
+	requireAdmin gin.HandlerFunc,
+	requireOrganizer gin.HandlerFunc,
+) (*gin.Engine, error) {
+	// Request logging replaces gin's own; it runs inside the tracing
+	// middleware so its lines carry the trace ID
+	router := gin.New()
+	// Client IPs, which rate limits are keyed by, are only taken from
+	// X-Forwarded-For when a trusted proxy sent the request
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, err
+	}
+	router.Use(gin.Recovery(), tracing.Middleware(), logging.Middleware(), appMetrics.Middleware())
 
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+	// Probes: /livez while the process serves, /readyz while it can also
+	// reach the database. /health is kept for existing checks.
+	router.GET("/livez", healthHandler.Livez)
+	router.GET("/readyz", healthHandler.Readyz)
+	router.GET("/health", healthHandler.Livez)
+
+	// Runtime and reconciler counters, for admins
+	router.GET("/debug/vars", requireAdmin, gin.WrapH(expvar.Handler()))
+
+	// Prometheus metrics
+	router.GET("/metrics", gin.WrapH(appMetrics.Handler()))
 
 	// API info endpoint
 	router.GET("/", func(c *gin.Context) {
@@ -70,7 +408,9 @@ func setupRouter(
 				"users":         "/api/v1/users",
 				"events":        "/api/v1/events",
 				"registrations": "/api/v1/registrations",
-				"health":        "/health",
+				"audit_events":  "/api/v1/audit-events",
+				"livez":         "/livez",
+				"readyz":        "/readyz",
 			},
 		})
 	})
@@ -79,36 +419,85 @@ func setupRouter(
 	v1 := router.Group("/api/v1")
 	{
 		// User routes
-		users := v1.Group("/users")
+		users := v1.Group("/users", limiter.Middleware("users"))
 		{
 			users.POST("", userHandler.CreateUser)
 			users.GET("", userHandler.GetAllUsers)
 			users.GET("/:id", userHandler.GetUser)
 			users.PUT("/:id", userHandler.UpdateUser)
 			users.DELETE("/:id", userHandler.DeleteUser)
+
+			// Account lifecycle; tokens arrive by email
+			users.POST("/:id/verification", userHandler.SendVerification)
+			users.POST("/verify", userHandler.VerifyEmail)
+			users.POST("/password-reset", userHandler.RequestPasswordReset)
+			users.POST("/password-reset/confirm", userHandler.ResetPassword)
+			users.POST("/:id/deactivate", userHandler.DeactivateUser)
+
+			// Personal data: export and erasure
+			users.GET("/:id/export", userHandler.ExportUser)
+			users.POST("/:id/erase", userHandler.EraseUser)
 		}
 
 		// Event routes
-		events := v1.Group("/events")
+		events := v1.Group("/events", limiter.Middleware("events"))
 		{
 			events.POST("", eventHandler.CreateEvent)
 			events.GET("", eventHandler.GetAllEvents)
 			events.GET("/:id", eventHandler.GetEvent)
 			events.PUT("/:id", eventHandler.UpdateEvent)
 			events.DELETE("/:id", eventHandler.DeleteEvent)
+			events.POST("/:id/publish", eventHandler.PublishEvent)
+			events.GET("/:id/availability/stream", availabilityHandler.StreamAvailability)
+			events.POST("/:id/queue", waitingRoomHandler.JoinQueue)
+			events.GET("/:id/queue/status", waitingRoomHandler.GetQueueStatus)
+			events.GET("/:id/registrations/export", exportHandler.ExportAttendees)
 			events.GET("/organizer/:organizerID", eventHandler.GetOrganizerEvents)
 		}
 
 		// Registration routes
-		registrations := v1.Group("/registrations")
+		registrations := v1.Group("/registrations", limiter.Middleware("registrations"))
 		{
 			registrations.POST("", registrationHandler.RegisterForEvent)
 			registrations.GET("/:id", registrationHandler.GetRegistration)
 			registrations.GET("/user/:userID", registrationHandler.GetUserRegistrations)
 			registrations.GET("/event/:eventID", registrationHandler.GetEventRegistrations)
 			registrations.DELETE("", registrationHandler.CancelRegistration)
+			registrations.POST("/:id/check-in", registrationHandler.CheckIn)
+			registrations.GET("/event/:eventID/review", registrationHandler.GetPendingReview)
+			registrations.POST("/:id/review", registrationHandler.ReviewRegistration)
+		}
+
+		// Notification template overrides, for the organizer or an admin
+		templates := v1.Group("/organizers/:organizerID/templates", limiter.Middleware("organizers"), requireOrganizer)
+		{
+			templates.GET("", notificationHandler.GetTemplates)
+			templates.PUT("/:type", notificationHandler.SaveTemplate)
+			templates.DELETE("/:type", notificationHandler.DeleteTemplate)
+		}
+
+		// Webhook subscriptions and delivery log, for the organizer or an admin
+		webhooks := v1.Group("/organizers/:organizerID/webhooks", limiter.Middleware("organizers"), requireOrganizer)
+		{
+			webhooks.POST("", webhookHandler.CreateSubscription)
+			webhooks.GET("", webhookHandler.GetSubscriptions)
+			webhooks.GET("/:id", webhookHandler.GetSubscription)
+			webhooks.PUT("/:id", webhookHandler.UpdateSubscription)
+			webhooks.DELETE("/:id", webhookHandler.DeleteSubscription)
+			webhooks.POST("/:id/rotate-secret", webhookHandler.RotateSecret)
+			webhooks.GET("/:id/deliveries", webhookHandler.GetDeliveries)
+		}
+
+		// Audit log, for admins and organizers
+		v1.GET("/audit-events", auditHandler.GetAuditEvents)
+
+		// Operator endpoints, for admins
+		admin := v1.Group("/admin", requireAdmin)
+		{
+			admin.GET("/reconciliation", reconciliationHandler.GetReconciliation)
+			admin.POST("/reconciliation", reconciliationHandler.Reconcile)
 		}
 	}
 
-	return router
+	return router, nil
 }
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"event-api/config"
+	"event-api/migrations"
+)
+
+const migrateUsage = `Usage: server migrate [-dir migrations] <command>
+
+Commands:
+  up             apply every pending migration
+  down [n]       revert the latest n migrations (default 1)
+  status         list migrations and when they were applied
+  create <name>  add empty up and down files for a new migration under -dir
+`
+
+// runMigrate runs the migrate subcommand and returns the exit code
+func runMigrate(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dir := flags.String("dir", "migrations", "migrations source directory, for create")
+	flags.Usage = func() { fmt.Fprint(os.Stderr, migrateUsage) }
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	args = flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	// create only writes files and needs no database
+	if args[0] == "create" {
+		if len(args) != 2 {
+			flags.Usage()
+			return 2
+		}
+		created, err := migrations.Create(*dir, args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate create: %v\n", err)
+			return 1
+		}
+		for _, file := range created {
+			fmt.Println("Created", file)
+		}
+		return 0
+	}
+
+	switch args[0] {
+	case "up", "down", "status":
+	default:
+		flags.Usage()
+		return 2
+	}
+
+	db, err := cfg.OpenDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+		return 1
+	}
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate up: %v\n", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				fmt.Fprintf(os.Stderr, "migrate down: invalid step count %q\n", args[1])
+				return 2
+			}
+		}
+		reverted, err := migrator.Down(steps)
+		for _, m := range reverted {
+			fmt.Printf("Reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate down: %v\n", err)
+			return 1
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No applied migrations")
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate status: %v\n", err)
+			return 1
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", s.Version, s.Name, applied)
+		}
+	}
+	return 0
+}
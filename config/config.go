@@ -2,34 +2,231 @@ package config
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
-	"event-api/models"
+	"event-api/logging"
+	"event-api/migrations"
+)
+
+// Database drivers
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// sqliteParams make SQLite safe for concurrent registrations. SQLite has no
+// SELECT FOR UPDATE, so every transaction begins IMMEDIATE and takes the write
+// lock up front: a transaction that reads the seats and then takes one cannot
+// interleave with another. busy_timeout makes the others wait for the lock
+// instead of failing, and WAL lets reads outside transactions carry on.
+const sqliteParams = "_txlock=immediate&_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+
+// Rate limit stores
+const (
+	RateLimitStoreMemory   = "memory"
+	RateLimitStorePostgres = "postgres"
 )
 
 type Config struct {
+	// DBDriver is "postgres" or "sqlite"; SQLite keeps everything in the
+	// single file SQLitePath and suits one instance only
+	DBDriver   string
+	SQLitePath string
+
 	DBHost     string
 	DBPort     string
 	DBUser     string
 	DBPassword string
 	DBName     string
 	ServerPort string
+
+	// Timeouts of the HTTP server. Availability streams are exempt from
+	// ServerWriteTimeout.
+	ServerReadTimeout  time.Duration
+	ServerWriteTimeout time.Duration
+	ServerIdleTimeout  time.Duration
+
+	// On SIGINT or SIGTERM the server fails readiness probes for
+	// ShutdownDelay, so load balancers stop sending requests, then stops
+	// accepting them and gives those in flight and the background workers
+	// ShutdownTimeout to finish
+	ShutdownDelay   time.Duration
+	ShutdownTimeout time.Duration
+
+	// MigrateOnStart applies pending migrations when connecting; turn it off
+	// to migrate only with the migrate subcommand
+	MigrateOnStart bool
+
+	// SeatStrategy allocates seats for events with row inventory:
+	// "pessimistic", "optimistic" or "atomic"
+	SeatStrategy string
+
+	// RegistrationTimeout bounds each registration transaction, including
+	// the wait for seat locks; past it the request fails with a retryable 503
+	RegistrationTimeout time.Duration
+
+	// RateLimits limits requests per client and route group, e.g.
+	// "registrations=30/1m,users=10/1m"; groups left out are not limited.
+	// RateLimitStore is "memory", counting per replica, or "postgres",
+	// counting across replicas in the database.
+	RateLimits     string
+	RateLimitStore string
+
+	// TrustedProxies are the addresses of proxies whose X-Forwarded-For
+	// header gives the client IP; requests from others are keyed by their
+	// own address
+	TrustedProxies []string
+
+	// SMTP relay used for notifications; when SMTPHost is empty
+	// messages are written to the log instead of being sent
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	OutboxPollInterval time.Duration
+	OutboxMaxAttempts  int
+
+	NotificationPollInterval time.Duration
+	NotificationMaxAttempts  int
+
+	// How long mailed email verification and password reset tokens stay valid
+	VerificationTokenTTL  time.Duration
+	PasswordResetTokenTTL time.Duration
+
+	// How long before an event starts attendees are reminded, e.g. "168h,24h,1h"
+	ReminderOffsets      []time.Duration
+	ReminderPollInterval time.Duration
+
+	WebhookPollInterval time.Duration
+	WebhookMaxAttempts  int
+	WebhookTimeout      time.Duration
+	// Deliver webhooks to loopback and private addresses; for development only
+	WebhookAllowPrivateTargets bool
+
+	AvailabilityHeartbeatInterval time.Duration
+	// How often availability is read on SQLite, which cannot notify
+	AvailabilityPollInterval time.Duration
+
+	// Waiting room tokens are signed with WaitingRoomSecret, which must be
+	// the same on every replica; a random one is used when it is empty
+	WaitingRoomSecret        string
+	WaitingRoomAdmitBatch    int
+	WaitingRoomAdmitInterval time.Duration
+	WaitingRoomAdmissionTTL  time.Duration
+
+	InventoryRebalanceInterval time.Duration
+
+	// How often available seats are checked against registrations; 0
+	// turns the reconciler off. With ReconcileAutoCorrect drift is also fixed.
+	ReconcileInterval    time.Duration
+	ReconcileAutoCorrect bool
+
+	// Rows soft-deleted more than RetentionDays days ago are purged every
+	// RetentionPurgeInterval, after deleted users are erased; 0 days keeps
+	// them forever
+	RetentionDays          int
+	RetentionPurgeInterval time.Duration
+
+	// TracingExporter is "none", "stdout" or "otlp"; the OTLP endpoint is
+	// read from the standard OTEL_EXPORTER_OTLP_* variables. A fraction
+	// TracingSampleRatio of new traces is kept; traces started by a caller
+	// follow the caller's decision.
+	TracingExporter    string
+	TracingSampleRatio float64
+
+	// LogLevel is "debug", "info", "warn" or "error" and LogFormat "json"
+	// or "text". SQL statements are logged at debug level, or at warn level
+	// when they take longer than SlowQueryThreshold; 0 turns that off.
+	LogLevel           string
+	LogFormat          string
+	SlowQueryThreshold time.Duration
 }
 
 // LoadConfig loads configuration from environment variables
 func LoadConfig() *Config {
 	return &Config{
+		DBDriver:   getEnv("DB_DRIVER", DriverPostgres),
+		SQLitePath: getEnv("SQLITE_PATH", "eventdb.sqlite"),
+
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "5432"),
 		DBUser:     getEnv("DB_USER", "postgres"),
 		DBPassword: getEnv("DB_PASSWORD", "postgres"),
 		DBName:     getEnv("DB_NAME", "eventdb"),
 		ServerPort: getEnv("SERVER_PORT", "8080"),
+
+		ServerReadTimeout:  getEnvDuration("SERVER_READ_TIMEOUT", 15*time.Second),
+		ServerWriteTimeout: getEnvDuration("SERVER_WRITE_TIMEOUT", 30*time.Second),
+		ServerIdleTimeout:  getEnvDuration("SERVER_IDLE_TIMEOUT", 2*time.Minute),
+
+		ShutdownDelay:   getEnvDuration("SHUTDOWN_DELAY", 0),
+		ShutdownTimeout: getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+
+		MigrateOnStart: getEnvBool("MIGRATE_ON_START", true),
+
+		SeatStrategy:        getEnv("SEAT_STRATEGY", "pessimistic"),
+		RegistrationTimeout: getEnvDuration("REGISTRATION_TIMEOUT", 5*time.Second),
+
+		RateLimits:     getEnv("RATE_LIMITS", "registrations=30/1m,users=10/1m"),
+		RateLimitStore: getEnv("RATE_LIMIT_STORE", RateLimitStoreMemory),
+		TrustedProxies: getEnvList("TRUSTED_PROXIES", nil),
+
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", "no-reply@example.com"),
+
+		OutboxPollInterval: getEnvDuration("OUTBOX_POLL_INTERVAL", time.Second),
+		OutboxMaxAttempts:  getEnvInt("OUTBOX_MAX_ATTEMPTS", 10),
+
+		NotificationPollInterval: getEnvDuration("NOTIFICATION_POLL_INTERVAL", 5*time.Second),
+		NotificationMaxAttempts:  getEnvInt("NOTIFICATION_MAX_ATTEMPTS", 8),
+
+		VerificationTokenTTL:  getEnvDuration("VERIFICATION_TOKEN_TTL", 24*time.Hour),
+		PasswordResetTokenTTL: getEnvDuration("PASSWORD_RESET_TOKEN_TTL", time.Hour),
+
+		ReminderOffsets:      getEnvDurations("REMINDER_OFFSETS", []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour}),
+		ReminderPollInterval: getEnvDuration("REMINDER_POLL_INTERVAL", time.Minute),
+
+		WebhookPollInterval:        getEnvDuration("WEBHOOK_POLL_INTERVAL", 2*time.Second),
+		WebhookMaxAttempts:         getEnvInt("WEBHOOK_MAX_ATTEMPTS", 10),
+		WebhookTimeout:             getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookAllowPrivateTargets: getEnvBool("WEBHOOK_ALLOW_PRIVATE_TARGETS", false),
+
+		AvailabilityHeartbeatInterval: getEnvDuration("AVAILABILITY_HEARTBEAT_INTERVAL", 15*time.Second),
+		AvailabilityPollInterval:      getEnvDuration("AVAILABILITY_POLL_INTERVAL", time.Second),
+
+		WaitingRoomSecret:        getEnv("WAITING_ROOM_SECRET", ""),
+		WaitingRoomAdmitBatch:    getEnvInt("WAITING_ROOM_ADMIT_BATCH", 100),
+		WaitingRoomAdmitInterval: getEnvDuration("WAITING_ROOM_ADMIT_INTERVAL", 10*time.Second),
+		WaitingRoomAdmissionTTL:  getEnvDuration("WAITING_ROOM_ADMISSION_TTL", 10*time.Minute),
+
+		InventoryRebalanceInterval: getEnvDuration("INVENTORY_REBALANCE_INTERVAL", 5*time.Second),
+
+		ReconcileInterval:    getEnvDuration("RECONCILE_INTERVAL", 10*time.Minute),
+		ReconcileAutoCorrect: getEnvBool("RECONCILE_AUTO_CORRECT", false),
+
+		RetentionDays:          getEnvInt("RETENTION_DAYS", 90),
+		RetentionPurgeInterval: getEnvDuration("RETENTION_PURGE_INTERVAL", time.Hour),
+
+		TracingExporter:    getEnv("TRACING_EXPORTER", "none"),
+		TracingSampleRatio: getEnvFloat("TRACING_SAMPLE_RATIO", 1),
+
+		LogLevel:           getEnv("LOG_LEVEL", "info"),
+		LogFormat:          getEnv("LOG_FORMAT", logging.FormatJSON),
+		SlowQueryThreshold: getEnvDuration("SLOW_QUERY_THRESHOLD", 200*time.Millisecond),
 	}
 }
 
@@ -41,8 +238,127 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-// ConnectDB establishes database connection using GORM
+// getEnvList gets a comma-separated list from the environment or returns default value
+func getEnvList(key string, defaultValue []string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	var list []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
+// getEnvDuration gets a duration such as "5s" from the environment or returns default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+		slog.Warn("Ignoring invalid duration", "key", key, "value", value)
+	}
+	return defaultValue
+}
+
+// getEnvDurations gets a comma-separated list of durations from the environment or returns default value
+func getEnvDurations(key string, defaultValue []time.Duration) []time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	var durations []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := time.ParseDuration(part)
+		if err != nil {
+			slog.Warn("Ignoring invalid duration list", "key", key, "value", value)
+			return defaultValue
+		}
+		durations = append(durations, d)
+	}
+	return durations
+}
+
+// getEnvInt gets an integer from the environment or returns default value
+func getEnvInt(key string, defaultValue int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+		slog.Warn("Ignoring invalid integer", "key", key, "value", value)
+	}
+	return defaultValue
+}
+
+// getEnvFloat gets a number such as "0.25" from the environment or returns default value
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, exists := os.LookupEnv(key); exists {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+		slog.Warn("Ignoring invalid number", "key", key, "value", value)
+	}
+	return defaultValue
+}
+
+// getEnvBool gets a boolean such as "true" or "0" from the environment or returns default value
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+		slog.Warn("Ignoring invalid boolean", "key", key, "value", value)
+	}
+	return defaultValue
+}
+
+// ConnectDB establishes database connection using GORM and, with
+// MigrateOnStart, applies pending migrations
 func (c *Config) ConnectDB() (*gorm.DB, error) {
+	db, err := c.OpenDB()
+	if err != nil {
+		return nil, err
+	}
+	if !c.MigrateOnStart {
+		return db, nil
+	}
+	if err := migrations.Up(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate: %w", err)
+	}
+	slog.Info("Migrations completed")
+	return db, nil
+}
+
+// OpenDB establishes database connection using GORM without migrating
+func (c *Config) OpenDB() (*gorm.DB, error) {
+	switch c.DBDriver {
+	case DriverPostgres:
+		return c.connectPostgres()
+	case DriverSQLite:
+		db, err := OpenSQLite(c.SQLitePath, &gorm.Config{
+			Logger: logging.NewGormLogger(c.SlowQueryThreshold),
+		})
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("SQLite database opened", "path", c.SQLitePath)
+		return db, nil
+	default:
+		return nil, fmt.Errorf("unknown DB_DRIVER %q", c.DBDriver)
+	}
+}
+
+// connectPostgres creates the database if needed and connects to it
+func (c *Config) connectPostgres() (*gorm.DB, error) {
 	// First, connect to postgres database to create our database if it doesn't exist
 	defaultDSN := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=postgres sslmode=disable",
@@ -60,7 +376,7 @@ func (c *Config) ConnectDB() (*gorm.DB, error) {
 	var count int64
 	defaultDB.Raw("SELECT COUNT(*) FROM pg_database WHERE datname = ?", c.DBName).Scan(&count)
 	if count == 0 {
-		log.Printf("Creating database: %s", c.DBName)
+		slog.Info("Creating database", "name", c.DBName)
 		defaultDB.Exec(fmt.Sprintf("CREATE DATABASE %s", c.DBName))
 	}
 
@@ -75,22 +391,23 @@ func (c *Config) ConnectDB() (*gorm.DB, error) {
 	)
 
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: logging.NewGormLogger(c.SlowQueryThreshold),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	// Auto-migrate the schema
-	if err := db.AutoMigrate(
-		&models.User{},
-		&models.Event{},
-		&models.Registration{},
-	); err != nil {
-		return nil, fmt.Errorf("failed to auto migrate: %w", err)
-	}
+	slog.Info("Database connection established")
+	return db, nil
+}
 
-	log.Println("Database connection established and migrations completed")
+// OpenSQLite opens the SQLite database file at path, creating it if needed.
+// The file must be on a local disk, and only one instance may use it.
+func OpenSQLite(path string, gormConfig *gorm.Config) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open("file:"+path+"?"+sqliteParams), gormConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
 	return db, nil
 }
 
@@ -0,0 +1,87 @@
+/*
+Package credential creates and checks the secrets of user accounts: the
+single-use tokens mailed for email verification and password resets, and
+password hashes.
+
+Tokens are random and only their SHA-256 digest is stored, so they can be
+looked up by digest; being random, they need no salt or stretching.
+Passwords are hashed with PBKDF2-HMAC-SHA256 and a random salt, encoded as
+"pbkdf2-sha256$<iterations>$<salt>$<hash>" so the cost can be raised later
+without invalidating stored hashes.
+*/
+package credential
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	passwordScheme     = "pbkdf2-sha256"
+	passwordIterations = 600_000
+	passwordSaltBytes  = 16
+	passwordKeyBytes   = 32
+	tokenBytes         = 32
+)
+
+// ErrMalformedHash is returned when a stored password hash cannot be parsed
+var ErrMalformedHash = errors.New("credential: malformed password hash")
+
+// NewToken returns a random URL-safe token and the digest to store for it
+func NewToken() (token, digest string) {
+	b := make([]byte, tokenBytes)
+	_, _ = rand.Read(b)
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token)
+}
+
+// HashToken returns the hex SHA-256 digest a token is stored and looked up by
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashPassword returns the encoded hash of a password with a new salt
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, passwordSaltBytes)
+	_, _ = rand.Read(salt)
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, passwordKeyBytes)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s$%d$%s$%s", passwordScheme, passwordIterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// CheckPassword reports whether password matches an encoded hash
+func CheckPassword(password, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != passwordScheme {
+		return false, ErrMalformedHash
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false, ErrMalformedHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false, ErrMalformedHash
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(want) == 0 {
+		return false, ErrMalformedHash
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(key, want) == 1, nil
+}
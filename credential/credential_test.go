@@ -0,0 +1,42 @@
+package credential
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPasswordRoundTrip(t *testing.T) {
+	encoded, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encoded, passwordScheme+"$") || strings.Contains(encoded, "correct horse") {
+		t.Fatalf("unexpected encoding %q", encoded)
+	}
+	if other, _ := HashPassword("correct horse"); other == encoded {
+		t.Fatal("two hashes of the same password share a salt")
+	}
+
+	if ok, err := CheckPassword("correct horse", encoded); err != nil || !ok {
+		t.Fatalf("CheckPassword(right) = %v, %v; want true", ok, err)
+	}
+	if ok, err := CheckPassword("wrong horse", encoded); err != nil || ok {
+		t.Fatalf("CheckPassword(wrong) = %v, %v; want false", ok, err)
+	}
+	for _, malformed := range []string{"", "plain", "md5$1$c2FsdA$aGFzaA", passwordScheme + "$x$c2FsdA$aGFzaA"} {
+		if _, err := CheckPassword("correct horse", malformed); !errors.Is(err, ErrMalformedHash) {
+			t.Errorf("CheckPassword(%q) err = %v, want ErrMalformedHash", malformed, err)
+		}
+	}
+}
+
+func TestNewToken(t *testing.T) {
+	token, digest := NewToken()
+	if digest != HashToken(token) || len(digest) != 64 {
+		t.Fatalf("digest %q does not match token", digest)
+	}
+	if other, _ := NewToken(); other == token {
+		t.Fatal("NewToken repeated a token")
+	}
+}
@@ -0,0 +1,253 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"event-api/models"
+
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Badges are laid out two across and four down on A4 pages, in points,
+// with cut lines around each
+const (
+	pageWidth    = 595.28
+	pageHeight   = 841.89
+	pageMargin   = 28.35
+	badgeColumns = 2
+	badgeRows    = 4
+	badgeWidth   = (pageWidth - 2*pageMargin) / badgeColumns
+	badgeHeight  = (pageHeight - 2*pageMargin) / badgeRows
+	badgePadding = 16
+	qrSize       = 84
+
+	badgesPerPage = badgeColumns * badgeRows
+)
+
+// Objects written before the pages; the page tree comes last, when all its
+// pages are known
+const (
+	objCatalog = 1
+	objPages   = 2
+	objFont    = 3
+	objBold    = 4
+	firstPage  = 5
+)
+
+// CheckInPath is what a badge's QR code holds: the API path that checks its
+// registration in
+func CheckInPath(registrationID uint) string {
+	return fmt.Sprintf("/api/v1/registrations/%d/check-in", registrationID)
+}
+
+// badgeWriter writes a PDF of name badges a page at a time. Only the offsets
+// of objects written so far and the current page are kept in memory.
+type badgeWriter struct {
+	w       *countingWriter
+	event   *models.Event
+	offsets []int64 // by object number; 0 is the free list head
+	pages   []int   // page object numbers
+	pending []models.Attendee
+}
+
+func newBadgeWriter(w io.Writer, event *models.Event) (*badgeWriter, error) {
+	bw := &badgeWriter{
+		w:       &countingWriter{w: bufio.NewWriter(w)},
+		event:   event,
+		offsets: make([]int64, firstPage),
+	}
+	// The binary comment marks the file as binary for transfer tools
+	bw.w.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	bw.object(objCatalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", objPages))
+	bw.object(objFont, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	bw.object(objBold, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	return bw, bw.w.err
+}
+
+func (bw *badgeWriter) Write(attendee models.Attendee) error {
+	bw.pending = append(bw.pending, attendee)
+	if len(bw.pending) == badgesPerPage {
+		return bw.writePage()
+	}
+	return bw.w.err
+}
+
+func (bw *badgeWriter) Close() error {
+	// An export without attendees is still a valid, blank document
+	if len(bw.pending) > 0 || len(bw.pages) == 0 {
+		if err := bw.writePage(); err != nil {
+			return err
+		}
+	}
+
+	kids := make([]string, len(bw.pages))
+	for i, page := range bw.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+	}
+	bw.object(objPages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(bw.pages)))
+
+	xref := bw.w.n
+	fmt.Fprintf(bw.w, "xref\n0 %d\n0000000000 65535 f \n", len(bw.offsets))
+	for _, offset := range bw.offsets[1:] {
+		fmt.Fprintf(bw.w, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(bw.w, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(bw.offsets), objCatalog, xref)
+	if bw.w.err != nil {
+		return bw.w.err
+	}
+	return bw.w.w.Flush()
+}
+
+// writePage writes the pending badges as the next page
+func (bw *badgeWriter) writePage() error {
+	var content bytes.Buffer
+	for i, attendee := range bw.pending {
+		x := pageMargin + float64(i%badgeColumns)*badgeWidth
+		y := pageHeight - pageMargin - float64(i/badgeColumns+1)*badgeHeight
+		if err := bw.drawBadge(&content, x, y, attendee); err != nil {
+			return err
+		}
+	}
+	bw.pending = bw.pending[:0]
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(content.Bytes())
+	zw.Close()
+
+	contents := len(bw.offsets)
+	page := contents + 1
+	bw.offsets = append(bw.offsets, 0, 0)
+	bw.object(contents, fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.Bytes()))
+	bw.object(page, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+		objPages, num(pageWidth), num(pageHeight), objFont, objBold, contents))
+	bw.pages = append(bw.pages, page)
+	return bw.w.err
+}
+
+// drawBadge draws one badge with its lower left corner at x, y
+func (bw *badgeWriter) drawBadge(content *bytes.Buffer, x, y float64, attendee models.Attendee) error {
+	// Cut lines
+	fmt.Fprintf(content, "q 0.6 G 0.5 w [3 3] 0 d %s %s %s %s re S Q\n", num(x), num(y), num(badgeWidth), num(badgeHeight))
+
+	// Text is clipped to the badge, so nothing runs onto its neighbours
+	fmt.Fprintf(content, "q %s %s %s %s re W n\n", num(x), num(y), num(badgeWidth), num(badgeHeight))
+	textWidth := badgeWidth - 2*badgePadding
+	top := y + badgeHeight - badgePadding
+	title := bw.event.Title
+	text(content, "F1", fitSize(title, 11, 7, textWidth), x+badgePadding, top-11, "0.35 g", title)
+	if bw.event.StartsAt != nil {
+		text(content, "F1", 9, x+badgePadding, top-25, "0.35 g", bw.event.StartsAt.UTC().Format("Monday, January 2, 2006"))
+	}
+	name := attendee.Name
+	if name == "" {
+		name = "Guest"
+	}
+	text(content, "F2", fitSize(name, 26, 12, textWidth), x+badgePadding, y+badgeHeight/2+20, "0 g", name)
+	text(content, "F1", 9, x+badgePadding, y+badgePadding, "0.35 g", fmt.Sprintf("Registration #%d", attendee.RegistrationID))
+	content.WriteString("Q\n")
+
+	// The padding leaves scanners the quiet zone the code needs around it
+	return drawQR(content, x+badgeWidth-badgePadding-qrSize, y+badgePadding, CheckInPath(attendee.RegistrationID))
+}
+
+// drawQR draws a QR code of value, qrSize points across, with its lower left
+// corner at x, y. Each run of dark modules in a row is one rectangle.
+func drawQR(content *bytes.Buffer, x, y float64, value string) error {
+	code, err := qrcode.New(value, qrcode.Medium)
+	if err != nil {
+		return err
+	}
+	code.DisableBorder = true
+	modules := code.Bitmap()
+	size := qrSize / float64(len(modules))
+
+	content.WriteString("0 g\n")
+	for r, row := range modules {
+		for c := 0; c < len(row); {
+			if !row[c] {
+				c++
+				continue
+			}
+			start := c
+			for c < len(row) && row[c] {
+				c++
+			}
+			fmt.Fprintf(content, "%s %s %s %s re\n",
+				num(x+float64(start)*size), num(y+float64(len(modules)-1-r)*size), num(float64(c-start)*size), num(size))
+		}
+	}
+	content.WriteString("f\n")
+	return nil
+}
+
+// text draws s at x, y in a font, size and fill color
+func text(content *bytes.Buffer, font string, size, x, y float64, color, s string) {
+	fmt.Fprintf(content, "%s BT /%s %s Tf %s %s Td (%s) Tj ET\n", color, font, num(size), num(x), num(y), pdfString(s))
+}
+
+// fitSize returns the largest font size from largest down to smallest at
+// which s likely fits in width. Helvetica averages under 0.6 em per character.
+func fitSize(s string, largest, smallest, width float64) float64 {
+	size := width / (0.6 * float64(max(utf8.RuneCountInString(s), 1)))
+	return min(max(size, smallest), largest)
+}
+
+// pdfString escapes s for a literal string in the fonts' WinAnsi encoding.
+// Characters the encoding lacks become question marks.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		c, ok := charmap.Windows1252.EncodeRune(r)
+		if !ok || c < ' ' {
+			c = '?'
+		}
+		if c == '(' || c == ')' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// num formats a coordinate with at most two decimals
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+// object writes indirect object n with the given body and records its offset
+func (bw *badgeWriter) object(n int, body string) {
+	bw.offsets[n] = bw.w.n
+	fmt.Fprintf(bw.w, "%d 0 obj\n%s\nendobj\n", n, body)
+}
+
+// countingWriter counts the bytes written, for the cross-reference table,
+// and keeps the first error
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
+
+func (cw *countingWriter) WriteString(s string) (int, error) {
+	return cw.Write([]byte(s))
+}
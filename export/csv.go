@@ -0,0 +1,48 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"event-api/models"
+)
+
+// csvWriter writes attendees as CSV rows
+type csvWriter struct {
+	w       *csv.Writer
+	columns []models.AttendeeColumn
+	record  []string
+}
+
+func newCSVWriter(w io.Writer, columns []models.AttendeeColumn) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}
+	for i, column := range columns {
+		cw.record[i] = string(column)
+	}
+	if err := cw.w.Write(cw.record); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) Write(attendee models.Attendee) error {
+	for i, column := range cw.columns {
+		cw.record[i] = defuseFormula(column.Value(attendee))
+	}
+	return cw.w.Write(cw.record)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// defuseFormula keeps spreadsheets from running a value as a formula, since
+// attendees choose their own names
+func defuseFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
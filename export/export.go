@@ -0,0 +1,76 @@
+// Package export writes attendee lists as CSV, XLSX or PDF name badges. Each
+// writer takes one attendee at a time and writes as it goes, so the size of
+// an export is bounded by the client rather than by memory.
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"event-api/models"
+)
+
+// Format is a file format of an attendee export
+type Format string
+
+// Export formats
+const (
+	FormatCSV    Format = "csv"
+	FormatXLSX   Format = "xlsx"
+	FormatBadges Format = "badges"
+)
+
+// ParseFormat parses a format name; an empty name means CSV
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(name); format {
+	case "":
+		return FormatCSV, nil
+	case FormatCSV, FormatXLSX, FormatBadges:
+		return format, nil
+	}
+	return "", fmt.Errorf("%w: unknown format %q, want csv, xlsx or badges", models.ErrInvalidInput, name)
+}
+
+// ContentType returns the MIME type of files in the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatBadges:
+		return "application/pdf"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Filename returns the download name of an event's export in the format
+func (f Format) Filename(eventID uint) string {
+	switch f {
+	case FormatXLSX:
+		return fmt.Sprintf("event-%d-attendees.xlsx", eventID)
+	case FormatBadges:
+		return fmt.Sprintf("event-%d-badges.pdf", eventID)
+	}
+	return fmt.Sprintf("event-%d-attendees.csv", eventID)
+}
+
+// Writer writes the attendees of an export one at a time. The file is only
+// complete once Close returns without error.
+type Writer interface {
+	Write(attendee models.Attendee) error
+	Close() error
+}
+
+// NewWriter starts an export of event's attendees in format to w. Lists have
+// the given columns, under a header row; badges show the attendee's name, the
+// event and a QR code of the registration's check-in path.
+func NewWriter(format Format, w io.Writer, event *models.Event, columns []models.AttendeeColumn) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatXLSX:
+		return newXLSXWriter(w, columns)
+	case FormatBadges:
+		return newBadgeWriter(w, event)
+	}
+	return nil, fmt.Errorf("%w: unknown format %q", models.ErrInvalidInput, format)
+}
@@ -0,0 +1,221 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"event-api/models"
+)
+
+var (
+	registeredAt = time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+	checkedInAt  = time.Date(2026, 3, 20, 18, 5, 0, 0, time.UTC)
+	attendees    = []models.Attendee{
+		{RegistrationID: 7, UserID: 3, Name: "Ada Lovelace", Email: "ada@example.com", RegisteredAt: registeredAt, CheckedInAt: &checkedInAt,
+			Answers: map[string]string{"Diet": "vegan", "T-shirt": "M"}},
+		{RegistrationID: 9, UserID: 4, Name: "=HYPERLINK(\"http://evil\")", Email: "eve@example.com", RegisteredAt: registeredAt},
+	}
+	exportEvent = &models.Event{ID: 1, Title: "Analytical Engines (Part 2)"}
+)
+
+// exportAll writes attendees in format and returns the file
+func exportAll(t *testing.T, format Format, columns []models.AttendeeColumn, attendees []models.Attendee) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf, exportEvent, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, attendee := range attendees {
+		if err := w.Write(attendee); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSV(t *testing.T) {
+	columns := []models.AttendeeColumn{models.ColumnRegistrationID, models.ColumnName, models.ColumnCheckedIn, models.ColumnCheckedInAt,
+		models.ColumnAnswers, models.AttendeeColumn("answer:Diet")}
+	records, err := csv.NewReader(bytes.NewReader(exportAll(t, FormatCSV, columns, attendees))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"registration_id", "name", "checked_in", "checked_in_at", "answers", "answer:Diet"},
+		{"7", "Ada Lovelace", "yes", "2026-03-20T18:05:00Z", "Diet: vegan\nT-shirt: M", "vegan"},
+		{"9", "'=HYPERLINK(\"http://evil\")", "no", "", "", ""},
+	}
+	if fmt.Sprint(records) != fmt.Sprint(want) {
+		t.Errorf("records = %q, want %q", records, want)
+	}
+}
+
+func TestXLSX(t *testing.T) {
+	columns := []models.AttendeeColumn{models.ColumnRegistrationID, models.ColumnName, models.ColumnRegisteredAt, models.ColumnCheckedInAt,
+		models.ColumnAnswers}
+	file := exportAll(t, FormatXLSX, columns, attendees)
+	archive, err := zip.NewReader(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[string][]byte{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parts[f.Name], err = io.ReadAll(r); err != nil {
+			t.Fatal(err)
+		}
+		// Every part must be well-formed XML
+		d := xml.NewDecoder(bytes.NewReader(parts[f.Name]))
+		for {
+			if _, err := d.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: %v", f.Name, err)
+			}
+		}
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref   string `xml:"r,attr"`
+				Type  string `xml:"t,attr"`
+				Value string `xml:"v"`
+				Text  string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatal(err)
+	}
+	if len(sheet.Rows) != 3 {
+		t.Fatalf("%d rows, want a header and 2 attendees", len(sheet.Rows))
+	}
+	cells := map[string]string{}
+	for _, row := range sheet.Rows {
+		for _, c := range row.Cells {
+			cells[c.Ref] = c.Type + ":" + c.Value + c.Text
+		}
+	}
+	serial, _ := strconv.ParseFloat(strings.TrimPrefix(cells["C2"], ":"), 64)
+	if got := excelEpoch.Add(time.Duration(serial * 24 * float64(time.Hour))).Round(time.Second); !got.Equal(registeredAt) {
+		t.Errorf("registered_at serial %v is %v, want %v", serial, got, registeredAt)
+	}
+	for ref, want := range map[string]string{
+		"A1": "inlineStr:registration_id",
+		"A2": ":7",
+		"B3": `inlineStr:=HYPERLINK("http://evil")`,
+		"E2": "inlineStr:Diet: vegan\nT-shirt: M",
+	} {
+		if cells[ref] != want {
+			t.Errorf("cell %s = %q, want %q", ref, cells[ref], want)
+		}
+	}
+	if _, ok := cells["D3"]; ok {
+		t.Error("empty check-in time written as a cell")
+	}
+	if got := columnName(27); got != "AB" {
+		t.Errorf("columnName(27) = %q, want AB", got)
+	}
+}
+
+var objectHeader = regexp.MustCompile(`^(\d+) 0 obj\n`)
+
+func TestBadges(t *testing.T) {
+	many := make([]models.Attendee, 0, badgesPerPage+1)
+	for i := range badgesPerPage + 1 {
+		many = append(many, models.Attendee{RegistrationID: uint(100 + i), Name: fmt.Sprintf("Zoë (%d)", i)})
+	}
+	for _, tc := range []struct {
+		name      string
+		attendees []models.Attendee
+		pages     int
+	}{
+		{"empty", nil, 1},
+		{"two pages", many, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			file := exportAll(t, FormatBadges, nil, tc.attendees)
+			if !bytes.HasPrefix(file, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(file, []byte("%%EOF\n")) {
+				t.Fatal("not a PDF")
+			}
+
+			// Every cross-reference entry points at its object
+			startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(file)
+			xref, _ := strconv.Atoi(string(startxref[1]))
+			entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(file[xref:], -1)
+			for i, entry := range entries {
+				offset, _ := strconv.Atoi(string(entry[1]))
+				header := objectHeader.FindSubmatch(file[offset:])
+				if header == nil || string(header[1]) != strconv.Itoa(i+1) {
+					t.Fatalf("xref entry %d points at %q", i+1, file[offset:min(offset+20, len(file))])
+				}
+			}
+			if !bytes.Contains(file, fmt.Appendf(nil, "/Count %d", tc.pages)) {
+				t.Errorf("page tree does not count %d pages", tc.pages)
+			}
+
+			// Page contents carry the names and a QR code per badge
+			var content bytes.Buffer
+			for _, stream := range regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`).FindAllSubmatch(file, -1) {
+				r, err := zlib.NewReader(bytes.NewReader(stream[1]))
+				if err != nil {
+					t.Fatal(err)
+				}
+				io.Copy(&content, r)
+			}
+			for _, attendee := range tc.attendees {
+				if !bytes.Contains(content.Bytes(), []byte(pdfString(attendee.Name))) {
+					t.Errorf("no badge for %q", attendee.Name)
+				}
+			}
+			if got := strings.Count(content.String(), "\nf\n"); got != len(tc.attendees) {
+				t.Errorf("%d QR codes, want %d", got, len(tc.attendees))
+			}
+		})
+	}
+}
+
+func TestPDFString(t *testing.T) {
+	if got, want := pdfString("Zoë (née) \\ 日本"), "Zo\xeb \\(n\xe9e\\) \\\\ ??"; got != want {
+		t.Errorf("pdfString = %q, want %q", got, want)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if format, err := ParseFormat(""); err != nil || format != FormatCSV {
+		t.Errorf("ParseFormat(\"\") = %q, %v; want csv", format, err)
+	}
+	if _, err := ParseFormat("pdf"); err == nil {
+		t.Error("ParseFormat accepted pdf")
+	}
+	if _, err := models.ParseAttendeeColumns("name,shoe_size"); err == nil {
+		t.Error("ParseAttendeeColumns accepted an unknown column")
+	}
+	if _, err := models.ParseAttendeeColumns("name,answer: "); err == nil {
+		t.Error("ParseAttendeeColumns accepted an answer column without a question")
+	}
+	if columns, err := models.ParseAttendeeColumns("answers, answer:Diet"); err != nil || columns[1].Question() != "Diet" {
+		t.Errorf("ParseAttendeeColumns(answers, answer:Diet) = %q, %v", columns, err)
+	}
+	if _, err := models.ParseAttendeeColumns("name, name"); err == nil {
+		t.Error("ParseAttendeeColumns accepted a repeated column")
+	}
+}
@@ -0,0 +1,161 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+
+	"event-api/models"
+)
+
+// The parts of a workbook with a single worksheet, apart from the worksheet
+// itself. Style 1 is the bold header and style 2 a date and time.
+var xlsxParts = []struct{ name, body string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Attendees" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`},
+	{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/><xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs></styleSheet>`},
+}
+
+const (
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+
+	xlsxStyleHeader = "1"
+	xlsxStyleTime   = "2"
+)
+
+// excelEpoch is day 0 of Excel's date serial numbers
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// xlsxWriter writes attendees as rows of a worksheet. Cells are inline
+// strings, numbers or dates in UTC, so no shared string table has to be
+// kept until the end.
+type xlsxWriter struct {
+	archive *zip.Writer
+	sheet   *bufio.Writer
+	columns []models.AttendeeColumn
+	row     int
+}
+
+func newXLSXWriter(w io.Writer, columns []models.AttendeeColumn) (*xlsxWriter, error) {
+	archive := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		f, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+	// The worksheet is the last part, so it can stay open while rows come in
+	f, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	xw := &xlsxWriter{archive: archive, sheet: bufio.NewWriter(f), columns: columns}
+	xw.sheet.WriteString(xlsxSheetStart)
+
+	xw.startRow()
+	for i, column := range columns {
+		xw.stringCell(i, string(column), xlsxStyleHeader)
+	}
+	xw.sheet.WriteString("</row>")
+	return xw, nil
+}
+
+func (xw *xlsxWriter) Write(attendee models.Attendee) error {
+	xw.startRow()
+	for i, column := range xw.columns {
+		switch column {
+		case models.ColumnRegistrationID, models.ColumnUserID:
+			xw.cell(i, "", "", column.Value(attendee))
+		case models.ColumnRegisteredAt:
+			xw.timeCell(i, &attendee.RegisteredAt)
+		case models.ColumnCheckedInAt:
+			xw.timeCell(i, attendee.CheckedInAt)
+		default:
+			xw.stringCell(i, column.Value(attendee), "")
+		}
+	}
+	_, err := xw.sheet.WriteString("</row>")
+	return err
+}
+
+func (xw *xlsxWriter) Close() error {
+	xw.sheet.WriteString(xlsxSheetEnd)
+	if err := xw.sheet.Flush(); err != nil {
+		return err
+	}
+	return xw.archive.Close()
+}
+
+// startRow opens the next row
+func (xw *xlsxWriter) startRow() {
+	xw.row++
+	xw.sheet.WriteString(`<row r="`)
+	xw.sheet.WriteString(strconv.Itoa(xw.row))
+	xw.sheet.WriteString(`">`)
+}
+
+// cell writes the cell of column i in the current row, with a type and
+// style unless they are empty, and value as its raw content
+func (xw *xlsxWriter) cell(i int, cellType, style, value string) {
+	xw.sheet.WriteString(`<c r="`)
+	xw.sheet.WriteString(columnName(i))
+	xw.sheet.WriteString(strconv.Itoa(xw.row))
+	if cellType != "" {
+		xw.sheet.WriteString(`" t="`)
+		xw.sheet.WriteString(cellType)
+	}
+	if style != "" {
+		xw.sheet.WriteString(`" s="`)
+		xw.sheet.WriteString(style)
+	}
+	xw.sheet.WriteString(`">`)
+	if cellType == "inlineStr" {
+		xw.sheet.WriteString(`<is><t xml:space="preserve">`)
+		xml.EscapeText(xw.sheet, []byte(value))
+		xw.sheet.WriteString(`</t></is></c>`)
+		return
+	}
+	xw.sheet.WriteString(`<v>`)
+	xw.sheet.WriteString(value)
+	xw.sheet.WriteString(`</v></c>`)
+}
+
+// stringCell writes a text cell; empty text leaves the cell out
+func (xw *xlsxWriter) stringCell(i int, value, style string) {
+	if value != "" {
+		xw.cell(i, "inlineStr", style, value)
+	}
+}
+
+// timeCell writes a date and time cell; a nil time leaves the cell out
+func (xw *xlsxWriter) timeCell(i int, t *time.Time) {
+	if t == nil {
+		return
+	}
+	days := t.UTC().Sub(excelEpoch).Seconds() / (24 * 60 * 60)
+	xw.cell(i, "", xlsxStyleTime, strconv.FormatFloat(days, 'f', -1, 64))
+}
+
+// columnName returns the letters of the zero-based column i: A, B, ..., AA
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"event-api/logging"
+	"event-api/models"
+	"event-api/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UserIDHeader identifies the user making a request. Authentication happens
+// in front of the API, which sets it for authenticated callers.
+const UserIDHeader = "X-User-ID"
+
+// requestActor returns who is making a state-changing request. It responds
+// with 400 and returns false when the user ID header is malformed.
+func requestActor(c *gin.Context) (models.Actor, bool) {
+	actor := models.Actor{RequestID: logging.RequestID(c.Request.Context()), IP: c.ClientIP()}
+	if c.GetHeader(UserIDHeader) == "" {
+		return actor, true
+	}
+	userID, ok := requestUserID(c)
+	if !ok {
+		return actor, false
+	}
+	actor.UserID = &userID
+	return actor, true
+}
+
+// requestUserID parses the user ID header. It responds with 400 and
+// returns false when the header is missing or malformed.
+func requestUserID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.GetHeader(UserIDHeader), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or missing " + UserIDHeader + " header"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// RequireAdmin lets a request through only when the user ID header names an
+// active admin. It responds with 401 for an unknown user and 403 for anyone
+// else.
+func RequireAdmin(userService service.UserService) gin.HandlerFunc {
+	return requireUser(userService, "only admins may use this endpoint", func(_ *gin.Context, user *models.User) bool {
+		return isActiveAdmin(user)
+	})
+}
+
+// RequireOrganizer lets a request to an /organizers/:organizerID route
+// through only when the user ID header names that organizer or an active
+// admin. It responds with 401 for an unknown user and 403 for anyone else.
+func RequireOrganizer(userService service.UserService) gin.HandlerFunc {
+	return requireUser(userService, "only the organizer or an admin may use this endpoint", func(c *gin.Context, user *models.User) bool {
+		organizerID, err := strconv.ParseUint(c.Param("organizerID"), 10, 32)
+		return err == nil && (uint(organizerID) == user.ID || isActiveAdmin(user))
+	})
+}
+
+// requireUser lets a request through when allow accepts the user named by
+// the user ID header, and responds with 403 and message otherwise
+func requireUser(userService service.UserService, message string, allow func(c *gin.Context, user *models.User) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := requestUserID(c)
+		if !ok {
+			c.Abort()
+			return
+		}
+		user, err := userService.GetUserByID(c.Request.Context(), userID)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": models.ErrUserNotFound.Error()})
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		case !allow(c, user):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": message})
+		default:
+			c.Next()
+		}
+	}
+}
+
+// isActiveAdmin reports whether user may act as an admin
+func isActiveAdmin(user *models.User) bool {
+	return user.Role == models.RoleAdmin && user.Active()
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"event-api/models"
+	"event-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler handles HTTP requests for the audit log
+type AuditHandler struct {
+	auditService service.AuditService
+}
+
+// NewAuditHandler creates a new AuditHandler
+func NewAuditHandler(auditService service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// GetAuditEvents handles GET /audit-events. The caller, identified by the
+// X-User-ID header, must be an admin or organizer. Query parameters filter
+// the result: actor_id, action, target_type, target_id, event_id,
+// request_id, since and until (RFC 3339), and before_id and limit to page
+// backwards from the newest entry.
+func (h *AuditHandler) GetAuditEvents(c *gin.Context) {
+	viewerID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+
+	filter := models.AuditFilter{
+		Action:     models.Action(c.Query("action")),
+		TargetType: c.Query("target_type"),
+		RequestID:  c.Query("request_id"),
+	}
+	for _, param := range []struct {
+		name  string
+		field *uint
+	}{
+		{"actor_id", &filter.ActorID},
+		{"target_id", &filter.TargetID},
+		{"event_id", &filter.EventID},
+		{"before_id", &filter.BeforeID},
+	} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param.name})
+			return
+		}
+		*param.field = uint(id)
+	}
+	for _, param := range []struct {
+		name  string
+		field *time.Time
+	}{
+		{"since", &filter.Since},
+		{"until", &filter.Until},
+	} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": param.name + " must be an RFC 3339 time"})
+			return
+		}
+		*param.field = t
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := h.auditService.GetAuditEvents(c.Request.Context(), viewerID, filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrUserNotFound):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, models.ErrUnauthorized):
+			c.JSON(http.StatusForbidden, gin.H{"error": "only admins and organizers may read the audit log"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"event-api/availability"
+	"event-api/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AvailabilityHandler streams live seat availability
+type AvailabilityHandler struct {
+	eventService      service.EventService
+	broker            *availability.Broker
+	heartbeatInterval time.Duration
+}
+
+// NewAvailabilityHandler creates a new AvailabilityHandler
+func NewAvailabilityHandler(eventService service.EventService, broker *availability.Broker, heartbeatInterval time.Duration) *AvailabilityHandler {
+	return &AvailabilityHandler{
+		eventService:      eventService,
+		broker:            broker,
+		heartbeatInterval: heartbeatInterval,
+	}
+}
+
+// StreamAvailability handles GET /events/:id/availability/stream
+func (h *AvailabilityHandler) StreamAvailability(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+		return
+	}
+
+	// Subscribe before reading the current state so no change can slip in between
+	sub := h.broker.Subscribe(uint(id))
+	defer sub.Close()
+
+	current, err := h.eventService.GetAvailability(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// A stream outlives the server's write timeout, which is meant for
+	// ordinary responses; recorders in tests do not support deadlines
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	// A resuming client only needs the current state if it changed since the
+	// last version it saw
+	lastSent := availability.ParseLastEventID(c.GetHeader("Last-Event-ID"))
+	if current.Version != lastSent {
+		if err := availability.WriteUpdate(c.Writer, *current); err != nil {
+			return
+		}
+		lastSent = current.Version
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-h.broker.Done():
+			// The server is shutting down
+			return
+		case <-heartbeat.C:
+			if err := availability.WriteHeartbeat(c.Writer); err != nil {
+				return
+			}
+		case update := <-sub.Updates():
+			// Notifications can repeat or arrive out of order after a resync
+			if update.Version <= lastSent {
+				continue
+			}
+			if err := availability.WriteUpdate(c.Writer, update); err != nil {
+				return
+			}
+			lastSent = update.Version
+		}
+		c.Writer.Flush()
+	}
+}
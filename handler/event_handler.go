@@ -39,7 +39,19 @@ func (h *EventHandler) CreateEvent(c *gin.Context) {
 	// Set available seats equal to capacity
 	event.AvailableSeats = event.Capacity
 
-	if err := h.eventService.CreateEvent(&event); err != nil {
+	// Events start as drafts; use POST /events/:id/publish to publish
+	event.PublishedAt = nil
+
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+
+	if err := h.eventService.CreateEvent(c.Request.Context(), actor, &event); err != nil {
+		if errors.Is(err, models.ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -55,7 +67,7 @@ func (h *EventHandler) GetEvent(c *gin.Context) {
 		return
 	}
 
-	event, err := h.eventService.GetEventByID(uint(id))
+	event, err := h.eventService.GetEventByID(c.Request.Context(), uint(id))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
@@ -70,7 +82,7 @@ func (h *EventHandler) GetEvent(c *gin.Context) {
 
 // GetAllEvents handles GET /events
 func (h *EventHandler) GetAllEvents(c *gin.Context) {
-	events, err := h.eventService.GetAllEvents()
+	events, err := h.eventService.GetAllEvents(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -87,7 +99,7 @@ func (h *EventHandler) GetOrganizerEvents(c *gin.Context) {
 		return
 	}
 
-	events, err := h.eventService.GetEventsByOrganizerID(uint(organizerID))
+	events, err := h.eventService.GetEventsByOrganizerID(c.Request.Context(), uint(organizerID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -112,25 +124,46 @@ func (h *EventHandler) UpdateEvent(c *gin.Context) {
 
 	event.ID = uint(id)
 
-	// Don't allow updating capacity to less than current registrations
-	existingEvent, err := h.eventService.GetEventByID(uint(id))
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+	actor, ok := requestActor(c)
+	if !ok {
 		return
 	}
 
-	// If capacity is being reduced, check that it doesn't go below current registrations
-	if event.Capacity < existingEvent.Capacity {
-		registrationsCount := existingEvent.Capacity - existingEvent.AvailableSeats
-		if event.Capacity < registrationsCount {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot reduce capacity below current registrations"})
-			return
+	// The service keeps available seats in step with capacity
+	if err := h.eventService.UpdateEvent(c.Request.Context(), actor, &event); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		case errors.Is(err, models.ErrCapacityTooLow), errors.Is(err, models.ErrInvalidInput):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
-		// Recalculate available seats
-		event.AvailableSeats = event.Capacity - registrationsCount
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+// PublishEvent handles POST /events/:id/publish
+func (h *EventHandler) PublishEvent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+		return
+	}
+
+	actor, ok := requestActor(c)
+	if !ok {
+		return
 	}
 
-	if err := h.eventService.UpdateEvent(&event); err != nil {
+	event, err := h.eventService.PublishEvent(c.Request.Context(), actor, uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -146,7 +179,16 @@ func (h *EventHandler) DeleteEvent(c *gin.Context) {
 		return
 	}
 
-	if err := h.eventService.DeleteEvent(uint(id)); err != nil {
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+
+	if err := h.eventService.DeleteEvent(c.Request.Context(), actor, uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout bounds the database ping of a readiness probe
+const readinessTimeout = 2 * time.Second
+
+// HealthHandler answers liveness and readiness probes
+type HealthHandler struct {
+	ping     func(ctx context.Context) error
+	draining atomic.Bool
+}
+
+// NewHealthHandler creates a HealthHandler that is ready while ping, such
+// as sql.DB.PingContext, succeeds
+func NewHealthHandler(ping func(ctx context.Context) error) *HealthHandler {
+	return &HealthHandler{ping: ping}
+}
+
+// Livez handles GET /livez. The process answers, so it is alive; a database
+// outage is not a reason to restart it.
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz handles GET /readyz: 200 while the database answers, 503 when it
+// does not or the server is shutting down
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+	if err := h.ping(ctx); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "error": "database: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
+
+// Drain fails every readiness probe from now on, so load balancers stop
+// sending requests before the server stops accepting them
+func (h *HealthHandler) Drain() {
+	h.draining.Store(true)
+}
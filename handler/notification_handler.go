@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"event-api/models"
+	"event-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler handles HTTP requests for notification templates
+type NotificationHandler struct {
+	notificationService service.NotificationService
+}
+
+// NewNotificationHandler creates a new NotificationHandler
+func NewNotificationHandler(notificationService service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+
+// GetTemplates handles GET /organizers/:organizerID/templates
+func (h *NotificationHandler) GetTemplates(c *gin.Context) {
+	organizerID, err := strconv.ParseUint(c.Param("organizerID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organizer ID"})
+		return
+	}
+
+	templates, err := h.notificationService.GetTemplates(c.Request.Context(), uint(organizerID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// SaveTemplate handles PUT /organizers/:organizerID/templates/:type
+func (h *NotificationHandler) SaveTemplate(c *gin.Context) {
+	organizerID, err := strconv.ParseUint(c.Param("organizerID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organizer ID"})
+		return
+	}
+
+	var template models.NotificationTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template.OrganizerID = uint(organizerID)
+	template.Type = models.NotificationType(c.Param("type"))
+
+	if err := h.notificationService.SaveTemplate(c.Request.Context(), &template); err != nil {
+		if errors.Is(err, models.ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// DeleteTemplate handles DELETE /organizers/:organizerID/templates/:type
+func (h *NotificationHandler) DeleteTemplate(c *gin.Context) {
+	organizerID, err := strconv.ParseUint(c.Param("organizerID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organizer ID"})
+		return
+	}
+
+	err = h.notificationService.DeleteTemplate(c.Request.Context(), uint(organizerID), models.NotificationType(c.Param("type")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "template deleted successfully"})
+}
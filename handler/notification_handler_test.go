@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"event-api/internal/testdb"
+	"event-api/models"
+	"event-api/repository"
+	"event-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTemplateRoutesRequireTheOrganizer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	db := testdb.NewSQLite(t)
+	users := service.NewUserService(repository.NewTransactor(db), repository.NewUserRepository(db),
+		repository.NewTokenRepository(db), repository.NewAuditRepository(db))
+	h := NewNotificationHandler(service.NewNotificationService(repository.NewNotificationRepository(db)))
+
+	router := gin.New()
+	templates := router.Group("/organizers/:organizerID/templates", RequireOrganizer(users))
+	templates.GET("", h.GetTemplates)
+	templates.PUT("/:type", h.SaveTemplate)
+	templates.DELETE("/:type", h.DeleteTemplate)
+
+	ids := map[string]uint{}
+	for name, role := range map[string]models.UserRole{"organizer": models.RoleOrganizer, "other": models.RoleOrganizer, "admin": models.RoleAdmin} {
+		user := &models.User{Name: name, Email: name + "@example.com", Role: role}
+		if err := users.CreateUser(ctx, models.Actor{}, user); err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = user.ID
+	}
+	base := "/organizers/" + strconv.FormatUint(uint64(ids["organizer"]), 10) + "/templates"
+	confirmed := base + "/" + string(models.NotificationRegistrationConfirmed)
+
+	request := func(method, path, userID, body string) int {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if userID != "" {
+			req.Header.Set(UserIDHeader, userID)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	as := func(name string) string { return strconv.FormatUint(uint64(ids[name]), 10) }
+	override := `{"subject": "See you at {{.Event.Title}}"}`
+
+	for _, tc := range []struct {
+		name         string
+		method, path string
+		userID, body string
+		want         int
+	}{
+		{"anonymous", http.MethodPut, confirmed, "", override, http.StatusBadRequest},
+		{"unknown user", http.MethodPut, confirmed, "999", override, http.StatusUnauthorized},
+		{"another organizer saves", http.MethodPut, confirmed, as("other"), override, http.StatusForbidden},
+		{"the organizer saves", http.MethodPut, confirmed, as("organizer"), override, http.StatusOK},
+		{"another organizer lists", http.MethodGet, base, as("other"), "", http.StatusForbidden},
+		{"another organizer deletes", http.MethodDelete, confirmed, as("other"), "", http.StatusForbidden},
+		{"an admin lists", http.MethodGet, base, as("admin"), "", http.StatusOK},
+		{"the organizer deletes", http.MethodDelete, confirmed, as("organizer"), "", http.StatusOK},
+	} {
+		if got := request(tc.method, tc.path, tc.userID, tc.body); got != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"event-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReconciliationHandler handles HTTP requests for seat counter reconciliation
+type ReconciliationHandler struct {
+	reconciliationService service.ReconciliationService
+}
+
+// NewReconciliationHandler creates a new ReconciliationHandler
+func NewReconciliationHandler(reconciliationService service.ReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{reconciliationService: reconciliationService}
+}
+
+// GetReconciliation handles GET /admin/reconciliation
+func (h *ReconciliationHandler) GetReconciliation(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"last_report": h.reconciliationService.LastReport(),
+		"stats":       h.reconciliationService.Stats(),
+	})
+}
+
+// Reconcile handles POST /admin/reconciliation?correct=true; without
+// correct it only reports drift
+func (h *ReconciliationHandler) Reconcile(c *gin.Context) {
+	correct, err := strconv.ParseBool(c.DefaultQuery("correct", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "correct must be true or false"})
+		return
+	}
+
+	report, err := h.reconciliationService.Reconcile(c.Request.Context(), correct)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -15,17 +15,30 @@ import (
 // RegistrationHandler handles HTTP requests for registrations
 type RegistrationHandler struct {
 	registrationService service.RegistrationService
+	waitingRoomService  service.WaitingRoomService
 }
 
 // NewRegistrationHandler creates a new RegistrationHandler
-func NewRegistrationHandler(registrationService service.RegistrationService) *RegistrationHandler {
-	return &RegistrationHandler{registrationService: registrationService}
+func NewRegistrationHandler(registrationService service.RegistrationService, waitingRoomService service.WaitingRoomService) *RegistrationHandler {
+	return &RegistrationHandler{
+		registrationService: registrationService,
+		waitingRoomService:  waitingRoomService,
+	}
 }
 
 // RegisterForEvent handles POST /registrations
 type RegisterRequest struct {
 	UserID  uint `json:"user_id" binding:"required"`
 	EventID uint `json:"event_id" binding:"required"`
+	// QueueToken is required for events with a waiting room; it may also be
+	// sent in the X-Queue-Token header
+	QueueToken string `json:"queue_token"`
+	// PaymentFingerprint and Address are optional and screened against the
+	// event's ticket rules; the address itself is not stored
+	PaymentFingerprint string `json:"payment_fingerprint"`
+	Address            string `json:"address"`
+	// Answers maps the event's custom questions to the attendee's answers
+	Answers map[string]string `json:"answers"`
 }
 
 // RegisterForEvent registers a user for an event
@@ -36,9 +49,26 @@ func (h *RegistrationHandler) RegisterForEvent(c *gin.Context) {
 		return
 	}
 
-	registration, err := h.registrationService.RegisterForEvent(req.UserID, req.EventID)
+	// Only users admitted from the waiting room may reach the seat lock
+	if err := h.waitingRoomService.Authorize(c.Request.Context(), req.EventID, req.UserID, queueToken(c, req.QueueToken)); err != nil {
+		respondQueueError(c, err)
+		return
+	}
+
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+
+	registration, err := h.registrationService.RegisterForEvent(c.Request.Context(), actor, req.UserID, req.EventID, models.RegistrationDetails{
+		PaymentFingerprint: req.PaymentFingerprint,
+		Address:            req.Address,
+		Answers:            req.Answers,
+	})
 	if err != nil {
 		switch {
+		case errors.Is(err, models.ErrInvalidInput):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		case errors.Is(err, models.ErrUserNotFound):
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		case errors.Is(err, models.ErrEventNotFound):
@@ -47,6 +77,13 @@ func (h *RegistrationHandler) RegisterForEvent(c *gin.Context) {
 			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		case errors.Is(err, models.ErrAlreadyRegistered):
 			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, models.ErrTicketLimitReached):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, models.ErrEmailNotVerified), errors.Is(err, models.ErrUserDeactivated):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, models.ErrSeatContention), errors.Is(err, models.ErrLockTimeout):
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
 		default:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
@@ -64,7 +101,7 @@ func (h *RegistrationHandler) GetRegistration(c *gin.Context) {
 		return
 	}
 
-	registration, err := h.registrationService.GetRegistrationByID(uint(id))
+	registration, err := h.registrationService.GetRegistrationByID(c.Request.Context(), uint(id))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "registration not found"})
@@ -85,7 +122,7 @@ func (h *RegistrationHandler) GetUserRegistrations(c *gin.Context) {
 		return
 	}
 
-	registrations, err := h.registrationService.GetUserRegistrations(uint(userID))
+	registrations, err := h.registrationService.GetUserRegistrations(c.Request.Context(), uint(userID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -102,7 +139,7 @@ func (h *RegistrationHandler) GetEventRegistrations(c *gin.Context) {
 		return
 	}
 
-	registrations, err := h.registrationService.GetEventRegistrations(uint(eventID))
+	registrations, err := h.registrationService.GetEventRegistrations(c.Request.Context(), uint(eventID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -125,11 +162,139 @@ func (h *RegistrationHandler) CancelRegistration(c *gin.Context) {
 		return
 	}
 
-	err := h.registrationService.CancelRegistration(req.UserID, req.EventID)
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+
+	err := h.registrationService.CancelRegistration(c.Request.Context(), actor, req.UserID, req.EventID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "registration not found"})
+		case errors.Is(err, models.ErrLockTimeout):
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "registration cancelled successfully"})
 }
+
+// CheckIn handles POST /registrations/:id/check-in
+func (h *RegistrationHandler) CheckIn(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid registration ID"})
+		return
+	}
+
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+
+	registration, err := h.registrationService.CheckIn(c.Request.Context(), actor, uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "registration not found"})
+		case errors.Is(err, models.ErrAlreadyCheckedIn):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, models.ErrLockTimeout):
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, registration)
+}
+
+// GetPendingReview handles GET /registrations/event/:eventID/review. The
+// caller, identified by the X-User-ID header, must organize the event or be
+// an admin.
+func (h *RegistrationHandler) GetPendingReview(c *gin.Context) {
+	eventID, err := strconv.ParseUint(c.Param("eventID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+		return
+	}
+	viewerID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+
+	registrations, err := h.registrationService.GetPendingReview(c.Request.Context(), viewerID, uint(eventID))
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, registrations)
+}
+
+// ReviewRequest is an organizer's decision on a flagged registration
+type ReviewRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=approve reject"`
+}
+
+// ReviewRegistration handles POST /registrations/:id/review. Rejecting
+// cancels the registration. The caller, identified by the X-User-ID header,
+// must organize the event or be an admin.
+func (h *RegistrationHandler) ReviewRegistration(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid registration ID"})
+		return
+	}
+
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reviewerID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+
+	registration, err := h.registrationService.ReviewRegistration(c.Request.Context(), actor, reviewerID, uint(id), req.Decision == "approve")
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, registration)
+}
+
+// respondReviewError maps errors of reviewing registrations to responses
+func respondReviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrUserNotFound):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrUnauthorized):
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the event's organizer and admins may review registrations"})
+	case errors.Is(err, models.ErrEventNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "registration not found"})
+	case errors.Is(err, models.ErrNotPendingReview):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrLockTimeout):
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,7 +1,11 @@
 package handler
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -12,14 +16,20 @@ import (
 	"gorm.io/gorm"
 )
 
-// UserHandler handles HTTP requests for users
+// UserHandler handles HTTP requests for users and their accounts
 type UserHandler struct {
-	userService service.UserService
+	userService    service.UserService
+	accountService service.AccountService
+	privacyService service.PrivacyService
 }
 
 // NewUserHandler creates a new UserHandler
-func NewUserHandler(userService service.UserService) *UserHandler {
-	return &UserHandler{userService: userService}
+func NewUserHandler(userService service.UserService, accountService service.AccountService, privacyService service.PrivacyService) *UserHandler {
+	return &UserHandler{
+		userService:    userService,
+		accountService: accountService,
+		privacyService: privacyService,
+	}
 }
 
 // CreateUser handles POST /users
@@ -35,7 +45,16 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		user.Role = models.RoleAttendee
 	}
 
-	if err := h.userService.CreateUser(&user); err != nil {
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.CreateUser(c.Request.Context(), actor, &user); err != nil {
+		if errors.Is(err, models.ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -51,7 +70,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetUserByID(uint(id))
+	user, err := h.userService.GetUserByID(c.Request.Context(), uint(id))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
@@ -66,7 +85,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 
 // GetAllUsers handles GET /users
 func (h *UserHandler) GetAllUsers(c *gin.Context) {
-	users, err := h.userService.GetAllUsers()
+	users, err := h.userService.GetAllUsers(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -90,7 +109,24 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	}
 
 	user.ID = uint(id)
-	if err := h.userService.UpdateUser(&user); err != nil {
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.UpdateUser(c.Request.Context(), actor, &user); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		if errors.Is(err, models.ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, models.ErrUserErased) {
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -106,10 +142,258 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if err := h.userService.DeleteUser(uint(id)); err != nil {
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.DeleteUser(c.Request.Context(), actor, uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "user deleted successfully"})
 }
+
+// SendVerification handles POST /users/:id/verification, mailing the user a
+// token that confirms their email address. The caller, identified by the
+// X-User-ID header, must be the user or an admin.
+func (h *UserHandler) SendVerification(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	requesterID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+
+	if err := h.accountService.SendVerification(c.Request.Context(), actor, requesterID, uint(id)); err != nil {
+		if errors.Is(err, models.ErrInvalidInput) {
+			c.JSON(http.StatusConflict, gin.H{"error": "email address already verified"})
+			return
+		}
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "verification email sent"})
+}
+
+// TokenRequest carries a token mailed to a user
+type TokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// VerifyEmail handles POST /users/verify, confirming an email address with
+// the token mailed to it
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	var req TokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.accountService.VerifyEmail(c.Request.Context(), actor, req.Token)
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// PasswordResetRequest asks for a password reset token
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// RequestPasswordReset handles POST /users/password-reset. It answers 202
+// whether or not the address has an account.
+func (h *UserHandler) RequestPasswordReset(c *gin.Context) {
+	var req PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+
+	if err := h.accountService.RequestPasswordReset(c.Request.Context(), actor, req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the address has an account, a reset email was sent"})
+}
+
+// ResetPasswordRequest sets a new password with a reset token
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ResetPassword handles POST /users/password-reset/confirm
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+
+	if err := h.accountService.ResetPassword(c.Request.Context(), actor, req.Token, req.Password); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password reset successfully"})
+}
+
+// DeactivateUser handles POST /users/:id/deactivate. The caller, identified
+// by the X-User-ID header, must be the user or an admin.
+func (h *UserHandler) DeactivateUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	requesterID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.accountService.DeactivateUser(c.Request.Context(), actor, requesterID, uint(id))
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ExportUser handles GET /users/:id/export with a ZIP archive of everything
+// stored about the user, as JSON files. The caller, identified by the
+// X-User-ID header, must be the user or an admin.
+func (h *UserHandler) ExportUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	requesterID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+
+	export, err := h.privacyService.ExportUser(c.Request.Context(), requesterID, uint(id))
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+	archive, err := zipExport(export)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d-export.zip"`, id))
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// EraseUser handles POST /users/:id/erase. The caller, identified by the
+// X-User-ID header, must be the user or an admin.
+func (h *UserHandler) EraseUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	requesterID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.privacyService.EraseUser(c.Request.Context(), actor, requesterID, uint(id))
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// zipExport packs a user export into a ZIP archive with one JSON file per
+// kind of record
+func zipExport(export *models.UserExport) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range []struct {
+		name string
+		data any
+	}{
+		{"profile.json", gin.H{"exported_at": export.ExportedAt, "user": export.User}},
+		{"registrations.json", export.Registrations},
+		{"audit_events.json", export.AuditEvents},
+	} {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return nil, err
+		}
+		body, err := json.MarshalIndent(file.data, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(body); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// respondAccountError maps errors of the account lifecycle to responses
+func respondAccountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrUserNotFound):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrUnauthorized):
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the user and admins may manage an account"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	case errors.Is(err, models.ErrUserDeactivated):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrInvalidToken), errors.Is(err, models.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
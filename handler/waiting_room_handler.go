@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"event-api/models"
+	"event-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QueueTokenHeader carries a waiting room token on status and registration requests
+const QueueTokenHeader = "X-Queue-Token"
+
+// WaitingRoomHandler handles HTTP requests for event waiting rooms
+type WaitingRoomHandler struct {
+	waitingRoomService service.WaitingRoomService
+}
+
+// NewWaitingRoomHandler creates a new WaitingRoomHandler
+func NewWaitingRoomHandler(waitingRoomService service.WaitingRoomService) *WaitingRoomHandler {
+	return &WaitingRoomHandler{waitingRoomService: waitingRoomService}
+}
+
+// JoinQueueRequest is the body of POST /events/:id/queue
+type JoinQueueRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+// JoinQueue handles POST /events/:id/queue
+func (h *WaitingRoomHandler) JoinQueue(c *gin.Context) {
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+		return
+	}
+
+	var req JoinQueueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := h.waitingRoomService.Join(c.Request.Context(), uint(eventID), req.UserID)
+	if err != nil {
+		respondQueueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// GetQueueStatus handles GET /events/:id/queue/status
+func (h *WaitingRoomHandler) GetQueueStatus(c *gin.Context) {
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+		return
+	}
+
+	status, err := h.waitingRoomService.GetStatus(c.Request.Context(), uint(eventID), queueToken(c, ""))
+	if err != nil {
+		respondQueueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// queueToken returns the token from the X-Queue-Token header, the token
+// query parameter, or fallback, in that order
+func queueToken(c *gin.Context, fallback string) string {
+	if token := c.GetHeader(QueueTokenHeader); token != "" {
+		return token
+	}
+	if token := c.Query("token"); token != "" {
+		return token
+	}
+	return fallback
+}
+
+// respondQueueError maps waiting room errors to HTTP responses
+func respondQueueError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrEventNotFound), errors.Is(err, models.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrWaitingRoomDisabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrInvalidQueueToken),
+		errors.Is(err, models.ErrNotAdmitted),
+		errors.Is(err, models.ErrAdmissionExpired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
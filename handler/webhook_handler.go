@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"event-api/models"
+	"event-api/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// WebhookHandler handles HTTP requests for webhook subscriptions
+type WebhookHandler struct {
+	webhookService service.WebhookService
+}
+
+// NewWebhookHandler creates a new WebhookHandler
+func NewWebhookHandler(webhookService service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+// WebhookWithSecret is a subscription together with its signing secret. It
+// is the body of create requests, where the secret is optional, and of the
+// create and rotate responses, the only ones that reveal the secret.
+type WebhookWithSecret struct {
+	models.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// newWebhookWithSecret shows a subscription's secret
+func newWebhookWithSecret(subscription *models.WebhookSubscription) WebhookWithSecret {
+	return WebhookWithSecret{WebhookSubscription: *subscription, Secret: subscription.Secret}
+}
+
+// CreateSubscription handles POST /organizers/:organizerID/webhooks
+func (h *WebhookHandler) CreateSubscription(c *gin.Context) {
+	organizerID, err := strconv.ParseUint(c.Param("organizerID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organizer ID"})
+		return
+	}
+
+	var req WebhookWithSecret
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription := req.WebhookSubscription
+	subscription.ID = 0
+	subscription.OrganizerID = uint(organizerID)
+	subscription.Active = true
+	subscription.Secret = req.Secret
+
+	if err := h.webhookService.CreateSubscription(c.Request.Context(), &subscription); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newWebhookWithSecret(&subscription))
+}
+
+// GetSubscriptions handles GET /organizers/:organizerID/webhooks
+func (h *WebhookHandler) GetSubscriptions(c *gin.Context) {
+	organizerID, err := strconv.ParseUint(c.Param("organizerID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organizer ID"})
+		return
+	}
+
+	subscriptions, err := h.webhookService.GetSubscriptions(c.Request.Context(), uint(organizerID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// GetSubscription handles GET /organizers/:organizerID/webhooks/:id
+func (h *WebhookHandler) GetSubscription(c *gin.Context) {
+	organizerID, id, ok := parseSubscriptionParams(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.webhookService.GetSubscription(c.Request.Context(), organizerID, id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// UpdateSubscription handles PUT /organizers/:organizerID/webhooks/:id
+func (h *WebhookHandler) UpdateSubscription(c *gin.Context) {
+	organizerID, id, ok := parseSubscriptionParams(c)
+	if !ok {
+		return
+	}
+
+	var subscription models.WebhookSubscription
+	if err := c.ShouldBindJSON(&subscription); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription.ID = id
+	subscription.OrganizerID = organizerID
+
+	if err := h.webhookService.UpdateSubscription(c.Request.Context(), &subscription); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// RotateSecret handles POST /organizers/:organizerID/webhooks/:id/rotate-secret
+func (h *WebhookHandler) RotateSecret(c *gin.Context) {
+	organizerID, id, ok := parseSubscriptionParams(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.webhookService.RotateSecret(c.Request.Context(), organizerID, id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newWebhookWithSecret(subscription))
+}
+
+// DeleteSubscription handles DELETE /organizers/:organizerID/webhooks/:id
+func (h *WebhookHandler) DeleteSubscription(c *gin.Context) {
+	organizerID, id, ok := parseSubscriptionParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteSubscription(c.Request.Context(), organizerID, id); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "webhook deleted successfully"})
+}
+
+// GetDeliveries handles GET /organizers/:organizerID/webhooks/:id/deliveries
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	organizerID, id, ok := parseSubscriptionParams(c)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.webhookService.GetDeliveries(c.Request.Context(), organizerID, id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// parseSubscriptionParams parses the organizer and subscription IDs from the path
+func parseSubscriptionParams(c *gin.Context) (organizerID, id uint, ok bool) {
+	org, err := strconv.ParseUint(c.Param("organizerID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organizer ID"})
+		return 0, 0, false
+	}
+	sub, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook ID"})
+		return 0, 0, false
+	}
+	return uint(org), uint(sub), true
+}
+
+// respondError maps service errors to HTTP responses
+func (h *WebhookHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+	case errors.Is(err, models.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
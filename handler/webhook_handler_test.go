@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"event-api/internal/testdb"
+	"event-api/models"
+	"event-api/repository"
+	"event-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWebhookRoutesRequireTheOrganizer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	db := testdb.NewSQLite(t)
+	users := service.NewUserService(repository.NewTransactor(db), repository.NewUserRepository(db),
+		repository.NewTokenRepository(db), repository.NewAuditRepository(db))
+	h := NewWebhookHandler(service.NewWebhookService(repository.NewWebhookRepository(db)))
+
+	router := gin.New()
+	webhooks := router.Group("/organizers/:organizerID/webhooks", RequireOrganizer(users))
+	webhooks.POST("", h.CreateSubscription)
+	webhooks.GET("", h.GetSubscriptions)
+	webhooks.POST("/:id/rotate-secret", h.RotateSecret)
+	webhooks.GET("/:id/deliveries", h.GetDeliveries)
+
+	ids := map[string]uint{}
+	for name, role := range map[string]models.UserRole{"organizer": models.RoleOrganizer, "other": models.RoleOrganizer, "admin": models.RoleAdmin} {
+		user := &models.User{Name: name, Email: name + "@example.com", Role: role}
+		if err := users.CreateUser(ctx, models.Actor{}, user); err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = user.ID
+	}
+	base := "/organizers/" + strconv.FormatUint(uint64(ids["organizer"]), 10) + "/webhooks"
+
+	request := func(method, path, userID, body string) int {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if userID != "" {
+			req.Header.Set(UserIDHeader, userID)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	as := func(name string) string { return strconv.FormatUint(uint64(ids[name]), 10) }
+	subscription := `{"url": "https://hooks.example.com/events", "event_types": ["registration.created"]}`
+
+	for _, tc := range []struct {
+		name         string
+		method, path string
+		userID, body string
+		want         int
+	}{
+		{"anonymous", http.MethodPost, base, "", subscription, http.StatusBadRequest},
+		{"unknown user", http.MethodPost, base, "999", subscription, http.StatusUnauthorized},
+		{"another organizer subscribes", http.MethodPost, base, as("other"), subscription, http.StatusForbidden},
+		{"the organizer subscribes", http.MethodPost, base, as("organizer"), subscription, http.StatusCreated},
+		{"another organizer lists", http.MethodGet, base, as("other"), "", http.StatusForbidden},
+		{"another organizer rotates", http.MethodPost, base + "/1/rotate-secret", as("other"), "", http.StatusForbidden},
+		{"another organizer reads deliveries", http.MethodGet, base + "/1/deliveries", as("other"), "", http.StatusForbidden},
+		{"an admin lists", http.MethodGet, base, as("admin"), "", http.StatusOK},
+		{"the organizer rotates", http.MethodPost, base + "/1/rotate-secret", as("organizer"), "", http.StatusOK},
+	} {
+		if got := request(tc.method, tc.path, tc.userID, tc.body); got != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
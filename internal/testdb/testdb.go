@@ -0,0 +1,165 @@
+/*
+Package testdb gives integration tests their own database.
+
+New returns a Postgres schema on the server in TEST_DATABASE_DSN, or on an
+embedded Postgres started for the test binary when TEST_EMBEDDED_POSTGRES=1
+(the binaries are downloaded on first use). Without either, those tests are
+skipped. NewSQLite needs nothing and always runs.
+*/
+package testdb
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"event-api/config"
+	"event-api/migrations"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Environment variables that enable integration tests
+const (
+	EnvDSN      = "TEST_DATABASE_DSN"
+	EnvEmbedded = "TEST_EMBEDDED_POSTGRES"
+)
+
+// maxOpenConns caps each test's pool so concurrent tests stay well under
+// the server's max_connections
+const maxOpenConns = 20
+
+// serverDSN is the server tests create their schemas on
+var serverDSN string
+
+// Main runs a package's tests, starting and stopping the embedded Postgres
+// when it is enabled. Call it from TestMain:
+//
+//	func TestMain(m *testing.M) { os.Exit(testdb.Main(m)) }
+func Main(m *testing.M) int {
+	serverDSN = os.Getenv(EnvDSN)
+	if serverDSN != "" || os.Getenv(EnvEmbedded) != "1" {
+		return m.Run()
+	}
+
+	port, err := freePort()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "testdb: %v\n", err)
+		return 1
+	}
+	runtime, err := os.MkdirTemp("", "testdb-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "testdb: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(runtime)
+
+	cache, _ := os.UserCacheDir()
+	server := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Port(port).
+		RuntimePath(runtime).
+		CachePath(filepath.Join(cache, "event-api", "embedded-postgres")).
+		Logger(nil))
+	if err := server.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "testdb: starting embedded postgres: %v\n", err)
+		return 1
+	}
+	defer server.Stop()
+
+	serverDSN = fmt.Sprintf("host=localhost port=%d user=postgres password=postgres dbname=postgres sslmode=disable", port)
+	return m.Run()
+}
+
+// New returns a connection to a new, fully migrated schema that is dropped
+// when the test ends. It skips the test when no Postgres is configured.
+func New(t testing.TB) *gorm.DB {
+	t.Helper()
+	if serverDSN == "" {
+		t.Skipf("integration test: set %s or %s=1", EnvDSN, EnvEmbedded)
+	}
+
+	admin, err := open(serverDSN, "")
+	if err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	schema := fmt.Sprintf("test_%x", rand.Uint64())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("testdb: creating schema: %v", err)
+	}
+
+	db, err := open(serverDSN, schema)
+	if err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := migrations.Up(db); err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	return db
+}
+
+// NewSQLite returns a fully migrated SQLite database in a file that is
+// removed when the test ends
+func NewSQLite(t testing.TB) *gorm.DB {
+	t.Helper()
+	db, err := config.OpenSQLite(filepath.Join(t.TempDir(), "test.db"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := migrations.Up(db); err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	return db
+}
+
+// open connects to dsn with search_path set to schema, if given
+func open(dsn, schema string) (*gorm.DB, error) {
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if schema != "" {
+		connConfig.RuntimeParams["search_path"] = schema
+	}
+
+	sqlDB := stdlib.OpenDB(*connConfig)
+	sqlDB.SetMaxOpenConns(maxOpenConns)
+	return gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+}
+
+// freePort asks the kernel for an unused TCP port
+func freePort() (uint32, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return uint32(l.Addr().(*net.TCPAddr).Port), nil
+}
@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// gormLogger writes GORM's messages and statements to the default slog
+// logger, with the context of the statement
+type gormLogger struct {
+	level         logger.LogLevel
+	slowThreshold time.Duration
+}
+
+// NewGormLogger logs failed statements as errors and statements slower than
+// slowThreshold as warnings; 0 turns slow query logging off. Every other
+// statement is logged at debug level. A missing record is not a failure.
+func NewGormLogger(slowThreshold time.Duration) logger.Interface {
+	return &gormLogger{level: logger.Info, slowThreshold: slowThreshold}
+}
+
+// LogMode returns a copy logging at level, so Silent sessions stay quiet
+func (l *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+// Info logs a GORM message at info level
+func (l *gormLogger) Info(ctx context.Context, msg string, data ...any) {
+	if l.level >= logger.Info {
+		slog.InfoContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Warn logs a GORM message at warn level
+func (l *gormLogger) Warn(ctx context.Context, msg string, data ...any) {
+	if l.level >= logger.Warn {
+		slog.WarnContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Error logs a GORM message at error level
+func (l *gormLogger) Error(ctx context.Context, msg string, data ...any) {
+	if l.level >= logger.Error {
+		slog.ErrorContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace logs a statement after it ran
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	statement := func() []any {
+		sql, rows := fc()
+		return []any{
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+			slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+		}
+	}
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= logger.Error:
+		slog.ErrorContext(ctx, "query failed", append(statement(), slog.Any("err", err))...)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= logger.Warn:
+		slog.WarnContext(ctx, "slow query", append(statement(),
+			slog.Float64("threshold_ms", float64(l.slowThreshold.Microseconds())/1000))...)
+	case l.level >= logger.Info && slog.Default().Enabled(ctx, slog.LevelDebug):
+		slog.DebugContext(ctx, "query", statement()...)
+	}
+}
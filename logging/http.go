@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the ID that ties log lines and audit entries to
+// a request. A client or proxy may send one; otherwise it is generated. It
+// is echoed in the response either way.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-chosen request IDs to the audit column
+const maxRequestIDLength = 64
+
+// quietRoutes are polled by health checks and scrapers, so their requests
+// are only logged at debug level
+var quietRoutes = map[string]bool{
+	"/health":     true,
+	"/livez":      true,
+	"/readyz":     true,
+	"/metrics":    true,
+	"/debug/vars": true,
+}
+
+// Middleware assigns every request its ID, puts the ID in the request
+// context for handlers, services and the GORM logger, and logs the request
+// once it is served. Server errors are logged at error level.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = newRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), id))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case quietRoutes[c.FullPath()]:
+			level = slog.LevelDebug
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("ip", c.ClientIP()),
+			slog.Int("bytes", c.Writer.Size()),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		slog.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// newRequestID generates a random request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
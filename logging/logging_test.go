@@ -0,0 +1,149 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// capture makes a JSON logger at level the default for the rest of the test
+// and returns the buffer it writes to
+func capture(t *testing.T, level string) *bytes.Buffer {
+	var buf bytes.Buffer
+	l, err := New(&buf, level, FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(l)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+// records decodes every line of buf
+func records(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+		out = append(out, record)
+	}
+	return out
+}
+
+func TestRedact(t *testing.T) {
+	buf := capture(t, "info")
+	slog.Info("registered jane.doe@example.com",
+		"email", "jane.doe@example.com",
+		"token", "17.1.2.9f3c",
+		"smtp_password", "hunter2",
+		"url", "/verify?token=abc123&next=/",
+		"err", errors.New("bad credentials for bob@example.org"),
+		"auth", "Bearer eyJhbGciOi.x.y",
+	)
+
+	record := records(t, buf)[0]
+	want := map[string]string{
+		"msg":           "registered j***@example.com",
+		"email":         "j***@example.com",
+		"token":         redacted,
+		"smtp_password": redacted,
+		"url":           "/verify?token=" + redacted + "&next=/",
+		"err":           "bad credentials for b***@example.org",
+		"auth":          "Bearer " + redacted,
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("%s = %q, want %q", key, record[key], value)
+		}
+	}
+}
+
+func TestMiddlewareThreadsRequestID(t *testing.T) {
+	buf := capture(t, "info")
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	var seen string
+	router.GET("/events/:id", func(c *gin.Context) {
+		seen = RequestID(c.Request.Context())
+		slog.InfoContext(c.Request.Context(), "handling")
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/events/1", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if seen != "req-42" || w.Header().Get(RequestIDHeader) != "req-42" {
+		t.Errorf("context ID %q, response header %q; want the client's req-42", seen, w.Header().Get(RequestIDHeader))
+	}
+	got := records(t, buf)
+	if len(got) != 2 {
+		t.Fatalf("got %d records, want the handler's and the request's", len(got))
+	}
+	for _, record := range got {
+		if record["request_id"] != "req-42" {
+			t.Errorf("%v: request_id = %v, want req-42", record["msg"], record["request_id"])
+		}
+	}
+	if got[1]["route"] != "/events/:id" || got[1]["status"] != float64(http.StatusOK) {
+		t.Errorf("request record = %v", got[1])
+	}
+
+	// Without a header, one is generated
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events/1", nil))
+	if len(w.Header().Get(RequestIDHeader)) != 32 {
+		t.Errorf("generated request ID %q", w.Header().Get(RequestIDHeader))
+	}
+}
+
+func TestGormLogger(t *testing.T) {
+	buf := capture(t, "info")
+	gormLog := NewGormLogger(100 * time.Millisecond)
+	ctx := WithRequestID(context.Background(), "req-7")
+	sql := func() (string, int64) { return "SELECT * FROM users WHERE email = 'ann@example.com'", 1 }
+
+	gormLog.Trace(ctx, time.Now(), sql, nil)                             // fast: debug only
+	gormLog.Trace(ctx, time.Now(), sql, gorm.ErrRecordNotFound)          // not a failure
+	gormLog.Trace(ctx, time.Now().Add(-time.Second), sql, nil)           // slow
+	gormLog.Trace(ctx, time.Now(), sql, errors.New("deadlock detected")) // failed
+	gormLog.LogMode(logger.Silent).Trace(ctx, time.Now(), sql, errors.New("quiet"))
+
+	got := records(t, buf)
+	if len(got) != 2 {
+		t.Fatalf("got %d records, want the slow and the failed query: %v", len(got), got)
+	}
+	if got[0]["msg"] != "slow query" || got[0]["level"] != "WARN" {
+		t.Errorf("first record = %v, want a slow query warning", got[0])
+	}
+	if got[1]["msg"] != "query failed" || got[1]["err"] != "deadlock detected" {
+		t.Errorf("second record = %v, want the failed query", got[1])
+	}
+	for _, record := range got {
+		if record["request_id"] != "req-7" {
+			t.Errorf("request_id = %v, want req-7", record["request_id"])
+		}
+		if sql := record["sql"].(string); strings.Contains(sql, "ann@") {
+			t.Errorf("email not redacted in %q", sql)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces a secret value
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are parts of attribute keys whose values are always secret
+var sensitiveKeys = []string{"password", "token", "secret", "authorization", "api_key"}
+
+var (
+	// emailPattern matches email addresses in free text and SQL
+	emailPattern = regexp.MustCompile(`([A-Za-z0-9._%+\-])[A-Za-z0-9._%+\-]*@([A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)+)`)
+	// secretParamPattern matches secrets in query strings, like token=abc
+	secretParamPattern = regexp.MustCompile(`(?i)\b(token|secret|password|api_key)=[^&\s"']+`)
+	// bearerPattern matches bearer credentials
+	bearerPattern = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`)
+)
+
+// Redact is a slog ReplaceAttr function that hides secrets. Attributes
+// whose key names a secret, such as "token" or "smtp_password", are replaced
+// entirely. In strings and errors, including the message, email addresses
+// keep only their first letter and domain (a***@example.com), and token=,
+// password= and bearer credentials are replaced.
+func Redact(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return slog.String(a.Key, redacted)
+		}
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, RedactString(err.Error()))
+		}
+	}
+	return a
+}
+
+// RedactString hides the email addresses and credentials in s
+func RedactString(s string) string {
+	s = emailPattern.ReplaceAllString(s, "$1***@$2")
+	s = secretParamPattern.ReplaceAllString(s, "$1="+redacted)
+	return bearerPattern.ReplaceAllString(s, "Bearer "+redacted)
+}
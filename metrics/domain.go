@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"event-api/models"
+	"event-api/repository"
+	"event-api/service"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// InstrumentEventRepository times the row lock taken by FindByIDForUpdate
+// into event_lock_wait_seconds
+func (m *Metrics) InstrumentEventRepository(repo repository.EventRepository) repository.EventRepository {
+	return &eventRepository{EventRepository: repo, lockWait: m.eventLockWait}
+}
+
+// eventRepository is an EventRepository that observes lock waits
+type eventRepository struct {
+	repository.EventRepository
+	lockWait prometheus.Histogram
+}
+
+// FindByIDForUpdate locks the event, observing how long that took
+func (r *eventRepository) FindByIDForUpdate(ctx context.Context, tx repository.Tx, id uint) (*models.Event, error) {
+	start := time.Now()
+	event, err := r.EventRepository.FindByIDForUpdate(ctx, tx, id)
+	r.lockWait.Observe(time.Since(start).Seconds())
+	return event, err
+}
+
+// InstrumentRegistrationService counts the outcome of every registration
+// attempt into registrations_total
+func (m *Metrics) InstrumentRegistrationService(svc service.RegistrationService) service.RegistrationService {
+	return &registrationService{RegistrationService: svc, outcomes: m.registrations}
+}
+
+// registrationService is a RegistrationService that counts outcomes
+type registrationService struct {
+	service.RegistrationService
+	outcomes *prometheus.CounterVec
+}
+
+// RegisterForEvent registers a user, counting the outcome
+func (s *registrationService) RegisterForEvent(ctx context.Context, actor models.Actor, userID, eventID uint, details models.RegistrationDetails) (*models.Registration, error) {
+	registration, err := s.RegistrationService.RegisterForEvent(ctx, actor, userID, eventID, details)
+	s.outcomes.WithLabelValues(outcome(err)).Inc()
+	return registration, err
+}
+
+// outcome classifies the result of a registration attempt
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeSucceeded
+	case errors.Is(err, models.ErrEventFull):
+		return OutcomeFull
+	case errors.Is(err, models.ErrAlreadyRegistered):
+		return OutcomeDuplicate
+	case errors.Is(err, models.ErrLockTimeout):
+		return OutcomeTimeout
+	case errors.Is(err, models.ErrTicketLimitReached):
+		return OutcomeLimited
+	default:
+		return OutcomeFailed
+	}
+}
+
+// WatchSeats exports event_seats_remaining for the events source returns,
+// reading them afresh on every scrape
+func (m *Metrics) WatchSeats(source func(ctx context.Context, now time.Time) ([]models.SeatCount, error)) error {
+	return m.Registry.Register(&seatsCollector{
+		source: source,
+		desc: prometheus.NewDesc("event_seats_remaining",
+			"Available seats of each published event that has not started.",
+			[]string{"event_id"}, nil),
+	})
+}
+
+// seatsCollector reads the remaining seats at scrape time
+type seatsCollector struct {
+	source func(ctx context.Context, now time.Time) ([]models.SeatCount, error)
+	desc   *prometheus.Desc
+}
+
+// Describe sends the one metric the collector exports
+func (c *seatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect sends one gauge per event, or an error when the read fails
+func (c *seatsCollector) Collect(ch chan<- prometheus.Metric) {
+	counts, err := c.source(context.Background(), time.Now())
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	for _, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue,
+			float64(count.Available), strconv.FormatUint(uint64(count.EventID), 10))
+	}
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+// queryStartKey holds a statement's start time on the GORM instance
+const queryStartKey = "metrics:query_start"
+
+// InstrumentDB times every statement db runs and exports its connection
+// pool stats under db_name dbName. Call it before db is used.
+func (m *Metrics) InstrumentDB(db *gorm.DB, dbName string) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if err := db.Use(&gormPlugin{metrics: m}); err != nil {
+		return err
+	}
+	return m.Registry.Register(collectors.NewDBStatsCollector(sqlDB, dbName))
+}
+
+// gormPlugin observes db_query_duration_seconds through GORM callbacks
+type gormPlugin struct {
+	metrics *Metrics
+}
+
+// Name identifies the plugin to GORM
+func (p *gormPlugin) Name() string {
+	return "metrics"
+}
+
+// Initialize registers a callback before and after each kind of statement
+func (p *gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("metrics:before_create", p.start),
+		cb.Create().After("gorm:create").Register("metrics:after_create", p.observe("create")),
+		cb.Query().Before("gorm:query").Register("metrics:before_query", p.start),
+		cb.Query().After("gorm:query").Register("metrics:after_query", p.observe("query")),
+		cb.Update().Before("gorm:update").Register("metrics:before_update", p.start),
+		cb.Update().After("gorm:update").Register("metrics:after_update", p.observe("update")),
+		cb.Delete().Before("gorm:delete").Register("metrics:before_delete", p.start),
+		cb.Delete().After("gorm:delete").Register("metrics:after_delete", p.observe("delete")),
+		cb.Row().Before("gorm:row").Register("metrics:before_row", p.start),
+		cb.Row().After("gorm:row").Register("metrics:after_row", p.observe("row")),
+		cb.Raw().Before("gorm:raw").Register("metrics:before_raw", p.start),
+		cb.Raw().After("gorm:raw").Register("metrics:after_raw", p.observe("raw")),
+	)
+}
+
+// start remembers when a statement began
+func (p *gormPlugin) start(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+// observe returns a callback recording how long a statement took
+func (p *gormPlugin) observe(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		p.metrics.dbQueryDuration.WithLabelValues(operation, db.Statement.Table).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Package metrics exposes the server's Prometheus metrics: HTTP requests per
+route, database query durations and connection pool stats, and registration
+outcomes, event lock waits and remaining seats.
+
+Every collector lives on the Registry of one Metrics value rather than the
+global default registry, so tests can create their own and assert on it.
+*/
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registration outcomes counted by registrations_total
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFull      = "full"
+	OutcomeDuplicate = "duplicate"
+	OutcomeTimeout   = "timeout"
+	OutcomeLimited   = "limited"
+	OutcomeFailed    = "failed"
+)
+
+// Metrics holds the application's collectors
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	httpRequests    *prometheus.CounterVec
+	httpDuration    *prometheus.HistogramVec
+	dbQueryDuration *prometheus.HistogramVec
+	registrations   *prometheus.CounterVec
+	eventLockWait   prometheus.Histogram
+}
+
+// New creates the collectors on a new registry, along with the Go runtime
+// and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by method, route and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		dbQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Database statement duration by operation and table.",
+			Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14),
+		}, []string{"operation", "table"}),
+		registrations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "registrations_total",
+			Help: "Registration attempts by outcome: succeeded, full, duplicate or failed.",
+		}, []string{"outcome"}),
+		eventLockWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "event_lock_wait_seconds",
+			Help:    "Time spent acquiring an event row lock with SELECT ... FOR UPDATE.",
+			Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14),
+		}),
+	}
+	m.Registry.MustRegister(
+		m.httpRequests,
+		m.httpDuration,
+		m.dbQueryDuration,
+		m.registrations,
+		m.eventLockWait,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	// Show every outcome from the start, so rates work before the first one
+	for _, outcome := range []string{OutcomeSucceeded, OutcomeFull, OutcomeDuplicate, OutcomeTimeout, OutcomeLimited, OutcomeFailed} {
+		m.registrations.WithLabelValues(outcome)
+	}
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
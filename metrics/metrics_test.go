@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"event-api/models"
+	"event-api/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestMiddlewareLabelsByRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := New()
+	router := gin.New()
+	router.Use(m.Middleware())
+	router.GET("/events/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/events/1", "/events/2", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/events/:id", "200")); got != 2 {
+		t.Errorf("requests to /events/:id = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", unmatchedRoute, "404")); got != 1 {
+		t.Errorf("unmatched requests = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.httpDuration); got != 2 {
+		t.Errorf("latency series = %d, want 2", got)
+	}
+}
+
+// fakeRegistrations fails registrations with the queued errors
+type fakeRegistrations struct {
+	service.RegistrationService
+	errs []error
+}
+
+func (f *fakeRegistrations) RegisterForEvent(context.Context, models.Actor, uint, uint, models.RegistrationDetails) (*models.Registration, error) {
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return &models.Registration{}, err
+}
+
+func TestRegistrationOutcomes(t *testing.T) {
+	m := New()
+	svc := m.InstrumentRegistrationService(&fakeRegistrations{errs: []error{
+		nil, nil, models.ErrEventFull, models.ErrAlreadyRegistered, models.ErrUserNotFound, models.ErrTicketLimitReached,
+	}})
+	for range 6 {
+		_, _ = svc.RegisterForEvent(context.Background(), models.Actor{}, 1, 1, models.RegistrationDetails{})
+	}
+
+	want := map[string]float64{OutcomeSucceeded: 2, OutcomeFull: 1, OutcomeDuplicate: 1, OutcomeLimited: 1, OutcomeFailed: 1}
+	for outcome, count := range want {
+		if got := testutil.ToFloat64(m.registrations.WithLabelValues(outcome)); got != count {
+			t.Errorf("%s registrations = %v, want %v", outcome, got, count)
+		}
+	}
+}
+
+func TestInstrumentDB(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New()
+	if err := m.InstrumentDB(db, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Exec("CREATE TABLE users (id integer PRIMARY KEY, name text)").Error; err != nil {
+		t.Fatal(err)
+	}
+	var users []struct{ ID uint }
+	if err := db.Table("users").Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if got := testutil.CollectAndCount(m.dbQueryDuration); got != 2 {
+		t.Errorf("query duration series = %d, want 2 (raw and query)", got)
+	}
+	if got, err := testutil.GatherAndCount(m.Registry, "go_sql_open_connections"); err != nil || got != 1 {
+		t.Errorf("go_sql_open_connections series = %d, err %v; want 1", got, err)
+	}
+}
+
+func TestWatchSeats(t *testing.T) {
+	m := New()
+	err := m.WatchSeats(func(context.Context, time.Time) ([]models.SeatCount, error) {
+		return []models.SeatCount{{EventID: 1, Available: 3}, {EventID: 2, Available: 0}}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `
+# HELP event_seats_remaining Available seats of each published event that has not started.
+# TYPE event_seats_remaining gauge
+event_seats_remaining{event_id="1"} 3
+event_seats_remaining{event_id="2"} 0
+`
+	if err := testutil.GatherAndCompare(m.Registry, strings.NewReader(want), "event_seats_remaining"); err != nil {
+		t.Error(err)
+	}
+}
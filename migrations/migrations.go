@@ -0,0 +1,333 @@
+/*
+Package migrations versions the database schema with plain SQL files
+embedded in the binary.
+
+Each migration is a pair of files per dialect, postgres/ and sqlite/,
+named NNNN_name.up.sql and NNNN_name.down.sql. Applied versions are
+recorded in the schema_migrations table. Every migration runs in its own
+transaction together with its record, so a failed one leaves no trace.
+
+Several instances may migrate at once: on Postgres each transaction first
+takes an advisory lock and then checks again whether its migration is still
+pending, and on SQLite every transaction already begins IMMEDIATE (see
+config.OpenSQLite) and runs alone.
+*/
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrationLockKey is the Postgres advisory lock key held while migrating
+const migrationLockKey = 7240130
+
+// dialects have one migration directory each
+var dialects = []string{"postgres", "sqlite"}
+
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// fileName matches migration files, e.g. 0001_initial_schema.up.sql
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// nameSeparators are the runs of characters Create replaces with underscores
+var nameSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+
+// createTable creates schema_migrations, per dialect
+var createTable = map[string]string{
+	"postgres": `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    bigint PRIMARY KEY,
+    name       varchar(255) NOT NULL,
+    applied_at timestamptz NOT NULL
+)`,
+	"sqlite": `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    integer PRIMARY KEY,
+    name       varchar(255) NOT NULL,
+    applied_at datetime NOT NULL
+)`,
+}
+
+// ErrUnknownVersion is returned when the database has a migration applied
+// that this binary does not know, so it cannot be reverted
+var ErrUnknownVersion = errors.New("applied migration is unknown to this build")
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+// Status is a migration and when it was applied, if it was
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// schemaMigration is a row of schema_migrations
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// TableName specifies the table name for schemaMigration
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrator applies and reverts the embedded migrations of one database
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator creates a Migrator for db with the migrations of its dialect
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	dialect := db.Dialector.Name()
+	if _, ok := createTable[dialect]; !ok {
+		return nil, fmt.Errorf("no migrations for dialect %q", dialect)
+	}
+	migrations, err := load(files, dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies every pending migration
+func Up(db *gorm.DB) error {
+	m, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	_, err = m.Up()
+	return err
+}
+
+// Up applies every pending migration in version order and returns the ones
+// this call applied
+func (m *Migrator) Up() ([]Migration, error) {
+	var applied []Migration
+	for _, migration := range m.migrations {
+		ran := false
+		err := m.locked(func(tx *gorm.DB) error {
+			var count int64
+			if err := tx.Model(&schemaMigration{}).Where("version = ?", migration.Version).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return nil
+			}
+			if err := tx.Exec(migration.up).Error; err != nil {
+				return err
+			}
+			ran = true
+			return tx.Create(&schemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		if ran {
+			applied = append(applied, migration)
+		}
+	}
+	return applied, nil
+}
+
+// Down reverts the latest steps applied migrations, newest first, and
+// returns the ones it reverted
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	var reverted []Migration
+	for i := 0; i < steps; i++ {
+		var migration *Migration
+		err := m.locked(func(tx *gorm.DB) error {
+			var latest schemaMigration
+			err := tx.Order("version DESC").Take(&latest).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			migration = m.find(latest.Version)
+			if migration == nil {
+				return fmt.Errorf("%w: %04d_%s", ErrUnknownVersion, latest.Version, latest.Name)
+			}
+			if err := tx.Exec(migration.down).Error; err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			return tx.Delete(&schemaMigration{}, "version = ?", migration.Version).Error
+		})
+		if err != nil {
+			return reverted, err
+		}
+		if migration == nil {
+			break
+		}
+		reverted = append(reverted, *migration)
+	}
+	return reverted, nil
+}
+
+// Status lists every known migration, and any applied one this build does
+// not know, in version order
+func (m *Migrator) Status() ([]Status, error) {
+	var rows []schemaMigration
+	err := m.locked(func(tx *gorm.DB) error {
+		return tx.Order("version").Find(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.AppliedAt = &appliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range applied {
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, Status{Version: row.Version, Name: row.Name, AppliedAt: &appliedAt})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// locked runs fn in a transaction that holds the migration lock, creating
+// schema_migrations first if needed
+func (m *Migrator) locked(fn func(tx *gorm.DB) error) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockKey).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec(createTable[tx.Dialector.Name()]).Error; err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// find returns the migration with a version
+func (m *Migrator) find(version int) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// load reads the migrations of a dialect from fsys, in version order
+func load(fsys fs.FS, dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("unknown migration dialect %q: %w", dialect, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s/%s", dialect, entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		body, err := fs.ReadFile(fsys, path.Join(dialect, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %s/%04d has two names, %s and %s", dialect, version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.up = string(body)
+		} else {
+			migration.down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == "" || migration.down == "" {
+			return nil, fmt.Errorf("migration %s/%04d_%s needs both an up and a down file", dialect, migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Create writes empty up and down files for a new migration under dir, one
+// pair per dialect, numbered after the latest existing one. It returns the
+// paths it created.
+func Create(dir, name string) ([]string, error) {
+	name = strings.Trim(nameSeparators.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, errors.New("migration name is empty")
+	}
+
+	version := 0
+	for _, dialect := range dialects {
+		entries, err := os.ReadDir(filepath.Join(dir, dialect))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if match := fileName.FindStringSubmatch(entry.Name()); match != nil {
+				if v, _ := strconv.Atoi(match[1]); v > version {
+					version = v
+				}
+			}
+		}
+	}
+	version++
+
+	var created []string
+	for _, dialect := range dialects {
+		for _, direction := range []string{"up", "down"} {
+			file := filepath.Join(dir, dialect, fmt.Sprintf("%04d_%s.%s.sql", version, name, direction))
+			body := fmt.Sprintf("-- %04d_%s (%s, %s)\n", version, name, dialect, direction)
+			if err := os.WriteFile(file, []byte(body), 0o644); err != nil {
+				return created, err
+			}
+			created = append(created, file)
+		}
+	}
+	return created, nil
+}
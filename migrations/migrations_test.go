@@ -0,0 +1,77 @@
+package migrations
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"event-api/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestUpDownStatus(t *testing.T) {
+	// config.OpenSQLite would import this package back
+	db, err := gorm.Open(sqlite.Open("file:"+filepath.Join(t.TempDir(), "test.db")+"?_txlock=immediate"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := m.Up()
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(applied) != len(m.migrations) {
+		t.Fatalf("Up applied %d migrations, want %d", len(applied), len(m.migrations))
+	}
+	if applied, err = m.Up(); err != nil || len(applied) != 0 {
+		t.Fatalf("second Up applied %d migrations, err %v; want none", len(applied), err)
+	}
+	for _, model := range []any{&models.User{}, &models.Event{}, &models.Registration{}, &models.EventInventoryShard{}} {
+		if !db.Migrator().HasTable(model) {
+			t.Errorf("table for %T missing after Up", model)
+		}
+	}
+	if !db.Migrator().HasIndex(&models.Registration{}, "idx_registrations_user_event") {
+		t.Error("unique index on registrations (user_id, event_id) missing after Up")
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			t.Errorf("migration %04d_%s pending after Up", s.Version, s.Name)
+		}
+	}
+
+	if _, err := m.Down(len(m.migrations)); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if db.Migrator().HasTable(&models.Registration{}) {
+		t.Error("registrations still exists after reverting every migration")
+	}
+	if reverted, err := m.Down(1); err != nil || len(reverted) != 0 {
+		t.Fatalf("Down with nothing applied reverted %d, err %v; want none", len(reverted), err)
+	}
+}
+
+func TestLoadRejectsUnpairedMigration(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sqlite/0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+		"sqlite/0001_a.down.sql": {Data: []byte("SELECT 1;")},
+		"sqlite/0002_b.up.sql":   {Data: []byte("SELECT 1;")},
+	}
+	if _, err := load(fsys, "sqlite"); err == nil {
+		t.Fatal("load accepted a migration without a down file")
+	}
+}
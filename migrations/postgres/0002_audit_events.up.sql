@@ -0,0 +1,31 @@
+-- Append-only log of every change made through the API. The trigger keeps
+-- entries from being changed or removed, even by hand.
+
+CREATE TABLE audit_events (
+    id           bigserial PRIMARY KEY,
+    actor_id     bigint,
+    action       varchar(50) NOT NULL,
+    target_type  varchar(50) NOT NULL,
+    target_id    bigint NOT NULL,
+    event_id     bigint,
+    before_state text,
+    after_state  text,
+    request_id   varchar(64) NOT NULL DEFAULT '',
+    ip           varchar(45) NOT NULL DEFAULT '',
+    created_at   timestamptz
+);
+CREATE INDEX idx_audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX idx_audit_events_target ON audit_events (target_type, target_id);
+CREATE INDEX idx_audit_events_event_id ON audit_events (event_id);
+CREATE INDEX idx_audit_events_created_at ON audit_events (created_at);
+
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+CREATE TRIGGER audit_events_no_truncate BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
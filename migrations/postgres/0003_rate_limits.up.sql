@@ -0,0 +1,9 @@
+-- Rate limit state shared by every replica. tat is the theoretical arrival
+-- time of a client's next request in Unix nanoseconds; rows whose tat has
+-- passed carry no state and are purged.
+
+CREATE TABLE rate_limits (
+    key varchar(255) PRIMARY KEY,
+    tat bigint NOT NULL
+);
+CREATE INDEX idx_rate_limits_tat ON rate_limits (tat);
@@ -0,0 +1,24 @@
+-- Email verification, passwords and deactivation of accounts, and events
+-- that only admit verified attendees. user_tokens holds the single-use
+-- verification and password reset tokens; only their SHA-256 digest is
+-- stored, so a leaked table does not leak usable tokens.
+
+ALTER TABLE users ADD COLUMN password_hash varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN email_verified_at timestamptz;
+ALTER TABLE users ADD COLUMN deactivated_at timestamptz;
+
+ALTER TABLE events ADD COLUMN require_verified_email boolean NOT NULL DEFAULT false;
+
+CREATE TABLE user_tokens (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint NOT NULL,
+    purpose    varchar(20) NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    email      varchar(255) NOT NULL DEFAULT '',
+    expires_at timestamptz NOT NULL,
+    used_at    timestamptz,
+    created_at timestamptz,
+    CONSTRAINT fk_user_tokens_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE UNIQUE INDEX idx_user_tokens_token_hash ON user_tokens (token_hash);
+CREATE INDEX idx_user_tokens_user_purpose ON user_tokens (user_id, purpose);
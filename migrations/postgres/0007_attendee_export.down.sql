@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_registrations_event_active;
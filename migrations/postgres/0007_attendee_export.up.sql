@@ -0,0 +1,4 @@
+-- Attendee list exports page through an event's active registrations in ID
+-- order.
+
+CREATE INDEX idx_registrations_event_active ON registrations (event_id, id) WHERE deleted_at IS NULL;
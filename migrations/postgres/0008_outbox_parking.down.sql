@@ -0,0 +1,2 @@
+ALTER TABLE outbox_events DROP COLUMN parked_at;
+ALTER TABLE outbox_events DROP COLUMN next_attempt_at;
@@ -0,0 +1,6 @@
+-- Retry backoff and a parked state for outbox events that keep failing.
+-- A failed event is retried from next_attempt_at on; once it has used up
+-- its attempts it is parked and no longer holds back its aggregate.
+
+ALTER TABLE outbox_events ADD COLUMN next_attempt_at timestamptz;
+ALTER TABLE outbox_events ADD COLUMN parked_at timestamptz;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS waiting_room_schedules;
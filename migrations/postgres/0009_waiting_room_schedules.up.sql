@@ -0,0 +1,8 @@
+-- When each event's waiting room may next admit a batch. The admitter reads
+-- and advances it under its lock, so every waiting room admits one batch
+-- per interval however many replicas run the admitter.
+
+CREATE TABLE waiting_room_schedules (
+    event_id      bigint PRIMARY KEY,
+    next_admit_at timestamptz NOT NULL
+);
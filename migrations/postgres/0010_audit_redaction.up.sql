@@ -0,0 +1,10 @@
+-- Erasing a user redacts their snapshots and client IPs in the audit log.
+-- Only those columns may change; which change was made, by whom and when
+-- stays fixed, and entries still cannot be removed.
+
+DROP TRIGGER audit_events_no_update ON audit_events;
+CREATE TRIGGER audit_events_no_update
+    BEFORE UPDATE OF id, actor_id, action, target_type, target_id, event_id, request_id, created_at ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+CREATE TRIGGER audit_events_no_delete BEFORE DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
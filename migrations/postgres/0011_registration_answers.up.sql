@@ -0,0 +1,4 @@
+-- Attendees' answers to an event's custom registration questions, as a JSON
+-- object from question to answer.
+
+ALTER TABLE registrations ADD COLUMN answers text;
@@ -0,0 +1 @@
+DROP SEQUENCE IF EXISTS inventory_version_seq;
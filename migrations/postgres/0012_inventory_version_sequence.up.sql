@@ -0,0 +1,11 @@
+-- Shard versions come from one sequence, so the newest shard version of a
+-- sharded event is its version and no two changes share one. It starts above
+-- every summed version reported before, so streams never see it go back.
+
+CREATE SEQUENCE IF NOT EXISTS inventory_version_seq;
+
+SELECT setval('inventory_version_seq', COALESCE((
+    SELECT MAX(version) FROM (
+        SELECT SUM(version) AS version FROM event_inventory_shards GROUP BY event_id
+    ) totals
+), 0) + 1);
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS event_inventory_shards;
+DROP TABLE IF EXISTS waiting_room_entries;
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
+DROP TABLE IF EXISTS event_reminders;
+DROP TABLE IF EXISTS notification_templates;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS registrations;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS users;
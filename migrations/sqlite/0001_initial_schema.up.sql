@@ -0,0 +1,157 @@
+-- The schema as AutoMigrate left it, plus the unique index on active
+-- registrations. IF NOT EXISTS lets databases created by AutoMigrate adopt
+-- this migration without changes. SQLite spells the column types as
+-- AutoMigrate did; it stores booleans as numeric 0 and 1.
+
+CREATE TABLE IF NOT EXISTS users (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    name       varchar(255) NOT NULL,
+    email      varchar(255) NOT NULL,
+    role       varchar(50) NOT NULL DEFAULT 'attendee',
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS events (
+    id               integer PRIMARY KEY AUTOINCREMENT,
+    title            varchar(255) NOT NULL,
+    capacity         integer NOT NULL,
+    available_seats  integer NOT NULL,
+    seats_version    integer NOT NULL DEFAULT 0,
+    inventory        varchar(20) NOT NULL DEFAULT 'row',
+    inventory_shards integer NOT NULL DEFAULT 0,
+    organizer_id     integer NOT NULL,
+    starts_at        datetime,
+    published_at     datetime,
+    waiting_room     numeric NOT NULL DEFAULT false,
+    created_at       datetime,
+    updated_at       datetime,
+    deleted_at       datetime,
+    CONSTRAINT fk_users_events FOREIGN KEY (organizer_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_events_deleted_at ON events (deleted_at);
+
+CREATE TABLE IF NOT EXISTS registrations (
+    id            integer PRIMARY KEY AUTOINCREMENT,
+    user_id       integer NOT NULL,
+    event_id      integer NOT NULL,
+    checked_in_at datetime,
+    created_at    datetime,
+    updated_at    datetime,
+    deleted_at    datetime,
+    CONSTRAINT fk_registrations_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_events_registrations FOREIGN KEY (event_id) REFERENCES events (id)
+);
+CREATE INDEX IF NOT EXISTS idx_registrations_deleted_at ON registrations (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_registrations_user_event ON registrations (user_id, event_id) WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id              integer PRIMARY KEY AUTOINCREMENT,
+    type            varchar(50) NOT NULL,
+    user_id         integer,
+    recipient       varchar(255) NOT NULL,
+    subject         varchar(255) NOT NULL,
+    text_body       text,
+    html_body       text,
+    status          varchar(20) NOT NULL DEFAULT 'pending',
+    attempts        integer NOT NULL DEFAULT 0,
+    next_attempt_at datetime,
+    last_error      text,
+    sent_at         datetime,
+    created_at      datetime,
+    updated_at      datetime
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_due ON notifications (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications (user_id);
+
+CREATE TABLE IF NOT EXISTS notification_templates (
+    id           integer PRIMARY KEY AUTOINCREMENT,
+    organizer_id integer NOT NULL,
+    type         varchar(50) NOT NULL,
+    subject      text,
+    text_body    text,
+    html_body    text,
+    created_at   datetime,
+    updated_at   datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_templates_organizer_type ON notification_templates (organizer_id, type);
+
+CREATE TABLE IF NOT EXISTS event_reminders (
+    id             integer PRIMARY KEY AUTOINCREMENT,
+    event_id       integer NOT NULL,
+    offset_seconds integer NOT NULL,
+    due_at         datetime NOT NULL,
+    sent_at        datetime,
+    created_at     datetime,
+    updated_at     datetime
+);
+CREATE INDEX IF NOT EXISTS idx_event_reminders_due_at ON event_reminders (due_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reminders_event_offset ON event_reminders (event_id, offset_seconds);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id           integer PRIMARY KEY AUTOINCREMENT,
+    organizer_id integer NOT NULL,
+    url          text NOT NULL,
+    secret       varchar(255) NOT NULL,
+    event_types  text NOT NULL,
+    active       numeric NOT NULL DEFAULT true,
+    created_at   datetime,
+    updated_at   datetime
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_organizer_id ON webhook_subscriptions (organizer_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              integer PRIMARY KEY AUTOINCREMENT,
+    subscription_id integer NOT NULL,
+    event_id        varchar(64) NOT NULL,
+    event_type      varchar(50) NOT NULL,
+    payload         text NOT NULL,
+    status          varchar(20) NOT NULL DEFAULT 'pending',
+    attempts        integer NOT NULL DEFAULT 0,
+    next_attempt_at datetime,
+    response_status integer,
+    last_error      text,
+    delivered_at    datetime,
+    created_at      datetime,
+    updated_at      datetime,
+    CONSTRAINT fk_webhook_deliveries_subscription FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions (id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id             integer PRIMARY KEY AUTOINCREMENT,
+    aggregate_type varchar(50) NOT NULL,
+    aggregate_id   integer NOT NULL,
+    type           varchar(50) NOT NULL,
+    payload        text NOT NULL,
+    attempts       integer NOT NULL DEFAULT 0,
+    last_error     text,
+    published_at   datetime,
+    created_at     datetime
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_published_at ON outbox_events (published_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_aggregate ON outbox_events (aggregate_type, aggregate_id);
+
+CREATE TABLE IF NOT EXISTS waiting_room_entries (
+    id          integer PRIMARY KEY AUTOINCREMENT,
+    event_id    integer NOT NULL,
+    user_id     integer NOT NULL,
+    admitted_at datetime,
+    expires_at  datetime,
+    created_at  datetime
+);
+CREATE INDEX IF NOT EXISTS idx_waiting_room_queue ON waiting_room_entries (event_id, admitted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_waiting_room_event_user ON waiting_room_entries (event_id, user_id);
+
+CREATE TABLE IF NOT EXISTS event_inventory_shards (
+    event_id  integer,
+    shard     integer,
+    available integer NOT NULL,
+    version   integer NOT NULL DEFAULT 0,
+    PRIMARY KEY (event_id, shard),
+    CONSTRAINT chk_inventory_shard_available CHECK (available >= 0)
+);
@@ -0,0 +1,30 @@
+-- Append-only log of every change made through the API. The triggers keep
+-- entries from being changed or removed, even by hand.
+
+CREATE TABLE audit_events (
+    id           integer PRIMARY KEY AUTOINCREMENT,
+    actor_id     integer,
+    action       varchar(50) NOT NULL,
+    target_type  varchar(50) NOT NULL,
+    target_id    integer NOT NULL,
+    event_id     integer,
+    before_state text,
+    after_state  text,
+    request_id   varchar(64) NOT NULL DEFAULT '',
+    ip           varchar(45) NOT NULL DEFAULT '',
+    created_at   datetime
+);
+CREATE INDEX idx_audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX idx_audit_events_target ON audit_events (target_type, target_id);
+CREATE INDEX idx_audit_events_event_id ON audit_events (event_id);
+CREATE INDEX idx_audit_events_created_at ON audit_events (created_at);
+
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+
+CREATE TRIGGER audit_events_no_delete BEFORE DELETE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
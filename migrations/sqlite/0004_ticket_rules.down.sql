@@ -0,0 +1,17 @@
+DROP INDEX IF EXISTS idx_registrations_review;
+DROP INDEX IF EXISTS idx_registrations_household_key;
+DROP INDEX IF EXISTS idx_registrations_payment_fingerprint;
+DROP INDEX IF EXISTS idx_registrations_event_email_domain;
+
+ALTER TABLE registrations DROP COLUMN flag_reasons;
+ALTER TABLE registrations DROP COLUMN review;
+ALTER TABLE registrations DROP COLUMN household_key;
+ALTER TABLE registrations DROP COLUMN payment_fingerprint;
+ALTER TABLE registrations DROP COLUMN email_domain;
+
+ALTER TABLE events DROP COLUMN rule_velocity_window_seconds;
+ALTER TABLE events DROP COLUMN rule_velocity_max;
+ALTER TABLE events DROP COLUMN rule_over_limit;
+ALTER TABLE events DROP COLUMN rule_max_per_household;
+ALTER TABLE events DROP COLUMN rule_max_per_payment_instrument;
+ALTER TABLE events DROP COLUMN rule_max_per_email_domain;
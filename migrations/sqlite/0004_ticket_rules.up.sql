@@ -0,0 +1,21 @@
+-- Per-event ticket limits and velocity checks against scalping, and the
+-- screening keys and review state of registrations. household_key is a
+-- digest of the attendee's address; the address itself is not stored.
+
+ALTER TABLE events ADD COLUMN rule_max_per_email_domain integer NOT NULL DEFAULT 0;
+ALTER TABLE events ADD COLUMN rule_max_per_payment_instrument integer NOT NULL DEFAULT 0;
+ALTER TABLE events ADD COLUMN rule_max_per_household integer NOT NULL DEFAULT 0;
+ALTER TABLE events ADD COLUMN rule_over_limit varchar(10) NOT NULL DEFAULT 'reject';
+ALTER TABLE events ADD COLUMN rule_velocity_max integer NOT NULL DEFAULT 0;
+ALTER TABLE events ADD COLUMN rule_velocity_window_seconds integer NOT NULL DEFAULT 0;
+
+ALTER TABLE registrations ADD COLUMN email_domain varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE registrations ADD COLUMN payment_fingerprint varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE registrations ADD COLUMN household_key varchar(64) NOT NULL DEFAULT '';
+ALTER TABLE registrations ADD COLUMN review varchar(20) NOT NULL DEFAULT '';
+ALTER TABLE registrations ADD COLUMN flag_reasons varchar(255) NOT NULL DEFAULT '';
+
+CREATE INDEX idx_registrations_event_email_domain ON registrations (event_id, email_domain);
+CREATE INDEX idx_registrations_payment_fingerprint ON registrations (payment_fingerprint, created_at);
+CREATE INDEX idx_registrations_household_key ON registrations (household_key, created_at);
+CREATE INDEX idx_registrations_review ON registrations (event_id, review) WHERE review <> '';
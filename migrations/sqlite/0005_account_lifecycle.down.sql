@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS user_tokens;
+
+ALTER TABLE events DROP COLUMN require_verified_email;
+
+ALTER TABLE users DROP COLUMN deactivated_at;
+ALTER TABLE users DROP COLUMN email_verified_at;
+ALTER TABLE users DROP COLUMN password_hash;
@@ -0,0 +1,24 @@
+-- Email verification, passwords and deactivation of accounts, and events
+-- that only admit verified attendees. user_tokens holds the single-use
+-- verification and password reset tokens; only their SHA-256 digest is
+-- stored, so a leaked table does not leak usable tokens.
+
+ALTER TABLE users ADD COLUMN password_hash varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN email_verified_at datetime;
+ALTER TABLE users ADD COLUMN deactivated_at datetime;
+
+ALTER TABLE events ADD COLUMN require_verified_email numeric NOT NULL DEFAULT false;
+
+CREATE TABLE user_tokens (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    user_id    integer NOT NULL,
+    purpose    varchar(20) NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    email      varchar(255) NOT NULL DEFAULT '',
+    expires_at datetime NOT NULL,
+    used_at    datetime,
+    created_at datetime,
+    CONSTRAINT fk_user_tokens_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE UNIQUE INDEX idx_user_tokens_token_hash ON user_tokens (token_hash);
+CREATE INDEX idx_user_tokens_user_purpose ON user_tokens (user_id, purpose);
@@ -0,0 +1,4 @@
+-- When a user's personal data was anonymized, on request or by the
+-- retention purge of deleted users.
+
+ALTER TABLE users ADD COLUMN erased_at datetime;
@@ -0,0 +1 @@
+-- Sharded inventory needs Postgres, so SQLite has no shard version sequence.
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// TokenPurpose is what a user token may be used for
+type TokenPurpose string
+
+const (
+	TokenVerifyEmail   TokenPurpose = "verify_email"
+	TokenResetPassword TokenPurpose = "reset_password"
+)
+
+// UserToken is a single-use token mailed to a user. Only the SHA-256 digest
+// of the token is stored; the token itself is only ever in the email.
+type UserToken struct {
+	ID        uint         `gorm:"primaryKey" json:"id"`
+	UserID    uint         `gorm:"not null" json:"user_id"`
+	Purpose   TokenPurpose `gorm:"type:varchar(20);not null" json:"purpose"`
+	TokenHash string       `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	// Email is the address the token was sent to; the token no longer
+	// works once the user's email changes
+	Email     string     `gorm:"type:varchar(255);not null;default:''" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// MinPasswordLength is the shortest password accepted
+const MinPasswordLength = 8
+
+// ValidateEmail checks that email is a bare address such as
+// "ada@example.com", without a display name or surrounding spaces
+func ValidateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" || !strings.Contains(EmailDomain(email), ".") {
+		return fmt.Errorf("%w: %q is not a valid email address", ErrInvalidInput, email)
+	}
+	return nil
+}
+
+// ValidatePassword checks that a new password is long enough
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("%w: password must be at least %d characters", ErrInvalidInput, MinPasswordLength)
+	}
+	return nil
+}
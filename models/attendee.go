@@ -0,0 +1,127 @@
+package models
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Attendee is one active registration of an event's attendee list. Name and
+// Email are empty when the user has been deleted.
+type Attendee struct {
+	RegistrationID uint
+	UserID         uint
+	Name           string
+	Email          string
+	RegisteredAt   time.Time
+	CheckedInAt    *time.Time
+	Review         ReviewStatus
+	Answers        map[string]string `gorm:"serializer:json"`
+}
+
+// AttendeeColumn is a column of an attendee list export
+type AttendeeColumn string
+
+// Attendee list columns
+const (
+	ColumnRegistrationID AttendeeColumn = "registration_id"
+	ColumnUserID         AttendeeColumn = "user_id"
+	ColumnName           AttendeeColumn = "name"
+	ColumnEmail          AttendeeColumn = "email"
+	ColumnRegisteredAt   AttendeeColumn = "registered_at"
+	ColumnCheckedIn      AttendeeColumn = "checked_in"
+	ColumnCheckedInAt    AttendeeColumn = "checked_in_at"
+	ColumnReview         AttendeeColumn = "review"
+	// ColumnAnswers holds every custom answer, one "question: answer" line
+	// each
+	ColumnAnswers AttendeeColumn = "answers"
+)
+
+// AnswerColumnPrefix starts the column of a single custom question, such as
+// "answer:Dietary needs"
+const AnswerColumnPrefix = "answer:"
+
+// AttendeeColumns lists every attendee list column, in their default order;
+// single answer columns come on top of these
+var AttendeeColumns = []AttendeeColumn{
+	ColumnRegistrationID, ColumnUserID, ColumnName, ColumnEmail,
+	ColumnRegisteredAt, ColumnCheckedIn, ColumnCheckedInAt, ColumnReview, ColumnAnswers,
+}
+
+// DefaultAttendeeColumns are exported when no columns are asked for
+var DefaultAttendeeColumns = []AttendeeColumn{
+	ColumnRegistrationID, ColumnName, ColumnEmail, ColumnRegisteredAt, ColumnCheckedIn,
+}
+
+// ParseAttendeeColumns parses a comma-separated list of columns, such as
+// "name,email,answer:Dietary needs". An empty list selects
+// DefaultAttendeeColumns.
+func ParseAttendeeColumns(list string) ([]AttendeeColumn, error) {
+	if strings.TrimSpace(list) == "" {
+		return DefaultAttendeeColumns, nil
+	}
+	var columns []AttendeeColumn
+	for name := range strings.SplitSeq(list, ",") {
+		column := AttendeeColumn(strings.TrimSpace(name))
+		if !slices.Contains(AttendeeColumns, column) && column.Question() == "" {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidInput, column)
+		}
+		if slices.Contains(columns, column) {
+			return nil, fmt.Errorf("%w: column %q given twice", ErrInvalidInput, column)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// Question returns the custom question of a single answer column, or ""
+// for other columns
+func (c AttendeeColumn) Question() string {
+	question, _ := strings.CutPrefix(string(c), AnswerColumnPrefix)
+	if question == string(c) {
+		return ""
+	}
+	return strings.TrimSpace(question)
+}
+
+// Value formats an attendee's value of the column as text, with times in
+// RFC 3339
+func (c AttendeeColumn) Value(a Attendee) string {
+	if question := c.Question(); question != "" {
+		return a.Answers[question]
+	}
+	switch c {
+	case ColumnRegistrationID:
+		return strconv.FormatUint(uint64(a.RegistrationID), 10)
+	case ColumnUserID:
+		return strconv.FormatUint(uint64(a.UserID), 10)
+	case ColumnName:
+		return a.Name
+	case ColumnEmail:
+		return a.Email
+	case ColumnRegisteredAt:
+		return a.RegisteredAt.UTC().Format(time.RFC3339)
+	case ColumnCheckedIn:
+		if a.CheckedInAt != nil {
+			return "yes"
+		}
+		return "no"
+	case ColumnCheckedInAt:
+		if a.CheckedInAt == nil {
+			return ""
+		}
+		return a.CheckedInAt.UTC().Format(time.RFC3339)
+	case ColumnReview:
+		return string(a.Review)
+	case ColumnAnswers:
+		lines := make([]string, 0, len(a.Answers))
+		for _, question := range slices.Sorted(maps.Keys(a.Answers)) {
+			lines = append(lines, question+": "+a.Answers[question])
+		}
+		return strings.Join(lines, "\n")
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audit target types
+const (
+	AuditTargetUser         = "user"
+	AuditTargetEvent        = "event"
+	AuditTargetRegistration = "registration"
+)
+
+// Actor is who made a change and where the request came from. UserID is
+// nil when the caller did not identify themselves, as with eventctl.
+type Actor struct {
+	UserID    *uint
+	RequestID string
+	IP        string
+}
+
+// AuditEvent records one change to a user, event or registration. It is
+// written in the same transaction as the change and never updated, except
+// that erasing a user redacts their personal data.
+type AuditEvent struct {
+	ID         uint            `gorm:"primaryKey" json:"id"`
+	ActorID    *uint           `gorm:"index" json:"actor_id"`
+	Action     Action          `gorm:"type:varchar(50);not null" json:"action"`
+	TargetType string          `gorm:"type:varchar(50);not null;index:idx_audit_events_target,priority:1" json:"target_type"`
+	TargetID   uint            `gorm:"not null;index:idx_audit_events_target,priority:2" json:"target_id"`
+	EventID    *uint           `gorm:"index" json:"event_id,omitempty"` // the event a change concerns, for organizer queries
+	Before     json.RawMessage `gorm:"column:before_state;type:text" json:"before"`
+	After      json.RawMessage `gorm:"column:after_state;type:text" json:"after"`
+	RequestID  string          `gorm:"type:varchar(64);not null;default:''" json:"request_id"`
+	IP         string          `gorm:"type:varchar(45);not null;default:''" json:"ip"`
+	CreatedAt  time.Time       `gorm:"index" json:"created_at"`
+}
+
+// NewAuditEvent builds an audit entry for a change by actor. before is nil
+// for creations and after is nil for deletions.
+func NewAuditEvent(actor Actor, action Action, targetType string, targetID uint, before, after any) (*AuditEvent, error) {
+	entry := &AuditEvent{
+		ActorID:    actor.UserID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		RequestID:  actor.RequestID,
+		IP:         actor.IP,
+	}
+	var err error
+	if entry.Before, err = snapshot(before); err != nil {
+		return nil, err
+	}
+	if entry.After, err = snapshot(after); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// snapshot marshals a record, leaving nil records empty
+func snapshot(record any) (json.RawMessage, error) {
+	body, err := json.Marshal(record)
+	if err != nil || string(body) == "null" {
+		return nil, err
+	}
+	return body, nil
+}
+
+// AuditFilter selects audit events; zero fields match everything
+type AuditFilter struct {
+	ActorID    uint
+	Action     Action
+	TargetType string
+	TargetID   uint
+	EventID    uint
+	RequestID  string
+	Since      time.Time
+	Until      time.Time
+	// OrganizerID limits the result to events this user organizes
+	OrganizerID uint
+	// BeforeID pages backwards: only entries older than this ID
+	BeforeID uint
+	Limit    int
+}
@@ -0,0 +1,15 @@
+package models
+
+// AvailabilityChannel is the Postgres NOTIFY channel that carries seat
+// availability changes to every replica
+const AvailabilityChannel = "event_availability"
+
+// AvailabilityUpdate is the seat availability of an event after a committed
+// change. Version increases with every change to the event's seats, so
+// clients can order updates and resume from the last one they saw.
+type AvailabilityUpdate struct {
+	EventID        uint  `json:"event_id"`
+	Capacity       int   `json:"capacity"`
+	AvailableSeats int   `json:"available_seats"`
+	Version        int64 `json:"version"`
+}
@@ -0,0 +1,60 @@
+package models
+
+// InventoryMode selects how an event's seats are counted
+type InventoryMode string
+
+const (
+	// InventoryRow keeps the count in events.available_seats, so every
+	// registration for the event locks the same row
+	InventoryRow InventoryMode = "row"
+	// InventorySharded splits the count across event_inventory_shards rows,
+	// so concurrent registrations lock different rows
+	InventorySharded InventoryMode = "sharded"
+)
+
+// Shard count limits for sharded events
+const (
+	DefaultInventoryShards = 16
+	MaxInventoryShards     = 256
+)
+
+// EventInventoryShard holds part of a sharded event's available seats.
+// Every seat taken or returned sets Version to the next value of
+// inventory_version_seq, so the newest shard version grows with every change
+// to the event's availability and is never shared by two changes.
+type EventInventoryShard struct {
+	EventID   uint  `gorm:"primaryKey;autoIncrement:false" json:"event_id"`
+	Shard     int   `gorm:"primaryKey;autoIncrement:false" json:"shard"`
+	Available int   `gorm:"not null;check:chk_inventory_shard_available,available >= 0" json:"available"`
+	Version   int64 `gorm:"not null;default:0" json:"version"`
+}
+
+// InventoryTotals are the summed seats and newest version of a sharded
+// event's shards
+type InventoryTotals struct {
+	EventID   uint
+	Available int
+	Version   int64
+}
+
+// SeatRecount is an event's available seats before and after recomputing
+// them from its registrations
+type SeatRecount struct {
+	EventID    uint `json:"event_id"`
+	Capacity   int  `json:"capacity"`
+	Registered int  `json:"registered"`
+	Before     int  `json:"available_before"`
+	After      int  `json:"available_after"`
+}
+
+// SplitSeats divides seats as evenly as possible across n shards
+func SplitSeats(seats, n int) []int {
+	split := make([]int, n)
+	for i := range split {
+		split[i] = seats / n
+		if i < seats%n {
+			split[i]++
+		}
+	}
+	return split
+}
@@ -2,19 +2,32 @@ package models
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"gorm.io/gorm"
 )
 
 // Custom errors for registration
 var (
-	ErrAlreadyRegistered = errors.New("user already registered for this event")
-	ErrEventFull        = errors.New("event is full")
-	ErrEventNotFound    = errors.New("event not found")
-	ErrUserNotFound     = errors.New("user not found")
-	ErrUnauthorized     = errors.New("unauthorized")
-	ErrInvalidInput     = errors.New("invalid input")
+	ErrAlreadyRegistered  = errors.New("user already registered for this event")
+	ErrEventFull          = errors.New("event is full")
+	ErrEventNotFound      = errors.New("event not found")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrInvalidInput       = errors.New("invalid input")
+	ErrAlreadyCheckedIn   = errors.New("registration already checked in")
+	ErrSeatContention     = errors.New("too many concurrent registrations, please retry")
+	ErrCapacityTooLow     = errors.New("cannot reduce capacity below current registrations")
+	ErrLockTimeout        = errors.New("timed out waiting for the event, please retry")
+	ErrTicketLimitReached = errors.New("ticket limit for this event reached")
+	ErrNotPendingReview   = errors.New("registration is not pending review")
+	ErrEmailNotVerified   = errors.New("email address not verified")
+	ErrUserDeactivated    = errors.New("user account is deactivated")
+	ErrInvalidToken       = errors.New("token is invalid or expired")
+	ErrUserErased         = errors.New("user has been erased")
 )
 
 // UserRole represents the role of a user in the system
@@ -23,49 +36,111 @@ type UserRole string
 const (
 	RoleOrganizer UserRole = "organizer"
 	RoleAttendee  UserRole = "attendee"
+	RoleAdmin     UserRole = "admin"
 )
 
 // User represents a user in the event registration system
 type User struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Name      string         `gorm:"type:varchar(255);not null" json:"name"`
-	Email     string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
-	Role      UserRole       `gorm:"type:varchar(50);not null;default:'attendee'" json:"role"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
-	Events    []Event        `gorm:"foreignKey:OrganizerID" json:"-"`
+	ID    uint     `gorm:"primaryKey" json:"id"`
+	Name  string   `gorm:"type:varchar(255);not null" json:"name"`
+	Email string   `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
+	Role  UserRole `gorm:"type:varchar(50);not null;default:'attendee'" json:"role"`
+	// Password is only read from requests; the service stores PasswordHash
+	// and clears it
+	Password        string         `gorm:"-" json:"password,omitempty"`
+	PasswordHash    string         `gorm:"type:varchar(255);not null;default:''" json:"-"`
+	EmailVerifiedAt *time.Time     `json:"email_verified_at,omitempty"`
+	DeactivatedAt   *time.Time     `json:"deactivated_at,omitempty"`
+	ErasedAt        *time.Time     `json:"erased_at,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+	Events          []Event        `gorm:"foreignKey:OrganizerID" json:"-"`
+}
+
+// Verified reports whether the user has confirmed their email address
+func (u *User) Verified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// Active reports whether the user's account has not been deactivated
+func (u *User) Active() bool {
+	return u.DeactivatedAt == nil
 }
 
 // Event represents an event in the ticketing system
 type Event struct {
-	ID             uint           `gorm:"primaryKey" json:"id"`
-	Title          string         `gorm:"type:varchar(255);not null" json:"title"`
-	Capacity       int            `gorm:"not null" json:"capacity"`
-	AvailableSeats int            `gorm:"not null" json:"available_seats"`
-	OrganizerID    uint           `gorm:"not null" json:"organizer_id"`
-	Organizer      *User          `gorm:"foreignKey:OrganizerID" json:"organizer,omitempty"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
-	Registrations  []Registration `gorm:"foreignKey:EventID" json:"-"`
+	ID              uint          `gorm:"primaryKey" json:"id"`
+	Title           string        `gorm:"type:varchar(255);not null" json:"title"`
+	Capacity        int           `gorm:"not null" json:"capacity"`
+	AvailableSeats  int           `gorm:"not null" json:"available_seats"`
+	SeatsVersion    int64         `gorm:"not null;default:0;<-:false" json:"-"` // bumped in SQL only, see NotifyAvailabilityWithTx
+	Inventory       InventoryMode `gorm:"type:varchar(20);not null;default:'row'" json:"inventory"`
+	InventoryShards int           `gorm:"not null;default:0" json:"inventory_shards,omitempty"`
+	OrganizerID     uint          `gorm:"not null" json:"organizer_id"`
+	Organizer       *User         `gorm:"foreignKey:OrganizerID" json:"organizer,omitempty"`
+	StartsAt        *time.Time    `json:"starts_at,omitempty"`
+	PublishedAt     *time.Time    `json:"published_at,omitempty"`
+	WaitingRoom     bool          `gorm:"not null;default:false" json:"waiting_room"`
+	// RequireVerifiedEmail admits only attendees with a verified email address
+	RequireVerifiedEmail bool           `gorm:"not null;default:false" json:"require_verified_email"`
+	TicketRules          TicketRules    `gorm:"embedded;embeddedPrefix:rule_" json:"ticket_rules"`
+	CreatedAt            time.Time      `json:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at"`
+	DeletedAt            gorm.DeletedAt `gorm:"index" json:"-"`
+	Registrations        []Registration `gorm:"foreignKey:EventID" json:"-"`
 }
 
 // Registration represents a user's registration for an event
 type Registration struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	UserID    uint           `gorm:"not null" json:"user_id"`
-	EventID   uint           `gorm:"not null" json:"event_id"`
-	User      *User          `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	Event     *Event         `gorm:"foreignKey:EventID" json:"event,omitempty"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;uniqueIndex:idx_registrations_user_event,where:deleted_at IS NULL" json:"user_id"`
+	EventID     uint       `gorm:"not null;uniqueIndex:idx_registrations_user_event,where:deleted_at IS NULL" json:"event_id"`
+	User        *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Event       *Event     `gorm:"foreignKey:EventID" json:"event,omitempty"`
+	CheckedInAt *time.Time `json:"checked_in_at,omitempty"`
+	// Screening keys, see TicketRules; HouseholdKey is a digest of the address
+	EmailDomain        string       `gorm:"type:varchar(255);not null;default:''" json:"-"`
+	PaymentFingerprint string       `gorm:"type:varchar(255);not null;default:''" json:"-"`
+	HouseholdKey       string       `gorm:"type:varchar(64);not null;default:''" json:"-"`
+	Review             ReviewStatus `gorm:"type:varchar(20);not null;default:''" json:"review,omitempty"`
+	// FlagReasons lists, comma-separated, why the registration was flagged
+	FlagReasons string `gorm:"type:varchar(255);not null;default:''" json:"flag_reasons,omitempty"`
+	// Answers maps the event's custom questions to the attendee's answers
+	Answers   map[string]string `gorm:"type:text;serializer:json" json:"answers,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	DeletedAt gorm.DeletedAt    `gorm:"index" json:"-"`
 
-	// Unique constraint on (user_id, event_id) - handled via GORM constraints
+	// Unique constraint on (user_id, event_id) among active registrations, so
+	// a cancelled registration does not block registering again
 }
 
 // TableName specifies the table name for Registration
 func (Registration) TableName() string {
 	return "registrations"
 }
+
+// Limits on the custom answers of a registration
+const (
+	MaxAnswers        = 50
+	MaxQuestionLength = 100
+	MaxAnswerLength   = 1000
+)
+
+// ValidateAnswers checks the custom answers of a registration against the
+// limits above; questions must not be blank
+func ValidateAnswers(answers map[string]string) error {
+	if len(answers) > MaxAnswers {
+		return fmt.Errorf("%w: at most %d answers", ErrInvalidInput, MaxAnswers)
+	}
+	for question, answer := range answers {
+		if strings.TrimSpace(question) == "" || utf8.RuneCountInString(question) > MaxQuestionLength {
+			return fmt.Errorf("%w: questions must have 1 to %d characters", ErrInvalidInput, MaxQuestionLength)
+		}
+		if utf8.RuneCountInString(answer) > MaxAnswerLength {
+			return fmt.Errorf("%w: answers must have at most %d characters", ErrInvalidInput, MaxAnswerLength)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// NotificationType identifies the kind of message sent to a user
+type NotificationType string
+
+const (
+	NotificationRegistrationConfirmed NotificationType = "registration_confirmed"
+	NotificationRegistrationCancelled NotificationType = "registration_cancelled"
+	NotificationEventUpdated          NotificationType = "event_updated"
+	NotificationEventCancelled        NotificationType = "event_cancelled"
+	NotificationEventReminder         NotificationType = "event_reminder"
+	NotificationVerifyEmail           NotificationType = "verify_email"
+	NotificationPasswordReset         NotificationType = "password_reset"
+)
+
+// NotificationTypes lists the notification types about an event, whose
+// templates organizers may override
+var NotificationTypes = []NotificationType{
+	NotificationRegistrationConfirmed,
+	NotificationRegistrationCancelled,
+	NotificationEventUpdated,
+	NotificationEventCancelled,
+	NotificationEventReminder,
+}
+
+// AccountNotificationTypes lists the notification types about a user's
+// account, which carry a token and always use the built-in templates
+var AccountNotificationTypes = []NotificationType{
+	NotificationVerifyEmail,
+	NotificationPasswordReset,
+}
+
+// Valid reports whether t is a known notification type
+func (t NotificationType) Valid() bool {
+	return slices.Contains(NotificationTypes, t) || t.Account()
+}
+
+// Account reports whether t is about a user's account rather than an event
+func (t NotificationType) Account() bool {
+	return slices.Contains(AccountNotificationTypes, t)
+}
+
+// NotificationStatus represents the delivery state of an outbox message
+type NotificationStatus string
+
+const (
+	NotificationPending NotificationStatus = "pending"
+	NotificationSent    NotificationStatus = "sent"
+	NotificationFailed  NotificationStatus = "failed"
+)
+
+// Notification is a rendered message waiting in the outbox for delivery.
+// Rows are written alongside the change that caused them and picked up
+// by the background worker, so a send is never lost when the process dies.
+type Notification struct {
+	ID            uint               `gorm:"primaryKey" json:"id"`
+	Type          NotificationType   `gorm:"type:varchar(50);not null" json:"type"`
+	UserID        uint               `gorm:"index" json:"user_id"`
+	Recipient     string             `gorm:"type:varchar(255);not null" json:"recipient"`
+	Subject       string             `gorm:"type:varchar(255);not null" json:"subject"`
+	TextBody      string             `gorm:"type:text" json:"text_body"`
+	HTMLBody      string             `gorm:"type:text" json:"html_body"`
+	Status        NotificationStatus `gorm:"type:varchar(20);not null;default:'pending';index:idx_notifications_due,priority:1" json:"status"`
+	Attempts      int                `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time          `gorm:"index:idx_notifications_due,priority:2" json:"next_attempt_at"`
+	LastError     string             `gorm:"type:text" json:"last_error,omitempty"`
+	SentAt        *time.Time         `json:"sent_at,omitempty"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+}
+
+// NotificationTemplate overrides the built-in template for one notification
+// type on behalf of an organizer. Empty fields fall back to the defaults.
+type NotificationTemplate struct {
+	ID          uint             `gorm:"primaryKey" json:"id"`
+	OrganizerID uint             `gorm:"not null;uniqueIndex:idx_templates_organizer_type" json:"organizer_id"`
+	Type        NotificationType `gorm:"type:varchar(50);not null;uniqueIndex:idx_templates_organizer_type" json:"type"`
+	Subject     string           `gorm:"type:text" json:"subject"`
+	TextBody    string           `gorm:"type:text" json:"text_body"`
+	HTMLBody    string           `gorm:"type:text" json:"html_body"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DomainEventType identifies something that happened to a registration or event.
+// Webhook event types mirror these names one to one.
+type DomainEventType string
+
+const (
+	DomainRegistrationCreated   DomainEventType = "registration.created"
+	DomainRegistrationCancelled DomainEventType = "registration.cancelled"
+	DomainRegistrationCheckedIn DomainEventType = "registration.checked_in"
+	DomainRegistrationFlagged   DomainEventType = "registration.flagged"
+	DomainEventPublished        DomainEventType = "event.published"
+	DomainEventUpdated          DomainEventType = "event.updated"
+	DomainEventCancelled        DomainEventType = "event.cancelled"
+)
+
+// AggregateEvent is the aggregate type for everything that happens to an event
+// and its registrations; relay ordering is guaranteed per aggregate
+const AggregateEvent = "event"
+
+// OutboxEvent is a domain event written in the same transaction as the change
+// it describes. The relay publishes unpublished rows to subscribers, retrying
+// failed ones from NextAttemptAt until they are parked.
+type OutboxEvent struct {
+	ID            uint            `gorm:"primaryKey" json:"id"`
+	AggregateType string          `gorm:"type:varchar(50);not null;index:idx_outbox_aggregate,priority:1" json:"aggregate_type"`
+	AggregateID   uint            `gorm:"not null;index:idx_outbox_aggregate,priority:2" json:"aggregate_id"`
+	Type          DomainEventType `gorm:"type:varchar(50);not null" json:"type"`
+	Payload       string          `gorm:"type:text;not null" json:"payload"`
+	Attempts      int             `gorm:"not null;default:0" json:"attempts"`
+	LastError     string          `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	ParkedAt      *time.Time      `json:"parked_at,omitempty"`
+	PublishedAt   *time.Time      `gorm:"index" json:"published_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// OutboxPayload is the snapshot stored with every domain event. Event is
+// always set; Registration, with its User, is set for registration events.
+type OutboxPayload struct {
+	Event        *Event        `json:"event"`
+	Registration *Registration `json:"registration,omitempty"`
+}
+
+// NewOutboxEvent builds an unpublished outbox row for an event aggregate
+func NewOutboxEvent(eventType DomainEventType, payload OutboxPayload) (*OutboxEvent, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxEvent{
+		AggregateType: AggregateEvent,
+		AggregateID:   payload.Event.ID,
+		Type:          eventType,
+		Payload:       string(body),
+	}, nil
+}
+
+// DecodePayload unmarshals the stored snapshot
+func (e *OutboxEvent) DecodePayload() (*OutboxPayload, error) {
+	var payload OutboxPayload
+	if err := json.Unmarshal([]byte(e.Payload), &payload); err != nil {
+		return nil, err
+	}
+	return &payload, nil
+}
@@ -0,0 +1,35 @@
+package models
+
+// Action is something a user may be allowed to do
+type Action string
+
+const (
+	ActionCreateEvent         Action = "event.create"
+	ActionUpdateEvent         Action = "event.update"
+	ActionPublishEvent        Action = "event.publish"
+	ActionDeleteEvent         Action = "event.delete"
+	ActionViewRegistrations   Action = "event.registrations.view"
+	ActionCheckIn             Action = "registration.check_in"
+	ActionReviewRegistration  Action = "registration.review"
+	ActionApproveRegistration Action = "registration.approve"
+	ActionRejectRegistration  Action = "registration.reject"
+	ActionRegister            Action = "registration.create"
+	ActionCancelRegistration  Action = "registration.cancel"
+	ActionManageTemplates     Action = "templates.manage"
+	ActionManageWebhooks      Action = "webhooks.manage"
+	ActionViewAudit           Action = "audit.view"
+	ActionCreateUser          Action = "user.create"
+	ActionUpdateUser          Action = "user.update"
+	ActionDeleteUser          Action = "user.delete"
+	ActionVerifyEmail         Action = "user.verify_email"
+	ActionResetPassword       Action = "user.reset_password"
+	ActionDeactivateUser      Action = "user.deactivate"
+	ActionEraseUser           Action = "user.erase"
+)
+
+// Permission is whether a user may perform an action, and why
+type Permission struct {
+	Action  Action `json:"action"`
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErasedName replaces the name of an erased user
+const ErasedName = "Erased user"
+
+// ErasedEmail returns the placeholder address of an erased user. The
+// .invalid top-level domain never resolves, so nothing can be mailed to it.
+func ErasedEmail(userID uint) string {
+	return fmt.Sprintf("erased-%d@erased.invalid", userID)
+}
+
+// RedactUser replaces the name and email in every snapshot of userID within
+// a JSON document, and drops the custom answers of the user's registrations.
+// A user snapshot is any object with that "id" and an "email" field, as
+// marshaled User values are, and a registration any object with that
+// "user_id", wherever they are nested, so they are found whatever name or
+// address the user had when they were written. It reports whether anything
+// changed and returns doc as it is otherwise.
+func RedactUser(doc []byte, userID uint) ([]byte, bool, error) {
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, false, err
+	}
+	if !redactUser(value, strconv.FormatUint(uint64(userID), 10), ErasedEmail(userID)) {
+		return doc, false, nil
+	}
+	redacted, err := json.Marshal(value)
+	if err != nil {
+		return nil, false, err
+	}
+	return redacted, true, nil
+}
+
+// redactUser redacts the snapshots of the user with id in a decoded value
+func redactUser(value any, id, erasedEmail string) bool {
+	changed := false
+	switch v := value.(type) {
+	case map[string]any:
+		if number, ok := v["id"].(json.Number); ok && number.String() == id {
+			if email, ok := v["email"]; ok && (email != erasedEmail || v["name"] != ErasedName) {
+				v["name"], v["email"] = ErasedName, erasedEmail
+				changed = true
+			}
+		}
+		if number, ok := v["user_id"].(json.Number); ok && number.String() == id {
+			if _, ok := v["answers"]; ok {
+				delete(v, "answers")
+				changed = true
+			}
+		}
+		for _, child := range v {
+			changed = redactUser(child, id, erasedEmail) || changed
+		}
+	case []any:
+		for _, child := range v {
+			changed = redactUser(child, id, erasedEmail) || changed
+		}
+	}
+	return changed
+}
+
+// UserExport is everything stored about a user, as handed to them on request
+type UserExport struct {
+	ExportedAt    time.Time            `json:"exported_at"`
+	User          *User                `json:"user"`
+	Registrations []RegistrationRecord `json:"registrations"`
+	AuditEvents   []AuditEvent         `json:"audit_events"`
+}
+
+// RegistrationRecord is one of a user's registrations in an export, cancelled
+// ones included, with the screening data that is otherwise never shown
+type RegistrationRecord struct {
+	ID                 uint              `json:"id"`
+	EventID            uint              `json:"event_id"`
+	EventTitle         string            `json:"event_title"`
+	EventStartsAt      *time.Time        `json:"event_starts_at,omitempty"`
+	CheckedInAt        *time.Time        `json:"checked_in_at,omitempty"`
+	EmailDomain        string            `json:"email_domain,omitempty"`
+	PaymentFingerprint string            `json:"payment_fingerprint,omitempty"`
+	HouseholdKey       string            `json:"household_key,omitempty"`
+	Review             ReviewStatus      `json:"review,omitempty"`
+	FlagReasons        string            `json:"flag_reasons,omitempty"`
+	Answers            map[string]string `json:"answers,omitempty"`
+	CreatedAt          time.Time         `json:"created_at"`
+	CancelledAt        *time.Time        `json:"cancelled_at,omitempty"`
+}
+
+// NewRegistrationRecord builds the export record of a registration loaded
+// with its event
+func NewRegistrationRecord(registration Registration) RegistrationRecord {
+	record := RegistrationRecord{
+		ID:                 registration.ID,
+		EventID:            registration.EventID,
+		CheckedInAt:        registration.CheckedInAt,
+		EmailDomain:        registration.EmailDomain,
+		PaymentFingerprint: registration.PaymentFingerprint,
+		HouseholdKey:       registration.HouseholdKey,
+		Review:             registration.Review,
+		FlagReasons:        registration.FlagReasons,
+		Answers:            registration.Answers,
+		CreatedAt:          registration.CreatedAt,
+	}
+	if registration.Event != nil {
+		record.EventTitle = registration.Event.Title
+		record.EventStartsAt = registration.Event.StartsAt
+	}
+	if registration.DeletedAt.Valid {
+		record.CancelledAt = &registration.DeletedAt.Time
+	}
+	return record
+}
+
+// PurgeReport counts the soft-deleted rows a retention purge erased or removed
+type PurgeReport struct {
+	UsersErased          int   `json:"users_erased"`
+	UsersDeleted         int64 `json:"users_deleted"`
+	EventsDeleted        int64 `json:"events_deleted"`
+	RegistrationsDeleted int64 `json:"registrations_deleted"`
+}
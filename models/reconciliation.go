@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// SeatCount is an event's seat counter next to its active registrations.
+// For sharded events Available is the sum of the shards.
+type SeatCount struct {
+	EventID    uint `json:"event_id"`
+	Capacity   int  `json:"capacity"`
+	Available  int  `json:"available_seats"`
+	Registered int  `json:"registered"`
+}
+
+// SeatDrift is an event whose seat counter disagrees with its registrations
+type SeatDrift struct {
+	SeatCount
+	// Expected is capacity minus active registrations, never below zero
+	Expected int `json:"expected_available"`
+	// Corrected is set when the counter was reset to Expected
+	Corrected bool `json:"corrected"`
+}
+
+// ReconciliationReport is the result of one pass over every event
+type ReconciliationReport struct {
+	StartedAt     time.Time   `json:"started_at"`
+	FinishedAt    time.Time   `json:"finished_at"`
+	EventsChecked int         `json:"events_checked"`
+	Drifts        []SeatDrift `json:"drifts"`
+}
+
+// ReconciliationStats are running totals of the seat reconciler
+type ReconciliationStats struct {
+	Runs              int64      `json:"runs"`
+	Failures          int64      `json:"failures"`
+	DriftsFound       int64      `json:"drifts_found"`
+	DriftsCorrected   int64      `json:"drifts_corrected"`
+	LastRunAt         *time.Time `json:"last_run_at,omitempty"`
+	LastDriftedEvents int        `json:"last_drifted_events"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// EventReminder is a scheduled reminder for everyone registered for an event.
+// One row exists per configured offset; rows are replaced whenever the
+// event's start time changes.
+type EventReminder struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	EventID       uint       `gorm:"not null;uniqueIndex:idx_reminders_event_offset" json:"event_id"`
+	OffsetSeconds int64      `gorm:"not null;uniqueIndex:idx_reminders_event_offset" json:"offset_seconds"`
+	DueAt         time.Time  `gorm:"not null;index" json:"due_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Offset returns how long before the event start the reminder is sent
+func (r EventReminder) Offset() time.Duration {
+	return time.Duration(r.OffsetSeconds) * time.Second
+}
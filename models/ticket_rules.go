@@ -0,0 +1,103 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode"
+)
+
+// OverLimit is what happens to a registration over one of an event's ticket limits
+type OverLimit string
+
+const (
+	// OverLimitReject turns the registration away with ErrTicketLimitReached
+	OverLimitReject OverLimit = "reject"
+	// OverLimitFlag accepts the registration and flags it for review
+	OverLimitFlag OverLimit = "flag"
+)
+
+// TicketRules are an event's limits against scalping; zero limits are off.
+// The ticket limits count the event's active registrations sharing an
+// email domain, payment instrument or household address with a new one.
+// The velocity check counts registrations for any event, cancelled ones
+// included, by the same user, payment instrument or household within the
+// last VelocityWindowSeconds; going over it always only flags.
+type TicketRules struct {
+	MaxPerEmailDomain       int       `gorm:"not null;default:0" json:"max_per_email_domain"`
+	MaxPerPaymentInstrument int       `gorm:"not null;default:0" json:"max_per_payment_instrument"`
+	MaxPerHousehold         int       `gorm:"not null;default:0" json:"max_per_household"`
+	OverLimit               OverLimit `gorm:"type:varchar(10);not null;default:'reject'" json:"over_limit"`
+	VelocityMax             int       `gorm:"not null;default:0" json:"velocity_max"`
+	VelocityWindowSeconds   int       `gorm:"not null;default:0" json:"velocity_window_seconds"`
+}
+
+// ScreeningKey is what registrations are grouped by when screening a new one
+type ScreeningKey string
+
+const (
+	ScreenUser              ScreeningKey = "user"
+	ScreenEmailDomain       ScreeningKey = "email_domain"
+	ScreenPaymentInstrument ScreeningKey = "payment_instrument"
+	ScreenHousehold         ScreeningKey = "household"
+)
+
+// FlagVelocity is the flag reason of registrations over the velocity check.
+// Registrations over a ticket limit are flagged with the limit's
+// ScreeningKey.
+const FlagVelocity = "velocity"
+
+// ReviewStatus is where a flagged registration stands with the organizer
+type ReviewStatus string
+
+const (
+	ReviewNone     ReviewStatus = ""
+	ReviewPending  ReviewStatus = "pending"
+	ReviewApproved ReviewStatus = "approved"
+	// ReviewRejected registrations are cancelled as they are rejected
+	ReviewRejected ReviewStatus = "rejected"
+)
+
+// RegistrationDetails are what an attendee tells about themselves when
+// registering. All are optional.
+type RegistrationDetails struct {
+	// PaymentFingerprint identifies the card or account paid with, as the
+	// payment provider fingerprints it; it never holds card data
+	PaymentFingerprint string
+	// Address is the attendee's postal address; only HouseholdKey of it
+	// is stored
+	Address string
+	// Answers are the attendee's answers to the event's custom questions,
+	// stored as given
+	Answers map[string]string
+}
+
+// EmailDomain returns the lowercased domain of an email address, or "" when
+// it has none
+func EmailDomain(email string) string {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(email[at+1:]))
+}
+
+// HouseholdKey returns a digest of a postal address that is equal for the
+// same address however it is spelled in case, spacing and punctuation, or
+// "" when the address is empty
+func HouseholdKey(address string) string {
+	var normalized strings.Builder
+	for _, field := range strings.FieldsFunc(strings.ToLower(address), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if normalized.Len() > 0 {
+			normalized.WriteByte(' ')
+		}
+		normalized.WriteString(field)
+	}
+	if normalized.Len() == 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(normalized.String()))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Errors returned by the waiting room
+var (
+	ErrWaitingRoomDisabled = errors.New("event has no waiting room")
+	ErrInvalidQueueToken   = errors.New("invalid queue token")
+	ErrNotAdmitted         = errors.New("not admitted from the waiting room yet")
+	ErrAdmissionExpired    = errors.New("waiting room admission expired")
+)
+
+// WaitingRoomEntry is a user's place in an event's waiting room. Entries are
+// admitted in id order; an admitted entry may register until ExpiresAt.
+type WaitingRoomEntry struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	EventID    uint       `gorm:"not null;uniqueIndex:idx_waiting_room_event_user;index:idx_waiting_room_queue,priority:1" json:"event_id"`
+	UserID     uint       `gorm:"not null;uniqueIndex:idx_waiting_room_event_user" json:"user_id"`
+	AdmittedAt *time.Time `gorm:"index:idx_waiting_room_queue,priority:2" json:"admitted_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// WaitingRoomSchedule is when an event's waiting room may next admit a batch
+type WaitingRoomSchedule struct {
+	EventID     uint      `gorm:"primaryKey;autoIncrement:false"`
+	NextAdmitAt time.Time `gorm:"not null"`
+}
+
+// QueueState is where a waiting room entry stands
+type QueueState string
+
+const (
+	QueueWaiting  QueueState = "waiting"
+	QueueAdmitted QueueState = "admitted"
+	QueueExpired  QueueState = "expired"
+)
+
+// QueueStatus is what a user in the waiting room is told about their entry
+type QueueStatus struct {
+	Token                string     `json:"token,omitempty"`
+	EventID              uint       `json:"event_id"`
+	Status               QueueState `json:"status"`
+	Position             int64      `json:"position,omitempty"`
+	EstimatedWaitSeconds int64      `json:"estimated_wait_seconds,omitempty"`
+	AdmittedUntil        *time.Time `json:"admitted_until,omitempty"`
+}
@@ -0,0 +1,92 @@
+package models
+
+import "time"
+
+// WebhookEventType identifies a lifecycle event delivered to webhook subscribers
+type WebhookEventType string
+
+const (
+	WebhookRegistrationCreated   WebhookEventType = "registration.created"
+	WebhookRegistrationCancelled WebhookEventType = "registration.cancelled"
+	WebhookRegistrationCheckedIn WebhookEventType = "registration.checked_in"
+	WebhookRegistrationFlagged   WebhookEventType = "registration.flagged"
+	WebhookEventPublished        WebhookEventType = "event.published"
+	WebhookEventUpdated          WebhookEventType = "event.updated"
+	WebhookEventCancelled        WebhookEventType = "event.cancelled"
+)
+
+// WebhookEventTypes lists every event type a subscription can ask for
+var WebhookEventTypes = []WebhookEventType{
+	WebhookRegistrationCreated,
+	WebhookRegistrationCancelled,
+	WebhookRegistrationCheckedIn,
+	WebhookRegistrationFlagged,
+	WebhookEventPublished,
+	WebhookEventUpdated,
+	WebhookEventCancelled,
+}
+
+// Valid reports whether t is a known webhook event type
+func (t WebhookEventType) Valid() bool {
+	for _, known := range WebhookEventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookSubscription is an organizer's endpoint for lifecycle events.
+// The signing secret is never serialized; handlers reveal it only when it
+// is created or rotated.
+type WebhookSubscription struct {
+	ID          uint               `gorm:"primaryKey" json:"id"`
+	OrganizerID uint               `gorm:"not null;index" json:"organizer_id"`
+	URL         string             `gorm:"type:text;not null" json:"url"`
+	Secret      string             `gorm:"type:varchar(255);not null" json:"-"`
+	EventTypes  []WebhookEventType `gorm:"type:text;serializer:json;not null" json:"event_types"`
+	Active      bool               `gorm:"not null;default:true" json:"active"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// Wants reports whether the subscription should receive events of type t
+func (s *WebhookSubscription) Wants(t WebhookEventType) bool {
+	if !s.Active {
+		return false
+	}
+	for _, want := range s.EventTypes {
+		if want == t {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeliveryStatus represents the state of a single webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one signed payload sent, or waiting to be sent, to one
+// subscription. Together the rows form the delivery log.
+type WebhookDelivery struct {
+	ID             uint                  `gorm:"primaryKey" json:"id"`
+	SubscriptionID uint                  `gorm:"not null;index" json:"subscription_id"`
+	Subscription   *WebhookSubscription  `gorm:"foreignKey:SubscriptionID" json:"-"`
+	EventID        string                `gorm:"type:varchar(64);not null" json:"event_id"`
+	EventType      WebhookEventType      `gorm:"type:varchar(50);not null" json:"event_type"`
+	Payload        string                `gorm:"type:text;not null" json:"payload"`
+	Status         WebhookDeliveryStatus `gorm:"type:varchar(20);not null;default:'pending';index:idx_webhook_deliveries_due,priority:1" json:"status"`
+	Attempts       int                   `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time             `gorm:"index:idx_webhook_deliveries_due,priority:2" json:"next_attempt_at"`
+	ResponseStatus int                   `json:"response_status,omitempty"`
+	LastError      string                `gorm:"type:text" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
@@ -0,0 +1,132 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer is a minimal SMTP stand-in that accepts one message
+// and hands its envelope and data back on a channel
+type fakeSMTPServer struct {
+	ln       net.Listener
+	received chan receivedMail
+}
+
+type receivedMail struct {
+	from, to, data string
+}
+
+func startFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTPServer{ln: ln, received: make(chan receivedMail, 1)}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTPServer) serve() {
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	var mail receivedMail
+	reply("220 localhost fake SMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			mail.from = strings.Trim(strings.TrimSpace(line)[len("MAIL FROM:"):], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			mail.to = strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>")
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			mail.data = data.String()
+			reply("250 OK")
+			s.received <- mail
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPSenderDeliversMultipartMessage(t *testing.T) {
+	server := startFakeSMTPServer(t)
+	host, port, _ := net.SplitHostPort(server.ln.Addr().String())
+
+	sender := NewSMTPSender(SMTPConfig{Host: host, Port: port, From: "events@example.com"})
+	err := sender.Send(context.Background(), Message{
+		To:      "jane@example.com",
+		Subject: "You're registered",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mail := <-server.received
+	if mail.from != "events@example.com" || mail.to != "jane@example.com" {
+		t.Fatalf("envelope = %q -> %q", mail.from, mail.to)
+	}
+	for _, want := range []string{
+		"Subject: You're registered",
+		"multipart/alternative",
+		"text/plain",
+		"plain body",
+		"text/html",
+		"<p>html body</p>",
+	} {
+		if !strings.Contains(mail.data, want) {
+			t.Errorf("message data missing %q:\n%s", want, mail.data)
+		}
+	}
+}
+
+func TestEncodeHeaderFoldsLineBreaks(t *testing.T) {
+	for value, want := range map[string]string{
+		"Gala\r\nBcc: eve@example.com": "Gala Bcc: eve@example.com",
+		"Gala\rBcc: eve@example.com":   "Gala Bcc: eve@example.com",
+		"Gala\nBcc: eve@example.com":   "Gala Bcc: eve@example.com",
+	} {
+		if got := encodeHeader(value); got != want {
+			t.Errorf("encodeHeader(%q) = %q, want %q", value, got, want)
+		}
+	}
+	if got := encodeHeader("Café\r\nBcc: eve@example.com"); strings.ContainsAny(got, "\r\n") {
+		t.Errorf("encoded header %q contains a line break", got)
+	}
+}
@@ -0,0 +1,149 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"event-api/models"
+
+	"gorm.io/gorm"
+)
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// TemplateData is the value passed to every notification template. Event
+// is set for event notifications; Token and ExpiresAt for account ones.
+type TemplateData struct {
+	User      *models.User
+	Event     *models.Event
+	Token     string
+	ExpiresAt time.Time
+}
+
+// TemplateSource looks up organizer-specific template overrides.
+// It returns gorm.ErrRecordNotFound when the organizer has no override.
+type TemplateSource interface {
+	FindTemplate(ctx context.Context, organizerID uint, notificationType models.NotificationType) (*models.NotificationTemplate, error)
+}
+
+// Renderer turns a notification type and its data into a Message,
+// preferring an organizer's override over the built-in template
+type Renderer struct {
+	source TemplateSource
+}
+
+// NewRenderer creates a Renderer. source may be nil, in which case
+// only the built-in templates are used.
+func NewRenderer(source TemplateSource) *Renderer {
+	return &Renderer{source: source}
+}
+
+// Render renders the subject, text and HTML bodies for a notification
+func (r *Renderer) Render(ctx context.Context, organizerID uint, notificationType models.NotificationType, data TemplateData) (Message, error) {
+	if !notificationType.Valid() {
+		return Message{}, fmt.Errorf("unknown notification type %q", notificationType)
+	}
+
+	override := &models.NotificationTemplate{}
+	if r.source != nil {
+		found, err := r.source.FindTemplate(ctx, organizerID, notificationType)
+		switch {
+		case err == nil:
+			override = found
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return Message{}, err
+		}
+	}
+
+	subject, err := r.renderText(notificationType, "subject", override.Subject, data)
+	if err != nil {
+		return Message{}, err
+	}
+	text, err := r.renderText(notificationType, "txt", override.TextBody, data)
+	if err != nil {
+		return Message{}, err
+	}
+	html, err := r.renderHTML(notificationType, override.HTMLBody, data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	msg := Message{
+		Subject: strings.TrimSpace(subject),
+		Text:    text,
+		HTML:    html,
+	}
+	if data.User != nil {
+		msg.To = data.User.Email
+	}
+	return msg, nil
+}
+
+// Validate checks that an override parses, so broken templates are rejected
+// when they are saved rather than when a message is sent
+func Validate(template *models.NotificationTemplate) error {
+	if _, err := texttemplate.New("subject").Parse(template.Subject); err != nil {
+		return fmt.Errorf("subject: %w", err)
+	}
+	if _, err := texttemplate.New("text").Parse(template.TextBody); err != nil {
+		return fmt.Errorf("text_body: %w", err)
+	}
+	if _, err := htmltemplate.New("html").Parse(template.HTMLBody); err != nil {
+		return fmt.Errorf("html_body: %w", err)
+	}
+	return nil
+}
+
+// renderText renders a text/template part, falling back to the built-in one
+func (r *Renderer) renderText(notificationType models.NotificationType, part, override string, data TemplateData) (string, error) {
+	src, err := templateSource(notificationType, part, override)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := texttemplate.New(string(notificationType) + "." + part).Parse(src)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// renderHTML renders the html/template part, falling back to the built-in one
+func (r *Renderer) renderHTML(notificationType models.NotificationType, override string, data TemplateData) (string, error) {
+	src, err := templateSource(notificationType, "html", override)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := htmltemplate.New(string(notificationType) + ".html").Parse(src)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// templateSource returns the override if set, otherwise the embedded default
+func templateSource(notificationType models.NotificationType, part, override string) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+	b, err := defaultTemplates.ReadFile(fmt.Sprintf("templates/%s.%s.tmpl", notificationType, part))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,122 @@
+package notification
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"event-api/models"
+
+	"gorm.io/gorm"
+)
+
+type stubTemplateSource map[models.NotificationType]*models.NotificationTemplate
+
+func (s stubTemplateSource) FindTemplate(_ context.Context, organizerID uint, notificationType models.NotificationType) (*models.NotificationTemplate, error) {
+	if t, ok := s[notificationType]; ok && t.OrganizerID == organizerID {
+		return t, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func testData() TemplateData {
+	return TemplateData{
+		User:  &models.User{ID: 7, Name: "Jane <Attendee>", Email: "jane@example.com"},
+		Event: &models.Event{ID: 3, Title: "Go Conference", OrganizerID: 1},
+	}
+}
+
+func TestRenderDefaultTemplates(t *testing.T) {
+	r := NewRenderer(nil)
+
+	for _, typ := range models.NotificationTypes {
+		msg, err := r.Render(context.Background(), 1, typ, testData())
+		if err != nil {
+			t.Fatalf("%s: %v", typ, err)
+		}
+		if msg.To != "jane@example.com" {
+			t.Errorf("%s: To = %q", typ, msg.To)
+		}
+		if !strings.Contains(msg.Subject, "Go Conference") {
+			t.Errorf("%s: subject %q does not mention the event", typ, msg.Subject)
+		}
+		if strings.Contains(msg.Subject, "\n") {
+			t.Errorf("%s: subject %q contains a newline", typ, msg.Subject)
+		}
+		if !strings.Contains(msg.Text, "Jane <Attendee>") {
+			t.Errorf("%s: text body should contain the raw name, got %q", typ, msg.Text)
+		}
+		if !strings.Contains(msg.HTML, "Jane &lt;Attendee&gt;") {
+			t.Errorf("%s: HTML body should escape the name, got %q", typ, msg.HTML)
+		}
+	}
+}
+
+func TestRenderOrganizerOverride(t *testing.T) {
+	r := NewRenderer(stubTemplateSource{
+		models.NotificationRegistrationConfirmed: {
+			OrganizerID: 1,
+			Type:        models.NotificationRegistrationConfirmed,
+			Subject:     "Welcome to {{.Event.Title}}!",
+		},
+	})
+
+	msg, err := r.Render(context.Background(), 1, models.NotificationRegistrationConfirmed, testData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "Welcome to Go Conference!" {
+		t.Errorf("Subject = %q, want override", msg.Subject)
+	}
+	if !strings.Contains(msg.Text, "is confirmed") {
+		t.Errorf("text body should fall back to the default, got %q", msg.Text)
+	}
+
+	other, err := r.Render(context.Background(), 2, models.NotificationRegistrationConfirmed, testData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Subject == msg.Subject {
+		t.Error("override for organizer 1 leaked to organizer 2")
+	}
+}
+
+func TestRenderAccountTemplates(t *testing.T) {
+	r := NewRenderer(nil)
+	data := TemplateData{
+		User:      &models.User{ID: 7, Name: "Jane <Attendee>", Email: "jane@example.com"},
+		Token:     "tok_abc",
+		ExpiresAt: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	for _, typ := range models.AccountNotificationTypes {
+		msg, err := r.Render(context.Background(), 0, typ, data)
+		if err != nil {
+			t.Fatalf("%s: %v", typ, err)
+		}
+		if msg.To != "jane@example.com" || msg.Subject == "" {
+			t.Errorf("%s: To = %q, Subject = %q", typ, msg.To, msg.Subject)
+		}
+		for part, body := range map[string]string{"text": msg.Text, "HTML": msg.HTML} {
+			if !strings.Contains(body, "tok_abc") || !strings.Contains(body, "01 May 2026 12:00") {
+				t.Errorf("%s: %s body lacks the token or its expiry: %q", typ, part, body)
+			}
+		}
+	}
+}
+
+func TestRenderUnknownType(t *testing.T) {
+	if _, err := NewRenderer(nil).Render(context.Background(), 1, "nope", testData()); err == nil {
+		t.Fatal("expected an error for an unknown notification type")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(&models.NotificationTemplate{Subject: "{{.Event.Title"}); err == nil {
+		t.Fatal("expected a parse error")
+	}
+	if err := Validate(&models.NotificationTemplate{HTMLBody: "<b>{{.User.Name}}</b>"}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,134 @@
+package notification
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"event-api/models"
+)
+
+// Outbox is the subset of the notification repository the worker needs
+type Outbox interface {
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Notification, error)
+	MarkSent(ctx context.Context, id uint, sentAt time.Time) error
+	MarkRetry(ctx context.Context, id uint, attempts int, nextAttemptAt time.Time, lastError string) error
+	MarkFailed(ctx context.Context, id uint, attempts int, lastError string) error
+}
+
+// WorkerConfig controls how the worker polls and retries
+type WorkerConfig struct {
+	PollInterval time.Duration // how often to look for due messages
+	BatchSize    int           // how many messages to claim per poll
+	MaxAttempts  int           // attempts before a message is marked failed
+	BaseBackoff  time.Duration // delay after the first failure, doubled on each retry
+	MaxBackoff   time.Duration // upper bound on the retry delay
+	SendTimeout  time.Duration // deadline for a single delivery attempt
+}
+
+// DefaultWorkerConfig returns sensible defaults for production use
+func DefaultWorkerConfig() WorkerConfig {
+	return WorkerConfig{
+		PollInterval: 5 * time.Second,
+		BatchSize:    50,
+		MaxAttempts:  8,
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   time.Hour,
+		SendTimeout:  30 * time.Second,
+	}
+}
+
+// Worker delivers pending notifications from the outbox with retries
+type Worker struct {
+	outbox Outbox
+	sender Sender
+	cfg    WorkerConfig
+	now    func() time.Time
+}
+
+// NewWorker creates a new Worker
+func NewWorker(outbox Outbox, sender Sender, cfg WorkerConfig) *Worker {
+	return &Worker{outbox: outbox, sender: sender, cfg: cfg, now: time.Now}
+}
+
+// Run polls the outbox until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.ProcessBatch(ctx); err != nil {
+			slog.ErrorContext(ctx, "notification worker: batch failed", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch claims one batch of due notifications and attempts to deliver
+// each of them. It returns the number of messages claimed.
+func (w *Worker) ProcessBatch(ctx context.Context) (int, error) {
+	// Claimed messages are leased for the duration of one delivery attempt
+	// so another replica does not pick them up while we are still sending
+	lease := w.cfg.SendTimeout * time.Duration(w.cfg.BatchSize)
+	notifications, err := w.outbox.ClaimDue(ctx, w.now(), lease, w.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, n := range notifications {
+		if ctx.Err() != nil {
+			break
+		}
+		w.deliver(ctx, n)
+	}
+	return len(notifications), nil
+}
+
+// deliver sends a single notification and records the outcome
+func (w *Worker) deliver(ctx context.Context, n models.Notification) {
+	sendCtx, cancel := context.WithTimeout(ctx, w.cfg.SendTimeout)
+	err := w.sender.Send(sendCtx, Message{
+		To:      n.Recipient,
+		Subject: n.Subject,
+		Text:    n.TextBody,
+		HTML:    n.HTMLBody,
+	})
+	cancel()
+
+	if err == nil {
+		if err := w.outbox.MarkSent(ctx, n.ID, w.now()); err != nil {
+			slog.ErrorContext(ctx, "notification worker: mark sent", "notification_id", n.ID, "err", err)
+		}
+		return
+	}
+
+	attempts := n.Attempts + 1
+	if attempts >= w.cfg.MaxAttempts {
+		slog.WarnContext(ctx, "notification worker: giving up", "notification_id", n.ID, "attempts", attempts, "err", err)
+		if err := w.outbox.MarkFailed(ctx, n.ID, attempts, err.Error()); err != nil {
+			slog.ErrorContext(ctx, "notification worker: mark failed", "notification_id", n.ID, "err", err)
+		}
+		return
+	}
+
+	next := w.now().Add(w.backoff(attempts))
+	if err := w.outbox.MarkRetry(ctx, n.ID, attempts, next, err.Error()); err != nil {
+		slog.ErrorContext(ctx, "notification worker: reschedule", "notification_id", n.ID, "err", err)
+	}
+}
+
+// backoff returns the delay before the given attempt number is retried
+func (w *Worker) backoff(attempts int) time.Duration {
+	d := w.cfg.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= w.cfg.MaxBackoff {
+			return w.cfg.MaxBackoff
+		}
+	}
+	return d
+}
@@ -0,0 +1,153 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"event-api/models"
+)
+
+// memoryOutbox is an in-memory Outbox used to exercise the worker
+type memoryOutbox struct {
+	mu            sync.Mutex
+	notifications map[uint]*models.Notification
+}
+
+func newMemoryOutbox(ns ...models.Notification) *memoryOutbox {
+	o := &memoryOutbox{notifications: map[uint]*models.Notification{}}
+	for i := range ns {
+		n := ns[i]
+		n.Status = models.NotificationPending
+		o.notifications[n.ID] = &n
+	}
+	return o
+}
+
+func (o *memoryOutbox) ClaimDue(_ context.Context, now time.Time, lease time.Duration, limit int) ([]models.Notification, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var due []models.Notification
+	for _, n := range o.notifications {
+		if len(due) == limit {
+			break
+		}
+		if n.Status == models.NotificationPending && !n.NextAttemptAt.After(now) {
+			due = append(due, *n)
+			n.NextAttemptAt = now.Add(lease)
+		}
+	}
+	return due, nil
+}
+
+func (o *memoryOutbox) MarkSent(_ context.Context, id uint, sentAt time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	n := o.notifications[id]
+	n.Status = models.NotificationSent
+	n.Attempts++
+	n.SentAt = &sentAt
+	return nil
+}
+
+func (o *memoryOutbox) MarkRetry(_ context.Context, id uint, attempts int, next time.Time, lastError string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	n := o.notifications[id]
+	n.Attempts = attempts
+	n.NextAttemptAt = next
+	n.LastError = lastError
+	return nil
+}
+
+func (o *memoryOutbox) MarkFailed(_ context.Context, id uint, attempts int, lastError string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	n := o.notifications[id]
+	n.Status = models.NotificationFailed
+	n.Attempts = attempts
+	n.LastError = lastError
+	return nil
+}
+
+func (o *memoryOutbox) get(id uint) models.Notification {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return *o.notifications[id]
+}
+
+func testWorkerConfig() WorkerConfig {
+	return WorkerConfig{
+		PollInterval: time.Millisecond,
+		BatchSize:    10,
+		MaxAttempts:  3,
+		BaseBackoff:  time.Minute,
+		MaxBackoff:   time.Hour,
+		SendTimeout:  time.Second,
+	}
+}
+
+func TestWorkerDeliversDueNotifications(t *testing.T) {
+	outbox := newMemoryOutbox(models.Notification{ID: 1, Recipient: "a@example.com", Subject: "hi"})
+	sender := NewMemorySender()
+	w := NewWorker(outbox, sender, testWorkerConfig())
+
+	n, err := w.ProcessBatch(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("ProcessBatch = %d, %v", n, err)
+	}
+
+	msgs := sender.Messages()
+	if len(msgs) != 1 || msgs[0].To != "a@example.com" {
+		t.Fatalf("unexpected messages: %+v", msgs)
+	}
+	if got := outbox.get(1); got.Status != models.NotificationSent || got.SentAt == nil {
+		t.Fatalf("notification not marked sent: %+v", got)
+	}
+
+	// A sent notification is never picked up again
+	if n, _ := w.ProcessBatch(context.Background()); n != 0 {
+		t.Fatalf("claimed %d notifications on second pass", n)
+	}
+}
+
+func TestWorkerRetriesWithBackoffThenFails(t *testing.T) {
+	outbox := newMemoryOutbox(models.Notification{ID: 1, Recipient: "a@example.com"})
+	sender := NewMemorySender()
+	sender.FailWith(errors.New("relay unavailable"))
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	w := NewWorker(outbox, sender, testWorkerConfig())
+	w.now = func() time.Time { return now }
+
+	w.ProcessBatch(context.Background())
+	got := outbox.get(1)
+	if got.Status != models.NotificationPending || got.Attempts != 1 {
+		t.Fatalf("after first failure: %+v", got)
+	}
+	if want := now.Add(time.Minute); !got.NextAttemptAt.Equal(want) {
+		t.Fatalf("NextAttemptAt = %v, want %v", got.NextAttemptAt, want)
+	}
+
+	// Not due yet
+	if n, _ := w.ProcessBatch(context.Background()); n != 0 {
+		t.Fatalf("claimed %d notifications before backoff elapsed", n)
+	}
+
+	now = now.Add(time.Minute)
+	w.ProcessBatch(context.Background())
+	if got := outbox.get(1); got.Attempts != 2 || !got.NextAttemptAt.Equal(now.Add(2*time.Minute)) {
+		t.Fatalf("after second failure: %+v", got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	w.ProcessBatch(context.Background())
+	if got := outbox.get(1); got.Status != models.NotificationFailed || got.Attempts != 3 {
+		t.Fatalf("after final failure: %+v", got)
+	}
+	if len(sender.Messages()) != 0 {
+		t.Fatal("no message should have been recorded")
+	}
+}
@@ -0,0 +1,191 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"event-api/models"
+	"event-api/repository"
+
+	"gorm.io/gorm"
+)
+
+// savepointName is the savepoint taken before each event is handed to subscribers
+const savepointName = "outbox_event"
+
+// Handler processes one domain event. tx is the relay's transaction: writes
+// made through it commit together with the event being marked published, so
+// database-backed subscribers see each event effectively once. Handlers with
+// effects outside the database must tolerate redelivery.
+type Handler func(ctx context.Context, tx *gorm.DB, event models.OutboxEvent) error
+
+// subscriber is a named Handler
+type subscriber struct {
+	name    string
+	handler Handler
+}
+
+// RelayConfig controls how the relay polls and retries
+type RelayConfig struct {
+	PollInterval time.Duration // how often to look for unpublished events
+	BatchSize    int           // how many events to publish per transaction
+	MaxAttempts  int           // attempts before a failing event is parked
+	BaseBackoff  time.Duration // delay after the first failure, doubled on each retry
+	MaxBackoff   time.Duration // upper bound on the retry delay
+}
+
+// DefaultRelayConfig returns sensible defaults for production use
+func DefaultRelayConfig() RelayConfig {
+	return RelayConfig{
+		PollInterval: time.Second,
+		BatchSize:    100,
+		MaxAttempts:  10,
+		BaseBackoff:  5 * time.Second,
+		MaxBackoff:   10 * time.Minute,
+	}
+}
+
+// Relay publishes committed outbox events to subscribers, at least once and
+// in id order per aggregate
+type Relay struct {
+	db          *gorm.DB
+	outboxRepo  repository.OutboxRepository
+	subscribers []subscriber
+	cfg         RelayConfig
+	now         func() time.Time
+}
+
+// NewRelay creates a new Relay
+func NewRelay(db *gorm.DB, outboxRepo repository.OutboxRepository, cfg RelayConfig) *Relay {
+	return &Relay{
+		db:         db,
+		outboxRepo: outboxRepo,
+		cfg:        cfg,
+		now:        time.Now,
+	}
+}
+
+// Subscribe registers a handler for every domain event. Subscribers are
+// called in registration order; it must be called before Run.
+func (r *Relay) Subscribe(name string, handler Handler) {
+	r.subscribers = append(r.subscribers, subscriber{name: name, handler: handler})
+}
+
+// Run publishes events until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while whole batches are published, then wait for the
+		// next tick; a batch with failures waits too
+		for {
+			n, err := r.ProcessBatch(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "outbox relay: batch failed", "err", err)
+			}
+			if err != nil || n < r.cfg.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+/*
+ProcessBatch publishes one batch of events and returns how many were published.
+
+The whole batch runs in one transaction that first takes the relay lock, so
+only one replica publishes at a time. Each event gets a savepoint: if any
+subscriber fails, its writes are rolled back to the savepoint, the failure is
+recorded, and later events for the same aggregate are held back until the
+failed one succeeds. The failed event is retried with exponential backoff;
+after MaxAttempts it is parked, which releases the events behind it. Events
+for other aggregates carry on.
+*/
+func (r *Relay) ProcessBatch(ctx context.Context) (int, error) {
+	published := 0
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		locked, err := r.outboxRepo.TryLockRelayWithTx(ctx, tx)
+		if err != nil || !locked {
+			return err
+		}
+
+		events, err := r.outboxRepo.FindUnpublishedWithTx(ctx, tx, r.now(), r.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		blocked := make(map[string]bool)
+		for _, event := range events {
+			key := fmt.Sprintf("%s:%d", event.AggregateType, event.AggregateID)
+			if blocked[key] {
+				continue
+			}
+
+			if err := tx.SavePoint(savepointName).Error; err != nil {
+				return err
+			}
+			if err := r.publish(ctx, tx, event); err != nil {
+				slog.ErrorContext(ctx, "outbox relay: publish failed", "outbox_event_id", event.ID, "type", event.Type, "err", err)
+				if err := tx.RollbackTo(savepointName).Error; err != nil {
+					return err
+				}
+				if err := r.recordFailureWithTx(ctx, tx, event, err); err != nil {
+					return err
+				}
+				blocked[key] = true
+				continue
+			}
+			if err := r.outboxRepo.MarkPublishedWithTx(ctx, tx, event.ID, r.now()); err != nil {
+				return err
+			}
+			published++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return published, nil
+}
+
+// recordFailureWithTx schedules the retry of a failed event, or parks it
+// once it has used up its attempts
+func (r *Relay) recordFailureWithTx(ctx context.Context, tx *gorm.DB, event models.OutboxEvent, publishErr error) error {
+	attempts := event.Attempts + 1
+	if attempts >= r.cfg.MaxAttempts {
+		slog.WarnContext(ctx, "outbox relay: parking event", "outbox_event_id", event.ID, "type", event.Type, "attempts", attempts, "err", publishErr)
+		return r.outboxRepo.ParkWithTx(ctx, tx, event.ID, publishErr.Error(), r.now())
+	}
+	return r.outboxRepo.RecordFailureWithTx(ctx, tx, event.ID, publishErr.Error(), r.now().Add(r.backoff(attempts)))
+}
+
+// backoff returns the delay before the given attempt number is retried
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := r.cfg.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= r.cfg.MaxBackoff {
+			return r.cfg.MaxBackoff
+		}
+	}
+	return d
+}
+
+// publish hands an event to every subscriber in turn
+func (r *Relay) publish(ctx context.Context, tx *gorm.DB, event models.OutboxEvent) error {
+	for _, sub := range r.subscribers {
+		if err := sub.handler(ctx, tx, event); err != nil {
+			return fmt.Errorf("%s: %w", sub.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"event-api/internal/testdb"
+	"event-api/models"
+	"event-api/repository"
+
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) { os.Exit(testdb.Main(m)) }
+
+// backends are the databases the relay runs on
+var backends = map[string]func(t testing.TB) *gorm.DB{
+	"sqlite":   testdb.NewSQLite,
+	"postgres": testdb.New,
+}
+
+func testRelayConfig() RelayConfig {
+	return RelayConfig{
+		PollInterval: time.Hour,
+		BatchSize:    10,
+		MaxAttempts:  3,
+		BaseBackoff:  time.Second,
+		MaxBackoff:   time.Minute,
+	}
+}
+
+// newTestRelay returns a relay on db with a clock that only moves when
+// the test advances it
+func newTestRelay(db *gorm.DB, cfg RelayConfig) (*Relay, *time.Time) {
+	now := time.Now().UTC().Truncate(time.Second)
+	relay := NewRelay(db, repository.NewOutboxRepository(db), cfg)
+	relay.now = func() time.Time { return now }
+	return relay, &now
+}
+
+// writeEvents stores one outbox event per aggregate ID, in order, and
+// returns their IDs
+func writeEvents(t *testing.T, db *gorm.DB, aggregateIDs ...uint) []uint {
+	t.Helper()
+	ids := make([]uint, len(aggregateIDs))
+	for i, aggregateID := range aggregateIDs {
+		event := models.OutboxEvent{AggregateType: models.AggregateEvent, AggregateID: aggregateID, Type: models.DomainEventUpdated, Payload: "{}"}
+		if err := db.Create(&event).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = event.ID
+	}
+	return ids
+}
+
+// loadEvent reads an outbox event back
+func loadEvent(t *testing.T, db *gorm.DB, id uint) models.OutboxEvent {
+	t.Helper()
+	var event models.OutboxEvent
+	if err := db.First(&event, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func TestRelayKeepsOrderPerAggregate(t *testing.T) {
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			db := open(t)
+			relay, now := newTestRelay(db, testRelayConfig())
+
+			// Aggregate 1's first event fails once; its writes must not stick
+			ids := writeEvents(t, db, 1, 2, 1, 1, 2)
+			var published []uint
+			failed := false
+			relay.Subscribe("test", func(ctx context.Context, tx *gorm.DB, event models.OutboxEvent) error {
+				if err := tx.Create(&models.Notification{Type: models.NotificationEventUpdated, Recipient: "a@example.com", Subject: "s"}).Error; err != nil {
+					return err
+				}
+				if event.ID == ids[0] && !failed {
+					failed = true
+					return errors.New("subscriber down")
+				}
+				published = append(published, event.ID)
+				return nil
+			})
+
+			if n, err := relay.ProcessBatch(ctx); err != nil || n != 2 {
+				t.Fatalf("first batch = %d, %v; want aggregate 2's 2 events", n, err)
+			}
+			if want := []uint{ids[1], ids[4]}; !slices.Equal(published, want) {
+				t.Fatalf("published %v, want %v with aggregate 1 held back", published, want)
+			}
+			head := loadEvent(t, db, ids[0])
+			if head.Attempts != 1 || head.LastError != "test: subscriber down" || head.NextAttemptAt == nil || !head.NextAttemptAt.Equal(now.Add(time.Second)) {
+				t.Fatalf("failed event = %+v, want a retry in 1s", head)
+			}
+			var notifications int64
+			db.Model(&models.Notification{}).Count(&notifications)
+			if notifications != 2 {
+				t.Fatalf("%d notifications, want the failed event's rolled back", notifications)
+			}
+
+			// Nothing is due before the backoff ends
+			if n, err := relay.ProcessBatch(ctx); err != nil || n != 0 {
+				t.Fatalf("batch during backoff = %d, %v; want 0", n, err)
+			}
+			*now = now.Add(time.Second)
+			if n, err := relay.ProcessBatch(ctx); err != nil || n != 3 {
+				t.Fatalf("batch after backoff = %d, %v; want aggregate 1's 3 events", n, err)
+			}
+			if want := []uint{ids[1], ids[4], ids[0], ids[2], ids[3]}; !slices.Equal(published, want) {
+				t.Fatalf("published %v, want %v", published, want)
+			}
+		})
+	}
+}
+
+func TestRelayParksEventsThatKeepFailing(t *testing.T) {
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			db := open(t)
+			relay, now := newTestRelay(db, testRelayConfig())
+			ids := writeEvents(t, db, 1, 1)
+			relay.Subscribe("test", func(ctx context.Context, tx *gorm.DB, event models.OutboxEvent) error {
+				if event.ID == ids[0] {
+					return errors.New("poison")
+				}
+				return nil
+			})
+
+			// Retried after 1s and 2s, then parked on the third attempt
+			for _, wait := range []time.Duration{0, time.Second, 2 * time.Second} {
+				*now = now.Add(wait)
+				if n, err := relay.ProcessBatch(ctx); err != nil || n != 0 {
+					t.Fatalf("batch = %d, %v; want the follower held back", n, err)
+				}
+			}
+			parked := loadEvent(t, db, ids[0])
+			if parked.Attempts != 3 || parked.ParkedAt == nil || parked.PublishedAt != nil {
+				t.Fatalf("event after 3 attempts = %+v, want parked", parked)
+			}
+
+			// Parking releases the aggregate
+			if n, err := relay.ProcessBatch(ctx); err != nil || n != 1 {
+				t.Fatalf("batch after parking = %d, %v; want the follower", n, err)
+			}
+			if n, err := relay.ProcessBatch(ctx); err != nil || n != 0 {
+				t.Fatalf("batch with only a parked event = %d, %v; want 0", n, err)
+			}
+		})
+	}
+}
+
+func TestRelayFailingBatchWaitsForNextTick(t *testing.T) {
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			db := open(t)
+			cfg := testRelayConfig()
+			cfg.BatchSize = 2
+			cfg.MaxAttempts = 1000
+			cfg.BaseBackoff = 0 // failed events are due again at once
+			relay := NewRelay(db, repository.NewOutboxRepository(db), cfg)
+
+			// A full batch of failing events behind which others wait
+			writeEvents(t, db, 1, 2, 1, 2, 3)
+			attempts := 0
+			var published []uint
+			relay.Subscribe("test", func(ctx context.Context, tx *gorm.DB, event models.OutboxEvent) error {
+				if event.AggregateID != 3 {
+					attempts++
+					return errors.New("down")
+				}
+				published = append(published, event.ID)
+				return nil
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			relay.Run(ctx)
+			if attempts != 2 {
+				t.Errorf("%d attempts before the next tick, want one per failing aggregate", attempts)
+			}
+			if len(published) != 0 {
+				t.Errorf("published %v beyond a full batch of failures, want them left to the next tick", published)
+			}
+		})
+	}
+}
+
+func TestRelayReadsPastHeldBackEvents(t *testing.T) {
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			db := open(t)
+			cfg := testRelayConfig()
+			cfg.BatchSize = 2
+			relay, _ := newTestRelay(db, cfg)
+
+			// More events wait behind the failing one than fit in a batch
+			ids := writeEvents(t, db, 1, 1, 1, 1, 2)
+			relay.Subscribe("test", func(ctx context.Context, tx *gorm.DB, event models.OutboxEvent) error {
+				if event.AggregateID == 1 {
+					return errors.New("down")
+				}
+				return nil
+			})
+
+			if n, err := relay.ProcessBatch(ctx); err != nil || n != 0 {
+				t.Fatalf("first batch = %d, %v; want 0", n, err)
+			}
+			if n, err := relay.ProcessBatch(ctx); err != nil || n != 1 {
+				t.Fatalf("second batch = %d, %v; want the other aggregate's event", n, err)
+			}
+			if event := loadEvent(t, db, ids[4]); event.PublishedAt == nil {
+				t.Fatalf("event of the other aggregate = %+v, want published", event)
+			}
+		})
+	}
+}
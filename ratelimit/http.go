@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"event-api/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader carries the API key of integrations that call the API
+const APIKeyHeader = "X-API-Key"
+
+// Limiter limits requests per route group and client
+type Limiter struct {
+	store  Store
+	limits map[string]Limit
+	now    func() time.Time
+}
+
+// NewLimiter creates a Limiter applying limits, by route group, with state
+// in store
+func NewLimiter(store Store, limits map[string]Limit) *Limiter {
+	return &Limiter{store: store, limits: limits, now: time.Now}
+}
+
+// Middleware limits the requests of each client to the routes of group.
+// Clients are told their limit in RateLimit-Limit, RateLimit-Remaining,
+// RateLimit-Reset and RateLimit-Policy headers; those over it get 429 with
+// Retry-After. Groups without a limit are not limited. When the store fails,
+// requests are let through rather than turned away.
+func (l *Limiter) Middleware(group string) gin.HandlerFunc {
+	limit, ok := l.limits[group]
+	if !ok {
+		return func(c *gin.Context) { c.Next() }
+	}
+	policy := fmt.Sprintf("%d;w=%d", limit.Requests, seconds(limit.Window))
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		result, err := l.store.Take(ctx, group+":"+clientKey(c), limit, l.now())
+		if err != nil {
+			slog.WarnContext(ctx, "rate limit unavailable, request allowed", "group", group, "err", err)
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+		header.Set("RateLimit-Policy", policy)
+		if !result.Allowed {
+			header.Set("Retry-After", strconv.Itoa(max(seconds(result.RetryAfter), 1)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// clientKey identifies who is making a request: the authenticated user, else
+// the API key, else the client IP
+func clientKey(c *gin.Context) string {
+	if id, err := strconv.ParseUint(c.GetHeader(handler.UserIDHeader), 10, 32); err == nil && id > 0 {
+		return "user:" + strconv.FormatUint(id, 10)
+	}
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		// Keys are secrets; only a digest is kept
+		sum := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(sum[:16])
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// seconds rounds d up to whole seconds
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
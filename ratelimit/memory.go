@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often MemoryStore forgets clients whose limit has
+// fully refilled
+const sweepInterval = time.Minute
+
+// MemoryStore keeps limit state in this process. Each replica counts the
+// requests it serves on its own.
+type MemoryStore struct {
+	mu        sync.Mutex
+	tats      map[string]int64
+	lastSweep int64
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tats: make(map[string]int64)}
+}
+
+// Take counts one request by key against limit at now
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nanos := now.UnixNano()
+	if nanos-s.lastSweep > int64(sweepInterval) {
+		for k, tat := range s.tats {
+			if tat <= nanos {
+				delete(s.tats, k)
+			}
+		}
+		s.lastSweep = nanos
+	}
+
+	tat, result := take(s.tats[key], nanos, limit)
+	s.tats[key] = tat
+	return result, nil
+}
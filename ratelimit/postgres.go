@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// takeSQL applies GCRA in a single statement: it inserts a new client,
+// or moves an existing client's TAT on by one interval when the request is
+// allowed. A denied request updates nothing and returns no row.
+const takeSQL = `
+INSERT INTO rate_limits AS r (key, tat) VALUES (?, ?)
+ON CONFLICT (key) DO UPDATE
+SET tat = CASE WHEN r.tat > ? THEN r.tat ELSE ? END + ?
+WHERE CASE WHEN r.tat > ? THEN r.tat ELSE ? END + ? - ? <= ?
+RETURNING tat`
+
+// PostgresStore keeps limit state in the rate_limits table, so every replica
+// counts against the same limits. It works on SQLite as well.
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+// NewPostgresStore creates a PostgresStore on db
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Take counts one request by key against limit at now
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	nanos := now.UnixNano()
+	interval, window := int64(limit.interval()), int64(limit.Window)
+
+	var tats []int64
+	err := s.db.WithContext(ctx).Raw(takeSQL,
+		key, nanos+interval,
+		nanos, nanos, interval,
+		nanos, nanos, interval, window, nanos,
+	).Scan(&tats).Error
+	if err != nil {
+		return Result{}, err
+	}
+	if len(tats) == 1 {
+		// The TAT before this request was one interval earlier
+		_, result := take(tats[0]-interval, nanos, limit)
+		return result, nil
+	}
+
+	// Denied: read the TAT the request was measured against
+	var tat int64
+	err = s.db.WithContext(ctx).Raw("SELECT tat FROM rate_limits WHERE key = ?", key).Row().Scan(&tat)
+	if err != nil {
+		return Result{}, err
+	}
+	_, result := take(tat, nanos, limit)
+	// The client may have become allowed since, but this request was not
+	result.Allowed = false
+	result.Remaining = 0
+	return result, nil
+}
+
+// Purge deletes the state of clients whose limit has fully refilled by now
+func (s *PostgresStore) Purge(ctx context.Context, now time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).Exec("DELETE FROM rate_limits WHERE tat < ?", now.UnixNano())
+	return result.RowsAffected, result.Error
+}
+
+// Run purges every interval until ctx is cancelled
+func (s *PostgresStore) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Purge(ctx, time.Now()); err != nil && !errors.Is(err, context.Canceled) {
+			slog.ErrorContext(ctx, "rate limit purge failed", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Package ratelimit limits how often each client may call a route group.
+
+Limits follow GCRA, the generic cell rate algorithm: a token bucket of
+Limit.Requests tokens refilled evenly over Limit.Window, kept as a single
+timestamp per client, the theoretical arrival time (TAT) of its next request.
+A request is allowed when it arrives no earlier than TAT minus the window, and
+then moves TAT on by one refill interval. A client that has been idle for a
+window has a TAT in the past and needs no state at all.
+
+The state lives in a Store: MemoryStore keeps it per replica, PostgresStore
+shares it between replicas through the database.
+*/
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests requests per Window, in bursts of up to Requests
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// interval is how often one request's worth of the limit is refilled
+func (l Limit) interval() time.Duration {
+	return l.Window / time.Duration(l.Requests)
+}
+
+// String formats l the way ParseLimits reads it, e.g. "20/1m0s"
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Window)
+}
+
+// Result is the outcome of counting one request against a limit
+type Result struct {
+	Allowed bool
+	// Remaining is how many more requests are allowed right away
+	Remaining int
+	// Reset is how long until the full burst is available again
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed, when this
+	// one was not
+	RetryAfter time.Duration
+}
+
+// Store keeps the limit state of every client
+type Store interface {
+	// Take counts one request by the client key against limit at now
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// take applies GCRA to a request at now by a client whose TAT is tat, both
+// in Unix nanoseconds. It returns the client's new TAT, which is unchanged
+// when the request is not allowed.
+func take(tat, now int64, limit Limit) (int64, Result) {
+	interval, window := int64(limit.interval()), int64(limit.Window)
+	tat = max(tat, now)
+	next := tat + interval
+	allowAt := next - window
+	if now < allowAt {
+		return tat, Result{
+			Reset:      time.Duration(tat - now),
+			RetryAfter: time.Duration(allowAt - now),
+		}
+	}
+	return next, Result{
+		Allowed:   true,
+		Remaining: int((now - allowAt) / interval),
+		Reset:     time.Duration(next - now),
+	}
+}
+
+// ParseLimits reads the limits of route groups from comma-separated
+// group=requests/window pairs, e.g. "registrations=20/1m,users=10/1m"
+func ParseLimits(s string) (map[string]Limit, error) {
+	limits := make(map[string]Limit)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		group, rate, ok := strings.Cut(part, "=")
+		requests, window, ok2 := strings.Cut(rate, "/")
+		if !ok || !ok2 || strings.TrimSpace(group) == "" {
+			return nil, fmt.Errorf("rate limit %q is not group=requests/window", part)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(requests))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("rate limit %q: requests must be a positive integer", part)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(window))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("rate limit %q: window must be a positive duration", part)
+		}
+		limits[strings.TrimSpace(group)] = Limit{Requests: n, Window: d}
+	}
+	return limits, nil
+}
@@ -0,0 +1,167 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"event-api/handler"
+	"event-api/internal/testdb"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testdb.Main(m))
+}
+
+// testLimit allows a burst of 3, refilling one request every 20s
+var testLimit = Limit{Requests: 3, Window: time.Minute}
+
+// assertBucket takes requests at the given offsets from start and checks
+// which are allowed
+func assertBucket(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+	start := time.Unix(1_700_000_000, 0)
+	steps := []struct {
+		at        time.Duration
+		allowed   bool
+		remaining int
+		retry     time.Duration
+	}{
+		{0, true, 2, 0},
+		{0, true, 1, 0},
+		{time.Second, true, 0, 0},
+		{time.Second, false, 0, 19 * time.Second}, // burst spent
+		{20 * time.Second, true, 0, 0},            // one refilled
+		{20 * time.Second, false, 0, 20 * time.Second},
+		{5 * time.Minute, true, 2, 0}, // idle: full burst again
+	}
+	for i, step := range steps {
+		got, err := store.Take(ctx, "client", testLimit, start.Add(step.at))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Allowed != step.allowed || got.Remaining != step.remaining || got.RetryAfter != step.retry {
+			t.Errorf("request %d at %s = %+v, want allowed %v, remaining %d, retry after %s",
+				i, step.at, got, step.allowed, step.remaining, step.retry)
+		}
+	}
+
+	// Clients are limited separately
+	if got, _ := store.Take(ctx, "other", testLimit, start.Add(time.Second)); !got.Allowed {
+		t.Error("another client was limited")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	assertBucket(t, NewMemoryStore())
+}
+
+func TestPostgresStore(t *testing.T) {
+	for _, backend := range []struct {
+		name string
+		open func(testing.TB) *gorm.DB
+	}{
+		{"sqlite", testdb.NewSQLite},
+		{"postgres", testdb.New},
+	} {
+		t.Run(backend.name, func(t *testing.T) {
+			store := NewPostgresStore(backend.open(t))
+			assertBucket(t, store)
+
+			// Only clients whose limit has refilled are purged
+			later := time.Unix(1_700_000_000, 0).Add(5 * time.Minute)
+			if _, err := store.Take(context.Background(), "busy", testLimit, later.Add(time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+			n, err := store.Purge(context.Background(), later.Add(time.Minute))
+			if err != nil || n != 2 {
+				t.Errorf("purged %d rows (err %v), want the 2 idle clients", n, err)
+			}
+		})
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits(" registrations=30/1m, users=5/10s,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limits) != 2 || limits["registrations"] != (Limit{30, time.Minute}) || limits["users"] != (Limit{5, 10 * time.Second}) {
+		t.Errorf("limits = %v", limits)
+	}
+	for _, bad := range []string{"users", "users=5", "users=0/1m", "users=5/soon", "=5/1m"} {
+		if _, err := ParseLimits(bad); err == nil {
+			t.Errorf("%q parsed", bad)
+		}
+	}
+}
+
+// failingStore fails every request
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit, time.Time) (Result, error) {
+	return Result{}, errors.New("database unavailable")
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := NewLimiter(NewMemoryStore(), map[string]Limit{"users": {Requests: 2, Window: time.Minute}})
+	now := time.Unix(1_700_000_000, 0)
+	limiter.now = func() time.Time { return now }
+
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/users", limiter.Middleware("users"), ok)
+	router.POST("/events", limiter.Middleware("events"), ok)
+
+	request := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("/users", nil)
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "2" ||
+		w.Header().Get("RateLimit-Remaining") != "1" || w.Header().Get("RateLimit-Policy") != "2;w=60" {
+		t.Errorf("first request: %d %v", w.Code, w.Header())
+	}
+	request("/users", nil)
+	w = request("/users", nil)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "30" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("third request: %d %v, want 429 retrying after 30s", w.Code, w.Header())
+	}
+
+	// Users and API keys have limits of their own, even behind the same IP
+	if w = request("/users", map[string]string{handler.UserIDHeader: "7"}); w.Code != http.StatusOK {
+		t.Errorf("user 7 got %d", w.Code)
+	}
+	if w = request("/users", map[string]string{APIKeyHeader: "k1"}); w.Code != http.StatusOK {
+		t.Errorf("API key got %d", w.Code)
+	}
+
+	// Groups without a limit are not limited
+	for range 3 {
+		if w = request("/events", nil); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+			t.Errorf("unlimited group: %d %v", w.Code, w.Header())
+		}
+	}
+
+	// A failing store lets requests through
+	limiter.store = failingStore{}
+	if w = request("/users", nil); w.Code != http.StatusOK {
+		t.Errorf("with a failing store got %d", w.Code)
+	}
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"event-api/models"
+
+	"gorm.io/gorm"
+)
+
+// AuditRepository defines the interface for the append-only audit log.
+// There is deliberately no way to change or delete an entry.
+type AuditRepository interface {
+	Find(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error)
+
+	// Transaction support
+	CreateWithTx(ctx context.Context, tx Tx, entry *models.AuditEvent) error
+}
+
+// auditRepository implements AuditRepository
+type auditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository creates a new AuditRepository
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// Find returns the entries matching filter, newest first
+func (r *auditRepository) Find(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	query := r.db.WithContext(ctx).Model(&models.AuditEvent{})
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.EventID != 0 {
+		query = query.Where("event_id = ?", filter.EventID)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+	if filter.OrganizerID != 0 {
+		// Deleted events included, so their history stays visible
+		query = query.Where("event_id IN (SELECT id FROM events WHERE organizer_id = ?)", filter.OrganizerID)
+	}
+	if filter.BeforeID != 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var entries []models.AuditEvent
+	err := query.Order("id DESC").Find(&entries).Error
+	return entries, err
+}
+
+// CreateWithTx appends an entry in the caller's transaction, so it exists
+// if and only if the change it describes commits
+func (r *auditRepository) CreateWithTx(ctx context.Context, tx Tx, entry *models.AuditEvent) error {
+	return txDB(ctx, tx).Create(entry).Error
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "gorm.io/gorm"
+
+// isSQLite reports whether db uses SQLite. SQLite has no row or advisory
+// locks and no notifications, but its transactions begin IMMEDIATE (see
+// config.OpenSQLite) and so already run one at a time.
+func isSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == "sqlite"
+}
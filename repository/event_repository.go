@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"event-api/models"
 
 	"gorm.io/gorm"
@@ -9,16 +12,27 @@ import (
 
 // EventRepository defines the interface for event data access
 type EventRepository interface {
-	Create(event *models.Event) error
-	FindByID(id uint) (*models.Event, error)
-	FindAll() ([]models.Event, error)
-	FindByOrganizerID(organizerID uint) ([]models.Event, error)
-	Update(event *models.Event) error
-	Delete(id uint) error
+	Create(ctx context.Context, event *models.Event) error
+	FindByID(ctx context.Context, id uint) (*models.Event, error)
+	FindAll(ctx context.Context) ([]models.Event, error)
+	FindByOrganizerID(ctx context.Context, organizerID uint) ([]models.Event, error)
+	Update(ctx context.Context, event *models.Event) error
+	Delete(ctx context.Context, id uint) error
+	FindSeatCounts(ctx context.Context) ([]models.SeatCount, error)
+	FindActiveSeatCounts(ctx context.Context, now time.Time) ([]models.SeatCount, error)
 
 	// Transaction-based operations for concurrency control
-	FindByIDForUpdate(tx *gorm.DB, id uint) (*models.Event, error)
-	DecreaseAvailableSeats(tx *gorm.DB, id uint) error
+	CreateWithTx(ctx context.Context, tx Tx, event *models.Event) error
+	FindByIDWithTx(ctx context.Context, tx Tx, id uint) (*models.Event, error)
+	FindByIDIncludingDeletedWithTx(ctx context.Context, tx Tx, id uint) (*models.Event, error)
+	FindByIDForUpdate(ctx context.Context, tx Tx, id uint) (*models.Event, error)
+	DecreaseAvailableSeats(ctx context.Context, tx Tx, id uint) error
+	DecreaseAvailableSeatsIfVersion(ctx context.Context, tx Tx, id uint, version int64) (bool, error)
+	DecreaseAvailableSeatsReturning(ctx context.Context, tx Tx, id uint) (*models.Event, error)
+	IncreaseAvailableSeats(ctx context.Context, tx Tx, id uint) error
+	UpdateWithTx(ctx context.Context, tx Tx, event *models.Event) error
+	DeleteWithTx(ctx context.Context, tx Tx, id uint) error
+	NotifyAvailabilityWithTx(ctx context.Context, tx Tx, id uint) error
 }
 
 // eventRepository implements EventRepository
@@ -32,14 +46,14 @@ func NewEventRepository(db *gorm.DB) EventRepository {
 }
 
 // Create creates a new event
-func (r *eventRepository) Create(event *models.Event) error {
-	return r.db.Create(event).Error
+func (r *eventRepository) Create(ctx context.Context, event *models.Event) error {
+	return r.db.WithContext(ctx).Create(event).Error
 }
 
 // FindByID finds an event by ID
-func (r *eventRepository) FindByID(id uint) (*models.Event, error) {
+func (r *eventRepository) FindByID(ctx context.Context, id uint) (*models.Event, error) {
 	var event models.Event
-	err := r.db.Preload("Organizer").First(&event, id).Error
+	err := r.db.WithContext(ctx).Preload("Organizer").First(&event, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -47,37 +61,105 @@ func (r *eventRepository) FindByID(id uint) (*models.Event, error) {
 }
 
 // FindAll returns all events
-func (r *eventRepository) FindAll() ([]models.Event, error) {
+func (r *eventRepository) FindAll(ctx context.Context) ([]models.Event, error) {
 	var events []models.Event
-	err := r.db.Preload("Organizer").Find(&events).Error
+	err := r.db.WithContext(ctx).Preload("Organizer").Find(&events).Error
 	return events, err
 }
 
+// seatCountsSQL reads the seat counter and active registrations of events
+// in one statement, so both come from the same snapshot. Further conditions
+// may be appended.
+const seatCountsSQL = `
+SELECT e.id AS event_id,
+       e.capacity,
+       CASE WHEN e.inventory = ?
+            THEN COALESCE((SELECT SUM(s.available) FROM event_inventory_shards s WHERE s.event_id = e.id), 0)
+            ELSE e.available_seats
+       END AS available,
+       (SELECT COUNT(*) FROM registrations r WHERE r.event_id = e.id AND r.deleted_at IS NULL) AS registered
+FROM events e
+WHERE e.deleted_at IS NULL`
+
+// FindSeatCounts returns the seat counter and active registrations of every event
+func (r *eventRepository) FindSeatCounts(ctx context.Context) ([]models.SeatCount, error) {
+	var counts []models.SeatCount
+	err := r.db.WithContext(ctx).Raw(seatCountsSQL+" ORDER BY e.id", models.InventorySharded).Scan(&counts).Error
+	return counts, err
+}
+
+// FindActiveSeatCounts is FindSeatCounts for the published events that have
+// not started by now
+func (r *eventRepository) FindActiveSeatCounts(ctx context.Context, now time.Time) ([]models.SeatCount, error) {
+	var counts []models.SeatCount
+	err := r.db.WithContext(ctx).Raw(seatCountsSQL+`
+  AND e.published_at IS NOT NULL
+  AND (e.starts_at IS NULL OR e.starts_at > ?)
+ORDER BY e.id`, models.InventorySharded, now).Scan(&counts).Error
+	return counts, err
+}
+
 // FindByOrganizerID returns all events created by an organizer
-func (r *eventRepository) FindByOrganizerID(organizerID uint) ([]models.Event, error) {
+func (r *eventRepository) FindByOrganizerID(ctx context.Context, organizerID uint) ([]models.Event, error) {
 	var events []models.Event
-	err := r.db.Where("organizer_id = ?", organizerID).Find(&events).Error
+	err := r.db.WithContext(ctx).Where("organizer_id = ?", organizerID).Find(&events).Error
 	return events, err
 }
 
 // Update updates an event
-func (r *eventRepository) Update(event *models.Event) error {
-	return r.db.Save(event).Error
+func (r *eventRepository) Update(ctx context.Context, event *models.Event) error {
+	return r.UpdateWithTx(ctx, r.db, event)
 }
 
 // Delete deletes an event by ID
-func (r *eventRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Event{}, id).Error
+func (r *eventRepository) Delete(ctx context.Context, id uint) error {
+	return r.DeleteWithTx(ctx, r.db, id)
+}
+
+// UpdateWithTx updates an event within a transaction
+func (r *eventRepository) UpdateWithTx(ctx context.Context, tx Tx, event *models.Event) error {
+	return txDB(ctx, tx).Save(event).Error
+}
+
+// DeleteWithTx deletes an event by ID within a transaction
+func (r *eventRepository) DeleteWithTx(ctx context.Context, tx Tx, id uint) error {
+	return txDB(ctx, tx).Delete(&models.Event{}, id).Error
+}
+
+// CreateWithTx creates a new event within a transaction
+func (r *eventRepository) CreateWithTx(ctx context.Context, tx Tx, event *models.Event) error {
+	return txDB(ctx, tx).Create(event).Error
+}
+
+// FindByIDWithTx finds an event by ID within a transaction, without locking it
+func (r *eventRepository) FindByIDWithTx(ctx context.Context, tx Tx, id uint) (*models.Event, error) {
+	var event models.Event
+	err := txDB(ctx, tx).First(&event, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// FindByIDIncludingDeletedWithTx finds an event by ID within a transaction,
+// even when it has been deleted
+func (r *eventRepository) FindByIDIncludingDeletedWithTx(ctx context.Context, tx Tx, id uint) (*models.Event, error) {
+	var event models.Event
+	err := txDB(ctx, tx).Unscoped().First(&event, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
 }
 
 // FindByIDForUpdate finds an event by ID with a row lock for updates
 // This is critical for concurrency control - it uses SELECT FOR UPDATE
 // to lock the row and prevent race conditions
-func (r *eventRepository) FindByIDForUpdate(tx *gorm.DB, id uint) (*models.Event, error) {
+func (r *eventRepository) FindByIDForUpdate(ctx context.Context, tx Tx, id uint) (*models.Event, error) {
 	var event models.Event
 	// ForUpdate() generates SELECT ... FOR UPDATE clause
 	// This locks the row until the transaction is committed or rolled back
-	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, id).Error
+	err := txDB(ctx, tx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -86,10 +168,10 @@ func (r *eventRepository) FindByIDForUpdate(tx *gorm.DB, id uint) (*models.Event
 
 // DecreaseAvailableSeats atomically decreases the available seats count
 // This is done within a transaction to ensure consistency
-func (r *eventRepository) DecreaseAvailableSeats(tx *gorm.DB, id uint) error {
+func (r *eventRepository) DecreaseAvailableSeats(ctx context.Context, tx Tx, id uint) error {
 	// Use UPDATE with a WHERE clause to ensure we only decrement if seats > 0
 	// This provides an additional layer of safety against overbooking
-	result := tx.Model(&models.Event{}).
+	result := txDB(ctx, tx).Model(&models.Event{}).
 		Where("id = ? AND available_seats > 0", id).
 		Update("available_seats", gorm.Expr("available_seats - 1"))
 
@@ -103,3 +185,62 @@ func (r *eventRepository) DecreaseAvailableSeats(tx *gorm.DB, id uint) error {
 
 	return nil
 }
+
+// NotifyAvailabilityWithTx bumps the event's seats version and queues a
+// Postgres notification with the new availability. It must run after the
+// seat change in the same transaction: the row is already locked, so versions
+// follow commit order, and Postgres only delivers the notification on commit.
+// SQLite only bumps the version, which the availability poller picks up.
+func (r *eventRepository) NotifyAvailabilityWithTx(ctx context.Context, tx Tx, id uint) error {
+	db := txDB(ctx, tx)
+	if isSQLite(db) {
+		return db.Exec("UPDATE events SET seats_version = seats_version + 1 WHERE id = ?", id).Error
+	}
+	return db.Exec(`
+		WITH e AS (
+			UPDATE events SET seats_version = seats_version + 1
+			WHERE id = ?
+			RETURNING id, capacity, available_seats, seats_version
+		)
+		SELECT pg_notify(?, json_build_object(
+			'event_id', id,
+			'capacity', capacity,
+			'available_seats', available_seats,
+			'version', seats_version
+		)::text) FROM e`, id, models.AvailabilityChannel).Error
+}
+
+// DecreaseAvailableSeatsIfVersion decrements the seat count only if nobody
+// changed it since seats_version was read. It returns false when the version
+// moved on and the caller should read again and retry.
+func (r *eventRepository) DecreaseAvailableSeatsIfVersion(ctx context.Context, tx Tx, id uint, version int64) (bool, error) {
+	result := txDB(ctx, tx).Model(&models.Event{}).
+		Where("id = ? AND seats_version = ? AND available_seats > 0", id, version).
+		Update("available_seats", gorm.Expr("available_seats - 1"))
+	return result.RowsAffected > 0, result.Error
+}
+
+// DecreaseAvailableSeatsReturning decrements the seat count in a single
+// conditional UPDATE, with no prior read, and returns the updated event.
+// It returns ErrEventFull when no seat was left.
+func (r *eventRepository) DecreaseAvailableSeatsReturning(ctx context.Context, tx Tx, id uint) (*models.Event, error) {
+	var event models.Event
+	result := txDB(ctx, tx).Raw(`
+		UPDATE events SET available_seats = available_seats - 1, updated_at = ?
+		WHERE id = ? AND available_seats > 0 AND deleted_at IS NULL
+		RETURNING *`, time.Now(), id).Scan(&event)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, models.ErrEventFull
+	}
+	return &event, nil
+}
+
+// IncreaseAvailableSeats gives a seat back
+func (r *eventRepository) IncreaseAvailableSeats(ctx context.Context, tx Tx, id uint) error {
+	return txDB(ctx, tx).Model(&models.Event{}).
+		Where("id = ?", id).
+		Update("available_seats", gorm.Expr("available_seats + 1")).Error
+}
@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"event-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// InventoryRepository defines the interface for sharded seat counters
+type InventoryRepository interface {
+	Supported() bool
+	TotalsByEventIDs(ctx context.Context, eventIDs []uint) (map[uint]models.InventoryTotals, error)
+	FindUnbalancedEventIDs(ctx context.Context, limit int) ([]uint, error)
+
+	// Transaction support
+	CreateShardsWithTx(ctx context.Context, tx Tx, eventID uint, seats []int) error
+	DeleteByEventIDWithTx(ctx context.Context, tx Tx, eventID uint) error
+	TakeSeatWithTx(ctx context.Context, tx Tx, eventID uint) error
+	ReturnSeatWithTx(ctx context.Context, tx Tx, eventID uint) error
+	TotalsWithTx(ctx context.Context, tx Tx, eventID uint) (models.InventoryTotals, error)
+	TotalsForUpdateWithTx(ctx context.Context, tx Tx, eventID uint) (models.InventoryTotals, error)
+	RedistributeWithTx(ctx context.Context, tx Tx, eventID uint, delta int) error
+	NotifyAvailabilityWithTx(ctx context.Context, tx Tx, eventID uint) error
+}
+
+// inventoryRepository implements InventoryRepository
+type inventoryRepository struct {
+	db *gorm.DB
+}
+
+// NewInventoryRepository creates a new InventoryRepository
+func NewInventoryRepository(db *gorm.DB) InventoryRepository {
+	return &inventoryRepository{db: db}
+}
+
+// Supported reports whether the database can hold sharded inventory. Shards
+// spread row lock contention, which SQLite does not have: it runs one write
+// transaction at a time anyway.
+func (r *inventoryRepository) Supported() bool {
+	return !isSQLite(r.db)
+}
+
+// takeSeatSQL decrements one random non-empty shard. The SKIP LOCKED variant
+// goes to a shard nobody else holds; the blocking variant is the fallback
+// when every non-empty shard is busy.
+const takeSeatSQL = `
+	UPDATE event_inventory_shards SET available = available - 1, version = nextval('inventory_version_seq')
+	WHERE (event_id, shard) = (
+		SELECT event_id, shard FROM event_inventory_shards
+		WHERE event_id = ? AND available > 0
+		ORDER BY random() LIMIT 1
+		FOR UPDATE %s
+	) AND available > 0`
+
+// returnSeatSQL increments one random shard, preferring unlocked ones the same way
+const returnSeatSQL = `
+	UPDATE event_inventory_shards SET available = available + 1, version = nextval('inventory_version_seq')
+	WHERE (event_id, shard) = (
+		SELECT event_id, shard FROM event_inventory_shards
+		WHERE event_id = ?
+		ORDER BY random() LIMIT 1
+		FOR UPDATE %s
+	)`
+
+// TotalsByEventIDs sums the shards of several events
+func (r *inventoryRepository) TotalsByEventIDs(ctx context.Context, eventIDs []uint) (map[uint]models.InventoryTotals, error) {
+	var rows []models.InventoryTotals
+	err := r.db.WithContext(ctx).Model(&models.EventInventoryShard{}).
+		Select("event_id, SUM(available) AS available, MAX(version) AS version").
+		Where("event_id IN ?", eventIDs).
+		Group("event_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[uint]models.InventoryTotals, len(rows))
+	for _, row := range rows {
+		totals[row.EventID] = row
+	}
+	return totals, nil
+}
+
+// FindUnbalancedEventIDs returns events that have an empty shard while
+// another shard could spare a seat
+func (r *inventoryRepository) FindUnbalancedEventIDs(ctx context.Context, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&models.EventInventoryShard{}).
+		Select("event_id").
+		Group("event_id").
+		Having("MIN(available) = 0 AND MAX(available) > 1").
+		Limit(limit).
+		Pluck("event_id", &ids).Error
+	return ids, err
+}
+
+// CreateShardsWithTx creates one shard per entry in seats
+func (r *inventoryRepository) CreateShardsWithTx(ctx context.Context, tx Tx, eventID uint, seats []int) error {
+	shards := make([]models.EventInventoryShard, len(seats))
+	for i, n := range seats {
+		shards[i] = models.EventInventoryShard{EventID: eventID, Shard: i, Available: n}
+	}
+	return txDB(ctx, tx).Create(&shards).Error
+}
+
+// DeleteByEventIDWithTx removes an event's shards
+func (r *inventoryRepository) DeleteByEventIDWithTx(ctx context.Context, tx Tx, eventID uint) error {
+	return txDB(ctx, tx).Where("event_id = ?", eventID).Delete(&models.EventInventoryShard{}).Error
+}
+
+// TakeSeatWithTx takes one seat from a random non-empty shard. Only that
+// shard is locked, so concurrent registrations for the same event rarely wait
+// on each other. It returns ErrEventFull when every shard is empty.
+func (r *inventoryRepository) TakeSeatWithTx(ctx context.Context, tx Tx, eventID uint) error {
+	return r.updateRandomShard(txDB(ctx, tx), takeSeatSQL, eventID, models.ErrEventFull)
+}
+
+// ReturnSeatWithTx puts one seat back into a random shard
+func (r *inventoryRepository) ReturnSeatWithTx(ctx context.Context, tx Tx, eventID uint) error {
+	return r.updateRandomShard(txDB(ctx, tx), returnSeatSQL, eventID, models.ErrEventNotFound)
+}
+
+// updateRandomShard runs a shard update, first skipping locked shards and
+// then waiting for one. It returns errNone when no shard qualifies.
+func (r *inventoryRepository) updateRandomShard(tx *gorm.DB, query string, eventID uint, errNone error) error {
+	for _, locking := range []string{"SKIP LOCKED", ""} {
+		result := tx.Exec(fmt.Sprintf(query, locking), eventID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return nil
+		}
+	}
+	return errNone
+}
+
+// TotalsWithTx sums an event's shards within a transaction
+func (r *inventoryRepository) TotalsWithTx(ctx context.Context, tx Tx, eventID uint) (models.InventoryTotals, error) {
+	totals := models.InventoryTotals{EventID: eventID}
+	err := txDB(ctx, tx).Model(&models.EventInventoryShard{}).
+		Select("COALESCE(SUM(available), 0) AS available, COALESCE(MAX(version), 0) AS version").
+		Where("event_id = ?", eventID).
+		Row().Scan(&totals.Available, &totals.Version)
+	return totals, err
+}
+
+// TotalsForUpdateWithTx locks every shard of an event, in order, and sums
+// them, so no seat can be taken or returned until the transaction ends
+func (r *inventoryRepository) TotalsForUpdateWithTx(ctx context.Context, tx Tx, eventID uint) (models.InventoryTotals, error) {
+	var shards []models.EventInventoryShard
+	err := txDB(ctx, tx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("event_id = ?", eventID).
+		Order("shard").
+		Find(&shards).Error
+	if err != nil {
+		return models.InventoryTotals{}, err
+	}
+
+	totals := models.InventoryTotals{EventID: eventID}
+	for _, shard := range shards {
+		totals.Available += shard.Available
+		totals.Version += shard.Version
+	}
+	return totals, nil
+}
+
+// RedistributeWithTx locks every shard of an event, adds delta seats to the
+// total and spreads the result evenly again. A delta of zero rebalances.
+// Shards are locked in order so concurrent redistributions cannot deadlock.
+func (r *inventoryRepository) RedistributeWithTx(ctx context.Context, tx Tx, eventID uint, delta int) error {
+	db := txDB(ctx, tx)
+	var shards []models.EventInventoryShard
+	err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("event_id = ?", eventID).
+		Order("shard").
+		Find(&shards).Error
+	if err != nil {
+		return err
+	}
+	if len(shards) == 0 {
+		return models.ErrEventNotFound
+	}
+
+	total := delta
+	for _, shard := range shards {
+		total += shard.Available
+	}
+	if total < 0 {
+		return models.ErrCapacityTooLow
+	}
+
+	for i, seats := range models.SplitSeats(total, len(shards)) {
+		if shards[i].Available == seats {
+			continue
+		}
+		// Seats only move between shards unless delta is non-zero, but take
+		// a new version anyway so the event's version keeps growing
+		err := db.Model(&models.EventInventoryShard{}).
+			Where("event_id = ? AND shard = ?", eventID, shards[i].Shard).
+			Updates(map[string]interface{}{
+				"available": seats,
+				"version":   gorm.Expr("nextval('inventory_version_seq')"),
+			}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyAvailabilityWithTx queues a Postgres notification with the summed
+// shards and their newest version. Unlike the row strategy it never touches
+// the events row. Every change takes its version from one sequence, so no two
+// changes report the same one; when two registrations commit at once, the
+// notification sent second may carry the older version, and streams catch
+// up with the next change.
+func (r *inventoryRepository) NotifyAvailabilityWithTx(ctx context.Context, tx Tx, eventID uint) error {
+	return txDB(ctx, tx).Exec(`
+		SELECT pg_notify(?, json_build_object(
+			'event_id', e.id,
+			'capacity', e.capacity,
+			'available_seats', s.available,
+			'version', s.version
+		)::text)
+		FROM events e, (
+			SELECT SUM(available) AS available, MAX(version) AS version
+			FROM event_inventory_shards WHERE event_id = ?
+		) s
+		WHERE e.id = ?`, models.AvailabilityChannel, eventID, eventID).Error
+}
@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"event-api/models"
+	"event-api/repository"
+)
+
+// auditRepository implements repository.AuditRepository
+type auditRepository struct {
+	store *Store
+}
+
+// NewAuditRepository creates a repository.AuditRepository backed by store
+func NewAuditRepository(store *Store) repository.AuditRepository {
+	return &auditRepository{store: store}
+}
+
+// Find returns the entries matching filter, newest first. Unlike the GORM
+// version, an organizer filter misses events deleted since, because the
+// store does not keep deleted events.
+func (r *auditRepository) Find(_ context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	var entries []models.AuditEvent
+	err := r.store.locked(func() error {
+		entries = r.store.audit.find(func(e models.AuditEvent) bool { return r.matches(e, filter) })
+		return nil
+	})
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID > entries[j].ID })
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, err
+}
+
+// matches reports whether an entry passes filter; the caller holds the store
+func (r *auditRepository) matches(e models.AuditEvent, filter models.AuditFilter) bool {
+	switch {
+	case filter.ActorID != 0 && (e.ActorID == nil || *e.ActorID != filter.ActorID),
+		filter.Action != "" && e.Action != filter.Action,
+		filter.TargetType != "" && e.TargetType != filter.TargetType,
+		filter.TargetID != 0 && e.TargetID != filter.TargetID,
+		filter.EventID != 0 && (e.EventID == nil || *e.EventID != filter.EventID),
+		filter.RequestID != "" && e.RequestID != filter.RequestID,
+		!filter.Since.IsZero() && e.CreatedAt.Before(filter.Since),
+		!filter.Until.IsZero() && !e.CreatedAt.Before(filter.Until),
+		filter.BeforeID != 0 && e.ID >= filter.BeforeID:
+		return false
+	}
+	if filter.OrganizerID != 0 {
+		if e.EventID == nil {
+			return false
+		}
+		event, ok := r.store.events.get(*e.EventID)
+		return ok && event.OrganizerID == filter.OrganizerID
+	}
+	return true
+}
+
+// CreateWithTx appends an entry in the caller's transaction
+func (r *auditRepository) CreateWithTx(_ context.Context, tx repository.Tx, entry *models.AuditEvent) error {
+	memTx := r.store.within(tx)
+	entry.ID = r.store.audit.nextID()
+	entry.CreatedAt = time.Now()
+	r.store.audit.put(memTx, entry.ID, *entry)
+	return nil
+}
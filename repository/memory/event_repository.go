@@ -0,0 +1,280 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"event-api/models"
+	"event-api/repository"
+
+	"gorm.io/gorm"
+)
+
+// eventRepository implements repository.EventRepository. Transactions
+// already run one at a time, so the row locks of the GORM version are
+// implicit here.
+type eventRepository struct {
+	store *Store
+}
+
+// NewEventRepository creates a repository.EventRepository backed by store
+func NewEventRepository(store *Store) repository.EventRepository {
+	return &eventRepository{store: store}
+}
+
+// Create creates a new event
+func (r *eventRepository) Create(_ context.Context, event *models.Event) error {
+	return r.store.locked(func() error {
+		r.create(nil, event)
+		return nil
+	})
+}
+
+// FindByID finds an event by ID, with its organizer
+func (r *eventRepository) FindByID(_ context.Context, id uint) (*models.Event, error) {
+	var event *models.Event
+	err := r.store.locked(func() error {
+		var err error
+		if event, err = r.findByID(id); err != nil {
+			return err
+		}
+		event.Organizer = r.organizer(event)
+		return nil
+	})
+	return event, err
+}
+
+// FindAll returns all events, with their organizers
+func (r *eventRepository) FindAll(_ context.Context) ([]models.Event, error) {
+	var events []models.Event
+	err := r.store.locked(func() error {
+		events = r.store.events.find(all)
+		for i := range events {
+			events[i].Organizer = r.organizer(&events[i])
+		}
+		return nil
+	})
+	return events, err
+}
+
+// FindByOrganizerID returns all events created by an organizer
+func (r *eventRepository) FindByOrganizerID(_ context.Context, organizerID uint) ([]models.Event, error) {
+	var events []models.Event
+	err := r.store.locked(func() error {
+		events = r.store.events.find(func(e models.Event) bool { return e.OrganizerID == organizerID })
+		return nil
+	})
+	return events, err
+}
+
+// Update updates an event
+func (r *eventRepository) Update(_ context.Context, event *models.Event) error {
+	return r.store.locked(func() error {
+		return r.update(nil, event)
+	})
+}
+
+// Delete deletes an event by ID
+func (r *eventRepository) Delete(_ context.Context, id uint) error {
+	return r.store.locked(func() error {
+		r.delete(nil, id)
+		return nil
+	})
+}
+
+// FindSeatCounts returns the seat counter and registrations of every event
+func (r *eventRepository) FindSeatCounts(_ context.Context) ([]models.SeatCount, error) {
+	return r.seatCounts(all)
+}
+
+// FindActiveSeatCounts is FindSeatCounts for the published events that have
+// not started by now
+func (r *eventRepository) FindActiveSeatCounts(_ context.Context, now time.Time) ([]models.SeatCount, error) {
+	return r.seatCounts(func(e models.Event) bool {
+		return e.PublishedAt != nil && (e.StartsAt == nil || e.StartsAt.After(now))
+	})
+}
+
+// seatCounts returns the seat counter and registrations of the events that match
+func (r *eventRepository) seatCounts(match func(models.Event) bool) ([]models.SeatCount, error) {
+	var counts []models.SeatCount
+	err := r.store.locked(func() error {
+		registered := make(map[uint]int)
+		for _, registration := range r.store.registrations.find(all) {
+			registered[registration.EventID]++
+		}
+		for _, event := range r.store.events.find(match) {
+			counts = append(counts, models.SeatCount{
+				EventID:    event.ID,
+				Capacity:   event.Capacity,
+				Available:  event.AvailableSeats,
+				Registered: registered[event.ID],
+			})
+		}
+		return nil
+	})
+	return counts, err
+}
+
+// FindByIDWithTx finds an event by ID within a transaction
+func (r *eventRepository) FindByIDWithTx(_ context.Context, tx repository.Tx, id uint) (*models.Event, error) {
+	r.store.within(tx)
+	return r.findByID(id)
+}
+
+// CreateWithTx creates a new event within a transaction
+func (r *eventRepository) CreateWithTx(_ context.Context, tx repository.Tx, event *models.Event) error {
+	r.create(r.store.within(tx), event)
+	return nil
+}
+
+// FindByIDIncludingDeletedWithTx finds an event by ID within a transaction,
+// even when it has been deleted
+func (r *eventRepository) FindByIDIncludingDeletedWithTx(_ context.Context, tx repository.Tx, id uint) (*models.Event, error) {
+	r.store.within(tx)
+	if event, ok := r.store.deletedEvents.get(id); ok {
+		return &event, nil
+	}
+	return r.findByID(id)
+}
+
+// FindByIDForUpdate finds an event by ID within a transaction
+func (r *eventRepository) FindByIDForUpdate(_ context.Context, tx repository.Tx, id uint) (*models.Event, error) {
+	r.store.within(tx)
+	return r.findByID(id)
+}
+
+// DecreaseAvailableSeats takes a seat, returning ErrEventFull when none is left
+func (r *eventRepository) DecreaseAvailableSeats(ctx context.Context, tx repository.Tx, id uint) error {
+	_, err := r.DecreaseAvailableSeatsReturning(ctx, tx, id)
+	return err
+}
+
+// DecreaseAvailableSeatsIfVersion takes a seat only if the seats version is
+// still version
+func (r *eventRepository) DecreaseAvailableSeatsIfVersion(ctx context.Context, tx repository.Tx, id uint, version int64) (bool, error) {
+	r.store.within(tx)
+	if event, ok := r.store.events.get(id); !ok || event.SeatsVersion != version {
+		return false, nil
+	}
+	_, err := r.DecreaseAvailableSeatsReturning(ctx, tx, id)
+	if errors.Is(err, models.ErrEventFull) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// DecreaseAvailableSeatsReturning takes a seat and returns the updated event.
+// It returns ErrEventFull when no seat was left.
+func (r *eventRepository) DecreaseAvailableSeatsReturning(_ context.Context, tx repository.Tx, id uint) (*models.Event, error) {
+	memTx := r.store.within(tx)
+	event, ok := r.store.events.get(id)
+	if !ok || event.AvailableSeats <= 0 {
+		return nil, models.ErrEventFull
+	}
+	event.AvailableSeats--
+	event.UpdatedAt = time.Now()
+	r.store.events.put(memTx, id, event)
+	return &event, nil
+}
+
+// IncreaseAvailableSeats gives a seat back
+func (r *eventRepository) IncreaseAvailableSeats(_ context.Context, tx repository.Tx, id uint) error {
+	memTx := r.store.within(tx)
+	event, ok := r.store.events.get(id)
+	if !ok {
+		return nil
+	}
+	event.AvailableSeats++
+	event.UpdatedAt = time.Now()
+	r.store.events.put(memTx, id, event)
+	return nil
+}
+
+// UpdateWithTx updates an event within a transaction
+func (r *eventRepository) UpdateWithTx(_ context.Context, tx repository.Tx, event *models.Event) error {
+	return r.update(r.store.within(tx), event)
+}
+
+// DeleteWithTx deletes an event by ID within a transaction
+func (r *eventRepository) DeleteWithTx(_ context.Context, tx repository.Tx, id uint) error {
+	r.delete(r.store.within(tx), id)
+	return nil
+}
+
+// NotifyAvailabilityWithTx bumps the event's seats version. Nothing listens
+// to an in-memory store, so no notification is sent.
+func (r *eventRepository) NotifyAvailabilityWithTx(_ context.Context, tx repository.Tx, id uint) error {
+	memTx := r.store.within(tx)
+	event, ok := r.store.events.get(id)
+	if !ok {
+		return nil
+	}
+	event.SeatsVersion++
+	r.store.events.put(memTx, id, event)
+	return nil
+}
+
+// findByID finds an event by ID; the caller holds the store
+func (r *eventRepository) findByID(id uint) (*models.Event, error) {
+	event, ok := r.store.events.get(id)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &event, nil
+}
+
+// create stores a new event; the caller holds the store
+func (r *eventRepository) create(tx *Tx, event *models.Event) {
+	now := time.Now()
+	event.ID = r.store.events.nextID()
+	event.CreatedAt, event.UpdatedAt = now, now
+	if event.Inventory == "" {
+		event.Inventory = models.InventoryRow
+	}
+	r.store.events.put(tx, event.ID, storedEvent(*event))
+}
+
+// delete moves an event to the deleted events, where only
+// FindByIDIncludingDeletedWithTx sees it; the caller holds the store
+func (r *eventRepository) delete(tx *Tx, id uint) {
+	event, ok := r.store.events.get(id)
+	if !ok {
+		return
+	}
+	r.store.events.remove(tx, id)
+	event.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.store.deletedEvents.put(tx, id, event)
+}
+
+// update saves an event. Like the GORM version it never writes the seats
+// version, which only seat changes move.
+func (r *eventRepository) update(tx *Tx, event *models.Event) error {
+	event.UpdatedAt = time.Now()
+	stored := storedEvent(*event)
+	if existing, ok := r.store.events.get(event.ID); ok {
+		stored.SeatsVersion = existing.SeatsVersion
+		if stored.CreatedAt.IsZero() {
+			stored.CreatedAt = existing.CreatedAt
+		}
+	}
+	r.store.events.put(tx, event.ID, stored)
+	return nil
+}
+
+// organizer returns a copy of an event's organizer; the caller holds the store
+func (r *eventRepository) organizer(event *models.Event) *models.User {
+	user, ok := r.store.users.get(event.OrganizerID)
+	if !ok {
+		return nil
+	}
+	return &user
+}
+
+// storedEvent strips the associations the events table does not hold
+func storedEvent(event models.Event) models.Event {
+	event.Organizer = nil
+	event.Registrations = nil
+	return event
+}
@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"event-api/models"
+	"event-api/repository"
+)
+
+// outboxRepository implements repository.OutboxRepository
+type outboxRepository struct {
+	store *Store
+}
+
+// NewOutboxRepository creates a repository.OutboxRepository backed by store
+func NewOutboxRepository(store *Store) repository.OutboxRepository {
+	return &outboxRepository{store: store}
+}
+
+// CountByAggregate counts the outbox events of one type for an aggregate
+func (r *outboxRepository) CountByAggregate(_ context.Context, aggregateType string, aggregateID uint, eventType models.DomainEventType) (int64, error) {
+	var count int64
+	err := r.store.locked(func() error {
+		count = int64(len(r.store.outbox.find(func(e models.OutboxEvent) bool {
+			return e.AggregateType == aggregateType && e.AggregateID == aggregateID && e.Type == eventType
+		})))
+		return nil
+	})
+	return count, err
+}
+
+// CreateWithTx writes a domain event in the caller's transaction
+func (r *outboxRepository) CreateWithTx(_ context.Context, tx repository.Tx, event *models.OutboxEvent) error {
+	memTx := r.store.within(tx)
+	event.ID = r.store.outbox.nextID()
+	event.CreatedAt = time.Now()
+	r.store.outbox.put(memTx, event.ID, *event)
+	return nil
+}
+
+// TryLockRelayWithTx always succeeds: transactions already run one at a time
+func (r *outboxRepository) TryLockRelayWithTx(_ context.Context, tx repository.Tx) (bool, error) {
+	r.store.within(tx)
+	return true, nil
+}
+
+// FindUnpublishedWithTx returns the oldest unpublished, unparked events that
+// are due at now, in id order, leaving out those behind an earlier event
+// of the same aggregate that is waiting to be retried
+func (r *outboxRepository) FindUnpublishedWithTx(_ context.Context, tx repository.Tx, now time.Time, limit int) ([]models.OutboxEvent, error) {
+	r.store.within(tx)
+	type aggregate struct {
+		typ string
+		id  uint
+	}
+	waiting := make(map[aggregate]bool)
+	var events []models.OutboxEvent
+	for _, e := range r.store.outbox.find(func(e models.OutboxEvent) bool { return e.PublishedAt == nil && e.ParkedAt == nil }) {
+		key := aggregate{e.AggregateType, e.AggregateID}
+		if waiting[key] {
+			continue
+		}
+		if e.NextAttemptAt != nil && e.NextAttemptAt.After(now) {
+			waiting[key] = true
+			continue
+		}
+		if events = append(events, e); len(events) == limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+// MarkPublishedWithTx records that every subscriber handled an event
+func (r *outboxRepository) MarkPublishedWithTx(_ context.Context, tx repository.Tx, id uint, publishedAt time.Time) error {
+	return r.update(r.store.within(tx), id, func(e *models.OutboxEvent) {
+		e.PublishedAt = &publishedAt
+		e.Attempts++
+		e.LastError = ""
+		e.NextAttemptAt = nil
+	})
+}
+
+// RecordFailureWithTx records a failed publish attempt; the event stays
+// unpublished and is retried from nextAttemptAt on
+func (r *outboxRepository) RecordFailureWithTx(_ context.Context, tx repository.Tx, id uint, lastError string, nextAttemptAt time.Time) error {
+	return r.update(r.store.within(tx), id, func(e *models.OutboxEvent) {
+		e.Attempts++
+		e.LastError = lastError
+		e.NextAttemptAt = &nextAttemptAt
+	})
+}
+
+// ParkWithTx records the last failed attempt of an event and sets it aside
+func (r *outboxRepository) ParkWithTx(_ context.Context, tx repository.Tx, id uint, lastError string, parkedAt time.Time) error {
+	return r.update(r.store.within(tx), id, func(e *models.OutboxEvent) {
+		e.Attempts++
+		e.LastError = lastError
+		e.ParkedAt = &parkedAt
+	})
+}
+
+// update applies fn to a stored event; the caller holds the store
+func (r *outboxRepository) update(tx *Tx, id uint, fn func(*models.OutboxEvent)) error {
+	event, ok := r.store.outbox.get(id)
+	if !ok {
+		return nil
+	}
+	fn(&event)
+	r.store.outbox.put(tx, id, event)
+	return nil
+}
@@ -0,0 +1,277 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"event-api/models"
+	"event-api/repository"
+
+	"gorm.io/gorm"
+)
+
+// registrationRepository implements repository.RegistrationRepository. As
+// in the registrations table, a user has at most one active registration per
+// event; cancelled registrations are removed.
+type registrationRepository struct {
+	store *Store
+}
+
+// NewRegistrationRepository creates a repository.RegistrationRepository backed by store
+func NewRegistrationRepository(store *Store) repository.RegistrationRepository {
+	return &registrationRepository{store: store}
+}
+
+// Create creates a new registration
+func (r *registrationRepository) Create(_ context.Context, registration *models.Registration) error {
+	return r.store.locked(func() error {
+		if !r.create(nil, registration) {
+			return gorm.ErrDuplicatedKey
+		}
+		return nil
+	})
+}
+
+// FindByID finds a registration by ID, with its user and event
+func (r *registrationRepository) FindByID(_ context.Context, id uint) (*models.Registration, error) {
+	var registration *models.Registration
+	err := r.store.locked(func() error {
+		found, ok := r.store.registrations.get(id)
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		registration = &found
+		registration.User = r.user(registration)
+		registration.Event = r.event(registration)
+		return nil
+	})
+	return registration, err
+}
+
+// FindByUserID returns all registrations for a user, with their events
+func (r *registrationRepository) FindByUserID(_ context.Context, userID uint) ([]models.Registration, error) {
+	var registrations []models.Registration
+	err := r.store.locked(func() error {
+		registrations = r.store.registrations.find(func(reg models.Registration) bool { return reg.UserID == userID })
+		for i := range registrations {
+			registrations[i].Event = r.event(&registrations[i])
+		}
+		return nil
+	})
+	return registrations, err
+}
+
+// FindByEventID returns all registrations for an event, with their users
+func (r *registrationRepository) FindByEventID(_ context.Context, eventID uint) ([]models.Registration, error) {
+	var registrations []models.Registration
+	err := r.store.locked(func() error {
+		registrations = r.store.registrations.find(func(reg models.Registration) bool { return reg.EventID == eventID })
+		for i := range registrations {
+			registrations[i].User = r.user(&registrations[i])
+		}
+		return nil
+	})
+	return registrations, err
+}
+
+// FindByUserAndEventID finds a registration by user and event ID
+func (r *registrationRepository) FindByUserAndEventID(_ context.Context, userID, eventID uint) (*models.Registration, error) {
+	var registration *models.Registration
+	err := r.store.locked(func() error {
+		var err error
+		registration, err = r.findByUserAndEvent(userID, eventID)
+		return err
+	})
+	return registration, err
+}
+
+// FindPendingReviewByEventID returns an event's flagged registrations
+// awaiting review, with their users, oldest first
+func (r *registrationRepository) FindPendingReviewByEventID(_ context.Context, eventID uint) ([]models.Registration, error) {
+	var registrations []models.Registration
+	err := r.store.locked(func() error {
+		registrations = r.store.registrations.find(func(reg models.Registration) bool {
+			return reg.EventID == eventID && reg.Review == models.ReviewPending
+		})
+		for i := range registrations {
+			registrations[i].User = r.user(&registrations[i])
+		}
+		return nil
+	})
+	return registrations, err
+}
+
+// Delete deletes a registration by ID
+func (r *registrationRepository) Delete(_ context.Context, id uint) error {
+	return r.store.locked(func() error {
+		r.store.registrations.remove(nil, id)
+		return nil
+	})
+}
+
+// DeleteByUserAndEvent deletes a registration by user and event ID
+func (r *registrationRepository) DeleteByUserAndEvent(_ context.Context, userID, eventID uint) error {
+	return r.store.locked(func() error {
+		r.deleteByUserAndEvent(nil, userID, eventID)
+		return nil
+	})
+}
+
+// CreateWithTx creates a new registration within a transaction. It returns
+// ErrAlreadyRegistered when the user already has one for the event.
+func (r *registrationRepository) CreateWithTx(_ context.Context, tx repository.Tx, registration *models.Registration) error {
+	if !r.create(r.store.within(tx), registration) {
+		return models.ErrAlreadyRegistered
+	}
+	return nil
+}
+
+// FindByIDForUpdate finds a registration by ID within a transaction
+func (r *registrationRepository) FindByIDForUpdate(_ context.Context, tx repository.Tx, id uint) (*models.Registration, error) {
+	r.store.within(tx)
+	registration, ok := r.store.registrations.get(id)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &registration, nil
+}
+
+// FindByUserAndEventIDWithTx finds a registration by user and event ID, with
+// its user, within a transaction
+func (r *registrationRepository) FindByUserAndEventIDWithTx(_ context.Context, tx repository.Tx, userID, eventID uint) (*models.Registration, error) {
+	r.store.within(tx)
+	registration, err := r.findByUserAndEvent(userID, eventID)
+	if err != nil {
+		return nil, err
+	}
+	registration.User = r.user(registration)
+	return registration, nil
+}
+
+// DeleteByUserAndEventWithTx deletes a registration by user and event ID
+// within a transaction and reports whether there was one
+func (r *registrationRepository) DeleteByUserAndEventWithTx(_ context.Context, tx repository.Tx, userID, eventID uint) (bool, error) {
+	return r.deleteByUserAndEvent(r.store.within(tx), userID, eventID), nil
+}
+
+// MarkCheckedInWithTx records when a registration was checked in
+func (r *registrationRepository) MarkCheckedInWithTx(_ context.Context, tx repository.Tx, id uint, at time.Time) error {
+	memTx := r.store.within(tx)
+	registration, ok := r.store.registrations.get(id)
+	if !ok {
+		return nil
+	}
+	registration.CheckedInAt = &at
+	registration.UpdatedAt = time.Now()
+	r.store.registrations.put(memTx, id, registration)
+	return nil
+}
+
+// CountByEventIDWithTx counts the registrations for an event within a transaction
+func (r *registrationRepository) CountByEventIDWithTx(_ context.Context, tx repository.Tx, eventID uint) (int64, error) {
+	r.store.within(tx)
+	return int64(len(r.store.registrations.find(func(reg models.Registration) bool { return reg.EventID == eventID }))), nil
+}
+
+// CountSimilarWithTx counts an event's registrations whose key equals value
+// within a transaction
+func (r *registrationRepository) CountSimilarWithTx(_ context.Context, tx repository.Tx, eventID uint, key models.ScreeningKey, value any) (int64, error) {
+	r.store.within(tx)
+	return int64(len(r.store.registrations.find(func(reg models.Registration) bool {
+		return reg.EventID == eventID && screeningValue(reg, key) == value
+	}))), nil
+}
+
+// CountRecentWithTx counts the registrations for any event whose key equals
+// value and that were made since, within a transaction. Cancelled
+// registrations are removed, so unlike in the database they do not count.
+func (r *registrationRepository) CountRecentWithTx(_ context.Context, tx repository.Tx, key models.ScreeningKey, value any, since time.Time) (int64, error) {
+	r.store.within(tx)
+	return int64(len(r.store.registrations.find(func(reg models.Registration) bool {
+		return screeningValue(reg, key) == value && !reg.CreatedAt.Before(since)
+	}))), nil
+}
+
+// UpdateReviewWithTx sets the review status of a registration within a transaction
+func (r *registrationRepository) UpdateReviewWithTx(_ context.Context, tx repository.Tx, id uint, review models.ReviewStatus) error {
+	memTx := r.store.within(tx)
+	registration, ok := r.store.registrations.get(id)
+	if !ok {
+		return nil
+	}
+	registration.Review = review
+	registration.UpdatedAt = time.Now()
+	r.store.registrations.put(memTx, id, registration)
+	return nil
+}
+
+// screeningValue returns what key groups a registration by
+func screeningValue(registration models.Registration, key models.ScreeningKey) any {
+	switch key {
+	case models.ScreenUser:
+		return registration.UserID
+	case models.ScreenEmailDomain:
+		return registration.EmailDomain
+	case models.ScreenPaymentInstrument:
+		return registration.PaymentFingerprint
+	case models.ScreenHousehold:
+		return registration.HouseholdKey
+	}
+	return nil
+}
+
+// create stores a new registration unless the user already has one for the
+// event; the caller holds the store
+func (r *registrationRepository) create(tx *Tx, registration *models.Registration) bool {
+	if _, err := r.findByUserAndEvent(registration.UserID, registration.EventID); err == nil {
+		return false
+	}
+	now := time.Now()
+	registration.ID = r.store.registrations.nextID()
+	registration.CreatedAt, registration.UpdatedAt = now, now
+
+	stored := *registration
+	stored.User, stored.Event = nil, nil
+	r.store.registrations.put(tx, registration.ID, stored)
+	return true
+}
+
+// findByUserAndEvent finds a registration; the caller holds the store
+func (r *registrationRepository) findByUserAndEvent(userID, eventID uint) (*models.Registration, error) {
+	registrations := r.store.registrations.find(func(reg models.Registration) bool {
+		return reg.UserID == userID && reg.EventID == eventID
+	})
+	if len(registrations) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &registrations[0], nil
+}
+
+// deleteByUserAndEvent removes a registration and reports whether there was
+// one; the caller holds the store
+func (r *registrationRepository) deleteByUserAndEvent(tx *Tx, userID, eventID uint) bool {
+	registration, err := r.findByUserAndEvent(userID, eventID)
+	if err != nil {
+		return false
+	}
+	r.store.registrations.remove(tx, registration.ID)
+	return true
+}
+
+// user returns a copy of a registration's user; the caller holds the store
+func (r *registrationRepository) user(registration *models.Registration) *models.User {
+	user, ok := r.store.users.get(registration.UserID)
+	if !ok {
+		return nil
+	}
+	return &user
+}
+
+// event returns a copy of a registration's event; the caller holds the store
+func (r *registrationRepository) event(registration *models.Registration) *models.Event {
+	event, ok := r.store.events.get(registration.EventID)
+	if !ok {
+		return nil
+	}
+	return &event
+}
@@ -0,0 +1,178 @@
+/*
+Package memory implements the user, token, event, registration, outbox and
+audit repositories in process memory, for fast tests that do not need Postgres.
+
+Every repository shares one Store. The Store is also the repository.Transactor
+for its repositories: a transaction holds the store's lock until it ends, so
+transactions run one at a time and a failed one is undone completely. Inside
+Transaction, use the store only through the methods that take the
+transaction; calling the others from there deadlocks.
+
+Lookups of missing records return gorm.ErrRecordNotFound, as the GORM
+repositories do, so services and handlers treat both backends alike.
+*/
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"event-api/models"
+	"event-api/repository"
+)
+
+// Store holds every record of the in-memory repositories
+type Store struct {
+	mu            sync.Mutex
+	users         table[models.User]
+	events        table[models.Event]
+	deletedEvents table[models.Event]
+	registrations table[models.Registration]
+	outbox        table[models.OutboxEvent]
+	audit         table[models.AuditEvent]
+	tokens        table[models.UserToken]
+}
+
+// NewStore creates an empty Store
+func NewStore() *Store {
+	return &Store{
+		users:         newTable[models.User](),
+		events:        newTable[models.Event](),
+		deletedEvents: newTable[models.Event](),
+		registrations: newTable[models.Registration](),
+		outbox:        newTable[models.OutboxEvent](),
+		audit:         newTable[models.AuditEvent](),
+		tokens:        newTable[models.UserToken](),
+	}
+}
+
+// Store is the Transactor of its repositories
+var _ repository.Transactor = (*Store)(nil)
+
+// Tx is a transaction of a Store
+type Tx struct {
+	store *Store
+	undo  []func()
+}
+
+// Transaction runs fn while holding the store, undoing its changes when it
+// returns an error or panics
+func (s *Store) Transaction(_ context.Context, fn func(tx repository.Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &Tx{store: s}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.rollback()
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// rollback undoes the transaction's changes, newest first
+func (tx *Tx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+	tx.undo = nil
+}
+
+// within returns the in-memory transaction behind tx
+func (s *Store) within(tx repository.Tx) *Tx {
+	memTx, ok := tx.(*Tx)
+	if !ok || memTx.store != s {
+		panic(fmt.Sprintf("memory: %T is not a transaction of this store", tx))
+	}
+	return memTx
+}
+
+// locked runs fn while holding the store, for work outside a transaction
+func (s *Store) locked(fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn()
+}
+
+// table is one kind of record, keyed by ID. IDs are never reused, even when
+// the transaction that took one rolls back, just like a Postgres sequence.
+type table[T any] struct {
+	rows   map[uint]T
+	lastID uint
+}
+
+func newTable[T any]() table[T] {
+	return table[T]{rows: make(map[uint]T)}
+}
+
+// nextID returns a new ID
+func (t *table[T]) nextID() uint {
+	t.lastID++
+	return t.lastID
+}
+
+// get returns the record with an ID
+func (t *table[T]) get(id uint) (T, bool) {
+	row, ok := t.rows[id]
+	return row, ok
+}
+
+// put stores a record, remembering in tx, if any, how to undo it
+func (t *table[T]) put(tx *Tx, id uint, row T) {
+	t.remember(tx, id)
+	t.rows[id] = row
+}
+
+// remove deletes a record, remembering in tx, if any, how to undo it
+func (t *table[T]) remove(tx *Tx, id uint) {
+	if _, ok := t.rows[id]; !ok {
+		return
+	}
+	t.remember(tx, id)
+	delete(t.rows, id)
+}
+
+// remember records how to restore a record to its current state
+func (t *table[T]) remember(tx *Tx, id uint) {
+	if tx == nil {
+		return
+	}
+	previous, existed := t.rows[id]
+	tx.undo = append(tx.undo, func() {
+		if existed {
+			t.rows[id] = previous
+		} else {
+			delete(t.rows, id)
+		}
+	})
+}
+
+// find returns the records that match, ordered by ID
+func (t *table[T]) find(match func(T) bool) []T {
+	ids := make([]uint, 0, len(t.rows))
+	for id, row := range t.rows {
+		if match(row) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	rows := make([]T, len(ids))
+	for i, id := range ids {
+		rows[i] = t.rows[id]
+	}
+	return rows
+}
+
+// all matches every record
+func all[T any](T) bool {
+	return true
+}
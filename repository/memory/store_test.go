@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"event-api/models"
+	"event-api/repository"
+)
+
+func TestTransactionRollsBackOnError(t *testing.T) {
+	store := NewStore()
+	events := NewEventRepository(store)
+	registrations := NewRegistrationRepository(store)
+
+	event := &models.Event{Title: "Rollback", Capacity: 1, AvailableSeats: 1}
+	if err := events.Create(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	errAbort := errors.New("abort")
+	err := store.Transaction(context.Background(), func(tx repository.Tx) error {
+		if err := events.DecreaseAvailableSeats(context.Background(), tx, event.ID); err != nil {
+			return err
+		}
+		if err := registrations.CreateWithTx(context.Background(), tx, &models.Registration{UserID: 1, EventID: event.ID}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("error = %v, want %v", err, errAbort)
+	}
+
+	found, err := events.FindByID(context.Background(), event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.AvailableSeats != 1 {
+		t.Errorf("available seats = %d, want 1", found.AvailableSeats)
+	}
+	if _, err := registrations.FindByUserAndEventID(context.Background(), 1, event.ID); err == nil {
+		t.Error("registration survived the rollback")
+	}
+}
+
+func TestTransactionRejectsForeignTx(t *testing.T) {
+	events := NewEventRepository(NewStore())
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a transaction of another store")
+		}
+	}()
+	NewStore().Transaction(context.Background(), func(tx repository.Tx) error {
+		_, err := events.FindByIDWithTx(context.Background(), tx, 1)
+		return err
+	})
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"event-api/models"
+	"event-api/repository"
+)
+
+// tokenRepository implements repository.TokenRepository
+type tokenRepository struct {
+	store *Store
+}
+
+// NewTokenRepository creates a repository.TokenRepository backed by store
+func NewTokenRepository(store *Store) repository.TokenRepository {
+	return &tokenRepository{store: store}
+}
+
+// Find returns the unused, unexpired token with tokenHash without using it
+func (r *tokenRepository) Find(_ context.Context, tokenHash string, purpose models.TokenPurpose, at time.Time) (*models.UserToken, error) {
+	var token *models.UserToken
+	err := r.store.locked(func() error {
+		var err error
+		token, err = r.findValid(tokenHash, purpose, at)
+		return err
+	})
+	return token, err
+}
+
+// CreateWithTx stores a new token within a transaction
+func (r *tokenRepository) CreateWithTx(_ context.Context, tx repository.Tx, token *models.UserToken) error {
+	memTx := r.store.within(tx)
+	token.ID = r.store.tokens.nextID()
+	token.CreatedAt = time.Now()
+	r.store.tokens.put(memTx, token.ID, *token)
+	return nil
+}
+
+// RevokeWithTx marks a user's unused tokens for purpose as used within a
+// transaction
+func (r *tokenRepository) RevokeWithTx(_ context.Context, tx repository.Tx, userID uint, purpose models.TokenPurpose, at time.Time) error {
+	r.revoke(r.store.within(tx), at, func(t models.UserToken) bool { return t.UserID == userID && t.Purpose == purpose })
+	return nil
+}
+
+// RevokeAllWithTx marks all of a user's unused tokens as used within a
+// transaction
+func (r *tokenRepository) RevokeAllWithTx(_ context.Context, tx repository.Tx, userID uint, at time.Time) error {
+	r.revoke(r.store.within(tx), at, func(t models.UserToken) bool { return t.UserID == userID })
+	return nil
+}
+
+// ConsumeWithTx marks the unused, unexpired token with tokenHash as used
+// within a transaction and returns it
+func (r *tokenRepository) ConsumeWithTx(_ context.Context, tx repository.Tx, tokenHash string, purpose models.TokenPurpose, at time.Time) (*models.UserToken, error) {
+	memTx := r.store.within(tx)
+	token, err := r.findValid(tokenHash, purpose, at)
+	if err != nil {
+		return nil, err
+	}
+	token.UsedAt = &at
+	r.store.tokens.put(memTx, token.ID, *token)
+	return token, nil
+}
+
+// findValid returns the usable token with tokenHash, or ErrInvalidToken;
+// the caller holds the store
+func (r *tokenRepository) findValid(tokenHash string, purpose models.TokenPurpose, at time.Time) (*models.UserToken, error) {
+	tokens := r.store.tokens.find(func(t models.UserToken) bool {
+		return t.TokenHash == tokenHash && t.Purpose == purpose && t.UsedAt == nil && t.ExpiresAt.After(at)
+	})
+	if len(tokens) == 0 {
+		return nil, models.ErrInvalidToken
+	}
+	return &tokens[0], nil
+}
+
+// revoke marks the unused tokens that match as used; the caller holds the
+// store
+func (r *tokenRepository) revoke(tx *Tx, at time.Time, match func(models.UserToken) bool) {
+	for _, token := range r.store.tokens.find(func(t models.UserToken) bool { return t.UsedAt == nil && match(t) }) {
+		token.UsedAt = &at
+		r.store.tokens.put(tx, token.ID, token)
+	}
+}
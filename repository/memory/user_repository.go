@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"event-api/models"
+	"event-api/repository"
+
+	"gorm.io/gorm"
+)
+
+// userRepository implements repository.UserRepository
+type userRepository struct {
+	store *Store
+}
+
+// NewUserRepository creates a repository.UserRepository backed by store
+func NewUserRepository(store *Store) repository.UserRepository {
+	return &userRepository{store: store}
+}
+
+// Create creates a new user. Emails are unique, as in the users table.
+func (r *userRepository) Create(_ context.Context, user *models.User) error {
+	return r.store.locked(func() error { return r.create(nil, user) })
+}
+
+// FindByID finds a user by ID
+func (r *userRepository) FindByID(_ context.Context, id uint) (*models.User, error) {
+	var user *models.User
+	err := r.store.locked(func() error {
+		var err error
+		user, err = r.findByID(id)
+		return err
+	})
+	return user, err
+}
+
+// FindByEmail finds a user by email
+func (r *userRepository) FindByEmail(_ context.Context, email string) (*models.User, error) {
+	var user *models.User
+	err := r.store.locked(func() error {
+		users := r.store.users.find(func(u models.User) bool { return u.Email == email })
+		if len(users) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		user = &users[0]
+		return nil
+	})
+	return user, err
+}
+
+// FindAll returns all users
+func (r *userRepository) FindAll(_ context.Context) ([]models.User, error) {
+	var users []models.User
+	err := r.store.locked(func() error {
+		users = r.store.users.find(all)
+		return nil
+	})
+	return users, err
+}
+
+// Update updates a user
+func (r *userRepository) Update(_ context.Context, user *models.User) error {
+	return r.store.locked(func() error { return r.update(nil, user) })
+}
+
+// Delete deletes a user by ID
+func (r *userRepository) Delete(_ context.Context, id uint) error {
+	return r.store.locked(func() error {
+		r.store.users.remove(nil, id)
+		return nil
+	})
+}
+
+// FindByIDWithTx finds a user by ID within a transaction
+func (r *userRepository) FindByIDWithTx(_ context.Context, tx repository.Tx, id uint) (*models.User, error) {
+	r.store.within(tx)
+	return r.findByID(id)
+}
+
+// CreateWithTx creates a new user within a transaction
+func (r *userRepository) CreateWithTx(_ context.Context, tx repository.Tx, user *models.User) error {
+	return r.create(r.store.within(tx), user)
+}
+
+// UpdateWithTx updates a user within a transaction
+func (r *userRepository) UpdateWithTx(_ context.Context, tx repository.Tx, user *models.User) error {
+	return r.update(r.store.within(tx), user)
+}
+
+// DeleteWithTx deletes a user by ID within a transaction
+func (r *userRepository) DeleteWithTx(_ context.Context, tx repository.Tx, id uint) error {
+	r.store.users.remove(r.store.within(tx), id)
+	return nil
+}
+
+// create stores a new user; the caller holds the store
+func (r *userRepository) create(tx *Tx, user *models.User) error {
+	if r.emailTaken(user.Email, 0) {
+		return gorm.ErrDuplicatedKey
+	}
+	now := time.Now()
+	user.ID = r.store.users.nextID()
+	user.CreatedAt, user.UpdatedAt = now, now
+	if user.Role == "" {
+		user.Role = models.RoleAttendee
+	}
+	r.store.users.put(tx, user.ID, storedUser(*user))
+	return nil
+}
+
+// update stores a changed user; the caller holds the store
+func (r *userRepository) update(tx *Tx, user *models.User) error {
+	if r.emailTaken(user.Email, user.ID) {
+		return gorm.ErrDuplicatedKey
+	}
+	existing, ok := r.store.users.get(user.ID)
+	if ok && user.CreatedAt.IsZero() {
+		user.CreatedAt = existing.CreatedAt
+	}
+	user.UpdatedAt = time.Now()
+	r.store.users.put(tx, user.ID, storedUser(*user))
+	return nil
+}
+
+// findByID finds a user by ID; the caller holds the store
+func (r *userRepository) findByID(id uint) (*models.User, error) {
+	user, ok := r.store.users.get(id)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &user, nil
+}
+
+// emailTaken reports whether a user other than id has email
+func (r *userRepository) emailTaken(email string, id uint) bool {
+	return len(r.store.users.find(func(u models.User) bool { return u.Email == email && u.ID != id })) > 0
+}
+
+// storedUser strips the associations the users table does not hold
+func storedUser(user models.User) models.User {
+	user.Events = nil
+	return user
+}
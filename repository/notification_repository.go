@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"event-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationRepository defines the interface for the notification outbox
+// and per-organizer template overrides
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *models.Notification) error
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Notification, error)
+	MarkSent(ctx context.Context, id uint, sentAt time.Time) error
+	MarkRetry(ctx context.Context, id uint, attempts int, nextAttemptAt time.Time, lastError string) error
+	MarkFailed(ctx context.Context, id uint, attempts int, lastError string) error
+
+	FindTemplate(ctx context.Context, organizerID uint, notificationType models.NotificationType) (*models.NotificationTemplate, error)
+	FindTemplatesByOrganizerID(ctx context.Context, organizerID uint) ([]models.NotificationTemplate, error)
+	SaveTemplate(ctx context.Context, template *models.NotificationTemplate) error
+	DeleteTemplate(ctx context.Context, organizerID uint, notificationType models.NotificationType) error
+
+	// Transaction support
+	CreateWithTx(ctx context.Context, tx *gorm.DB, notification *models.Notification) error
+	FindRecipientsWithTx(ctx context.Context, tx *gorm.DB, eventID uint) ([]models.User, error)
+}
+
+// notificationRepository implements NotificationRepository
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+// NewNotificationRepository creates a new NotificationRepository
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+// Create adds a notification to the outbox
+func (r *notificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	return r.CreateWithTx(ctx, r.db, notification)
+}
+
+// CreateWithTx adds a notification to the outbox within a transaction,
+// so the message only becomes visible if the surrounding change commits
+func (r *notificationRepository) CreateWithTx(ctx context.Context, tx *gorm.DB, notification *models.Notification) error {
+	if notification.Status == "" {
+		notification.Status = models.NotificationPending
+	}
+	if notification.NextAttemptAt.IsZero() {
+		notification.NextAttemptAt = time.Now()
+	}
+	return tx.WithContext(ctx).Create(notification).Error
+}
+
+// FindRecipientsWithTx returns everyone registered for an event, in
+// registration order. Deleted users and cancelled registrations are left out.
+func (r *notificationRepository) FindRecipientsWithTx(ctx context.Context, tx *gorm.DB, eventID uint) ([]models.User, error) {
+	var users []models.User
+	err := tx.WithContext(ctx).
+		Joins("JOIN registrations ON registrations.user_id = users.id AND registrations.deleted_at IS NULL").
+		Where("registrations.event_id = ?", eventID).
+		Order("registrations.id").
+		Find(&users).Error
+	return users, err
+}
+
+// ClaimDue returns up to limit pending notifications whose next attempt is due
+// and pushes their next attempt forward by lease. Rows are selected with
+// FOR UPDATE SKIP LOCKED, so concurrent workers never claim the same message.
+func (r *notificationRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.NotificationPending, now).
+			Order("next_attempt_at, id").
+			Limit(limit).
+			Find(&notifications).Error
+		if err != nil || len(notifications) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(notifications))
+		for i, n := range notifications {
+			ids[i] = n.ID
+		}
+		return tx.Model(&models.Notification{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// MarkSent records a successful delivery
+func (r *notificationRepository) MarkSent(ctx context.Context, id uint, sentAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Notification{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     models.NotificationSent,
+		"attempts":   gorm.Expr("attempts + 1"),
+		"sent_at":    sentAt,
+		"last_error": "",
+	}).Error
+}
+
+// MarkRetry records a failed attempt and schedules the next one
+func (r *notificationRepository) MarkRetry(ctx context.Context, id uint, attempts int, nextAttemptAt time.Time, lastError string) error {
+	return r.db.WithContext(ctx).Model(&models.Notification{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":        attempts,
+		"next_attempt_at": nextAttemptAt,
+		"last_error":      lastError,
+	}).Error
+}
+
+// MarkFailed gives up on a notification after the final attempt
+func (r *notificationRepository) MarkFailed(ctx context.Context, id uint, attempts int, lastError string) error {
+	return r.db.WithContext(ctx).Model(&models.Notification{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     models.NotificationFailed,
+		"attempts":   attempts,
+		"last_error": lastError,
+	}).Error
+}
+
+// FindTemplate finds an organizer's override for a notification type
+func (r *notificationRepository) FindTemplate(ctx context.Context, organizerID uint, notificationType models.NotificationType) (*models.NotificationTemplate, error) {
+	var template models.NotificationTemplate
+	err := r.db.WithContext(ctx).Where("organizer_id = ? AND type = ?", organizerID, notificationType).First(&template).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// FindTemplatesByOrganizerID returns all overrides for an organizer
+func (r *notificationRepository) FindTemplatesByOrganizerID(ctx context.Context, organizerID uint) ([]models.NotificationTemplate, error) {
+	var templates []models.NotificationTemplate
+	err := r.db.WithContext(ctx).Where("organizer_id = ?", organizerID).Order("type").Find(&templates).Error
+	return templates, err
+}
+
+// SaveTemplate creates or replaces an organizer's override for a notification type
+func (r *notificationRepository) SaveTemplate(ctx context.Context, template *models.NotificationTemplate) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "organizer_id"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"subject", "text_body", "html_body", "updated_at"}),
+	}).Create(template).Error
+}
+
+// DeleteTemplate removes an organizer's override, restoring the default
+func (r *notificationRepository) DeleteTemplate(ctx context.Context, organizerID uint, notificationType models.NotificationType) error {
+	return r.db.WithContext(ctx).Where("organizer_id = ? AND type = ?", organizerID, notificationType).
+		Delete(&models.NotificationTemplate{}).Error
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"event-api/models"
+
+	"gorm.io/gorm"
+)
+
+// outboxRelayLockKey is the Postgres advisory lock key held by the active relay
+const outboxRelayLockKey = 7240129
+
+// OutboxRepository defines the interface for the transactional outbox
+type OutboxRepository interface {
+	CountByAggregate(ctx context.Context, aggregateType string, aggregateID uint, eventType models.DomainEventType) (int64, error)
+
+	// Transaction support
+	CreateWithTx(ctx context.Context, tx Tx, event *models.OutboxEvent) error
+	TryLockRelayWithTx(ctx context.Context, tx Tx) (bool, error)
+	FindUnpublishedWithTx(ctx context.Context, tx Tx, now time.Time, limit int) ([]models.OutboxEvent, error)
+	MarkPublishedWithTx(ctx context.Context, tx Tx, id uint, publishedAt time.Time) error
+	RecordFailureWithTx(ctx context.Context, tx Tx, id uint, lastError string, nextAttemptAt time.Time) error
+	ParkWithTx(ctx context.Context, tx Tx, id uint, lastError string, parkedAt time.Time) error
+}
+
+// outboxRepository implements OutboxRepository
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository creates a new OutboxRepository
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// CountByAggregate counts the outbox events of one type for an aggregate
+func (r *outboxRepository) CountByAggregate(ctx context.Context, aggregateType string, aggregateID uint, eventType models.DomainEventType) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.OutboxEvent{}).
+		Where("aggregate_type = ? AND aggregate_id = ? AND type = ?", aggregateType, aggregateID, eventType).
+		Count(&count).Error
+	return count, err
+}
+
+// CreateWithTx writes a domain event in the caller's transaction, so it
+// exists if and only if the change it describes commits
+func (r *outboxRepository) CreateWithTx(ctx context.Context, tx Tx, event *models.OutboxEvent) error {
+	return txDB(ctx, tx).Create(event).Error
+}
+
+// TryLockRelayWithTx takes a transaction-scoped advisory lock so only one
+// relay publishes at a time, which keeps per-aggregate ordering across
+// replicas. It returns false if another relay holds the lock.
+func (r *outboxRepository) TryLockRelayWithTx(ctx context.Context, tx Tx) (bool, error) {
+	if isSQLite(txDB(ctx, tx)) {
+		return true, nil
+	}
+	var locked bool
+	err := txDB(ctx, tx).Raw("SELECT pg_try_advisory_xact_lock(?)", outboxRelayLockKey).Scan(&locked).Error
+	return locked, err
+}
+
+// FindUnpublishedWithTx returns the oldest unpublished, unparked events that
+// are due at now, in id order. IDs are taken on insert, so this is only the
+// commit order for changes that lock the aggregate's row; registrations on a
+// sharded event do not, and may commit out of id order. Events behind an
+// earlier event of the same aggregate that is waiting to be retried are left
+// out too, so a failing aggregate takes up no room in the batch while it
+// backs off.
+func (r *outboxRepository) FindUnpublishedWithTx(ctx context.Context, tx Tx, now time.Time, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := txDB(ctx, tx).
+		Where("published_at IS NULL AND parked_at IS NULL AND (next_attempt_at IS NULL OR next_attempt_at <= ?)", now).
+		Where(`NOT EXISTS (SELECT 1 FROM outbox_events waiting
+			WHERE waiting.aggregate_type = outbox_events.aggregate_type AND waiting.aggregate_id = outbox_events.aggregate_id
+			AND waiting.id < outbox_events.id AND waiting.published_at IS NULL AND waiting.parked_at IS NULL
+			AND waiting.next_attempt_at > ?)`, now).
+		Order("id").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// MarkPublishedWithTx records that every subscriber handled an event
+func (r *outboxRepository) MarkPublishedWithTx(ctx context.Context, tx Tx, id uint, publishedAt time.Time) error {
+	return txDB(ctx, tx).Model(&models.OutboxEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"published_at":    publishedAt,
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      "",
+		"next_attempt_at": nil,
+	}).Error
+}
+
+// RecordFailureWithTx records a failed publish attempt; the event stays
+// unpublished and is retried from nextAttemptAt on
+func (r *outboxRepository) RecordFailureWithTx(ctx context.Context, tx Tx, id uint, lastError string, nextAttemptAt time.Time) error {
+	return txDB(ctx, tx).Model(&models.OutboxEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      lastError,
+		"next_attempt_at": nextAttemptAt,
+	}).Error
+}
+
+// ParkWithTx records the last failed attempt of an event and sets it aside;
+// it is not retried until an operator clears parked_at
+func (r *outboxRepository) ParkWithTx(ctx context.Context, tx Tx, id uint, lastError string, parkedAt time.Time) error {
+	return txDB(ctx, tx).Model(&models.OutboxEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": lastError,
+		"parked_at":  parkedAt,
+	}).Error
+}
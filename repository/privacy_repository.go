@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"event-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PrivacyRepository defines the interface for reading and erasing everything
+// stored about a user, and for purging soft-deleted rows
+type PrivacyRepository interface {
+	FindRegistrationsByUserID(ctx context.Context, userID uint) ([]models.Registration, error)
+	FindAuditEventsByUserID(ctx context.Context, userID uint) ([]models.AuditEvent, error)
+	FindUnerasedDeletedUsers(ctx context.Context, before time.Time) ([]models.User, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (models.PurgeReport, error)
+
+	// Transaction support
+	FindUserByIDWithTx(ctx context.Context, tx Tx, id uint) (*models.User, error)
+	EraseUserWithTx(ctx context.Context, tx Tx, user *models.User, at time.Time) error
+}
+
+// privacyRepository implements PrivacyRepository
+type privacyRepository struct {
+	db *gorm.DB
+}
+
+// NewPrivacyRepository creates a new PrivacyRepository
+func NewPrivacyRepository(db *gorm.DB) PrivacyRepository {
+	return &privacyRepository{db: db}
+}
+
+// FindRegistrationsByUserID returns all of a user's registrations, cancelled
+// ones and those for deleted events included, with their events, oldest first
+func (r *privacyRepository) FindRegistrationsByUserID(ctx context.Context, userID uint) ([]models.Registration, error) {
+	var registrations []models.Registration
+	err := r.db.WithContext(ctx).Unscoped().
+		Preload("Event", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("user_id = ?", userID).Order("id").Find(&registrations).Error
+	return registrations, err
+}
+
+// FindAuditEventsByUserID returns the audit entries of changes a user made,
+// or made to the user or their registrations, oldest first
+func (r *privacyRepository) FindAuditEventsByUserID(ctx context.Context, userID uint) ([]models.AuditEvent, error) {
+	var entries []models.AuditEvent
+	err := r.db.WithContext(ctx).
+		Where("actor_id = ?", userID).
+		Or("target_type = ? AND target_id = ?", models.AuditTargetUser, userID).
+		Or("target_type = ? AND target_id IN (SELECT id FROM registrations WHERE user_id = ?)", models.AuditTargetRegistration, userID).
+		Order("id").Find(&entries).Error
+	return entries, err
+}
+
+// FindUnerasedDeletedUsers returns the users deleted before a time whose
+// personal data is still stored
+func (r *privacyRepository) FindUnerasedDeletedUsers(ctx context.Context, before time.Time) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("deleted_at < ? AND erased_at IS NULL", before).Order("id").Find(&users).Error
+	return users, err
+}
+
+// FindUserByIDWithTx finds a user by ID, deleted or not, and locks it within
+// a transaction
+func (r *privacyRepository) FindUserByIDWithTx(ctx context.Context, tx Tx, id uint) (*models.User, error) {
+	var user models.User
+	err := txDB(ctx, tx).Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// EraseUserWithTx anonymizes a user within a transaction. The users row
+// keeps its ID, so registrations and seat counts stay intact, but its name
+// and email are replaced and its password and verification are cleared.
+// The screening data and answers of the user's registrations, their unused
+// tokens and the emails sent to them are removed. Their snapshots in stored
+// outbox and webhook payloads and in audit entries are redacted, and the
+// audit entries of their own changes lose the client IP.
+func (r *privacyRepository) EraseUserWithTx(ctx context.Context, tx Tx, user *models.User, at time.Time) error {
+	db := txDB(ctx, tx)
+	erasedEmail := models.ErasedEmail(user.ID)
+
+	err := db.Unscoped().Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]any{
+		"name":              models.ErasedName,
+		"email":             erasedEmail,
+		"password_hash":     "",
+		"email_verified_at": nil,
+		"deactivated_at":    gorm.Expr("COALESCE(deactivated_at, ?)", at),
+		"erased_at":         at,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	err = db.Unscoped().Model(&models.Registration{}).Where("user_id = ?", user.ID).Updates(map[string]any{
+		"email_domain":        "",
+		"payment_fingerprint": "",
+		"household_key":       "",
+		"answers":             nil,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	if err := db.Where("user_id = ?", user.ID).Delete(&models.UserToken{}).Error; err != nil {
+		return err
+	}
+
+	// Messages not yet sent are given up rather than sent to nobody
+	err = db.Model(&models.Notification{}).Where("user_id = ?", user.ID).Updates(map[string]any{
+		"recipient":  erasedEmail,
+		"subject":    "",
+		"text_body":  "",
+		"html_body":  "",
+		"status":     gorm.Expr("CASE WHEN status = ? THEN ? ELSE status END", models.NotificationPending, models.NotificationFailed),
+		"last_error": gorm.Expr("CASE WHEN status = ? THEN ? ELSE last_error END", models.NotificationPending, "recipient erased"),
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	// The user's own requests are not tied to them by address either
+	if err := db.Model(&models.AuditEvent{}).Where("actor_id = ?", user.ID).Update("ip", "").Error; err != nil {
+		return err
+	}
+	for _, stored := range []struct{ table, column string }{
+		{"outbox_events", "payload"},
+		{"webhook_deliveries", "payload"},
+		{"audit_events", "before_state"},
+		{"audit_events", "after_state"},
+	} {
+		if err := redactSnapshotsWithTx(db, stored.table, stored.column, user.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redactSnapshotsWithTx rewrites the JSON documents in a column that hold a
+// snapshot of a user, replacing the user's name and email with
+// models.RedactUser. Marshaled users hold `"id":<id>,` and registrations
+// `"user_id":<id>,`, which narrows the rows to decode. SQLite stores audit snapshots as blobs, which LIKE does
+// not match without the cast, and they are written back as bytes for
+// json.RawMessage to scan.
+func redactSnapshotsWithTx(db *gorm.DB, table, column string, userID uint) error {
+	var rows []struct {
+		ID  uint
+		Doc string
+	}
+	doc := "CAST(" + column + " AS TEXT)"
+	err := db.Table(table).Select("id, "+doc+" AS doc").
+		Where(doc+" LIKE ? OR "+doc+" LIKE ?", fmt.Sprintf(`%%"id":%d,%%`, userID), fmt.Sprintf(`%%"user_id":%d,%%`, userID)).
+		Order("id").Find(&rows).Error
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		redacted, changed, err := models.RedactUser([]byte(row.Doc), userID)
+		if err != nil {
+			return fmt.Errorf("redacting %s %d: %w", table, row.ID, err)
+		}
+		if !changed {
+			continue
+		}
+		if err := db.Table(table).Where("id = ?", row.ID).Update(column, redacted).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PurgeDeleted permanently removes rows soft-deleted before a time:
+// cancelled registrations, deleted events with everything that belongs to
+// them, and deleted users nothing refers to anymore. Audit entries are kept.
+func (r *privacyRepository) PurgeDeleted(ctx context.Context, before time.Time) (models.PurgeReport, error) {
+	var report models.PurgeReport
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		deletedEvents := tx.Unscoped().Model(&models.Event{}).Select("id").Where("deleted_at < ?", before)
+
+		result := tx.Unscoped().Where("deleted_at < ? OR event_id IN (?)", before, deletedEvents).Delete(&models.Registration{})
+		if result.Error != nil {
+			return result.Error
+		}
+		report.RegistrationsDeleted = result.RowsAffected
+
+		for _, model := range []any{&models.EventReminder{}, &models.WaitingRoomEntry{}, &models.WaitingRoomSchedule{}, &models.EventInventoryShard{}} {
+			if err := tx.Where("event_id IN (?)", deletedEvents).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		result = tx.Unscoped().Where("deleted_at < ?", before).Delete(&models.Event{})
+		if result.Error != nil {
+			return result.Error
+		}
+		report.EventsDeleted = result.RowsAffected
+
+		// Users stay while a registration or event refers to them; they
+		// have been erased by then
+		unreferenced := tx.Unscoped().Model(&models.User{}).Select("id").
+			Where("deleted_at < ?", before).
+			Where("NOT EXISTS (SELECT 1 FROM registrations WHERE registrations.user_id = users.id)").
+			Where("NOT EXISTS (SELECT 1 FROM events WHERE events.organizer_id = users.id)")
+		for _, model := range []any{&models.UserToken{}, &models.WaitingRoomEntry{}} {
+			if err := tx.Where("user_id IN (?)", unreferenced).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		result = tx.Unscoped().Where("id IN (?)", unreferenced).Delete(&models.User{})
+		if result.Error != nil {
+			return result.Error
+		}
+		report.UsersDeleted = result.RowsAffected
+		return nil
+	})
+	return report, err
+}
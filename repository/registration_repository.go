@@ -1,6 +1,10 @@
 package repository
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"event-api/models"
 
 	"gorm.io/gorm"
@@ -9,16 +13,42 @@ import (
 
 // RegistrationRepository defines the interface for registration data access
 type RegistrationRepository interface {
-	Create(registration *models.Registration) error
-	FindByID(id uint) (*models.Registration, error)
-	FindByUserID(userID uint) ([]models.Registration, error)
-	FindByEventID(eventID uint) ([]models.Registration, error)
-	FindByUserAndEventID(userID, eventID uint) (*models.Registration, error)
-	Delete(id uint) error
-	DeleteByUserAndEvent(userID, eventID uint) error
+	Create(ctx context.Context, registration *models.Registration) error
+	FindByID(ctx context.Context, id uint) (*models.Registration, error)
+	FindByUserID(ctx context.Context, userID uint) ([]models.Registration, error)
+	FindByEventID(ctx context.Context, eventID uint) ([]models.Registration, error)
+	FindByUserAndEventID(ctx context.Context, userID, eventID uint) (*models.Registration, error)
+	FindPendingReviewByEventID(ctx context.Context, eventID uint) ([]models.Registration, error)
+	Delete(ctx context.Context, id uint) error
+	DeleteByUserAndEvent(ctx context.Context, userID, eventID uint) error
 	
 	// Transaction support
-	CreateWithTx(tx *gorm.DB, registration *models.Registration) error
+	CreateWithTx(ctx context.Context, tx Tx, registration *models.Registration) error
+	FindByIDForUpdate(ctx context.Context, tx Tx, id uint) (*models.Registration, error)
+	FindByUserAndEventIDWithTx(ctx context.Context, tx Tx, userID, eventID uint) (*models.Registration, error)
+	DeleteByUserAndEventWithTx(ctx context.Context, tx Tx, userID, eventID uint) (bool, error)
+	MarkCheckedInWithTx(ctx context.Context, tx Tx, id uint, at time.Time) error
+	CountByEventIDWithTx(ctx context.Context, tx Tx, eventID uint) (int64, error)
+	CountSimilarWithTx(ctx context.Context, tx Tx, eventID uint, key models.ScreeningKey, value any) (int64, error)
+	CountRecentWithTx(ctx context.Context, tx Tx, key models.ScreeningKey, value any, since time.Time) (int64, error)
+	UpdateReviewWithTx(ctx context.Context, tx Tx, id uint, review models.ReviewStatus) error
+}
+
+// screeningColumns are the registrations columns screening keys group by
+var screeningColumns = map[models.ScreeningKey]string{
+	models.ScreenUser:              "user_id",
+	models.ScreenEmailDomain:       "email_domain",
+	models.ScreenPaymentInstrument: "payment_fingerprint",
+	models.ScreenHousehold:         "household_key",
+}
+
+// screeningColumn returns the column key groups by
+func screeningColumn(key models.ScreeningKey) (string, error) {
+	column, ok := screeningColumns[key]
+	if !ok {
+		return "", fmt.Errorf("unknown screening key %q", key)
+	}
+	return column, nil
 }
 
 // registrationRepository implements RegistrationRepository
@@ -32,14 +62,14 @@ func NewRegistrationRepository(db *gorm.DB) RegistrationRepository {
 }
 
 // Create creates a new registration
-func (r *registrationRepository) Create(registration *models.Registration) error {
-	return r.db.Create(registration).Error
+func (r *registrationRepository) Create(ctx context.Context, registration *models.Registration) error {
+	return r.db.WithContext(ctx).Create(registration).Error
 }
 
 // FindByID finds a registration by ID
-func (r *registrationRepository) FindByID(id uint) (*models.Registration, error) {
+func (r *registrationRepository) FindByID(ctx context.Context, id uint) (*models.Registration, error) {
 	var registration models.Registration
-	err := r.db.Preload("User").Preload("Event").First(&registration, id).Error
+	err := r.db.WithContext(ctx).Preload("User").Preload("Event").First(&registration, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -47,45 +77,136 @@ func (r *registrationRepository) FindByID(id uint) (*models.Registration, error)
 }
 
 // FindByUserID returns all registrations for a user
-func (r *registrationRepository) FindByUserID(userID uint) ([]models.Registration, error) {
+func (r *registrationRepository) FindByUserID(ctx context.Context, userID uint) ([]models.Registration, error) {
 	var registrations []models.Registration
-	err := r.db.Preload("Event").Where("user_id = ?", userID).Find(&registrations).Error
+	err := r.db.WithContext(ctx).Preload("Event").Where("user_id = ?", userID).Find(&registrations).Error
 	return registrations, err
 }
 
 // FindByEventID returns all registrations for an event
-func (r *registrationRepository) FindByEventID(eventID uint) ([]models.Registration, error) {
+func (r *registrationRepository) FindByEventID(ctx context.Context, eventID uint) ([]models.Registration, error) {
 	var registrations []models.Registration
-	err := r.db.Preload("User").Where("event_id = ?", eventID).Find(&registrations).Error
+	err := r.db.WithContext(ctx).Preload("User").Where("event_id = ?", eventID).Find(&registrations).Error
 	return registrations, err
 }
 
 // FindByUserAndEventID finds a registration by user and event ID
-func (r *registrationRepository) FindByUserAndEventID(userID, eventID uint) (*models.Registration, error) {
+func (r *registrationRepository) FindByUserAndEventID(ctx context.Context, userID, eventID uint) (*models.Registration, error) {
 	var registration models.Registration
-	err := r.db.Where("user_id = ? AND event_id = ?", userID, eventID).First(&registration).Error
+	err := r.db.WithContext(ctx).Where("user_id = ? AND event_id = ?", userID, eventID).First(&registration).Error
 	if err != nil {
 		return nil, err
 	}
 	return &registration, nil
 }
 
+// FindPendingReviewByEventID returns an event's flagged registrations
+// awaiting review, with their users, oldest first
+func (r *registrationRepository) FindPendingReviewByEventID(ctx context.Context, eventID uint) ([]models.Registration, error) {
+	var registrations []models.Registration
+	err := r.db.WithContext(ctx).Preload("User").
+		Where("event_id = ? AND review = ?", eventID, models.ReviewPending).
+		Order("id").Find(&registrations).Error
+	return registrations, err
+}
+
 // Delete deletes a registration by ID
-func (r *registrationRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Registration{}, id).Error
+func (r *registrationRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Registration{}, id).Error
 }
 
 // DeleteByUserAndEvent deletes a registration by user and event ID
-func (r *registrationRepository) DeleteByUserAndEvent(userID, eventID uint) error {
-	return r.db.Where("user_id = ? AND event_id = ?", userID, eventID).Delete(&models.Registration{}).Error
+func (r *registrationRepository) DeleteByUserAndEvent(ctx context.Context, userID, eventID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ? AND event_id = ?", userID, eventID).Delete(&models.Registration{}).Error
 }
 
 // CreateWithTx creates a new registration within a transaction
 // This is the critical method for atomic registration with seat decrement
-func (r *registrationRepository) CreateWithTx(tx *gorm.DB, registration *models.Registration) error {
+// It returns ErrAlreadyRegistered when the user already has an active
+// registration for the event
+func (r *registrationRepository) CreateWithTx(ctx context.Context, tx Tx, registration *models.Registration) error {
 	// Use ON CONFLICT DO NOTHING to handle race conditions on unique constraint
 	// The actual seat availability check happens in the service layer
-	return tx.Clauses(clause.OnConflict{
+	result := txDB(ctx, tx).Clauses(clause.OnConflict{
 		DoNothing: true,
-	}).Create(registration).Error
+	}).Create(registration)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrAlreadyRegistered
+	}
+	return nil
+}
+
+// FindByIDForUpdate finds a registration by ID and locks it
+func (r *registrationRepository) FindByIDForUpdate(ctx context.Context, tx Tx, id uint) (*models.Registration, error) {
+	var registration models.Registration
+	err := txDB(ctx, tx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&registration, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &registration, nil
+}
+
+// FindByUserAndEventIDWithTx finds a registration by user and event ID, with
+// its user, within a transaction
+func (r *registrationRepository) FindByUserAndEventIDWithTx(ctx context.Context, tx Tx, userID, eventID uint) (*models.Registration, error) {
+	var registration models.Registration
+	err := txDB(ctx, tx).Preload("User").Where("user_id = ? AND event_id = ?", userID, eventID).First(&registration).Error
+	if err != nil {
+		return nil, err
+	}
+	return &registration, nil
+}
+
+// DeleteByUserAndEventWithTx deletes a registration by user and event ID
+// within a transaction and reports whether there was one
+func (r *registrationRepository) DeleteByUserAndEventWithTx(ctx context.Context, tx Tx, userID, eventID uint) (bool, error) {
+	result := txDB(ctx, tx).Where("user_id = ? AND event_id = ?", userID, eventID).Delete(&models.Registration{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// MarkCheckedInWithTx records when a registration was checked in
+func (r *registrationRepository) MarkCheckedInWithTx(ctx context.Context, tx Tx, id uint, at time.Time) error {
+	return txDB(ctx, tx).Model(&models.Registration{}).Where("id = ?", id).Update("checked_in_at", at).Error
+}
+
+// CountByEventIDWithTx counts the active registrations for an event within a transaction
+func (r *registrationRepository) CountByEventIDWithTx(ctx context.Context, tx Tx, eventID uint) (int64, error) {
+	var count int64
+	err := txDB(ctx, tx).Model(&models.Registration{}).Where("event_id = ?", eventID).Count(&count).Error
+	return count, err
+}
+
+// CountSimilarWithTx counts an event's active registrations whose key
+// equals value within a transaction
+func (r *registrationRepository) CountSimilarWithTx(ctx context.Context, tx Tx, eventID uint, key models.ScreeningKey, value any) (int64, error) {
+	column, err := screeningColumn(key)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = txDB(ctx, tx).Model(&models.Registration{}).
+		Where("event_id = ? AND "+column+" = ?", eventID, value).Count(&count).Error
+	return count, err
+}
+
+// CountRecentWithTx counts the registrations for any event, cancelled ones
+// included, whose key equals value and that were made since, within a
+// transaction
+func (r *registrationRepository) CountRecentWithTx(ctx context.Context, tx Tx, key models.ScreeningKey, value any, since time.Time) (int64, error) {
+	column, err := screeningColumn(key)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = txDB(ctx, tx).Unscoped().Model(&models.Registration{}).
+		Where(column+" = ? AND created_at >= ?", value, since).Count(&count).Error
+	return count, err
+}
+
+// UpdateReviewWithTx sets the review status of a registration within a transaction
+func (r *registrationRepository) UpdateReviewWithTx(ctx context.Context, tx Tx, id uint, review models.ReviewStatus) error {
+	return txDB(ctx, tx).Model(&models.Registration{}).Where("id = ?", id).Update("review", review).Error
 }
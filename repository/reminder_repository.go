@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"event-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReminderRepository defines the interface for scheduled event reminders
+type ReminderRepository interface {
+	// Transaction support
+	ReplaceForEventWithTx(ctx context.Context, tx Tx, eventID uint, reminders []models.EventReminder) error
+	DeleteByEventIDWithTx(ctx context.Context, tx Tx, eventID uint) error
+	ClaimDueWithTx(ctx context.Context, tx Tx, now time.Time, limit int) ([]models.EventReminder, error)
+	MarkSentWithTx(ctx context.Context, tx Tx, id uint, sentAt time.Time) error
+	FindEventWithTx(ctx context.Context, tx Tx, eventID uint) (*models.Event, error)
+	FindRecipientsWithTx(ctx context.Context, tx Tx, eventID uint) ([]models.User, error)
+}
+
+// reminderRepository implements ReminderRepository
+type reminderRepository struct {
+	db *gorm.DB
+}
+
+// NewReminderRepository creates a new ReminderRepository
+func NewReminderRepository(db *gorm.DB) ReminderRepository {
+	return &reminderRepository{db: db}
+}
+
+// ReplaceForEventWithTx swaps an event's reminders for a new set
+func (r *reminderRepository) ReplaceForEventWithTx(ctx context.Context, tx Tx, eventID uint, reminders []models.EventReminder) error {
+	if err := r.DeleteByEventIDWithTx(ctx, tx, eventID); err != nil {
+		return err
+	}
+	if len(reminders) == 0 {
+		return nil
+	}
+	return txDB(ctx, tx).Create(&reminders).Error
+}
+
+// DeleteByEventIDWithTx removes every reminder for an event
+func (r *reminderRepository) DeleteByEventIDWithTx(ctx context.Context, tx Tx, eventID uint) error {
+	return txDB(ctx, tx).Where("event_id = ?", eventID).Delete(&models.EventReminder{}).Error
+}
+
+// ClaimDueWithTx locks up to limit unsent reminders that are due.
+// FOR UPDATE SKIP LOCKED lets several replicas poll at once: each row is
+// held by exactly one transaction until it commits with sent_at set,
+// so a reminder is never sent twice.
+func (r *reminderRepository) ClaimDueWithTx(ctx context.Context, tx Tx, now time.Time, limit int) ([]models.EventReminder, error) {
+	var reminders []models.EventReminder
+	err := txDB(ctx, tx).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("sent_at IS NULL AND due_at <= ?", now).
+		Order("due_at, id").
+		Limit(limit).
+		Find(&reminders).Error
+	return reminders, err
+}
+
+// MarkSentWithTx records that a reminder has been sent
+func (r *reminderRepository) MarkSentWithTx(ctx context.Context, tx Tx, id uint, sentAt time.Time) error {
+	return txDB(ctx, tx).Model(&models.EventReminder{}).Where("id = ?", id).Update("sent_at", sentAt).Error
+}
+
+// FindEventWithTx finds the event a reminder is for
+func (r *reminderRepository) FindEventWithTx(ctx context.Context, tx Tx, eventID uint) (*models.Event, error) {
+	var event models.Event
+	if err := txDB(ctx, tx).First(&event, eventID).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// FindRecipientsWithTx returns the confirmed attendees of an event, in
+// registration order. Registrations still awaiting review are left out.
+func (r *reminderRepository) FindRecipientsWithTx(ctx context.Context, tx Tx, eventID uint) ([]models.User, error) {
+	var users []models.User
+	err := txDB(ctx, tx).
+		Joins("JOIN registrations ON registrations.user_id = users.id AND registrations.deleted_at IS NULL").
+		Where("registrations.event_id = ? AND registrations.review <> ?", eventID, models.ReviewPending).
+		Order("registrations.id").
+		Find(&users).Error
+	return users, err
+}
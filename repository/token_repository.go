@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"event-api/models"
+
+	"gorm.io/gorm"
+)
+
+// TokenRepository defines the interface for single-use user tokens
+type TokenRepository interface {
+	Find(ctx context.Context, tokenHash string, purpose models.TokenPurpose, at time.Time) (*models.UserToken, error)
+
+	// Transaction support
+	CreateWithTx(ctx context.Context, tx Tx, token *models.UserToken) error
+	RevokeWithTx(ctx context.Context, tx Tx, userID uint, purpose models.TokenPurpose, at time.Time) error
+	RevokeAllWithTx(ctx context.Context, tx Tx, userID uint, at time.Time) error
+	ConsumeWithTx(ctx context.Context, tx Tx, tokenHash string, purpose models.TokenPurpose, at time.Time) (*models.UserToken, error)
+}
+
+// tokenRepository implements TokenRepository
+type tokenRepository struct {
+	db *gorm.DB
+}
+
+// NewTokenRepository creates a new TokenRepository
+func NewTokenRepository(db *gorm.DB) TokenRepository {
+	return &tokenRepository{db: db}
+}
+
+// Find returns the unused, unexpired token with tokenHash without using it.
+// It returns ErrInvalidToken when there is no such token.
+func (r *tokenRepository) Find(ctx context.Context, tokenHash string, purpose models.TokenPurpose, at time.Time) (*models.UserToken, error) {
+	var token models.UserToken
+	err := r.db.WithContext(ctx).Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", tokenHash, purpose, at).
+		First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// CreateWithTx stores a new token within a transaction
+func (r *tokenRepository) CreateWithTx(ctx context.Context, tx Tx, token *models.UserToken) error {
+	return txDB(ctx, tx).Create(token).Error
+}
+
+// RevokeWithTx marks a user's unused tokens for purpose as used within a
+// transaction, so only the newest one works
+func (r *tokenRepository) RevokeWithTx(ctx context.Context, tx Tx, userID uint, purpose models.TokenPurpose, at time.Time) error {
+	return txDB(ctx, tx).Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", at).Error
+}
+
+// RevokeAllWithTx marks all of a user's unused tokens as used within a
+// transaction, whatever their purpose
+func (r *tokenRepository) RevokeAllWithTx(ctx context.Context, tx Tx, userID uint, at time.Time) error {
+	return txDB(ctx, tx).Model(&models.UserToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", at).Error
+}
+
+// ConsumeWithTx marks the unused, unexpired token with tokenHash as used
+// within a transaction and returns it. It returns ErrInvalidToken when there
+// is no such token, including when a concurrent request consumed it first.
+func (r *tokenRepository) ConsumeWithTx(ctx context.Context, tx Tx, tokenHash string, purpose models.TokenPurpose, at time.Time) (*models.UserToken, error) {
+	db := txDB(ctx, tx)
+	var token models.UserToken
+	err := db.Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", tokenHash, purpose, at).
+		First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Only one transaction gets to set used_at
+	result := db.Model(&models.UserToken{}).Where("id = ? AND used_at IS NULL", token.ID).Update("used_at", at)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, models.ErrInvalidToken
+	}
+	token.UsedAt = &at
+	return &token, nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"event-api/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// pgLockNotAvailable is the Postgres error code of an expired lock_timeout
+const pgLockNotAvailable = "55P03"
+
+/*
+Tx is an open transaction, passed to the repository methods that run inside
+one (those ending in WithTx, ForUpdate and the seat counters).
+
+Each backend only accepts transactions it started: the GORM repositories take
+the *gorm.DB of a GORM transaction, and the in-memory repositories take the
+transaction of their memory.Store. Mixing backends is a programming error and
+panics.
+*/
+type Tx any
+
+// Transactor runs fn in a transaction, committing when it returns nil and
+// rolling back when it returns an error. Statements within the transaction
+// run with ctx. When ctx has a deadline, waiting for a lock stops there and
+// the transaction fails with models.ErrLockTimeout.
+type Transactor interface {
+	Transaction(ctx context.Context, fn func(tx Tx) error) error
+}
+
+// gormTransactor implements Transactor with GORM transactions
+type gormTransactor struct {
+	db *gorm.DB
+}
+
+// NewTransactor creates a Transactor for the GORM repositories
+func NewTransactor(db *gorm.DB) Transactor {
+	return &gormTransactor{db: db}
+}
+
+// Transaction runs fn in a database transaction
+func (t *gormTransactor) Transaction(ctx context.Context, fn func(tx Tx) error) error {
+	db := t.db.WithContext(ctx)
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return db.Transaction(func(tx *gorm.DB) error { return fn(tx) })
+	}
+
+	// Cancelling ctx closes the connection, but the database only notices
+	// once a lock it is waiting for is granted, so the lock waits themselves
+	// must end at the deadline
+	var err error
+	if isSQLite(db) {
+		err = withBusyTimeout(db, deadline, func(conn *gorm.DB) error {
+			return conn.Transaction(func(tx *gorm.DB) error { return fn(tx) })
+		})
+	} else {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(fmt.Sprintf("SET LOCAL lock_timeout = %d", untilMillis(deadline))).Error; err != nil {
+				return err
+			}
+			return fn(tx)
+		})
+	}
+	return lockTimeout(ctx, err)
+}
+
+// withBusyTimeout runs fn on one SQLite connection that waits for the write
+// lock until deadline at most, and then restores its busy timeout
+func withBusyTimeout(db *gorm.DB, deadline time.Time, fn func(conn *gorm.DB) error) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		// A new session, so the statements below do not build on each other
+		conn = conn.Session(&gorm.Session{})
+		var previous int
+		if err := conn.Raw("PRAGMA busy_timeout").Scan(&previous).Error; err != nil {
+			return err
+		}
+		if err := conn.Exec(fmt.Sprintf("PRAGMA busy_timeout = %d", untilMillis(deadline))).Error; err != nil {
+			return err
+		}
+		defer conn.WithContext(context.WithoutCancel(conn.Statement.Context)).
+			Exec(fmt.Sprintf("PRAGMA busy_timeout = %d", previous))
+		return fn(conn)
+	})
+}
+
+// untilMillis returns the milliseconds until deadline, rounded up so a
+// timeout set to it does not expire before the deadline does
+func untilMillis(deadline time.Time) int64 {
+	return max(time.Until(deadline).Milliseconds()+1, 1)
+}
+
+// lockTimeout returns ErrLockTimeout for a transaction that failed because
+// its deadline passed or a lock wait timed out, and err otherwise
+func lockTimeout(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) ||
+		errors.As(err, &pgErr) && pgErr.Code == pgLockNotAvailable {
+		return models.ErrLockTimeout
+	}
+	return err
+}
+
+// txDB returns the GORM transaction behind tx, running statements with ctx
+func txDB(ctx context.Context, tx Tx) *gorm.DB {
+	db, ok := tx.(*gorm.DB)
+	if !ok {
+		panic(fmt.Sprintf("repository: %T is not a GORM transaction", tx))
+	}
+	return db.WithContext(ctx)
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"event-api/models"
 
 	"gorm.io/gorm"
@@ -8,12 +9,18 @@ import (
 
 // UserRepository defines the interface for user data access
 type UserRepository interface {
-	Create(user *models.User) error
-	FindByID(id uint) (*models.User, error)
-	FindByEmail(email string) (*models.User, error)
-	FindAll() ([]models.User, error)
-	Update(user *models.User) error
-	Delete(id uint) error
+	Create(ctx context.Context, user *models.User) error
+	FindByID(ctx context.Context, id uint) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindAll(ctx context.Context) ([]models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, id uint) error
+
+	// Transaction support
+	FindByIDWithTx(ctx context.Context, tx Tx, id uint) (*models.User, error)
+	CreateWithTx(ctx context.Context, tx Tx, user *models.User) error
+	UpdateWithTx(ctx context.Context, tx Tx, user *models.User) error
+	DeleteWithTx(ctx context.Context, tx Tx, id uint) error
 }
 
 // userRepository implements UserRepository
@@ -27,14 +34,24 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 }
 
 // Create creates a new user
-func (r *userRepository) Create(user *models.User) error {
-	return r.db.Create(user).Error
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
 // FindByID finds a user by ID
-func (r *userRepository) FindByID(id uint) (*models.User, error) {
+func (r *userRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindByIDWithTx finds a user by ID within a transaction
+func (r *userRepository) FindByIDWithTx(ctx context.Context, tx Tx, id uint) (*models.User, error) {
 	var user models.User
-	err := r.db.First(&user, id).Error
+	err := txDB(ctx, tx).First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -42,9 +59,9 @@ func (r *userRepository) FindByID(id uint) (*models.User, error) {
 }
 
 // FindByEmail finds a user by email
-func (r *userRepository) FindByEmail(email string) (*models.User, error) {
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -52,18 +69,33 @@ func (r *userRepository) FindByEmail(email string) (*models.User, error) {
 }
 
 // FindAll returns all users
-func (r *userRepository) FindAll() ([]models.User, error) {
+func (r *userRepository) FindAll(ctx context.Context) ([]models.User, error) {
 	var users []models.User
-	err := r.db.Find(&users).Error
+	err := r.db.WithContext(ctx).Find(&users).Error
 	return users, err
 }
 
 // Update updates a user
-func (r *userRepository) Update(user *models.User) error {
-	return r.db.Save(user).Error
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Save(user).Error
 }
 
 // Delete deletes a user by ID
-func (r *userRepository) Delete(id uint) error {
-	return r.db.Delete(&models.User{}, id).Error
+func (r *userRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.User{}, id).Error
+}
+
+// CreateWithTx creates a new user within a transaction
+func (r *userRepository) CreateWithTx(ctx context.Context, tx Tx, user *models.User) error {
+	return txDB(ctx, tx).Create(user).Error
+}
+
+// UpdateWithTx updates a user within a transaction
+func (r *userRepository) UpdateWithTx(ctx context.Context, tx Tx, user *models.User) error {
+	return txDB(ctx, tx).Save(user).Error
+}
+
+// DeleteWithTx deletes a user by ID within a transaction
+func (r *userRepository) DeleteWithTx(ctx context.Context, tx Tx, id uint) error {
+	return txDB(ctx, tx).Delete(&models.User{}, id).Error
 }
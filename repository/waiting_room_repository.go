@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"event-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// waitingRoomAdmitterLockKey is the Postgres advisory lock key held by the active admitter
+const waitingRoomAdmitterLockKey = 7240131
+
+// WaitingRoomRepository defines the interface for waiting room entries
+type WaitingRoomRepository interface {
+	Create(ctx context.Context, entry *models.WaitingRoomEntry) error
+	FindByID(ctx context.Context, id uint) (*models.WaitingRoomEntry, error)
+	FindByEventAndUser(ctx context.Context, eventID, userID uint) (*models.WaitingRoomEntry, error)
+	Delete(ctx context.Context, id uint) error
+	CountWaitingUpTo(ctx context.Context, eventID, entryID uint) (int64, error)
+
+	// Transaction support
+	TryLockAdmitterWithTx(ctx context.Context, tx *gorm.DB) (bool, error)
+	FindDueEventIDsWithTx(ctx context.Context, tx *gorm.DB, now time.Time) ([]uint, error)
+	AdmitNextWithTx(ctx context.Context, tx *gorm.DB, eventIDs []uint, perEvent int, admittedAt, expiresAt time.Time) (int64, error)
+	ScheduleWithTx(ctx context.Context, tx *gorm.DB, eventIDs []uint, nextAdmitAt time.Time) error
+}
+
+// waitingRoomRepository implements WaitingRoomRepository
+type waitingRoomRepository struct {
+	db *gorm.DB
+}
+
+// NewWaitingRoomRepository creates a new WaitingRoomRepository
+func NewWaitingRoomRepository(db *gorm.DB) WaitingRoomRepository {
+	return &waitingRoomRepository{db: db}
+}
+
+// Create adds an entry at the back of the queue. A concurrent join by the
+// same user is ignored; callers re-read the entry afterwards.
+func (r *waitingRoomRepository) Create(ctx context.Context, entry *models.WaitingRoomEntry) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(entry).Error
+}
+
+// FindByID finds an entry by ID
+func (r *waitingRoomRepository) FindByID(ctx context.Context, id uint) (*models.WaitingRoomEntry, error) {
+	var entry models.WaitingRoomEntry
+	if err := r.db.WithContext(ctx).First(&entry, id).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// FindByEventAndUser finds a user's entry for an event
+func (r *waitingRoomRepository) FindByEventAndUser(ctx context.Context, eventID, userID uint) (*models.WaitingRoomEntry, error) {
+	var entry models.WaitingRoomEntry
+	err := r.db.WithContext(ctx).Where("event_id = ? AND user_id = ?", eventID, userID).First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Delete removes an entry
+func (r *waitingRoomRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.WaitingRoomEntry{}, id).Error
+}
+
+// CountWaitingUpTo returns an entry's 1-based position among the entries
+// still waiting for its event
+func (r *waitingRoomRepository) CountWaitingUpTo(ctx context.Context, eventID, entryID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.WaitingRoomEntry{}).
+		Where("event_id = ? AND admitted_at IS NULL AND id <= ?", eventID, entryID).
+		Count(&count).Error
+	return count, err
+}
+
+// TryLockAdmitterWithTx takes a transaction-scoped advisory lock so only one
+// replica at a time reads and advances the admission schedules. It returns
+// false if another replica holds the lock.
+func (r *waitingRoomRepository) TryLockAdmitterWithTx(ctx context.Context, tx *gorm.DB) (bool, error) {
+	if isSQLite(tx) {
+		return true, nil
+	}
+	var locked bool
+	err := tx.WithContext(ctx).Raw("SELECT pg_try_advisory_xact_lock(?)", waitingRoomAdmitterLockKey).Scan(&locked).Error
+	return locked, err
+}
+
+// FindDueEventIDsWithTx returns the events with users waiting whose
+// waiting room may admit its next batch at now
+func (r *waitingRoomRepository) FindDueEventIDsWithTx(ctx context.Context, tx *gorm.DB, now time.Time) ([]uint, error) {
+	var eventIDs []uint
+	err := tx.WithContext(ctx).Model(&models.WaitingRoomEntry{}).
+		Where("admitted_at IS NULL").
+		Where("NOT EXISTS (SELECT 1 FROM waiting_room_schedules s WHERE s.event_id = waiting_room_entries.event_id AND s.next_admit_at > ?)", now).
+		Distinct().Order("event_id").Pluck("event_id", &eventIDs).Error
+	return eventIDs, err
+}
+
+// AdmitNextWithTx admits the first perEvent waiting entries of each of the
+// given events and returns how many were admitted
+func (r *waitingRoomRepository) AdmitNextWithTx(ctx context.Context, tx *gorm.DB, eventIDs []uint, perEvent int, admittedAt, expiresAt time.Time) (int64, error) {
+	if len(eventIDs) == 0 {
+		return 0, nil
+	}
+	result := tx.WithContext(ctx).Exec(`
+		UPDATE waiting_room_entries SET admitted_at = ?, expires_at = ?
+		WHERE id IN (
+			SELECT id FROM (
+				SELECT id, row_number() OVER (PARTITION BY event_id ORDER BY id) AS place
+				FROM waiting_room_entries
+				WHERE admitted_at IS NULL AND event_id IN ?
+			) queue
+			WHERE place <= ?
+		)`, admittedAt, expiresAt, eventIDs, perEvent)
+	return result.RowsAffected, result.Error
+}
+
+// ScheduleWithTx sets when the given events' waiting rooms may next admit
+func (r *waitingRoomRepository) ScheduleWithTx(ctx context.Context, tx *gorm.DB, eventIDs []uint, nextAdmitAt time.Time) error {
+	if len(eventIDs) == 0 {
+		return nil
+	}
+	schedules := make([]models.WaitingRoomSchedule, len(eventIDs))
+	for i, eventID := range eventIDs {
+		schedules[i] = models.WaitingRoomSchedule{EventID: eventID, NextAdmitAt: nextAdmitAt}
+	}
+	return tx.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "event_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"next_admit_at"}),
+	}).Create(&schedules).Error
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"event-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebhookRepository defines the interface for webhook subscriptions and their delivery log
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	FindSubscriptionByID(ctx context.Context, id uint) (*models.WebhookSubscription, error)
+	FindSubscriptionsByOrganizerID(ctx context.Context, organizerID uint) ([]models.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id uint) error
+
+	FindDeliveriesBySubscriptionID(ctx context.Context, subscriptionID uint, limit int) ([]models.WebhookDelivery, error)
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, id uint, responseStatus int, deliveredAt time.Time) error
+	MarkRetry(ctx context.Context, id uint, attempts, responseStatus int, nextAttemptAt time.Time, lastError string) error
+	MarkFailed(ctx context.Context, id uint, attempts, responseStatus int, lastError string) error
+
+	// Transaction support
+	FindActiveSubscriptionsWithTx(ctx context.Context, tx *gorm.DB, organizerID uint) ([]models.WebhookSubscription, error)
+	CreateDeliveriesWithTx(ctx context.Context, tx *gorm.DB, deliveries []models.WebhookDelivery) error
+}
+
+// webhookRepository implements WebhookRepository
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository creates a new WebhookRepository
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+// CreateSubscription creates a new webhook subscription
+func (r *webhookRepository) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	return r.db.WithContext(ctx).Create(subscription).Error
+}
+
+// FindSubscriptionByID finds a webhook subscription by ID
+func (r *webhookRepository) FindSubscriptionByID(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	err := r.db.WithContext(ctx).First(&subscription, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// FindSubscriptionsByOrganizerID returns all webhook subscriptions of an organizer
+func (r *webhookRepository) FindSubscriptionsByOrganizerID(ctx context.Context, organizerID uint) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := r.db.WithContext(ctx).Where("organizer_id = ?", organizerID).Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// UpdateSubscription updates a webhook subscription
+func (r *webhookRepository) UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	return r.db.WithContext(ctx).Save(subscription).Error
+}
+
+// DeleteSubscription deletes a webhook subscription and its delivery log
+func (r *webhookRepository) DeleteSubscription(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.WebhookSubscription{}, id).Error
+	})
+}
+
+// FindDeliveriesBySubscriptionID returns the most recent deliveries for a subscription
+func (r *webhookRepository) FindDeliveriesBySubscriptionID(ctx context.Context, subscriptionID uint, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.WithContext(ctx).Where("subscription_id = ?", subscriptionID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ClaimDueDeliveries returns up to limit pending deliveries whose next attempt
+// is due, with their subscription loaded, and pushes their next attempt
+// forward by lease so other workers skip them while they are in flight
+func (r *webhookRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+			Order("next_attempt_at, id").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(deliveries))
+		for i, d := range deliveries {
+			ids[i] = d.ID
+		}
+		err = tx.Model(&models.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+		if err != nil {
+			return err
+		}
+
+		// Load subscriptions separately; Preload cannot be combined with the locking clause
+		var subscriptions []models.WebhookSubscription
+		subscriptionIDs := make([]uint, len(deliveries))
+		for i, d := range deliveries {
+			subscriptionIDs[i] = d.SubscriptionID
+		}
+		if err := tx.Where("id IN ?", subscriptionIDs).Find(&subscriptions).Error; err != nil {
+			return err
+		}
+		byID := make(map[uint]*models.WebhookSubscription, len(subscriptions))
+		for i := range subscriptions {
+			byID[subscriptions[i].ID] = &subscriptions[i]
+		}
+		for i := range deliveries {
+			deliveries[i].Subscription = byID[deliveries[i].SubscriptionID]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// MarkDelivered records a successful delivery
+func (r *webhookRepository) MarkDelivered(ctx context.Context, id uint, responseStatus int, deliveredAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          models.WebhookDeliveryDelivered,
+		"attempts":        gorm.Expr("attempts + 1"),
+		"response_status": responseStatus,
+		"delivered_at":    deliveredAt,
+		"last_error":      "",
+	}).Error
+}
+
+// MarkRetry records a failed attempt and schedules the next one
+func (r *webhookRepository) MarkRetry(ctx context.Context, id uint, attempts, responseStatus int, nextAttemptAt time.Time, lastError string) error {
+	return r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":        attempts,
+		"response_status": responseStatus,
+		"next_attempt_at": nextAttemptAt,
+		"last_error":      lastError,
+	}).Error
+}
+
+// MarkFailed gives up on a delivery after the final attempt
+func (r *webhookRepository) MarkFailed(ctx context.Context, id uint, attempts, responseStatus int, lastError string) error {
+	return r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          models.WebhookDeliveryFailed,
+		"attempts":        attempts,
+		"response_status": responseStatus,
+		"last_error":      lastError,
+	}).Error
+}
+
+// FindActiveSubscriptionsWithTx returns an organizer's active subscriptions within a transaction
+func (r *webhookRepository) FindActiveSubscriptionsWithTx(ctx context.Context, tx *gorm.DB, organizerID uint) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := tx.WithContext(ctx).Where("organizer_id = ? AND active = ?", organizerID, true).Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// CreateDeliveriesWithTx queues deliveries within a transaction
+func (r *webhookRepository) CreateDeliveriesWithTx(ctx context.Context, tx *gorm.DB, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return tx.WithContext(ctx).Create(&deliveries).Error
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"event-api/credential"
+	"event-api/models"
+	"event-api/repository"
+	"event-api/tracing"
+
+	"gorm.io/gorm"
+)
+
+// AccountConfig controls how long mailed tokens stay valid
+type AccountConfig struct {
+	VerificationTTL  time.Duration
+	PasswordResetTTL time.Duration
+}
+
+// AccountService runs the account lifecycle: verifying email addresses,
+// resetting passwords and deactivating accounts. Tokens are mailed through
+// the notification outbox in the transaction that issues them; each is
+// single-use, expires, and is stored only as a digest.
+type AccountService interface {
+	SendVerification(ctx context.Context, actor models.Actor, requesterID, userID uint) error
+	VerifyEmail(ctx context.Context, actor models.Actor, token string) (*models.User, error)
+	RequestPasswordReset(ctx context.Context, actor models.Actor, email string) error
+	ResetPassword(ctx context.Context, actor models.Actor, token, password string) error
+	DeactivateUser(ctx context.Context, actor models.Actor, requesterID, userID uint) (*models.User, error)
+}
+
+type accountService struct {
+	db                  *gorm.DB
+	userRepo            repository.UserRepository
+	tokenRepo           repository.TokenRepository
+	auditRepo           repository.AuditRepository
+	notificationService NotificationService
+	config              AccountConfig
+}
+
+// NewAccountService creates a new AccountService
+func NewAccountService(
+	db *gorm.DB,
+	userRepo repository.UserRepository,
+	tokenRepo repository.TokenRepository,
+	auditRepo repository.AuditRepository,
+	notificationService NotificationService,
+	config AccountConfig,
+) AccountService {
+	return &accountService{
+		db:                  db,
+		userRepo:            userRepo,
+		tokenRepo:           tokenRepo,
+		auditRepo:           auditRepo,
+		notificationService: notificationService,
+		config:              config,
+	}
+}
+
+// SendVerification mails userID a token confirming their email address,
+// revoking earlier ones. Users may ask for themselves and admins for anyone.
+// It fails with ErrUserNotFound for an unknown requester, ErrUnauthorized,
+// gorm.ErrRecordNotFound for an unknown user, ErrUserDeactivated, and
+// ErrInvalidInput when the address is verified already.
+func (s *accountService) SendVerification(ctx context.Context, actor models.Actor, requesterID, userID uint) (err error) {
+	ctx, span := tracing.Start(ctx, "AccountService.SendVerification", tracing.UserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	user, err := authorizeAccount(ctx, s.userRepo, requesterID, userID)
+	if err != nil {
+		return err
+	}
+	if !user.Active() {
+		return models.ErrUserDeactivated
+	}
+	if user.Verified() {
+		return models.ErrInvalidInput
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return s.issueWithTx(ctx, tx, user, models.TokenVerifyEmail, s.config.VerificationTTL, models.NotificationVerifyEmail)
+	})
+}
+
+// VerifyEmail consumes a verification token and marks its user's email
+// address verified. It fails with ErrInvalidToken for unknown, used or
+// expired tokens, and for tokens sent to an address the user has since
+// changed.
+func (s *accountService) VerifyEmail(ctx context.Context, actor models.Actor, token string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "AccountService.VerifyEmail")
+	defer func() { tracing.End(span, err) }()
+
+	var user *models.User
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		issued, err := s.tokenRepo.ConsumeWithTx(ctx, tx, credential.HashToken(token), models.TokenVerifyEmail, now)
+		if err != nil {
+			return err
+		}
+		existing, err := s.userRepo.FindByIDWithTx(ctx, tx, issued.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.ErrInvalidToken
+		}
+		if err != nil {
+			return err
+		}
+		if existing.Email != issued.Email || !existing.Active() {
+			return models.ErrInvalidToken
+		}
+		if existing.Verified() {
+			user = existing
+			return nil
+		}
+
+		updated := *existing
+		updated.EmailVerifiedAt = &now
+		if err := s.userRepo.UpdateWithTx(ctx, tx, &updated); err != nil {
+			return err
+		}
+		user = &updated
+		return s.auditWithTx(ctx, tx, actor, models.ActionVerifyEmail, existing, &updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(tracing.UserID(user.ID))
+	return user, nil
+}
+
+// RequestPasswordReset mails a password reset token to the active user with
+// email, revoking earlier ones. It does nothing for unknown addresses, so
+// callers cannot learn which addresses have accounts.
+func (s *accountService) RequestPasswordReset(ctx context.Context, actor models.Actor, email string) (err error) {
+	ctx, span := tracing.Start(ctx, "AccountService.RequestPasswordReset")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !user.Active() {
+		return nil
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return s.issueWithTx(ctx, tx, user, models.TokenResetPassword, s.config.PasswordResetTTL, models.NotificationPasswordReset)
+	})
+}
+
+// ResetPassword consumes a password reset token and sets its user's new
+// password. It fails with ErrInvalidInput for a password that is too short
+// and ErrInvalidToken for unknown, used or expired tokens, and for tokens
+// mailed to an address the user no longer has.
+func (s *accountService) ResetPassword(ctx context.Context, actor models.Actor, token, password string) (err error) {
+	ctx, span := tracing.Start(ctx, "AccountService.ResetPassword")
+	defer func() { tracing.End(span, err) }()
+
+	if err := models.ValidatePassword(password); err != nil {
+		return err
+	}
+	// The token is checked before hashing, so guessed tokens cost no
+	// hashing work
+	digest := credential.HashToken(token)
+	if _, err := s.tokenRepo.Find(ctx, digest, models.TokenResetPassword, time.Now()); err != nil {
+		return err
+	}
+	hash, err := credential.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		issued, err := s.tokenRepo.ConsumeWithTx(ctx, tx, digest, models.TokenResetPassword, time.Now())
+		if err != nil {
+			return err
+		}
+		existing, err := s.userRepo.FindByIDWithTx(ctx, tx, issued.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.ErrInvalidToken
+		}
+		if err != nil {
+			return err
+		}
+		if existing.Email != issued.Email || !existing.Active() {
+			return models.ErrInvalidToken
+		}
+
+		updated := *existing
+		updated.PasswordHash = hash
+		if err := s.userRepo.UpdateWithTx(ctx, tx, &updated); err != nil {
+			return err
+		}
+		return s.auditWithTx(ctx, tx, actor, models.ActionResetPassword, existing, &updated)
+	})
+}
+
+// DeactivateUser deactivates userID's account, so they can no longer
+// register for events or receive tokens, and revokes their unused tokens.
+// Their registrations are kept. Users may deactivate themselves and admins
+// anyone; deactivating an inactive account changes nothing.
+func (s *accountService) DeactivateUser(ctx context.Context, actor models.Actor, requesterID, userID uint) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "AccountService.DeactivateUser", tracing.UserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := authorizeAccount(ctx, s.userRepo, requesterID, userID); err != nil {
+		return nil, err
+	}
+
+	var user *models.User
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		existing, err := s.userRepo.FindByIDWithTx(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		if !existing.Active() {
+			user = existing
+			return nil
+		}
+
+		now := time.Now()
+		updated := *existing
+		updated.DeactivatedAt = &now
+		if err := s.userRepo.UpdateWithTx(ctx, tx, &updated); err != nil {
+			return err
+		}
+		for _, purpose := range []models.TokenPurpose{models.TokenVerifyEmail, models.TokenResetPassword} {
+			if err := s.tokenRepo.RevokeWithTx(ctx, tx, userID, purpose, now); err != nil {
+				return err
+			}
+		}
+		user = &updated
+		return s.auditWithTx(ctx, tx, actor, models.ActionDeactivateUser, existing, &updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// authorizeAccount returns userID's account when requesterID may manage it:
+// when it is their own, or they are an active admin. It fails with
+// ErrUserNotFound for an unknown requester and ErrUnauthorized.
+func authorizeAccount(ctx context.Context, userRepo repository.UserRepository, requesterID, userID uint) (*models.User, error) {
+	requester, err := userRepo.FindByID(ctx, requesterID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if requester.ID == userID {
+		return requester, nil
+	}
+	if requester.Role != models.RoleAdmin || !requester.Active() {
+		return nil, models.ErrUnauthorized
+	}
+	return userRepo.FindByID(ctx, userID)
+}
+
+// issueWithTx revokes user's unused tokens for purpose, stores a new one and
+// mails it within tx
+func (s *accountService) issueWithTx(ctx context.Context, tx *gorm.DB, user *models.User, purpose models.TokenPurpose, ttl time.Duration, notificationType models.NotificationType) error {
+	now := time.Now()
+	if err := s.tokenRepo.RevokeWithTx(ctx, tx, user.ID, purpose, now); err != nil {
+		return err
+	}
+	token, digest := credential.NewToken()
+	issued := &models.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: digest,
+		Email:     user.Email,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := s.tokenRepo.CreateWithTx(ctx, tx, issued); err != nil {
+		return err
+	}
+	return s.notificationService.EnqueueAccountWithTx(ctx, tx, notificationType, user, token, issued.ExpiresAt)
+}
+
+// auditWithTx writes the audit entry of a change to an account within tx
+func (s *accountService) auditWithTx(ctx context.Context, tx *gorm.DB, actor models.Actor, action models.Action, before, after *models.User) error {
+	entry, err := models.NewAuditEvent(actor, action, models.AuditTargetUser, after.ID, before, after)
+	if err != nil {
+		return err
+	}
+	return s.auditRepo.CreateWithTx(ctx, tx, entry)
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"event-api/credential"
+	"event-api/internal/testdb"
+	"event-api/models"
+	"event-api/repository"
+
+	"gorm.io/gorm"
+)
+
+// mailedToken matches the token line of an account email
+var mailedToken = regexp.MustCompile(`\n\n([A-Za-z0-9_-]{43})\n\n`)
+
+func TestAccountLifecycle(t *testing.T) {
+	// Tokens are mailed through the notification outbox, which needs GORM
+	for name, open := range map[string]func(t testing.TB) *gorm.DB{
+		"sqlite":   testdb.NewSQLite,
+		"postgres": testdb.New,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			db := open(t)
+			f := newGormFixture(t, db, SeatStrategyPessimistic)
+			accounts := NewAccountService(db, f.userRepo, repository.NewTokenRepository(db), f.auditRepo,
+				NewNotificationService(repository.NewNotificationRepository(db)),
+				AccountConfig{VerificationTTL: time.Hour, PasswordResetTTL: time.Hour})
+
+			// lastToken returns the token of the newest email of a type
+			lastToken := func(notificationType models.NotificationType) string {
+				t.Helper()
+				var n models.Notification
+				if err := db.Where("type = ?", notificationType).Order("id DESC").First(&n).Error; err != nil {
+					t.Fatalf("finding %s email: %v", notificationType, err)
+				}
+				match := mailedToken.FindStringSubmatch(n.TextBody)
+				if match == nil {
+					t.Fatalf("no token in %q", n.TextBody)
+				}
+				return match[1]
+			}
+
+			if err := f.users.CreateUser(ctx, testActor, &models.User{Name: "Bad", Email: "Bad <bad@example.com>"}); !errors.Is(err, models.ErrInvalidInput) {
+				t.Fatalf("invalid email: error = %v, want %v", err, models.ErrInvalidInput)
+			}
+			if err := f.users.CreateUser(ctx, testActor, &models.User{Name: "Short", Email: "short@example.com", Password: "short"}); !errors.Is(err, models.ErrInvalidInput) {
+				t.Fatalf("short password: error = %v, want %v", err, models.ErrInvalidInput)
+			}
+			now := time.Now()
+			user := &models.User{Name: "Ada", Email: "ada@example.com", Password: "first password", EmailVerifiedAt: &now}
+			if err := f.users.CreateUser(ctx, testActor, user); err != nil {
+				t.Fatalf("creating user: %v", err)
+			}
+			if user.Password != "" || user.Verified() {
+				t.Fatalf("created user kept the password or a client-set verification: %+v", user)
+			}
+			other := f.createUsers(t, 1)[0]
+
+			// Verification: only the user or an admin may ask, and a token
+			// works once
+			if err := accounts.SendVerification(ctx, testActor, other, user.ID); !errors.Is(err, models.ErrUnauthorized) {
+				t.Fatalf("verification by another user: error = %v, want %v", err, models.ErrUnauthorized)
+			}
+			if err := accounts.SendVerification(ctx, testActor, user.ID, user.ID); err != nil {
+				t.Fatalf("sending verification: %v", err)
+			}
+			stale := lastToken(models.NotificationVerifyEmail)
+			if err := accounts.SendVerification(ctx, testActor, user.ID, user.ID); err != nil {
+				t.Fatalf("resending verification: %v", err)
+			}
+			token := lastToken(models.NotificationVerifyEmail)
+			if _, err := accounts.VerifyEmail(ctx, testActor, stale); !errors.Is(err, models.ErrInvalidToken) {
+				t.Errorf("superseded token: error = %v, want %v", err, models.ErrInvalidToken)
+			}
+			verified, err := accounts.VerifyEmail(ctx, testActor, token)
+			if err != nil || !verified.Verified() {
+				t.Fatalf("verified user = %+v, err %v", verified, err)
+			}
+			if _, err := accounts.VerifyEmail(ctx, testActor, token); !errors.Is(err, models.ErrInvalidToken) {
+				t.Errorf("reused token: error = %v, want %v", err, models.ErrInvalidToken)
+			}
+
+			// Changing the email address unverifies it, and tokens sent to
+			// the old address no longer verify
+			if err := accounts.SendVerification(ctx, testActor, user.ID, user.ID); !errors.Is(err, models.ErrInvalidInput) {
+				t.Errorf("verifying twice: error = %v, want %v", err, models.ErrInvalidInput)
+			}
+			changed := *verified
+			changed.Email = "ada@example.org"
+			if err := f.users.UpdateUser(ctx, testActor, &changed); err != nil || changed.Verified() {
+				t.Fatalf("changing email: verified %v, err %v; want unverified", changed.Verified(), err)
+			}
+			if err := accounts.SendVerification(ctx, testActor, user.ID, user.ID); err != nil {
+				t.Fatalf("sending verification: %v", err)
+			}
+			token = lastToken(models.NotificationVerifyEmail)
+			changed.Email = "ada@example.net"
+			if err := f.users.UpdateUser(ctx, testActor, &changed); err != nil {
+				t.Fatalf("changing email: %v", err)
+			}
+			if _, err := accounts.VerifyEmail(ctx, testActor, token); !errors.Is(err, models.ErrInvalidToken) {
+				t.Errorf("token for a previous address: error = %v, want %v", err, models.ErrInvalidToken)
+			}
+
+			// Reset tokens mailed to a previous address do not work either,
+			// whether the change revoked them or happened behind the
+			// service's back
+			if err := accounts.RequestPasswordReset(ctx, testActor, changed.Email); err != nil {
+				t.Fatalf("requesting reset: %v", err)
+			}
+			token = lastToken(models.NotificationPasswordReset)
+			changed.Email = "ada@example.com"
+			if err := f.users.UpdateUser(ctx, testActor, &changed); err != nil {
+				t.Fatalf("changing email: %v", err)
+			}
+			if err := accounts.ResetPassword(ctx, testActor, token, "stolen password"); !errors.Is(err, models.ErrInvalidToken) {
+				t.Errorf("revoked reset token: error = %v, want %v", err, models.ErrInvalidToken)
+			}
+			if err := accounts.RequestPasswordReset(ctx, testActor, changed.Email); err != nil {
+				t.Fatalf("requesting reset: %v", err)
+			}
+			token = lastToken(models.NotificationPasswordReset)
+			if err := db.Model(&models.User{}).Where("id = ?", user.ID).Update("email", "ada@example.org").Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := accounts.ResetPassword(ctx, testActor, token, "stolen password"); !errors.Is(err, models.ErrInvalidToken) {
+				t.Errorf("reset token for a previous address: error = %v, want %v", err, models.ErrInvalidToken)
+			}
+			changed.Email = "ada@example.org"
+
+			// Password reset: unknown addresses are not revealed, and expired
+			// tokens are refused
+			if err := accounts.RequestPasswordReset(ctx, testActor, "nobody@example.com"); err != nil {
+				t.Fatalf("reset for an unknown address: %v", err)
+			}
+			if err := accounts.RequestPasswordReset(ctx, testActor, changed.Email); err != nil {
+				t.Fatalf("requesting reset: %v", err)
+			}
+			token = lastToken(models.NotificationPasswordReset)
+			if err := accounts.ResetPassword(ctx, testActor, token, "short"); !errors.Is(err, models.ErrInvalidInput) {
+				t.Errorf("short password: error = %v, want %v", err, models.ErrInvalidInput)
+			}
+			if err := db.Model(&models.UserToken{}).Where("purpose = ?", models.TokenResetPassword).
+				Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := accounts.ResetPassword(ctx, testActor, token, "second password"); !errors.Is(err, models.ErrInvalidToken) {
+				t.Errorf("expired token: error = %v, want %v", err, models.ErrInvalidToken)
+			}
+			if err := accounts.RequestPasswordReset(ctx, testActor, changed.Email); err != nil {
+				t.Fatalf("requesting reset: %v", err)
+			}
+			token = lastToken(models.NotificationPasswordReset)
+			if err := accounts.ResetPassword(ctx, testActor, token, "second password"); err != nil {
+				t.Fatalf("resetting password: %v", err)
+			}
+			if err := accounts.ResetPassword(ctx, testActor, token, "third password"); !errors.Is(err, models.ErrInvalidToken) {
+				t.Errorf("reused token: error = %v, want %v", err, models.ErrInvalidToken)
+			}
+			stored, err := f.userRepo.FindByID(ctx, user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok, err := credential.CheckPassword("second password", stored.PasswordHash); err != nil || !ok {
+				t.Errorf("new password does not match: %v, %v", ok, err)
+			}
+
+			// Deactivation revokes unused tokens and blocks registering
+			if err := accounts.RequestPasswordReset(ctx, testActor, changed.Email); err != nil {
+				t.Fatalf("requesting reset: %v", err)
+			}
+			token = lastToken(models.NotificationPasswordReset)
+			if _, err := accounts.DeactivateUser(ctx, testActor, other, user.ID); !errors.Is(err, models.ErrUnauthorized) {
+				t.Fatalf("deactivation by another user: error = %v, want %v", err, models.ErrUnauthorized)
+			}
+			deactivated, err := accounts.DeactivateUser(ctx, testActor, user.ID, user.ID)
+			if err != nil || deactivated.Active() {
+				t.Fatalf("deactivated user = %+v, err %v", deactivated, err)
+			}
+			if err := accounts.ResetPassword(ctx, testActor, token, "fourth password"); !errors.Is(err, models.ErrInvalidToken) {
+				t.Errorf("token of a deactivated user: error = %v, want %v", err, models.ErrInvalidToken)
+			}
+			if err := accounts.SendVerification(ctx, testActor, user.ID, user.ID); !errors.Is(err, models.ErrUserDeactivated) {
+				t.Errorf("verification for a deactivated user: error = %v, want %v", err, models.ErrUserDeactivated)
+			}
+			event := f.createEvent(t, 1)
+			if _, err := f.registrations.RegisterForEvent(ctx, testActor, user.ID, event.ID, models.RegistrationDetails{}); !errors.Is(err, models.ErrUserDeactivated) {
+				t.Errorf("registering a deactivated user: error = %v, want %v", err, models.ErrUserDeactivated)
+			}
+
+			var actions []models.Action
+			if err := db.Model(&models.AuditEvent{}).Where("target_type = ? AND target_id = ?", models.AuditTargetUser, user.ID).
+				Order("id").Pluck("action", &actions).Error; err != nil {
+				t.Fatal(err)
+			}
+			want := []models.Action{models.ActionCreateUser, models.ActionVerifyEmail, models.ActionUpdateUser,
+				models.ActionUpdateUser, models.ActionUpdateUser, models.ActionResetPassword, models.ActionDeactivateUser}
+			if len(actions) != len(want) {
+				t.Fatalf("audit actions = %v, want %v", actions, want)
+			}
+			for i := range want {
+				if actions[i] != want[i] {
+					t.Fatalf("audit actions = %v, want %v", actions, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"event-api/models"
+	"event-api/repository"
+	"event-api/tracing"
+
+	"gorm.io/gorm"
+)
+
+// Audit query page sizes
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditService answers questions about the audit log. Admins see every
+// entry; organizers see the entries of the events they organize.
+type AuditService interface {
+	GetAuditEvents(ctx context.Context, viewerID uint, filter models.AuditFilter) ([]models.AuditEvent, error)
+}
+
+type auditService struct {
+	userRepo  repository.UserRepository
+	auditRepo repository.AuditRepository
+}
+
+// NewAuditService creates a new AuditService
+func NewAuditService(userRepo repository.UserRepository, auditRepo repository.AuditRepository) AuditService {
+	return &auditService{userRepo: userRepo, auditRepo: auditRepo}
+}
+
+// GetAuditEvents returns the newest entries matching filter that viewerID
+// may see. It fails with ErrUnauthorized for anyone but admins and
+// organizers.
+func (s *auditService) GetAuditEvents(ctx context.Context, viewerID uint, filter models.AuditFilter) (_ []models.AuditEvent, err error) {
+	_, span := tracing.Start(ctx, "AuditService.GetAuditEvents", tracing.UserID(viewerID))
+	defer func() { tracing.End(span, err) }()
+
+	viewer, err := s.userRepo.FindByID(ctx, viewerID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch viewer.Role {
+	case models.RoleAdmin:
+	case models.RoleOrganizer:
+		filter.OrganizerID = viewer.ID
+	default:
+		return nil, models.ErrUnauthorized
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	filter.Limit = min(filter.Limit, maxAuditLimit)
+
+	entries, err := s.auditRepo.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []models.AuditEvent{}
+	}
+	return entries, nil
+}
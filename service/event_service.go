@@ -1,57 +1,462 @@
 package service
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"event-api/models"
 	"event-api/repository"
+	"event-api/tracing"
 )
 
 // EventService handles event business logic
 type EventService interface {
-	CreateEvent(event *models.Event) error
-	GetEventByID(id uint) (*models.Event, error)
-	GetAllEvents() ([]models.Event, error)
-	GetEventsByOrganizerID(organizerID uint) ([]models.Event, error)
-	UpdateEvent(event *models.Event) error
-	DeleteEvent(id uint) error
+	CreateEvent(ctx context.Context, actor models.Actor, event *models.Event) error
+	GetEventByID(ctx context.Context, id uint) (*models.Event, error)
+	GetAvailability(ctx context.Context, id uint) (*models.AvailabilityUpdate, error)
+	GetAllEvents(ctx context.Context) ([]models.Event, error)
+	GetEventsByOrganizerID(ctx context.Context, organizerID uint) ([]models.Event, error)
+	UpdateEvent(ctx context.Context, actor models.Actor, event *models.Event) error
+	PublishEvent(ctx context.Context, actor models.Actor, id uint) (*models.Event, error)
+	DeleteEvent(ctx context.Context, actor models.Actor, id uint) error
+	RecountSeats(ctx context.Context, id uint) (*models.SeatRecount, error)
 }
 
 type eventService struct {
-	eventRepo repository.EventRepository
+	transactor       repository.Transactor
+	eventRepo        repository.EventRepository
+	registrationRepo repository.RegistrationRepository
+	outboxRepo       repository.OutboxRepository
+	inventoryRepo    repository.InventoryRepository
+	auditRepo        repository.AuditRepository
+	reminderService  ReminderService
 }
 
 // NewEventService creates a new EventService
-func NewEventService(eventRepo repository.EventRepository) EventService {
-	return &eventService{eventRepo: eventRepo}
+func NewEventService(
+	transactor repository.Transactor,
+	eventRepo repository.EventRepository,
+	registrationRepo repository.RegistrationRepository,
+	outboxRepo repository.OutboxRepository,
+	inventoryRepo repository.InventoryRepository,
+	auditRepo repository.AuditRepository,
+	reminderService ReminderService,
+) EventService {
+	return &eventService{
+		transactor:       transactor,
+		eventRepo:        eventRepo,
+		registrationRepo: registrationRepo,
+		outboxRepo:       outboxRepo,
+		inventoryRepo:    inventoryRepo,
+		auditRepo:        auditRepo,
+		reminderService:  reminderService,
+	}
 }
 
-// CreateEvent creates a new event
-func (s *eventService) CreateEvent(event *models.Event) error {
+// CreateEvent creates a new event, audits it and schedules its reminders
+func (s *eventService) CreateEvent(ctx context.Context, actor models.Actor, event *models.Event) (err error) {
+	ctx, span := tracing.Start(ctx, "EventService.CreateEvent")
+	defer func() { tracing.End(span, err) }()
+
+	if err := normalizeInventory(event); err != nil {
+		return err
+	}
+	if err := normalizeTicketRules(&event.TicketRules); err != nil {
+		return err
+	}
+	if event.Inventory == models.InventorySharded && !s.inventoryRepo.Supported() {
+		return fmt.Errorf("%w: sharded inventory needs Postgres", models.ErrInvalidInput)
+	}
+
 	// Set available seats equal to capacity on creation
 	event.AvailableSeats = event.Capacity
-	return s.eventRepo.Create(event)
+	err = s.transactor.Transaction(ctx, func(tx repository.Tx) error {
+		if err := s.eventRepo.CreateWithTx(ctx, tx, event); err != nil {
+			return err
+		}
+		if event.Inventory == models.InventorySharded {
+			if err := s.inventoryRepo.CreateShardsWithTx(ctx, tx, event.ID, models.SplitSeats(event.Capacity, event.InventoryShards)); err != nil {
+				return err
+			}
+		}
+		if err := s.auditWithTx(ctx, tx, actor, models.ActionCreateEvent, event.ID, nil, event); err != nil {
+			return err
+		}
+		return s.reminderService.ScheduleForEventWithTx(ctx, tx, event)
+	})
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(tracing.EventID(event.ID))
+	return nil
+}
+
+// normalizeInventory applies inventory defaults and validates the shard count
+func normalizeInventory(event *models.Event) error {
+	switch event.Inventory {
+	case "", models.InventoryRow:
+		event.Inventory = models.InventoryRow
+		event.InventoryShards = 0
+	case models.InventorySharded:
+		if event.InventoryShards == 0 {
+			event.InventoryShards = models.DefaultInventoryShards
+		}
+		if event.InventoryShards < 1 || event.InventoryShards > models.MaxInventoryShards {
+			return fmt.Errorf("%w: inventory_shards must be between 1 and %d", models.ErrInvalidInput, models.MaxInventoryShards)
+		}
+	default:
+		return fmt.Errorf("%w: unknown inventory %q", models.ErrInvalidInput, event.Inventory)
+	}
+	return nil
+}
+
+// normalizeTicketRules applies ticket rule defaults and validates them
+func normalizeTicketRules(rules *models.TicketRules) error {
+	if rules.MaxPerEmailDomain < 0 || rules.MaxPerPaymentInstrument < 0 || rules.MaxPerHousehold < 0 ||
+		rules.VelocityMax < 0 || rules.VelocityWindowSeconds < 0 {
+		return fmt.Errorf("%w: ticket rules cannot be negative", models.ErrInvalidInput)
+	}
+	if (rules.VelocityMax > 0) != (rules.VelocityWindowSeconds > 0) {
+		return fmt.Errorf("%w: velocity_max and velocity_window_seconds go together", models.ErrInvalidInput)
+	}
+	switch rules.OverLimit {
+	case "":
+		rules.OverLimit = models.OverLimitReject
+	case models.OverLimitReject, models.OverLimitFlag:
+	default:
+		return fmt.Errorf("%w: over_limit must be %q or %q", models.ErrInvalidInput, models.OverLimitReject, models.OverLimitFlag)
+	}
+	return nil
 }
 
 // GetEventByID gets an event by ID
-func (s *eventService) GetEventByID(id uint) (*models.Event, error) {
-	return s.eventRepo.FindByID(id)
+func (s *eventService) GetEventByID(ctx context.Context, id uint) (_ *models.Event, err error) {
+	_, span := tracing.Start(ctx, "EventService.GetEventByID", tracing.EventID(id))
+	defer func() { tracing.End(span, err) }()
+
+	event, err := s.eventRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	events := []models.Event{*event}
+	if err := s.fillShardedSeats(ctx, events); err != nil {
+		return nil, err
+	}
+	return &events[0], nil
+}
+
+// GetAvailability gets the current seat availability of an event
+func (s *eventService) GetAvailability(ctx context.Context, id uint) (_ *models.AvailabilityUpdate, err error) {
+	ctx, span := tracing.Start(ctx, "EventService.GetAvailability", tracing.EventID(id))
+	defer func() { tracing.End(span, err) }()
+
+	event, err := s.GetEventByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AvailabilityUpdate{
+		EventID:        event.ID,
+		Capacity:       event.Capacity,
+		AvailableSeats: event.AvailableSeats,
+		Version:        event.SeatsVersion,
+	}, nil
 }
 
 // GetAllEvents gets all events
-func (s *eventService) GetAllEvents() ([]models.Event, error) {
-	return s.eventRepo.FindAll()
+func (s *eventService) GetAllEvents(ctx context.Context) (_ []models.Event, err error) {
+	_, span := tracing.Start(ctx, "EventService.GetAllEvents")
+	defer func() { tracing.End(span, err) }()
+
+	events, err := s.eventRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return events, s.fillShardedSeats(ctx, events)
 }
 
 // GetEventsByOrganizerID gets events by organizer ID
-func (s *eventService) GetEventsByOrganizerID(organizerID uint) ([]models.Event, error) {
-	return s.eventRepo.FindByOrganizerID(organizerID)
+func (s *eventService) GetEventsByOrganizerID(ctx context.Context, organizerID uint) (_ []models.Event, err error) {
+	_, span := tracing.Start(ctx, "EventService.GetEventsByOrganizerID", tracing.UserID(organizerID))
+	defer func() { tracing.End(span, err) }()
+
+	events, err := s.eventRepo.FindByOrganizerID(ctx, organizerID)
+	if err != nil {
+		return nil, err
+	}
+	return events, s.fillShardedSeats(ctx, events)
+}
+
+// fillShardedSeats replaces the unused available_seats column of sharded
+// events with the sum of their shards
+func (s *eventService) fillShardedSeats(ctx context.Context, events []models.Event) error {
+	var ids []uint
+	for _, event := range events {
+		if event.Inventory == models.InventorySharded {
+			ids = append(ids, event.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	totals, err := s.inventoryRepo.TotalsByEventIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range events {
+		if t, ok := totals[events[i].ID]; ok {
+			events[i].AvailableSeats = t.Available
+			events[i].SeatsVersion = t.Version
+		}
+	}
+	return nil
+}
+
+// UpdateEvent updates an event, audits it and records event.updated.
+// Available seats follow capacity changes; capacity cannot drop below the
+// number of registrations. Publishing and the inventory mode cannot be
+// changed here.
+func (s *eventService) UpdateEvent(ctx context.Context, actor models.Actor, event *models.Event) (err error) {
+	ctx, span := tracing.Start(ctx, "EventService.UpdateEvent", tracing.EventID(event.ID))
+	defer func() { tracing.End(span, err) }()
+
+	if err := normalizeTicketRules(&event.TicketRules); err != nil {
+		return err
+	}
+
+	var updated models.Event
+	err = s.transactor.Transaction(ctx, func(tx repository.Tx) error {
+		// Lock the row so registrations cannot change the seats meanwhile
+		existing, err := s.eventRepo.FindByIDForUpdate(ctx, tx, event.ID)
+		if err != nil {
+			return err
+		}
+		event.PublishedAt = existing.PublishedAt
+		event.Inventory = existing.Inventory
+		event.InventoryShards = existing.InventoryShards
+
+		if event.Inventory == models.InventorySharded {
+			if err := s.fillShardedSeatsWithTx(ctx, tx, existing); err != nil {
+				return err
+			}
+			if err := s.updateShardedWithTx(ctx, tx, existing, event, &updated); err != nil {
+				return err
+			}
+			if err := s.auditWithTx(ctx, tx, actor, models.ActionUpdateEvent, event.ID, existing, &updated); err != nil {
+				return err
+			}
+			// The start time may have moved, so rebuild the reminder schedule
+			return s.reminderService.ScheduleForEventWithTx(ctx, tx, &updated)
+		}
+
+		registered := existing.Capacity - existing.AvailableSeats
+		if event.Capacity < registered {
+			return models.ErrCapacityTooLow
+		}
+		event.AvailableSeats = event.Capacity - registered
+
+		if err := s.eventRepo.UpdateWithTx(ctx, tx, event); err != nil {
+			return err
+		}
+		// Capacity and available seats may have changed
+		if err := s.eventRepo.NotifyAvailabilityWithTx(ctx, tx, event.ID); err != nil {
+			return err
+		}
+		reloaded, err := s.eventRepo.FindByIDWithTx(ctx, tx, event.ID)
+		if err != nil {
+			return err
+		}
+		updated = *reloaded
+		if err := s.auditWithTx(ctx, tx, actor, models.ActionUpdateEvent, event.ID, existing, &updated); err != nil {
+			return err
+		}
+		if err := s.recordWithTx(ctx, tx, models.DomainEventUpdated, &updated); err != nil {
+			return err
+		}
+		return s.reminderService.ScheduleForEventWithTx(ctx, tx, &updated)
+	})
+	if err != nil {
+		return err
+	}
+	*event = updated
+	return nil
+}
+
+// updateShardedWithTx updates a sharded event, adding or removing the
+// capacity change across its shards, and loads the result into updated
+func (s *eventService) updateShardedWithTx(ctx context.Context, tx repository.Tx, existing, event, updated *models.Event) error {
+	if err := s.inventoryRepo.RedistributeWithTx(ctx, tx, event.ID, event.Capacity-existing.Capacity); err != nil {
+		return err
+	}
+	if err := s.eventRepo.UpdateWithTx(ctx, tx, event); err != nil {
+		return err
+	}
+	if err := s.inventoryRepo.NotifyAvailabilityWithTx(ctx, tx, event.ID); err != nil {
+		return err
+	}
+
+	reloaded, err := s.eventRepo.FindByIDWithTx(ctx, tx, event.ID)
+	if err != nil {
+		return err
+	}
+	*updated = *reloaded
+	if err := s.fillShardedSeatsWithTx(ctx, tx, updated); err != nil {
+		return err
+	}
+	return s.recordWithTx(ctx, tx, models.DomainEventUpdated, updated)
+}
+
+// fillShardedSeatsWithTx is fillShardedSeats for one event within tx
+func (s *eventService) fillShardedSeatsWithTx(ctx context.Context, tx repository.Tx, event *models.Event) error {
+	if event.Inventory != models.InventorySharded {
+		return nil
+	}
+	totals, err := s.inventoryRepo.TotalsWithTx(ctx, tx, event.ID)
+	if err != nil {
+		return err
+	}
+	event.AvailableSeats = totals.Available
+	event.SeatsVersion = totals.Version
+	return nil
+}
+
+// PublishEvent marks an event as published, audits it and records
+// event.published. Publishing an already published event is a no-op.
+func (s *eventService) PublishEvent(ctx context.Context, actor models.Actor, id uint) (_ *models.Event, err error) {
+	ctx, span := tracing.Start(ctx, "EventService.PublishEvent", tracing.EventID(id))
+	defer func() { tracing.End(span, err) }()
+
+	var event *models.Event
+	err = s.transactor.Transaction(ctx, func(tx repository.Tx) error {
+		var err error
+		event, err = s.eventRepo.FindByIDForUpdate(ctx, tx, id)
+		if err != nil || event.PublishedAt != nil {
+			return err
+		}
+
+		before := *event
+		now := time.Now()
+		event.PublishedAt = &now
+		if err := s.eventRepo.UpdateWithTx(ctx, tx, event); err != nil {
+			return err
+		}
+		if err := s.fillShardedSeatsWithTx(ctx, tx, event); err != nil {
+			return err
+		}
+		// Publishing leaves the seats alone
+		before.AvailableSeats = event.AvailableSeats
+		if err := s.auditWithTx(ctx, tx, actor, models.ActionPublishEvent, id, &before, event); err != nil {
+			return err
+		}
+		return s.recordWithTx(ctx, tx, models.DomainEventPublished, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// DeleteEvent deletes an event, audits it and records event.cancelled
+func (s *eventService) DeleteEvent(ctx context.Context, actor models.Actor, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "EventService.DeleteEvent", tracing.EventID(id))
+	defer func() { tracing.End(span, err) }()
+
+	err = s.transactor.Transaction(ctx, func(tx repository.Tx) error {
+		event, err := s.eventRepo.FindByIDForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.fillShardedSeatsWithTx(ctx, tx, event); err != nil {
+			return err
+		}
+		if err := s.eventRepo.DeleteWithTx(ctx, tx, id); err != nil {
+			return err
+		}
+		if err := s.inventoryRepo.DeleteByEventIDWithTx(ctx, tx, id); err != nil {
+			return err
+		}
+		if err := s.auditWithTx(ctx, tx, actor, models.ActionDeleteEvent, id, event, nil); err != nil {
+			return err
+		}
+		if err := s.recordWithTx(ctx, tx, models.DomainEventCancelled, event); err != nil {
+			return err
+		}
+		return s.reminderService.CancelForEventWithTx(ctx, tx, id)
+	})
+	return err
+}
+
+// RecountSeats sets an event's available seats to its capacity minus its
+// active registrations, correcting any drift of the counter. The event, or
+// every shard of a sharded event, stays locked while counting, so no
+// registration can take or return a seat in between.
+func (s *eventService) RecountSeats(ctx context.Context, id uint) (_ *models.SeatRecount, err error) {
+	ctx, span := tracing.Start(ctx, "EventService.RecountSeats", tracing.EventID(id))
+	defer func() { tracing.End(span, err) }()
+
+	var recount *models.SeatRecount
+	err = s.transactor.Transaction(ctx, func(tx repository.Tx) error {
+		event, err := s.eventRepo.FindByIDForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if event.Inventory == models.InventorySharded {
+			totals, err := s.inventoryRepo.TotalsForUpdateWithTx(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			event.AvailableSeats = totals.Available
+		}
+
+		registered, err := s.registrationRepo.CountByEventIDWithTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		recount = &models.SeatRecount{
+			EventID:    id,
+			Capacity:   event.Capacity,
+			Registered: int(registered),
+			Before:     event.AvailableSeats,
+			After:      max(event.Capacity-int(registered), 0),
+		}
+		if recount.After == recount.Before {
+			return nil
+		}
+
+		if event.Inventory == models.InventorySharded {
+			if err := s.inventoryRepo.RedistributeWithTx(ctx, tx, id, recount.After-recount.Before); err != nil {
+				return err
+			}
+			return s.inventoryRepo.NotifyAvailabilityWithTx(ctx, tx, id)
+		}
+		event.AvailableSeats = recount.After
+		if err := s.eventRepo.UpdateWithTx(ctx, tx, event); err != nil {
+			return err
+		}
+		return s.eventRepo.NotifyAvailabilityWithTx(ctx, tx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recount, nil
 }
 
-// UpdateEvent updates an event
-func (s *eventService) UpdateEvent(event *models.Event) error {
-	return s.eventRepo.Update(event)
+// recordWithTx writes an event lifecycle domain event to the outbox within tx
+func (s *eventService) recordWithTx(ctx context.Context, tx repository.Tx, eventType models.DomainEventType, event *models.Event) error {
+	outboxEvent, err := models.NewOutboxEvent(eventType, models.OutboxPayload{Event: event})
+	if err != nil {
+		return err
+	}
+	return s.outboxRepo.CreateWithTx(ctx, tx, outboxEvent)
 }
 
-// DeleteEvent deletes an event
-func (s *eventService) DeleteEvent(id uint) error {
-	return s.eventRepo.Delete(id)
+// auditWithTx writes the audit entry of a change to an event within tx
+func (s *eventService) auditWithTx(ctx context.Context, tx repository.Tx, actor models.Actor, action models.Action, id uint, before, after *models.Event) error {
+	entry, err := models.NewAuditEvent(actor, action, models.AuditTargetEvent, id, before, after)
+	if err != nil {
+		return err
+	}
+	entry.EventID = &id
+	return s.auditRepo.CreateWithTx(ctx, tx, entry)
 }
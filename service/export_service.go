@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"iter"
+
+	"event-api/models"
+	"event-api/repository"
+	"event-api/tracing"
+
+	"gorm.io/gorm"
+)
+
+// exportPageSize is how many attendees an export reads per query
+const exportPageSize = 1000
+
+// ExportService hands organizers their events' attendee lists
+type ExportService interface {
+	ExportAttendees(ctx context.Context, viewerID, eventID uint) (*models.Event, iter.Seq2[models.Attendee, error], error)
+}
+
+type exportService struct {
+	userRepo     repository.UserRepository
+	eventRepo    repository.EventRepository
+	attendeeRepo repository.AttendeeRepository
+}
+
+// NewExportService creates a new ExportService
+func NewExportService(userRepo repository.UserRepository, eventRepo repository.EventRepository, attendeeRepo repository.AttendeeRepository) ExportService {
+	return &exportService{
+		userRepo:     userRepo,
+		eventRepo:    eventRepo,
+		attendeeRepo: attendeeRepo,
+	}
+}
+
+// ExportAttendees returns an event and its active registrations in ID order,
+// read a page at a time as the sequence is ranged over, so no more than one
+// page is ever in memory. Only the event's organizer and admins may export
+// it; it fails with ErrUserNotFound for an unknown viewer, ErrUnauthorized
+// and ErrEventNotFound before anything is read. A failed page read ends the
+// sequence with its error.
+func (s *exportService) ExportAttendees(ctx context.Context, viewerID, eventID uint) (_ *models.Event, _ iter.Seq2[models.Attendee, error], err error) {
+	ctx, span := tracing.Start(ctx, "ExportService.ExportAttendees", tracing.UserID(viewerID), tracing.EventID(eventID))
+	defer func() { tracing.End(span, err) }()
+
+	event, err := s.eventRepo.FindByID(ctx, eventID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, models.ErrEventNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	viewer, err := s.userRepo.FindByID(ctx, viewerID)
+	if err := mayReview(viewer, err, event); err != nil {
+		return nil, nil, err
+	}
+
+	attendees := func(yield func(models.Attendee, error) bool) {
+		var afterID uint
+		for {
+			page, err := s.attendeeRepo.FindPageByEventID(ctx, eventID, afterID, exportPageSize)
+			if err != nil {
+				yield(models.Attendee{}, err)
+				return
+			}
+			for _, attendee := range page {
+				if !yield(attendee, nil) {
+					return
+				}
+			}
+			if len(page) < exportPageSize {
+				return
+			}
+			afterID = page[len(page)-1].RegistrationID
+		}
+	}
+	return event, attendees, nil
+}
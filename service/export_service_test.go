@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"event-api/internal/testdb"
+	"event-api/models"
+	"event-api/repository"
+
+	"gorm.io/gorm"
+)
+
+func TestExportAttendees(t *testing.T) {
+	for name, open := range map[string]func(t testing.TB) *gorm.DB{
+		"sqlite":   testdb.NewSQLite,
+		"postgres": testdb.New,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			db := open(t)
+			f := newGormFixture(t, db, SeatStrategyPessimistic)
+			exports := NewExportService(f.userRepo, f.eventRepo, repository.NewAttendeeRepository(db))
+			event := f.createEvent(t, 2*exportPageSize)
+
+			// More attendees than fit on one page, written directly for speed
+			n := exportPageSize + 2
+			users := make([]models.User, n)
+			for i := range users {
+				users[i] = models.User{Name: fmt.Sprintf("Attendee %d", i), Email: fmt.Sprintf("attendee%d@example.com", i)}
+			}
+			if err := db.CreateInBatches(users, 200).Error; err != nil {
+				t.Fatal(err)
+			}
+			registrations := make([]models.Registration, n)
+			for i := range registrations {
+				registrations[i] = models.Registration{UserID: users[i].ID, EventID: event.ID}
+			}
+			registrations[0].Answers = map[string]string{"Diet": "vegan"}
+			if err := db.CreateInBatches(registrations, 200).Error; err != nil {
+				t.Fatal(err)
+			}
+			cancelled, deleted := registrations[1], registrations[2]
+			if err := db.Delete(&cancelled).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Delete(&models.User{}, deleted.UserID).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			tooLong := map[string]string{"Diet": strings.Repeat("x", models.MaxAnswerLength+1)}
+			if _, err := f.registrations.RegisterForEvent(ctx, testActor, users[1].ID, event.ID, models.RegistrationDetails{Answers: tooLong}); !errors.Is(err, models.ErrInvalidInput) {
+				t.Fatalf("registering with a too long answer: error = %v, want %v", err, models.ErrInvalidInput)
+			}
+
+			organizer := event.OrganizerID
+			if _, _, err := exports.ExportAttendees(ctx, users[0].ID, event.ID); !errors.Is(err, models.ErrUnauthorized) {
+				t.Fatalf("export by an attendee: error = %v, want %v", err, models.ErrUnauthorized)
+			}
+			if _, _, err := exports.ExportAttendees(ctx, organizer, event.ID+1); !errors.Is(err, models.ErrEventNotFound) {
+				t.Fatalf("export of an unknown event: error = %v, want %v", err, models.ErrEventNotFound)
+			}
+			_, attendees, err := exports.ExportAttendees(ctx, organizer, event.ID)
+			if err != nil {
+				t.Fatalf("exporting: %v", err)
+			}
+
+			var got []models.Attendee
+			for attendee, err := range attendees {
+				if err != nil {
+					t.Fatalf("reading attendees: %v", err)
+				}
+				got = append(got, attendee)
+			}
+			if len(got) != n-1 {
+				t.Fatalf("exported %d attendees, want %d without the cancelled one", len(got), n-1)
+			}
+			for i := 1; i < len(got); i++ {
+				if got[i].RegistrationID <= got[i-1].RegistrationID {
+					t.Fatalf("attendees out of order at %d: %d after %d", i, got[i].RegistrationID, got[i-1].RegistrationID)
+				}
+			}
+			if got[0].Name != "Attendee 0" || got[0].Email != "attendee0@example.com" || got[0].RegisteredAt.IsZero() || got[0].Answers["Diet"] != "vegan" {
+				t.Errorf("first attendee = %+v", got[0])
+			}
+			if got[1].RegistrationID != deleted.ID || got[1].Name != "" || got[1].Email != "" {
+				t.Errorf("attendee of a deleted user = %+v, want no name or email", got[1])
+			}
+
+			// Breaking off early must stop the sequence; yielding again panics
+			count := 0
+			for range attendees {
+				if count++; count == 3 {
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"event-api/repository"
+
+	"gorm.io/gorm"
+)
+
+// inventoryRebalanceBatchSize is how many events are rebalanced per pass
+const inventoryRebalanceBatchSize = 50
+
+// InventoryService maintains the shards of events with sharded inventory
+type InventoryService interface {
+	Rebalance(ctx context.Context) (int, error)
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type inventoryService struct {
+	db            *gorm.DB
+	inventoryRepo repository.InventoryRepository
+}
+
+// NewInventoryService creates a new InventoryService
+func NewInventoryService(db *gorm.DB, inventoryRepo repository.InventoryRepository) InventoryService {
+	return &inventoryService{db: db, inventoryRepo: inventoryRepo}
+}
+
+/*
+Rebalance spreads the remaining seats of events with empty shards evenly
+across all their shards again, and returns how many events it rebalanced.
+
+Registrations pick a random non-empty shard, so as shards run dry the
+remaining seats concentrate in fewer rows and contention creeps back.
+Rebalancing locks all shards of one event for a moment; the total never
+changes, so it cannot oversell.
+*/
+func (s *inventoryService) Rebalance(ctx context.Context) (int, error) {
+	ids, err := s.inventoryRepo.FindUnbalancedEventIDs(ctx, inventoryRebalanceBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	rebalanced := 0
+	for _, id := range ids {
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return s.inventoryRepo.RedistributeWithTx(ctx, tx, id, 0)
+		})
+		if err != nil {
+			return rebalanced, err
+		}
+		rebalanced++
+	}
+	return rebalanced, nil
+}
+
+// Run rebalances every interval until ctx is cancelled
+func (s *inventoryService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := s.Rebalance(ctx); err != nil {
+			slog.ErrorContext(ctx, "inventory rebalancer failed", "err", err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"event-api/models"
+	"event-api/notification"
+	"event-api/repository"
+
+	"gorm.io/gorm"
+)
+
+// NotificationService renders notifications and places them in the outbox
+type NotificationService interface {
+	EnqueueWithTx(ctx context.Context, tx *gorm.DB, notificationType models.NotificationType, user *models.User, event *models.Event) error
+	EnqueueAccountWithTx(ctx context.Context, tx *gorm.DB, notificationType models.NotificationType, user *models.User, token string, expiresAt time.Time) error
+	HandleOutboxEvent(ctx context.Context, tx *gorm.DB, event models.OutboxEvent) error
+
+	GetTemplates(ctx context.Context, organizerID uint) ([]models.NotificationTemplate, error)
+	SaveTemplate(ctx context.Context, template *models.NotificationTemplate) error
+	DeleteTemplate(ctx context.Context, organizerID uint, notificationType models.NotificationType) error
+}
+
+type notificationService struct {
+	notificationRepo repository.NotificationRepository
+	renderer         *notification.Renderer
+}
+
+// NewNotificationService creates a new NotificationService
+func NewNotificationService(notificationRepo repository.NotificationRepository) NotificationService {
+	return &notificationService{
+		notificationRepo: notificationRepo,
+		renderer:         notification.NewRenderer(notificationRepo),
+	}
+}
+
+// EnqueueWithTx renders a notification for one user and writes it to the outbox
+// inside tx, so it is only delivered if the surrounding change commits
+func (s *notificationService) EnqueueWithTx(ctx context.Context, tx *gorm.DB, notificationType models.NotificationType, user *models.User, event *models.Event) error {
+	msg, err := s.renderer.Render(ctx, event.OrganizerID, notificationType, notification.TemplateData{
+		User:  user,
+		Event: event,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.createWithTx(ctx, tx, notificationType, user, msg)
+}
+
+// EnqueueAccountWithTx renders an account notification carrying a token for
+// one user and writes it to the outbox inside tx
+func (s *notificationService) EnqueueAccountWithTx(ctx context.Context, tx *gorm.DB, notificationType models.NotificationType, user *models.User, token string, expiresAt time.Time) error {
+	if !notificationType.Account() {
+		return fmt.Errorf("%q is not an account notification", notificationType)
+	}
+	// Organizers do not own accounts, so there is no override to look up
+	msg, err := s.renderer.Render(ctx, 0, notificationType, notification.TemplateData{
+		User:      user,
+		Token:     token,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	return s.createWithTx(ctx, tx, notificationType, user, msg)
+}
+
+// createWithTx writes a rendered message for user to the outbox inside tx
+func (s *notificationService) createWithTx(ctx context.Context, tx *gorm.DB, notificationType models.NotificationType, user *models.User, msg notification.Message) error {
+	return s.notificationRepo.CreateWithTx(ctx, tx, &models.Notification{
+		Type:      notificationType,
+		UserID:    user.ID,
+		Recipient: msg.To,
+		Subject:   msg.Subject,
+		TextBody:  msg.Text,
+		HTMLBody:  msg.HTML,
+	})
+}
+
+// HandleOutboxEvent turns a domain event into emails for the affected users.
+// It is registered as an outbox relay subscriber.
+func (s *notificationService) HandleOutboxEvent(ctx context.Context, tx *gorm.DB, event models.OutboxEvent) error {
+	payload, err := event.DecodePayload()
+	if err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case models.DomainRegistrationCreated:
+		return s.EnqueueWithTx(ctx, tx, models.NotificationRegistrationConfirmed, payload.Registration.User, payload.Event)
+	case models.DomainRegistrationCancelled:
+		return s.EnqueueWithTx(ctx, tx, models.NotificationRegistrationCancelled, payload.Registration.User, payload.Event)
+	case models.DomainEventUpdated:
+		return s.notifyAttendeesWithTx(ctx, tx, models.NotificationEventUpdated, payload.Event)
+	case models.DomainEventCancelled:
+		return s.notifyAttendeesWithTx(ctx, tx, models.NotificationEventCancelled, payload.Event)
+	}
+	return nil
+}
+
+// notifyAttendeesWithTx enqueues a notification for everyone registered for an event
+func (s *notificationService) notifyAttendeesWithTx(ctx context.Context, tx *gorm.DB, notificationType models.NotificationType, event *models.Event) error {
+	users, err := s.notificationRepo.FindRecipientsWithTx(ctx, tx, event.ID)
+	if err != nil {
+		return err
+	}
+
+	for i := range users {
+		if err := s.EnqueueWithTx(ctx, tx, notificationType, &users[i], event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTemplates gets all template overrides for an organizer
+func (s *notificationService) GetTemplates(ctx context.Context, organizerID uint) ([]models.NotificationTemplate, error) {
+	return s.notificationRepo.FindTemplatesByOrganizerID(ctx, organizerID)
+}
+
+// SaveTemplate validates and stores a template override
+func (s *notificationService) SaveTemplate(ctx context.Context, template *models.NotificationTemplate) error {
+	if !template.Type.Valid() || template.Type.Account() {
+		return models.ErrInvalidInput
+	}
+	if err := notification.Validate(template); err != nil {
+		return fmt.Errorf("%w: %v", models.ErrInvalidInput, err)
+	}
+	return s.notificationRepo.SaveTemplate(ctx, template)
+}
+
+// DeleteTemplate removes a template override
+func (s *notificationService) DeleteTemplate(ctx context.Context, organizerID uint, notificationType models.NotificationType) error {
+	return s.notificationRepo.DeleteTemplate(ctx, organizerID, notificationType)
+}
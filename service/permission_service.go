@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"event-api/models"
+	"event-api/repository"
+	"event-api/tracing"
+
+	"gorm.io/gorm"
+)
+
+// PermissionService decides what a user may do. Organizers create events
+// and manage their own events, templates and webhooks; anyone may register
+// for an event while seats are left and cancel their own registration.
+// Admins and organizers may read the audit log.
+type PermissionService interface {
+	CheckPermissions(ctx context.Context, userID, eventID uint) ([]models.Permission, error)
+}
+
+type permissionService struct {
+	userRepo         repository.UserRepository
+	registrationRepo repository.RegistrationRepository
+	eventService     EventService
+}
+
+// NewPermissionService creates a new PermissionService
+func NewPermissionService(
+	userRepo repository.UserRepository,
+	registrationRepo repository.RegistrationRepository,
+	eventService EventService,
+) PermissionService {
+	return &permissionService{
+		userRepo:         userRepo,
+		registrationRepo: registrationRepo,
+		eventService:     eventService,
+	}
+}
+
+// CheckPermissions evaluates every action for a user as if they made the
+// request: the account-wide ones, and those on eventID unless it is 0
+func (s *permissionService) CheckPermissions(ctx context.Context, userID, eventID uint) (_ []models.Permission, err error) {
+	ctx, span := tracing.Start(ctx, "PermissionService.CheckPermissions", tracing.UserID(userID), tracing.EventID(eventID))
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	isOrganizer := user.Role == models.RoleOrganizer
+	permissions := []models.Permission{
+		organizerOnly(models.ActionCreateEvent, isOrganizer),
+		organizerOnly(models.ActionManageTemplates, isOrganizer),
+		organizerOnly(models.ActionManageWebhooks, isOrganizer),
+		viewAudit(user.Role),
+	}
+	if eventID == 0 {
+		return permissions, nil
+	}
+
+	// Through the service, so sharded events count their seats too
+	event, err := s.eventService.GetEventByID(ctx, eventID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.ErrEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	owner := models.Permission{Allowed: true, Reason: "organizer of the event"}
+	if event.OrganizerID != user.ID {
+		owner = models.Permission{Reason: fmt.Sprintf("event is organized by user %d", event.OrganizerID)}
+	}
+	for _, action := range []models.Action{
+		models.ActionUpdateEvent,
+		models.ActionPublishEvent,
+		models.ActionDeleteEvent,
+		models.ActionViewRegistrations,
+		models.ActionCheckIn,
+		models.ActionReviewRegistration,
+	} {
+		owner.Action = action
+		permissions = append(permissions, owner)
+	}
+
+	registration, err := s.registrationRepo.FindByUserAndEventID(ctx, userID, eventID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	register := models.Permission{Action: models.ActionRegister}
+	cancel := models.Permission{Action: models.ActionCancelRegistration}
+	switch {
+	case registration != nil:
+		register.Reason = models.ErrAlreadyRegistered.Error()
+		cancel.Allowed, cancel.Reason = true, fmt.Sprintf("holds registration %d", registration.ID)
+	case !user.Active():
+		register.Reason = models.ErrUserDeactivated.Error()
+		cancel.Reason = "not registered"
+	case event.RequireVerifiedEmail && !user.Verified():
+		register.Reason = models.ErrEmailNotVerified.Error()
+		cancel.Reason = "not registered"
+	case event.AvailableSeats <= 0:
+		register.Reason = models.ErrEventFull.Error()
+		cancel.Reason = "not registered"
+	case event.WaitingRoom:
+		register.Allowed, register.Reason = true, "after admission from the waiting room"
+		cancel.Reason = "not registered"
+	default:
+		register.Allowed, register.Reason = true, "seats available"
+		cancel.Reason = "not registered"
+	}
+	return append(permissions, register, cancel), nil
+}
+
+// organizerOnly allows an account-wide action to organizers
+func organizerOnly(action models.Action, isOrganizer bool) models.Permission {
+	if isOrganizer {
+		return models.Permission{Action: action, Allowed: true, Reason: "organizer"}
+	}
+	return models.Permission{Action: action, Reason: "requires the organizer role"}
+}
+
+// viewAudit allows reading the audit log: all of it to admins, and that of
+// their own events to organizers
+func viewAudit(role models.UserRole) models.Permission {
+	switch role {
+	case models.RoleAdmin:
+		return models.Permission{Action: models.ActionViewAudit, Allowed: true, Reason: "admin"}
+	case models.RoleOrganizer:
+		return models.Permission{Action: models.ActionViewAudit, Allowed: true, Reason: "entries of own events"}
+	default:
+		return models.Permission{Action: models.ActionViewAudit, Reason: "requires the organizer or admin role"}
+	}
+}
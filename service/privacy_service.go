@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"event-api/models"
+	"event-api/repository"
+	"event-api/tracing"
+
+	"gorm.io/gorm"
+)
+
+// PrivacyService hands users the data stored about them, erases it on
+// request, and purges soft-deleted rows once they are past retention.
+// Erasure anonymizes the users row rather than deleting it, so registrations,
+// seat counts and attendee totals stay correct. The audit log keeps its
+// entries, with the user's snapshots redacted; the entry of an erasure holds
+// no personal data.
+type PrivacyService interface {
+	ExportUser(ctx context.Context, requesterID, userID uint) (*models.UserExport, error)
+	EraseUser(ctx context.Context, actor models.Actor, requesterID, userID uint) (*models.User, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (models.PurgeReport, error)
+	Run(ctx context.Context, interval, retention time.Duration)
+}
+
+type privacyService struct {
+	db          *gorm.DB
+	userRepo    repository.UserRepository
+	privacyRepo repository.PrivacyRepository
+	auditRepo   repository.AuditRepository
+}
+
+// NewPrivacyService creates a new PrivacyService
+func NewPrivacyService(
+	db *gorm.DB,
+	userRepo repository.UserRepository,
+	privacyRepo repository.PrivacyRepository,
+	auditRepo repository.AuditRepository,
+) PrivacyService {
+	return &privacyService{
+		db:          db,
+		userRepo:    userRepo,
+		privacyRepo: privacyRepo,
+		auditRepo:   auditRepo,
+	}
+}
+
+// ExportUser collects userID's profile, registrations and the audit entries
+// about them. Users may export their own data and admins anyone's. It fails
+// with ErrUserNotFound for an unknown requester, ErrUnauthorized, and
+// gorm.ErrRecordNotFound for an unknown user.
+func (s *privacyService) ExportUser(ctx context.Context, requesterID, userID uint) (_ *models.UserExport, err error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.ExportUser", tracing.UserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	user, err := authorizeAccount(ctx, s.userRepo, requesterID, userID)
+	if err != nil {
+		return nil, err
+	}
+	registrations, err := s.privacyRepo.FindRegistrationsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	auditEvents, err := s.privacyRepo.FindAuditEventsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &models.UserExport{
+		ExportedAt:    time.Now().UTC(),
+		User:          user,
+		Registrations: make([]models.RegistrationRecord, 0, len(registrations)),
+		AuditEvents:   auditEvents,
+	}
+	for _, registration := range registrations {
+		export.Registrations = append(export.Registrations, models.NewRegistrationRecord(registration))
+	}
+	return export, nil
+}
+
+// EraseUser anonymizes userID's personal data and deactivates the account.
+// Users may erase themselves and admins anyone; erasing an erased user
+// changes nothing. It fails like ExportUser.
+func (s *privacyService) EraseUser(ctx context.Context, actor models.Actor, requesterID, userID uint) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.EraseUser", tracing.UserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := authorizeAccount(ctx, s.userRepo, requesterID, userID); err != nil {
+		return nil, err
+	}
+
+	var user *models.User
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		user, err = s.eraseWithTx(ctx, tx, actor, userID, time.Now())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// PurgeDeleted erases users deleted before a time that still hold personal
+// data, then permanently removes the rows soft-deleted before it
+func (s *privacyService) PurgeDeleted(ctx context.Context, before time.Time) (_ models.PurgeReport, err error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.PurgeDeleted")
+	defer func() { tracing.End(span, err) }()
+
+	users, err := s.privacyRepo.FindUnerasedDeletedUsers(ctx, before)
+	if err != nil {
+		return models.PurgeReport{}, err
+	}
+	// Each user in its own transaction, so one failure does not hold up the rest
+	erased := 0
+	for _, user := range users {
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			_, err := s.eraseWithTx(ctx, tx, models.Actor{}, user.ID, time.Now())
+			return err
+		})
+		if err != nil {
+			return models.PurgeReport{UsersErased: erased}, err
+		}
+		erased++
+	}
+
+	report, err := s.privacyRepo.PurgeDeleted(ctx, before)
+	report.UsersErased = erased
+	return report, err
+}
+
+// Run purges rows deleted more than retention ago every interval until ctx
+// is cancelled
+func (s *privacyService) Run(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := s.PurgeDeleted(ctx, time.Now().Add(-retention))
+		if err != nil {
+			slog.ErrorContext(ctx, "retention purge failed", "err", err)
+		} else if report != (models.PurgeReport{}) {
+			slog.InfoContext(ctx, "retention purge",
+				"users_erased", report.UsersErased,
+				"users_deleted", report.UsersDeleted,
+				"events_deleted", report.EventsDeleted,
+				"registrations_deleted", report.RegistrationsDeleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// eraseWithTx anonymizes a user, deleted or not, and audits it within tx.
+// The audit entry has no before state, so it does not keep what was erased,
+// nor the client IP of a user erasing themselves.
+func (s *privacyService) eraseWithTx(ctx context.Context, tx *gorm.DB, actor models.Actor, userID uint, at time.Time) (*models.User, error) {
+	existing, err := s.privacyRepo.FindUserByIDWithTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.ErasedAt != nil {
+		return existing, nil
+	}
+	if err := s.privacyRepo.EraseUserWithTx(ctx, tx, existing, at); err != nil {
+		return nil, err
+	}
+	erased, err := s.privacyRepo.FindUserByIDWithTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if actor.UserID != nil && *actor.UserID == userID {
+		actor.IP = ""
+	}
+	entry, err := models.NewAuditEvent(actor, models.ActionEraseUser, models.AuditTargetUser, userID, nil, erased)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.auditRepo.CreateWithTx(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+	return erased, nil
+}
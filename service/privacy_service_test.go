@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"event-api/internal/testdb"
+	"event-api/models"
+	"event-api/repository"
+
+	"gorm.io/gorm"
+)
+
+func TestEraseAndPurgeUsers(t *testing.T) {
+	for name, open := range map[string]func(t testing.TB) *gorm.DB{
+		"sqlite":   testdb.NewSQLite,
+		"postgres": testdb.New,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			db := open(t)
+			f := newGormFixture(t, db, SeatStrategyPessimistic)
+			privacy := NewPrivacyService(db, f.userRepo, repository.NewPrivacyRepository(db), f.auditRepo)
+
+			user := &models.User{Name: "Ada", Email: "ada@example.com"}
+			if err := f.users.CreateUser(ctx, testActor, user); err != nil {
+				t.Fatalf("creating user: %v", err)
+			}
+			other := f.createUsers(t, 1)[0]
+			event := f.createEvent(t, 5)
+			details := models.RegistrationDetails{PaymentFingerprint: "fp_ada", Address: "1 Analytical Row"}
+			if _, err := f.registrations.RegisterForEvent(ctx, testActor, user.ID, event.ID, details); err != nil {
+				t.Fatalf("registering: %v", err)
+			}
+
+			// Export: everything about the user, screening data included
+			if _, err := privacy.ExportUser(ctx, other, user.ID); !errors.Is(err, models.ErrUnauthorized) {
+				t.Fatalf("export by another user: error = %v, want %v", err, models.ErrUnauthorized)
+			}
+			export, err := privacy.ExportUser(ctx, user.ID, user.ID)
+			if err != nil {
+				t.Fatalf("exporting: %v", err)
+			}
+			if export.User.Email != user.Email || len(export.Registrations) != 1 ||
+				export.Registrations[0].PaymentFingerprint != "fp_ada" || export.Registrations[0].EventTitle != event.Title {
+				t.Fatalf("export = %+v", export)
+			}
+			if len(export.AuditEvents) != 2 {
+				t.Fatalf("exported %d audit events, want the creation and the registration", len(export.AuditEvents))
+			}
+
+			// Erasure anonymizes the user but keeps the seat taken
+			if _, err := privacy.EraseUser(ctx, testActor, other, user.ID); !errors.Is(err, models.ErrUnauthorized) {
+				t.Fatalf("erasure by another user: error = %v, want %v", err, models.ErrUnauthorized)
+			}
+			erased, err := privacy.EraseUser(ctx, testActor, user.ID, user.ID)
+			if err != nil {
+				t.Fatalf("erasing: %v", err)
+			}
+			if erased.Name != models.ErasedName || erased.Email != models.ErasedEmail(user.ID) || erased.ErasedAt == nil || erased.Active() {
+				t.Fatalf("erased user = %+v", erased)
+			}
+			f.assertSeats(t, event.ID, 4, 1)
+			again, err := privacy.EraseUser(ctx, testActor, user.ID, user.ID)
+			if err != nil || !again.ErasedAt.Equal(*erased.ErasedAt) {
+				t.Fatalf("erasing twice: %+v, %v", again, err)
+			}
+
+			for table, column := range map[string]string{"users": "email", "outbox_events": "payload", "notifications": "recipient"} {
+				var n int64
+				if err := db.Table(table).Where(column+" LIKE ?", "%ada@example.com%").Count(&n).Error; err != nil || n != 0 {
+					t.Errorf("%d %s rows still hold the erased address (%v)", n, table, err)
+				}
+			}
+			var screened int64
+			if err := db.Model(&models.Registration{}).Where("payment_fingerprint <> '' OR household_key <> ''").Count(&screened).Error; err != nil || screened != 0 {
+				t.Errorf("%d registrations still hold screening data (%v)", screened, err)
+			}
+			changed := *erased
+			changed.Email = "ada@example.org"
+			if err := f.users.UpdateUser(ctx, testActor, &changed); !errors.Is(err, models.ErrUserErased) {
+				t.Errorf("updating an erased user: error = %v, want %v", err, models.ErrUserErased)
+			}
+			var erasures int64
+			if err := db.Model(&models.AuditEvent{}).Where("action = ? AND before_state IS NULL", models.ActionEraseUser).Count(&erasures).Error; err != nil || erasures != 1 {
+				t.Errorf("%d erasure audit entries without a before state, want 1 (%v)", erasures, err)
+			}
+
+			// Retention: deleted users are erased, then deleted rows purged
+			// once nothing refers to them
+			if _, err := f.registrations.RegisterForEvent(ctx, testActor, other, event.ID, models.RegistrationDetails{}); err != nil {
+				t.Fatalf("registering: %v", err)
+			}
+			if err := f.registrations.CancelRegistration(ctx, testActor, other, event.ID); err != nil {
+				t.Fatalf("cancelling: %v", err)
+			}
+			if err := f.users.DeleteUser(ctx, testActor, other); err != nil {
+				t.Fatalf("deleting user: %v", err)
+			}
+			cancelled := f.createEvent(t, 5)
+			if err := f.events.DeleteEvent(ctx, testActor, cancelled.ID); err != nil {
+				t.Fatalf("deleting event: %v", err)
+			}
+
+			report, err := privacy.PurgeDeleted(ctx, time.Now().Add(-time.Hour))
+			if err != nil || report != (models.PurgeReport{}) {
+				t.Fatalf("purge before retention = %+v, %v; want nothing purged", report, err)
+			}
+			report, err = privacy.PurgeDeleted(ctx, time.Now().Add(time.Hour))
+			if err != nil {
+				t.Fatalf("purging: %v", err)
+			}
+			want := models.PurgeReport{UsersErased: 1, UsersDeleted: 1, EventsDeleted: 1, RegistrationsDeleted: 1}
+			if report != want {
+				t.Errorf("purge report = %+v, want %+v", report, want)
+			}
+			var remaining int64
+			if err := db.Unscoped().Model(&models.User{}).Where("id = ?", other).Count(&remaining).Error; err != nil || remaining != 0 {
+				t.Errorf("purged user still stored (%v)", err)
+			}
+			f.assertSeats(t, event.ID, 4, 1)
+		})
+	}
+}
+
+func TestEraseRedactsEveryIdentityOfARenamedUser(t *testing.T) {
+	for name, open := range map[string]func(t testing.TB) *gorm.DB{
+		"sqlite":   testdb.NewSQLite,
+		"postgres": testdb.New,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			db := open(t)
+			f := newGormFixture(t, db, SeatStrategyPessimistic)
+			privacy := NewPrivacyService(db, f.userRepo, repository.NewPrivacyRepository(db), f.auditRepo)
+			webhooks := NewWebhookService(repository.NewWebhookRepository(db))
+
+			// Snapshots are taken under the old identity, then the new one
+			actor := models.Actor{RequestID: "test", IP: "203.0.113.7"}
+			user := &models.User{Name: "Ada Lovelace", Email: "ada@example.com"}
+			if err := f.users.CreateUser(ctx, actor, user); err != nil {
+				t.Fatalf("creating user: %v", err)
+			}
+			actor.UserID = &user.ID
+			first, second := f.createEvent(t, 5), f.createEvent(t, 5)
+			subscription := &models.WebhookSubscription{OrganizerID: first.OrganizerID, URL: "https://hooks.example.com/events",
+				EventTypes: []models.WebhookEventType{models.WebhookRegistrationCreated}}
+			if err := webhooks.CreateSubscription(ctx, subscription); err != nil {
+				t.Fatalf("subscribing: %v", err)
+			}
+			answers := models.RegistrationDetails{Answers: map[string]string{"Diet": "no shellfish"}}
+			if _, err := f.registrations.RegisterForEvent(ctx, actor, user.ID, first.ID, answers); err != nil {
+				t.Fatalf("registering: %v", err)
+			}
+			renamed := *user
+			renamed.Name, renamed.Email = "Ada King", "ada.king@example.org"
+			if err := f.users.UpdateUser(ctx, actor, &renamed); err != nil {
+				t.Fatalf("renaming user: %v", err)
+			}
+			if _, err := f.registrations.RegisterForEvent(ctx, actor, user.ID, second.ID, models.RegistrationDetails{}); err != nil {
+				t.Fatalf("registering: %v", err)
+			}
+			var outbox []models.OutboxEvent
+			if err := db.Order("id").Find(&outbox).Error; err != nil {
+				t.Fatal(err)
+			}
+			for _, event := range outbox {
+				if err := db.Transaction(func(tx *gorm.DB) error { return webhooks.HandleOutboxEvent(ctx, tx, event) }); err != nil {
+					t.Fatalf("queueing webhooks: %v", err)
+				}
+			}
+
+			stored := func() int64 {
+				t.Helper()
+				var n int64
+				for _, column := range []struct{ table, column string }{
+					{"outbox_events", "payload"},
+					{"webhook_deliveries", "payload"},
+					{"audit_events", "before_state"},
+					{"audit_events", "after_state"},
+				} {
+					for _, identity := range []string{"Ada Lovelace", "ada@example.com", "Ada King", "ada.king@example.org", "shellfish"} {
+						var found int64
+						if err := db.Table(column.table).Where("CAST("+column.column+" AS TEXT) LIKE ?", "%"+identity+"%").Count(&found).Error; err != nil {
+							t.Fatal(err)
+						}
+						n += found
+					}
+				}
+				return n
+			}
+			if stored() == 0 {
+				t.Fatal("no snapshots of the user before erasing")
+			}
+
+			if _, err := privacy.EraseUser(ctx, actor, user.ID, user.ID); err != nil {
+				t.Fatalf("erasing: %v", err)
+			}
+			if n := stored(); n != 0 {
+				t.Errorf("%d snapshots still hold the old or new identity or the answers", n)
+			}
+			var answered int64
+			if err := db.Model(&models.Registration{}).Where("answers IS NOT NULL").Count(&answered).Error; err != nil || answered != 0 {
+				t.Errorf("%d registrations keep their answers (%v)", answered, err)
+			}
+			var ips int64
+			if err := db.Model(&models.AuditEvent{}).Where("ip <> ''").Count(&ips).Error; err != nil || ips != 1 {
+				t.Errorf("%d audit entries keep a client IP, want only the creation made before the user signed in (%v)", ips, err)
+			}
+			export, err := privacy.ExportUser(ctx, user.ID, user.ID)
+			if err != nil {
+				t.Fatalf("exporting: %v", err)
+			}
+			body, err := json.Marshal(export)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, identity := range []string{"Ada", "example.com", "example.org", "shellfish"} {
+				if strings.Contains(string(body), identity) {
+					t.Errorf("export of the erased user holds %q: %s", identity, body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"event-api/models"
+	"event-api/repository"
+
+	"gorm.io/gorm"
+)
+
+// ReconciliationService detects and corrects drift of the denormalized
+// available seats counters
+type ReconciliationService interface {
+	Reconcile(ctx context.Context, correct bool) (*models.ReconciliationReport, error)
+	LastReport() *models.ReconciliationReport
+	Stats() models.ReconciliationStats
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type reconciliationService struct {
+	eventRepo    repository.EventRepository
+	eventService EventService
+	autoCorrect  bool
+
+	mu    sync.Mutex
+	last  *models.ReconciliationReport
+	stats models.ReconciliationStats
+}
+
+// NewReconciliationService creates a new ReconciliationService. With
+// autoCorrect, Run also corrects the drift it finds.
+func NewReconciliationService(eventRepo repository.EventRepository, eventService EventService, autoCorrect bool) ReconciliationService {
+	return &reconciliationService{
+		eventRepo:    eventRepo,
+		eventService: eventService,
+		autoCorrect:  autoCorrect,
+	}
+}
+
+/*
+Reconcile compares every event's available seats with its capacity minus its
+active registrations and reports the events where they differ.
+
+Counters and registrations are read in one statement, so a registration
+committing meanwhile cannot show up as drift. With correct, each drifted
+event is recounted through EventService.RecountSeats, which locks the event
+and counts again before writing, so a correction never races a registration.
+*/
+func (s *reconciliationService) Reconcile(ctx context.Context, correct bool) (*models.ReconciliationReport, error) {
+	report := &models.ReconciliationReport{StartedAt: time.Now(), Drifts: []models.SeatDrift{}}
+	err := s.reconcile(ctx, report, correct)
+	report.FinishedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Runs++
+	s.stats.LastRunAt = &report.FinishedAt
+	if err != nil {
+		s.stats.Failures++
+		return nil, err
+	}
+	s.stats.DriftsFound += int64(len(report.Drifts))
+	for _, drift := range report.Drifts {
+		if drift.Corrected {
+			s.stats.DriftsCorrected++
+		}
+	}
+	s.stats.LastDriftedEvents = len(report.Drifts)
+	s.last = report
+	return report, nil
+}
+
+// reconcile fills in report
+func (s *reconciliationService) reconcile(ctx context.Context, report *models.ReconciliationReport, correct bool) error {
+	counts, err := s.eventRepo.FindSeatCounts(ctx)
+	if err != nil {
+		return err
+	}
+	report.EventsChecked = len(counts)
+
+	for _, count := range counts {
+		drift := models.SeatDrift{
+			SeatCount: count,
+			Expected:  max(count.Capacity-count.Registered, 0),
+		}
+		if drift.Available == drift.Expected {
+			continue
+		}
+
+		if correct {
+			recount, err := s.eventService.RecountSeats(ctx, count.EventID)
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				// Deleted since it was read
+				continue
+			case err != nil:
+				return err
+			}
+			drift.Corrected = recount.Before != recount.After
+		}
+		report.Drifts = append(report.Drifts, drift)
+	}
+	return nil
+}
+
+// LastReport returns the report of the latest successful pass, or nil
+func (s *reconciliationService) LastReport() *models.ReconciliationReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// Stats returns the running totals since the service started
+func (s *reconciliationService) Stats() models.ReconciliationStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Run reconciles every interval until ctx is cancelled, correcting drift
+// when the service was created with autoCorrect
+func (s *reconciliationService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		report, err := s.Reconcile(ctx, s.autoCorrect)
+		if err != nil {
+			slog.ErrorContext(ctx, "seat reconciler failed", "err", err)
+			continue
+		}
+		for _, drift := range report.Drifts {
+			slog.WarnContext(ctx, "seat counter drift", "event_id", drift.EventID,
+				"available", drift.Available, "expected", drift.Expected, "corrected", drift.Corrected)
+		}
+	}
+}
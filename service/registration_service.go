@@ -1,45 +1,76 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"event-api/models"
 	"event-api/repository"
+	"event-api/tracing"
 
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 // RegistrationService handles registration business logic
 type RegistrationService interface {
-	RegisterForEvent(userID, eventID uint) (*models.Registration, error)
-	GetRegistrationByID(id uint) (*models.Registration, error)
-	GetUserRegistrations(userID uint) ([]models.Registration, error)
-	GetEventRegistrations(eventID uint) ([]models.Registration, error)
-	CancelRegistration(userID, eventID uint) error
+	RegisterForEvent(ctx context.Context, actor models.Actor, userID, eventID uint, details models.RegistrationDetails) (*models.Registration, error)
+	GetRegistrationByID(ctx context.Context, id uint) (*models.Registration, error)
+	GetUserRegistrations(ctx context.Context, userID uint) ([]models.Registration, error)
+	GetEventRegistrations(ctx context.Context, eventID uint) ([]models.Registration, error)
+	CancelRegistration(ctx context.Context, actor models.Actor, userID, eventID uint) error
+	CheckIn(ctx context.Context, actor models.Actor, id uint) (*models.Registration, error)
+	GetPendingReview(ctx context.Context, viewerID, eventID uint) ([]models.Registration, error)
+	ReviewRegistration(ctx context.Context, actor models.Actor, reviewerID, id uint, approve bool) (*models.Registration, error)
 }
 
 type registrationService struct {
-	db               *gorm.DB
-	eventRepo        repository.EventRepository
+	transactor       repository.Transactor
 	registrationRepo repository.RegistrationRepository
 	userRepo         repository.UserRepository
+	eventRepo        repository.EventRepository
+	outboxRepo       repository.OutboxRepository
+	auditRepo        repository.AuditRepository
+	seats            SeatAllocator
+	timeout          time.Duration
 }
 
 // NewRegistrationService creates a new RegistrationService
-// This is the core service that handles concurrency-safe event registration
+// This is the core service that handles concurrency-safe event registration.
+// Each registration transaction, including the wait for seat locks, must
+// finish within timeout or fails with ErrLockTimeout; 0 means no limit.
 func NewRegistrationService(
-	db *gorm.DB,
-	eventRepo repository.EventRepository,
+	transactor repository.Transactor,
 	registrationRepo repository.RegistrationRepository,
 	userRepo repository.UserRepository,
+	eventRepo repository.EventRepository,
+	outboxRepo repository.OutboxRepository,
+	auditRepo repository.AuditRepository,
+	seats SeatAllocator,
+	timeout time.Duration,
 ) RegistrationService {
 	return &registrationService{
-		db:               db,
-		eventRepo:        eventRepo,
+		transactor:       transactor,
 		registrationRepo: registrationRepo,
 		userRepo:         userRepo,
+		eventRepo:        eventRepo,
+		outboxRepo:       outboxRepo,
+		auditRepo:        auditRepo,
+		seats:            seats,
+		timeout:          timeout,
 	}
 }
 
+// withTimeout bounds a registration transaction by the service's timeout
+func (s *registrationService) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.timeout)
+}
+
 /*
 RegisterForEvent implements the critical concurrency-safe registration logic.
 
@@ -48,151 +79,441 @@ CONCURRENCY STRATEGY:
 
 The registration must be atomic to prevent overbooking. Here's the step-by-step process:
 
-1. BEGIN TRANSACTION - Start a database transaction to ensure atomicity
-2. SELECT FOR UPDATE - Lock the event row to prevent other transactions from modifying it
-3. CHECK AVAILABLE SEATS - Verify that available_seats > 0
-4. INSERT REGISTRATION - Add the registration record
-5. DECREMENT SEATS - Atomically decrease available_seats by 1
-6. COMMIT - Save all changes or ROLLBACK on any error
+ 1. BEGIN TRANSACTION - Start a transaction through the Transactor
+ 2. RESERVE A SEAT - The SeatAllocator takes one seat or fails with ErrEventFull;
+    events requiring verified attendees fail with ErrEmailNotVerified here
+ 3. SCREEN - Check the event's ticket rules; over a limit the registration is
+    rejected with ErrTicketLimitReached or flagged for the organizer's review
+ 4. INSERT REGISTRATION - Add the registration record
+ 5. RECORD DOMAIN EVENT - Write registration.created, and registration.flagged
+    when flagged, to the outbox and the audit log
+ 6. COMMIT - Save all changes or ROLLBACK on any error
+
+How step 2 stays safe depends on the allocator (see seat_allocator.go):
+  - pessimistic: SELECT FOR UPDATE locks the event row, so concurrent
+    registrations wait until the lock is released and then see the new count
+  - optimistic: the decrement only applies if seats_version is unchanged since
+    it was read; otherwise the seat count is read again and retried
+  - atomic: a single UPDATE ... WHERE available_seats > 0 RETURNING
+  - sharded events: one random non-empty shard row is locked and decremented
+
+Every variant decrements with a condition that the count stays non-negative,
+and if any step fails the entire transaction is rolled back, including the
+seat taken in step 2. Screening after the seat is taken means that with the
+pessimistic strategy, and on SQLite, registrations for the event are screened
+one at a time, so ticket limits are exact; the other strategies may let
+concurrent registrations past a limit together.
 
-Why this works:
-- The SELECT FOR UPDATE clause locks the row until the transaction completes
-- Other concurrent transactions will wait at step 2 until the lock is released
-- This ensures only one transaction can modify the seats count at a time
-- The WHERE clause in step 5 (available_seats > 0) provides an additional safety net
-- If any step fails, the entire transaction is rolled back
+The transaction has a deadline (see NewRegistrationService). When a lock wait
+outlasts it, the transaction is rolled back with ErrLockTimeout and the client
+may retry; when the client disconnects, the wait is abandoned as well.
 
 This approach prevents race conditions like:
 - Multiple goroutines reading available_seats = 1 simultaneously
 - Multiple goroutines inserting registrations
 - Overbooking due to concurrent seat decrements
 */
-func (s *registrationService) RegisterForEvent(userID, eventID uint) (*models.Registration, error) {
+func (s *registrationService) RegisterForEvent(ctx context.Context, actor models.Actor, userID, eventID uint, details models.RegistrationDetails) (_ *models.Registration, err error) {
+	ctx, span := tracing.Start(ctx, "RegistrationService.RegisterForEvent", tracing.UserID(userID), tracing.EventID(eventID))
+	defer func() { tracing.End(span, err) }()
+
+	if err := models.ValidateAnswers(details.Answers); err != nil {
+		return nil, err
+	}
+
 	// Validate user exists
-	_, err := s.userRepo.FindByID(userID)
+	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, models.ErrUserNotFound
 		}
 		return nil, err
 	}
+	if !user.Active() {
+		return nil, models.ErrUserDeactivated
+	}
 
-	// Start a new database transaction
-	// All operations within this transaction will be atomic
-	tx := s.db.Begin()
+	// All operations within this transaction will be atomic; returning an
+	// error rolls every one of them back. A client that gives up, or a lock
+	// wait that outlasts the timeout, ends it and frees the connection.
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var registration *models.Registration
+	err = s.transactor.Transaction(ctx, func(tx repository.Tx) error {
+		// Check if user is already registered (within transaction)
+		_, err := s.registrationRepo.FindByUserAndEventIDWithTx(ctx, tx, userID, eventID)
+		if err == nil {
+			return models.ErrAlreadyRegistered
+		}
+		if err != gorm.ErrRecordNotFound {
+			return err
+		}
 
-	// Check if user is already registered (within transaction)
-	var existingReg models.Registration
-	err = tx.Where("user_id = ? AND event_id = ?", userID, eventID).First(&existingReg).Error
-	if err == nil {
-		// User already registered - rollback and return error
-		tx.Rollback()
-		return nil, models.ErrAlreadyRegistered
-	}
-	if err != gorm.ErrRecordNotFound {
-		tx.Rollback()
-		return nil, err
-	}
+		// CRITICAL: Take a seat. This fails with ErrEventFull once none are left.
+		event, err := s.seats.ReserveWithTx(ctx, tx, eventID)
+		if err != nil {
+			return err
+		}
+		// Rolling back returns the seat
+		if event.RequireVerifiedEmail && !user.Verified() {
+			return models.ErrEmailNotVerified
+		}
 
-	// CRITICAL: Lock the event row using SELECT FOR UPDATE
-	// This prevents other transactions from modifying this row until we commit/rollback
-	event, err := s.eventRepo.FindByIDForUpdate(tx, eventID)
-	if err != nil {
-		tx.Rollback()
-		if err == gorm.ErrRecordNotFound {
-			return nil, models.ErrEventNotFound
+		// Create the registration record. Even though we checked above, a
+		// concurrent request may have registered the same user first; the
+		// unique index turns that into ErrAlreadyRegistered, and rolling
+		// back also returns the seat taken above.
+		registration = &models.Registration{
+			UserID:             userID,
+			EventID:            eventID,
+			EmailDomain:        models.EmailDomain(user.Email),
+			PaymentFingerprint: strings.TrimSpace(details.PaymentFingerprint),
+			HouseholdKey:       models.HouseholdKey(details.Address),
+			Answers:            details.Answers,
+		}
+		reasons, err := s.screenWithTx(ctx, tx, event.TicketRules, registration, time.Now())
+		if err != nil {
+			return err
+		}
+		if len(reasons) > 0 {
+			registration.Review = models.ReviewPending
+			registration.FlagReasons = strings.Join(reasons, ",")
+		}
+		if err := s.registrationRepo.CreateWithTx(ctx, tx, registration); err != nil {
+			return err
+		}
+		if err := s.auditWithTx(ctx, tx, actor, models.ActionRegister, nil, registration); err != nil {
+			return err
 		}
+
+		// Record the domain event in the same transaction. The outbox relay turns
+		// it into emails and webhooks only once this registration has committed,
+		// and a rolled-back registration leaves no trace.
+		created := *registration
+		created.User = user
+		if err := s.recordWithTx(ctx, tx, models.DomainRegistrationCreated, event, &created); err != nil {
+			return err
+		}
+		if registration.Review == models.ReviewPending {
+			return s.recordWithTx(ctx, tx, models.DomainRegistrationFlagged, event, &created)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
+	span.SetAttributes(tracing.RegistrationID(registration.ID))
+	return registration, nil
+}
 
-	// CRITICAL: Check if seats are available
-	// This check happens AFTER acquiring the lock, so it's safe
-	if event.AvailableSeats <= 0 {
-		tx.Rollback()
-		return nil, models.ErrEventFull
-	}
+// GetRegistrationByID gets a registration by ID
+func (s *registrationService) GetRegistrationByID(ctx context.Context, id uint) (_ *models.Registration, err error) {
+	_, span := tracing.Start(ctx, "RegistrationService.GetRegistrationByID", tracing.RegistrationID(id))
+	defer func() { tracing.End(span, err) }()
+	return s.registrationRepo.FindByID(ctx, id)
+}
 
-	// Create the registration record
-	registration := &models.Registration{
-		UserID:  userID,
-		EventID: eventID,
-	}
+// GetUserRegistrations gets all registrations for a user
+func (s *registrationService) GetUserRegistrations(ctx context.Context, userID uint) (_ []models.Registration, err error) {
+	_, span := tracing.Start(ctx, "RegistrationService.GetUserRegistrations", tracing.UserID(userID))
+	defer func() { tracing.End(span, err) }()
+	return s.registrationRepo.FindByUserID(ctx, userID)
+}
+
+// GetEventRegistrations gets all registrations for an event
+func (s *registrationService) GetEventRegistrations(ctx context.Context, eventID uint) (_ []models.Registration, err error) {
+	_, span := tracing.Start(ctx, "RegistrationService.GetEventRegistrations", tracing.EventID(eventID))
+	defer func() { tracing.End(span, err) }()
+	return s.registrationRepo.FindByEventID(ctx, eventID)
+}
+
+// CancelRegistration cancels a user's registration for an event and audits
+// it. It returns gorm.ErrRecordNotFound when there is none.
+func (s *registrationService) CancelRegistration(ctx context.Context, actor models.Actor, userID, eventID uint) (err error) {
+	ctx, span := tracing.Start(ctx, "RegistrationService.CancelRegistration", tracing.UserID(userID), tracing.EventID(eventID))
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return s.transactor.Transaction(ctx, func(tx repository.Tx) error {
+		// Load the registration so the domain event can describe it
+		registration, err := s.registrationRepo.FindByUserAndEventIDWithTx(ctx, tx, userID, eventID)
+		if err != nil {
+			return err
+		}
+
+		// Delete the registration. A concurrent cancellation may have
+		// deleted it first, and must be the only one to return the seat.
+		deleted, err := s.registrationRepo.DeleteByUserAndEventWithTx(ctx, tx, userID, eventID)
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			return gorm.ErrRecordNotFound
+		}
+
+		// Increment available seats. A deleted event took its seats with
+		// it, so there is nothing to give back.
+		event, err := s.eventRepo.FindByIDIncludingDeletedWithTx(ctx, tx, eventID)
+		if err != nil {
+			return err
+		}
+		if !event.DeletedAt.Valid {
+			if err := s.seats.ReleaseWithTx(ctx, tx, eventID); err != nil {
+				return err
+			}
+		}
+		if err := s.auditWithTx(ctx, tx, actor, models.ActionCancelRegistration, registration, nil); err != nil {
+			return err
+		}
+		return s.recordWithTx(ctx, tx, models.DomainRegistrationCancelled, event, registration)
+	})
+}
+
+// CheckIn marks a registration as checked in at the event and audits it
+func (s *registrationService) CheckIn(ctx context.Context, actor models.Actor, id uint) (_ *models.Registration, err error) {
+	ctx, span := tracing.Start(ctx, "RegistrationService.CheckIn", tracing.RegistrationID(id))
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var registration *models.Registration
+	err = s.transactor.Transaction(ctx, func(tx repository.Tx) error {
+		var err error
+		registration, err = s.registrationRepo.FindByIDForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if registration.CheckedInAt != nil {
+			return models.ErrAlreadyCheckedIn
+		}
 
-	// Use ON CONFLICT to handle race condition on unique constraint
-	// Even though we checked above, this provides defense in depth
-	err = tx.Clauses(clause.OnConflict{
-		DoNothing: true,
-	}).Create(registration).Error
+		before := *registration
+		now := time.Now()
+		if err := s.registrationRepo.MarkCheckedInWithTx(ctx, tx, id, now); err != nil {
+			return err
+		}
+		registration.CheckedInAt = &now
+		if err := s.auditWithTx(ctx, tx, actor, models.ActionCheckIn, &before, registration); err != nil {
+			return err
+		}
 
+		if registration.User, err = s.userRepo.FindByIDWithTx(ctx, tx, registration.UserID); err != nil {
+			return err
+		}
+		event, err := s.eventRepo.FindByIDWithTx(ctx, tx, registration.EventID)
+		if err != nil {
+			return err
+		}
+		return s.recordWithTx(ctx, tx, models.DomainRegistrationCheckedIn, event, registration)
+	})
 	if err != nil {
-		tx.Rollback()
 		return nil, err
 	}
+	span.SetAttributes(tracing.UserID(registration.UserID), tracing.EventID(registration.EventID))
+	return registration, nil
+}
 
-	// CRITICAL: Atomically decrement available seats
-	// Use UPDATE with WHERE clause for additional safety
-	result := tx.Model(&models.Event{}).
-		Where("id = ? AND available_seats > 0", eventID).
-		Update("available_seats", gorm.Expr("available_seats - 1"))
-
-	if result.Error != nil {
-		tx.Rollback()
-		return nil, result.Error
+// screenWithTx checks a new registration against an event's ticket rules
+// within tx and returns why it should be flagged, if it should. It fails
+// with ErrTicketLimitReached when a ticket limit is reached and the rules
+// reject registrations over it.
+func (s *registrationService) screenWithTx(ctx context.Context, tx repository.Tx, rules models.TicketRules, registration *models.Registration, now time.Time) ([]string, error) {
+	var reasons []string
+	for _, limit := range []struct {
+		key   models.ScreeningKey
+		value string
+		max   int
+	}{
+		{models.ScreenEmailDomain, registration.EmailDomain, rules.MaxPerEmailDomain},
+		{models.ScreenPaymentInstrument, registration.PaymentFingerprint, rules.MaxPerPaymentInstrument},
+		{models.ScreenHousehold, registration.HouseholdKey, rules.MaxPerHousehold},
+	} {
+		if limit.max <= 0 || limit.value == "" {
+			continue
+		}
+		count, err := s.registrationRepo.CountSimilarWithTx(ctx, tx, registration.EventID, limit.key, limit.value)
+		if err != nil {
+			return nil, err
+		}
+		if count < int64(limit.max) {
+			continue
+		}
+		if rules.OverLimit != models.OverLimitFlag {
+			return nil, fmt.Errorf("%w: at most %d per %s", models.ErrTicketLimitReached, limit.max, strings.ReplaceAll(string(limit.key), "_", " "))
+		}
+		reasons = append(reasons, string(limit.key))
 	}
 
-	// Check if the update actually affected any rows
-	// This is our final safety net - if no rows affected, seats are gone
-	if result.RowsAffected == 0 {
-		tx.Rollback()
-		return nil, models.ErrEventFull
+	if rules.VelocityMax <= 0 || rules.VelocityWindowSeconds <= 0 {
+		return reasons, nil
+	}
+	since := now.Add(-time.Duration(rules.VelocityWindowSeconds) * time.Second)
+	for _, recent := range []struct {
+		key   models.ScreeningKey
+		value any
+	}{
+		{models.ScreenUser, registration.UserID},
+		{models.ScreenPaymentInstrument, registration.PaymentFingerprint},
+		{models.ScreenHousehold, registration.HouseholdKey},
+	} {
+		if recent.value == "" {
+			continue
+		}
+		count, err := s.registrationRepo.CountRecentWithTx(ctx, tx, recent.key, recent.value, since)
+		if err != nil {
+			return nil, err
+		}
+		if count >= int64(rules.VelocityMax) {
+			return append(reasons, models.FlagVelocity), nil
+		}
 	}
+	return reasons, nil
+}
+
+// GetPendingReview returns an event's flagged registrations awaiting
+// review. Only the event's organizer and admins may see them.
+func (s *registrationService) GetPendingReview(ctx context.Context, viewerID, eventID uint) (_ []models.Registration, err error) {
+	ctx, span := tracing.Start(ctx, "RegistrationService.GetPendingReview", tracing.UserID(viewerID), tracing.EventID(eventID))
+	defer func() { tracing.End(span, err) }()
 
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
+	event, err := s.eventRepo.FindByID(ctx, eventID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.ErrEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	viewer, err := s.userRepo.FindByID(ctx, viewerID)
+	if err := mayReview(viewer, err, event); err != nil {
 		return nil, err
 	}
 
-	// Reload the registration with associations
-	s.registrationRepo.FindByUserAndEventID(userID, eventID)
-	return registration, nil
+	registrations, err := s.registrationRepo.FindPendingReviewByEventID(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if registrations == nil {
+		registrations = []models.Registration{}
+	}
+	return registrations, nil
 }
 
-// GetRegistrationByID gets a registration by ID
-func (s *registrationService) GetRegistrationByID(id uint) (*models.Registration, error) {
-	return s.registrationRepo.FindByID(id)
-}
+// ReviewRegistration approves a flagged registration, or rejects it, which
+// cancels it and returns its seat, and audits the decision. Only the
+// event's organizer and admins may review; registrations not pending review
+// fail with ErrNotPendingReview.
+func (s *registrationService) ReviewRegistration(ctx context.Context, actor models.Actor, reviewerID, id uint, approve bool) (_ *models.Registration, err error) {
+	ctx, span := tracing.Start(ctx, "RegistrationService.ReviewRegistration", tracing.UserID(reviewerID), tracing.RegistrationID(id))
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 
-// GetUserRegistrations gets all registrations for a user
-func (s *registrationService) GetUserRegistrations(userID uint) ([]models.Registration, error) {
-	return s.registrationRepo.FindByUserID(userID)
-}
+	var registration *models.Registration
+	err = s.transactor.Transaction(ctx, func(tx repository.Tx) error {
+		var err error
+		registration, err = s.registrationRepo.FindByIDForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		event, err := s.eventRepo.FindByIDWithTx(ctx, tx, registration.EventID)
+		if err != nil {
+			return err
+		}
+		reviewer, err := s.userRepo.FindByIDWithTx(ctx, tx, reviewerID)
+		if err := mayReview(reviewer, err, event); err != nil {
+			return err
+		}
+		if registration.Review != models.ReviewPending {
+			return models.ErrNotPendingReview
+		}
 
-// GetEventRegistrations gets all registrations for an event
-func (s *registrationService) GetEventRegistrations(eventID uint) ([]models.Registration, error) {
-	return s.registrationRepo.FindByEventID(eventID)
-}
+		before := *registration
+		if approve {
+			registration.Review = models.ReviewApproved
+			if err := s.registrationRepo.UpdateReviewWithTx(ctx, tx, id, registration.Review); err != nil {
+				return err
+			}
+			return s.auditWithTx(ctx, tx, actor, models.ActionApproveRegistration, &before, registration)
+		}
 
-// CancelRegistration cancels a user's registration for an event
-func (s *registrationService) CancelRegistration(userID, eventID uint) error {
-	// Start transaction for atomic update
-	tx := s.db.Begin()
+		// Rejecting cancels the registration, keeping the decision on the
+		// cancelled row
+		registration.Review = models.ReviewRejected
+		if err := s.registrationRepo.UpdateReviewWithTx(ctx, tx, id, registration.Review); err != nil {
+			return err
+		}
+		deleted, err := s.registrationRepo.DeleteByUserAndEventWithTx(ctx, tx, registration.UserID, registration.EventID)
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			return gorm.ErrRecordNotFound
+		}
+		if err := s.seats.ReleaseWithTx(ctx, tx, registration.EventID); err != nil {
+			return err
+		}
+		if err := s.auditWithTx(ctx, tx, actor, models.ActionRejectRegistration, &before, nil); err != nil {
+			return err
+		}
+
+		if registration.User, err = s.userRepo.FindByIDWithTx(ctx, tx, registration.UserID); err != nil {
+			return err
+		}
+		if event, err = s.eventRepo.FindByIDWithTx(ctx, tx, registration.EventID); err != nil {
+			return err
+		}
+		return s.recordWithTx(ctx, tx, models.DomainRegistrationCancelled, event, registration)
+	})
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(tracing.EventID(registration.EventID))
+	return registration, nil
+}
 
-	// Find and delete the registration
-	err := tx.Where("user_id = ? AND event_id = ?", userID, eventID).Delete(&models.Registration{}).Error
+// mayReview fails unless user, found with err, is an admin or organizes
+// event
+func mayReview(user *models.User, err error, event *models.Event) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.ErrUserNotFound
+	}
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
+	if user.Role != models.RoleAdmin && user.ID != event.OrganizerID {
+		return models.ErrUnauthorized
+	}
+	return nil
+}
 
-	// Increment available seats
-	result := tx.Model(&models.Event{}).
-		Where("id = ?", eventID).
-		Update("available_seats", gorm.Expr("available_seats + 1"))
+// recordWithTx writes a registration domain event to the outbox within tx
+func (s *registrationService) recordWithTx(ctx context.Context, tx repository.Tx, eventType models.DomainEventType, event *models.Event, registration *models.Registration) error {
+	snapshot := *registration
+	snapshot.Event = nil // carried once, at the top level of the payload
 
-	if result.Error != nil {
-		tx.Rollback()
-		return result.Error
+	outboxEvent, err := models.NewOutboxEvent(eventType, models.OutboxPayload{
+		Event:        event,
+		Registration: &snapshot,
+	})
+	if err != nil {
+		return err
 	}
+	return s.outboxRepo.CreateWithTx(ctx, tx, outboxEvent)
+}
 
-	return tx.Commit().Error
+// auditWithTx writes the audit entry of a change to a registration within
+// tx; before is nil for registering and after is nil for cancelling
+func (s *registrationService) auditWithTx(ctx context.Context, tx repository.Tx, actor models.Actor, action models.Action, before, after *models.Registration) error {
+	current := after
+	if current == nil {
+		current = before
+	}
+	entry, err := models.NewAuditEvent(actor, action, models.AuditTargetRegistration, current.ID, before, after)
+	if err != nil {
+		return err
+	}
+	eventID := current.EventID
+	entry.EventID = &eventID
+	return s.auditRepo.CreateWithTx(ctx, tx, entry)
 }
@@ -0,0 +1,973 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"event-api/internal/testdb"
+	"event-api/models"
+	"event-api/repository"
+	"event-api/repository/memory"
+	"event-api/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testdb.Main(m))
+}
+
+// testActor is who the audit log credits with the tests' changes
+var testActor = models.Actor{RequestID: "test"}
+
+// strategySharded runs a test against a sharded event instead of a row strategy
+const strategySharded SeatStrategy = "sharded"
+
+// allStrategies is every way a seat can be taken
+var allStrategies = append(append([]SeatStrategy{}, SeatStrategies...), strategySharded)
+
+// backends are the repository implementations the registration suite runs
+// against. Sharded inventory only exists in Postgres.
+var backends = []struct {
+	name       string
+	strategies []SeatStrategy
+	open       func(t *testing.T, strategy SeatStrategy) *fixture
+}{
+	{"memory", SeatStrategies, newMemoryFixture},
+	{"sqlite", SeatStrategies, newSQLiteFixture},
+	{"postgres", allStrategies, newPostgresFixture},
+}
+
+// forEachBackend runs test for every backend and seat strategy
+func forEachBackend(t *testing.T, test func(t *testing.T, f *fixture)) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			for _, strategy := range backend.strategies {
+				t.Run(string(strategy), func(t *testing.T) {
+					test(t, backend.open(t, strategy))
+				})
+			}
+		})
+	}
+}
+
+// fixture wires the registration service to one backend
+type fixture struct {
+	registrations    RegistrationService
+	users            UserService
+	userRepo         repository.UserRepository
+	eventRepo        repository.EventRepository
+	registrationRepo repository.RegistrationRepository
+	outboxRepo       repository.OutboxRepository
+	auditRepo        repository.AuditRepository
+	inventory        models.InventoryMode
+
+	// events is only available with GORM
+	events EventService
+}
+
+func newMemoryFixture(t *testing.T, strategy SeatStrategy) *fixture {
+	t.Helper()
+	store := memory.NewStore()
+	userRepo := memory.NewUserRepository(store)
+	eventRepo := memory.NewEventRepository(store)
+	registrationRepo := memory.NewRegistrationRepository(store)
+	outboxRepo := memory.NewOutboxRepository(store)
+	auditRepo := memory.NewAuditRepository(store)
+
+	seats, err := NewRowSeatAllocator(strategy, eventRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fixture{
+		registrations:    NewRegistrationService(store, registrationRepo, userRepo, eventRepo, outboxRepo, auditRepo, seats, 0),
+		users:            NewUserService(store, userRepo, memory.NewTokenRepository(store), auditRepo),
+		userRepo:         userRepo,
+		eventRepo:        eventRepo,
+		registrationRepo: registrationRepo,
+		outboxRepo:       outboxRepo,
+		auditRepo:        auditRepo,
+		inventory:        models.InventoryRow,
+	}
+}
+
+func newPostgresFixture(t *testing.T, strategy SeatStrategy) *fixture {
+	t.Helper()
+	return newGormFixture(t, testdb.New(t), strategy)
+}
+
+func newSQLiteFixture(t *testing.T, strategy SeatStrategy) *fixture {
+	t.Helper()
+	return newGormFixture(t, testdb.NewSQLite(t), strategy)
+}
+
+func newGormFixture(t *testing.T, db *gorm.DB, strategy SeatStrategy) *fixture {
+	t.Helper()
+	if err := tracing.InstrumentDB(db); err != nil {
+		t.Fatal(err)
+	}
+	inventory := models.InventoryRow
+	if strategy == strategySharded {
+		inventory = models.InventorySharded
+		strategy = SeatStrategyPessimistic
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	eventRepo := repository.NewEventRepository(db)
+	registrationRepo := repository.NewRegistrationRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	inventoryRepo := repository.NewInventoryRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	transactor := repository.NewTransactor(db)
+	reminderService := NewReminderService(db, repository.NewReminderRepository(db),
+		NewNotificationService(repository.NewNotificationRepository(db)), nil)
+
+	seats, err := NewSeatAllocator(strategy, eventRepo, inventoryRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fixture{
+		registrations:    NewRegistrationService(transactor, registrationRepo, userRepo, eventRepo, outboxRepo, auditRepo, seats, 0),
+		users:            NewUserService(transactor, userRepo, repository.NewTokenRepository(db), auditRepo),
+		userRepo:         userRepo,
+		eventRepo:        eventRepo,
+		registrationRepo: registrationRepo,
+		outboxRepo:       outboxRepo,
+		auditRepo:        auditRepo,
+		inventory:        inventory,
+		events:           NewEventService(transactor, eventRepo, registrationRepo, outboxRepo, inventoryRepo, auditRepo, reminderService),
+	}
+}
+
+// createUsers creates n attendees and returns their IDs
+func (f *fixture) createUsers(t *testing.T, n int) []uint {
+	t.Helper()
+	ids := make([]uint, n)
+	for i := range ids {
+		user := &models.User{
+			Name:  fmt.Sprintf("User %d", i),
+			Email: fmt.Sprintf("user%d@example.com", i),
+			Role:  models.RoleAttendee,
+		}
+		if err := f.users.CreateUser(context.Background(), testActor, user); err != nil {
+			t.Fatalf("creating user: %v", err)
+		}
+		ids[i] = user.ID
+	}
+	return ids
+}
+
+// createEvent creates an event with the fixture's inventory mode
+func (f *fixture) createEvent(t *testing.T, capacity int) *models.Event {
+	t.Helper()
+	return f.createEventWithRules(t, capacity, models.TicketRules{})
+}
+
+// createEventWithRules creates an event with the fixture's inventory mode
+// and the given ticket rules
+func (f *fixture) createEventWithRules(t *testing.T, capacity int, rules models.TicketRules) *models.Event {
+	t.Helper()
+	// Every event of a fixture shares one organizer
+	organizer, err := f.users.GetUserByEmail(context.Background(), "organizer@example.com")
+	if err != nil {
+		organizer = &models.User{Name: "Organizer", Email: "organizer@example.com", Role: models.RoleOrganizer}
+		if err := f.users.CreateUser(context.Background(), testActor, organizer); err != nil {
+			t.Fatalf("creating organizer: %v", err)
+		}
+	}
+	event := &models.Event{
+		Title:       "Integration Test Event",
+		Capacity:    capacity,
+		OrganizerID: organizer.ID,
+		Inventory:   f.inventory,
+		TicketRules: rules,
+	}
+	if f.inventory != models.InventorySharded {
+		event.AvailableSeats = capacity
+		if err := f.eventRepo.Create(context.Background(), event); err != nil {
+			t.Fatalf("creating event: %v", err)
+		}
+		return event
+	}
+
+	// Only the event service splits the seats into shards
+	event.InventoryShards = 4
+	if err := f.events.CreateEvent(context.Background(), testActor, event); err != nil {
+		t.Fatalf("creating event: %v", err)
+	}
+	return event
+}
+
+// assertSeats checks the available seats reported for an event and the
+// number of active registrations backing them
+func (f *fixture) assertSeats(t *testing.T, eventID uint, wantAvailable, wantRegistered int) {
+	t.Helper()
+	var event *models.Event
+	var err error
+	if f.events != nil {
+		// The event service sums the shards of sharded events
+		event, err = f.events.GetEventByID(context.Background(), eventID)
+	} else {
+		event, err = f.eventRepo.FindByID(context.Background(), eventID)
+	}
+	if err != nil {
+		t.Fatalf("loading event: %v", err)
+	}
+	if event.AvailableSeats != wantAvailable {
+		t.Errorf("available seats = %d, want %d", event.AvailableSeats, wantAvailable)
+	}
+
+	registrations, err := f.registrationRepo.FindByEventID(context.Background(), eventID)
+	if err != nil {
+		t.Fatalf("loading registrations: %v", err)
+	}
+	if len(registrations) != wantRegistered {
+		t.Errorf("active registrations = %d, want %d", len(registrations), wantRegistered)
+	}
+}
+
+// assertOutbox checks how many domain events of one type an event has
+func (f *fixture) assertOutbox(t *testing.T, eventID uint, eventType models.DomainEventType, want int) {
+	t.Helper()
+	count, err := f.outboxRepo.CountByAggregate(context.Background(), models.AggregateEvent, eventID, eventType)
+	if err != nil {
+		t.Fatalf("counting outbox events: %v", err)
+	}
+	if count != int64(want) {
+		t.Errorf("%s outbox events = %d, want %d", eventType, count, want)
+	}
+}
+
+// registerConcurrently registers every user at once and returns the errors
+func (f *fixture) registerConcurrently(userIDs []uint, eventID uint) []error {
+	errs := make([]error, len(userIDs))
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i, userID := range userIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, errs[i] = f.registrations.RegisterForEvent(context.Background(), testActor, userID, eventID, models.RegistrationDetails{})
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+func TestRegisterForEventDoesNotOverbook(t *testing.T) {
+	const capacity, attendees = 10, 50
+
+	forEachBackend(t, func(t *testing.T, f *fixture) {
+		event := f.createEvent(t, capacity)
+		users := f.createUsers(t, attendees)
+
+		var succeeded, full int
+		for _, err := range f.registerConcurrently(users, event.ID) {
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.Is(err, models.ErrEventFull):
+				full++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}
+
+		if succeeded != capacity || full != attendees-capacity {
+			t.Errorf("succeeded = %d, full = %d, want %d and %d", succeeded, full, capacity, attendees-capacity)
+		}
+		f.assertSeats(t, event.ID, 0, capacity)
+		f.assertOutbox(t, event.ID, models.DomainRegistrationCreated, capacity)
+	})
+}
+
+func TestRegisterForEventRejectsDuplicate(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, f *fixture) {
+		event := f.createEvent(t, 5)
+		users := f.createUsers(t, 1)
+
+		if _, err := f.registrations.RegisterForEvent(context.Background(), testActor, users[0], event.ID, models.RegistrationDetails{}); err != nil {
+			t.Fatalf("first registration: %v", err)
+		}
+		if _, err := f.registrations.RegisterForEvent(context.Background(), testActor, users[0], event.ID, models.RegistrationDetails{}); !errors.Is(err, models.ErrAlreadyRegistered) {
+			t.Fatalf("second registration error = %v, want %v", err, models.ErrAlreadyRegistered)
+		}
+		f.assertSeats(t, event.ID, 4, 1)
+		f.assertOutbox(t, event.ID, models.DomainRegistrationCreated, 1)
+	})
+}
+
+func TestRegisterForEventRejectsConcurrentDuplicates(t *testing.T) {
+	const attempts = 20
+
+	forEachBackend(t, func(t *testing.T, f *fixture) {
+		event := f.createEvent(t, 5)
+		user := f.createUsers(t, 1)[0]
+
+		sameUser := make([]uint, attempts)
+		for i := range sameUser {
+			sameUser[i] = user
+		}
+
+		var succeeded int
+		for _, err := range f.registerConcurrently(sameUser, event.ID) {
+			switch {
+			case err == nil:
+				succeeded++
+			case !errors.Is(err, models.ErrAlreadyRegistered):
+				t.Errorf("unexpected error: %v", err)
+			}
+		}
+
+		if succeeded != 1 {
+			t.Errorf("succeeded = %d, want 1", succeeded)
+		}
+		f.assertSeats(t, event.ID, 4, 1)
+		f.assertOutbox(t, event.ID, models.DomainRegistrationCreated, 1)
+	})
+}
+
+func TestCancelAndReregister(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, f *fixture) {
+		event := f.createEvent(t, 3)
+		user := f.createUsers(t, 1)[0]
+
+		if _, err := f.registrations.RegisterForEvent(context.Background(), testActor, user, event.ID, models.RegistrationDetails{}); err != nil {
+			t.Fatalf("registering: %v", err)
+		}
+		f.assertSeats(t, event.ID, 2, 1)
+
+		if err := f.registrations.CancelRegistration(context.Background(), testActor, user, event.ID); err != nil {
+			t.Fatalf("cancelling: %v", err)
+		}
+		f.assertSeats(t, event.ID, 3, 0)
+		f.assertOutbox(t, event.ID, models.DomainRegistrationCancelled, 1)
+
+		// Cancelling again must not hand out a seat that was never taken
+		if err := f.registrations.CancelRegistration(context.Background(), testActor, user, event.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("cancelling again error = %v, want %v", err, gorm.ErrRecordNotFound)
+		}
+		f.assertSeats(t, event.ID, 3, 0)
+
+		registration, err := f.registrations.RegisterForEvent(context.Background(), testActor, user, event.ID, models.RegistrationDetails{})
+		if err != nil {
+			t.Fatalf("registering again: %v", err)
+		}
+		if registration.UserID != user || registration.EventID != event.ID {
+			t.Errorf("registration = user %d event %d, want user %d event %d",
+				registration.UserID, registration.EventID, user, event.ID)
+		}
+		f.assertSeats(t, event.ID, 2, 1)
+		f.assertOutbox(t, event.ID, models.DomainRegistrationCreated, 2)
+	})
+}
+
+func TestCancelRegistrationForDeletedEvent(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, f *fixture) {
+		ctx := context.Background()
+		event := f.createEvent(t, 3)
+		user := f.createUsers(t, 1)[0]
+
+		if _, err := f.registrations.RegisterForEvent(ctx, testActor, user, event.ID, models.RegistrationDetails{}); err != nil {
+			t.Fatalf("registering: %v", err)
+		}
+		deleteEvent := f.eventRepo.Delete
+		if f.events != nil {
+			// Also removes the shards of a sharded event
+			deleteEvent = func(ctx context.Context, id uint) error { return f.events.DeleteEvent(ctx, testActor, id) }
+		}
+		if err := deleteEvent(ctx, event.ID); err != nil {
+			t.Fatalf("deleting the event: %v", err)
+		}
+
+		if err := f.registrations.CancelRegistration(ctx, testActor, user, event.ID); err != nil {
+			t.Fatalf("cancelling: %v", err)
+		}
+		if _, err := f.registrationRepo.FindByUserAndEventID(ctx, user, event.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("registration after cancelling: error = %v, want %v", err, gorm.ErrRecordNotFound)
+		}
+		f.assertOutbox(t, event.ID, models.DomainRegistrationCancelled, 1)
+	})
+}
+
+func TestRegisterForUnknownEvent(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, f *fixture) {
+		user := f.createUsers(t, 1)[0]
+
+		if _, err := f.registrations.RegisterForEvent(context.Background(), testActor, user, 9999, models.RegistrationDetails{}); !errors.Is(err, models.ErrEventNotFound) {
+			t.Fatalf("error = %v, want %v", err, models.ErrEventNotFound)
+		}
+		if _, err := f.registrations.RegisterForEvent(context.Background(), testActor, 9999, 9999, models.RegistrationDetails{}); !errors.Is(err, models.ErrUserNotFound) {
+			t.Fatalf("error = %v, want %v", err, models.ErrUserNotFound)
+		}
+	})
+}
+
+func TestCheckIn(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, f *fixture) {
+		event := f.createEvent(t, 3)
+		user := f.createUsers(t, 1)[0]
+
+		registration, err := f.registrations.RegisterForEvent(context.Background(), testActor, user, event.ID, models.RegistrationDetails{})
+		if err != nil {
+			t.Fatalf("registering: %v", err)
+		}
+
+		checkedIn, err := f.registrations.CheckIn(context.Background(), testActor, registration.ID)
+		if err != nil {
+			t.Fatalf("checking in: %v", err)
+		}
+		if checkedIn.CheckedInAt == nil || checkedIn.User == nil || checkedIn.User.ID != user {
+			t.Errorf("checked in registration = %+v, want check-in time and user %d", checkedIn, user)
+		}
+		if _, err := f.registrations.CheckIn(context.Background(), testActor, registration.ID); !errors.Is(err, models.ErrAlreadyCheckedIn) {
+			t.Fatalf("second check-in error = %v, want %v", err, models.ErrAlreadyCheckedIn)
+		}
+		f.assertOutbox(t, event.ID, models.DomainRegistrationCheckedIn, 1)
+	})
+}
+
+// contendedEventRepository loses every optimistic race for a seat
+type contendedEventRepository struct {
+	repository.EventRepository
+}
+
+func (contendedEventRepository) DecreaseAvailableSeatsIfVersion(context.Context, repository.Tx, uint, int64) (bool, error) {
+	return false, nil
+}
+
+func TestOptimisticBackoffStopsWhenCancelled(t *testing.T) {
+	store := memory.NewStore()
+	eventRepo := memory.NewEventRepository(store)
+	event := &models.Event{Title: "Contended", Capacity: 1, AvailableSeats: 1}
+	if err := eventRepo.Create(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	seats, err := NewRowSeatAllocator(SeatStrategyOptimistic, contendedEventRepository{eventRepo})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = store.Transaction(ctx, func(tx repository.Tx) error {
+		_, err := seats.ReserveWithTx(ctx, tx, event.ID)
+		return err
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestRegisterForEventLockTimeout(t *testing.T) {
+	// The memory store has no lock to wait for
+	for _, backend := range []struct {
+		name string
+		open func(t testing.TB) *gorm.DB
+	}{
+		{"sqlite", testdb.NewSQLite},
+		{"postgres", testdb.New},
+	} {
+		t.Run(backend.name, func(t *testing.T) {
+			db := backend.open(t)
+			f := newGormFixture(t, db, SeatStrategyPessimistic)
+			event := f.createEvent(t, 1)
+			user := f.createUsers(t, 1)[0]
+
+			const timeout = 200 * time.Millisecond
+			seats, err := NewRowSeatAllocator(SeatStrategyPessimistic, f.eventRepo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			registrations := NewRegistrationService(repository.NewTransactor(db), f.registrationRepo,
+				repository.NewUserRepository(db), f.eventRepo, f.outboxRepo, f.auditRepo, seats, timeout)
+
+			// Another transaction holds the event's lock until released
+			locked, release, done := make(chan struct{}), make(chan struct{}), make(chan error)
+			go func() {
+				done <- db.Transaction(func(tx *gorm.DB) error {
+					if _, err := f.eventRepo.FindByIDForUpdate(context.Background(), tx, event.ID); err != nil {
+						return err
+					}
+					close(locked)
+					<-release
+					return nil
+				})
+			}()
+			<-locked
+
+			start := time.Now()
+			_, err = registrations.RegisterForEvent(context.Background(), testActor, user, event.ID, models.RegistrationDetails{})
+			elapsed := time.Since(start)
+			close(release)
+			if err := <-done; err != nil {
+				t.Fatalf("holding the lock: %v", err)
+			}
+			if !errors.Is(err, models.ErrLockTimeout) {
+				t.Fatalf("error = %v, want %v", err, models.ErrLockTimeout)
+			}
+			if elapsed > 10*timeout {
+				t.Errorf("gave up after %v, want about %v", elapsed, timeout)
+			}
+
+			// Once the lock is free the retry gets the seat
+			if _, err := registrations.RegisterForEvent(context.Background(), testActor, user, event.ID, models.RegistrationDetails{}); err != nil {
+				t.Fatalf("retrying: %v", err)
+			}
+			f.assertSeats(t, event.ID, 0, 1)
+		})
+	}
+}
+
+func TestTicketRules(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, f *fixture) {
+		ctx := context.Background()
+		users := f.createUsers(t, 4) // all @example.com
+		register := func(user uint, event *models.Event, card, address string) (*models.Registration, error) {
+			return f.registrations.RegisterForEvent(ctx, testActor, user, event.ID, models.RegistrationDetails{PaymentFingerprint: card, Address: address})
+		}
+
+		// Over a limit, registrations are rejected
+		limited := f.createEventWithRules(t, 10, models.TicketRules{
+			MaxPerEmailDomain:       3,
+			MaxPerPaymentInstrument: 1,
+			MaxPerHousehold:         2,
+		})
+		for i, attempt := range []struct {
+			user          uint
+			card, address string
+			wantErr       error
+		}{
+			{users[0], "card-a", "1 Main St", nil},
+			{users[1], "card-a", "", models.ErrTicketLimitReached},
+			{users[1], "card-b", "1 main st.", nil},
+			{users[2], "card-c", " 1 MAIN  ST", models.ErrTicketLimitReached},
+			{users[2], "card-c", "", nil},
+			{users[3], "card-d", "", models.ErrTicketLimitReached}, // fourth @example.com
+		} {
+			if _, err := register(attempt.user, limited, attempt.card, attempt.address); !errors.Is(err, attempt.wantErr) {
+				t.Fatalf("attempt %d: error = %v, want %v", i, err, attempt.wantErr)
+			}
+		}
+		f.assertSeats(t, limited.ID, 7, 3)
+
+		// With OverLimitFlag, they are flagged for the organizer instead
+		flagging := f.createEventWithRules(t, 10, models.TicketRules{MaxPerPaymentInstrument: 1, OverLimit: models.OverLimitFlag})
+		if _, err := register(users[0], flagging, "card-a", ""); err != nil {
+			t.Fatalf("registering: %v", err)
+		}
+		rejected, err := register(users[1], flagging, "card-a", "")
+		if err != nil || rejected.Review != models.ReviewPending || rejected.FlagReasons != "payment_instrument" {
+			t.Fatalf("flagged registration = %+v, err %v; want pending review for payment_instrument", rejected, err)
+		}
+		approved, err := register(users[2], flagging, "card-a", "")
+		if err != nil {
+			t.Fatalf("registering: %v", err)
+		}
+		f.assertOutbox(t, flagging.ID, models.DomainRegistrationFlagged, 2)
+
+		if _, err := f.registrations.GetPendingReview(ctx, users[3], flagging.ID); !errors.Is(err, models.ErrUnauthorized) {
+			t.Errorf("attendee reading the review queue: error = %v, want %v", err, models.ErrUnauthorized)
+		}
+		pending, err := f.registrations.GetPendingReview(ctx, flagging.OrganizerID, flagging.ID)
+		if err != nil || len(pending) != 2 {
+			t.Fatalf("review queue = %d registrations, err %v; want 2", len(pending), err)
+		}
+
+		// Rejecting cancels and returns the seat; approving keeps it
+		if _, err := f.registrations.ReviewRegistration(ctx, testActor, flagging.OrganizerID, rejected.ID, false); err != nil {
+			t.Fatalf("rejecting: %v", err)
+		}
+		reviewed, err := f.registrations.ReviewRegistration(ctx, testActor, flagging.OrganizerID, approved.ID, true)
+		if err != nil || reviewed.Review != models.ReviewApproved {
+			t.Fatalf("approved registration = %+v, err %v", reviewed, err)
+		}
+		if _, err := f.registrations.ReviewRegistration(ctx, testActor, flagging.OrganizerID, approved.ID, true); !errors.Is(err, models.ErrNotPendingReview) {
+			t.Errorf("reviewing twice: error = %v, want %v", err, models.ErrNotPendingReview)
+		}
+		f.assertSeats(t, flagging.ID, 8, 2)
+		f.assertOutbox(t, flagging.ID, models.DomainRegistrationCancelled, 1)
+		if pending, _ := f.registrations.GetPendingReview(ctx, flagging.OrganizerID, flagging.ID); len(pending) != 0 {
+			t.Errorf("review queue after reviewing = %d registrations, want none", len(pending))
+		}
+
+		// users[0] has registered twice within the hour, users[3] never
+		velocity := f.createEventWithRules(t, 10, models.TicketRules{VelocityMax: 2, VelocityWindowSeconds: 3600})
+		fast, err := register(users[0], velocity, "", "")
+		if err != nil || fast.FlagReasons != models.FlagVelocity {
+			t.Errorf("fast registration = %+v, err %v; want flagged for velocity", fast, err)
+		}
+		slow, err := register(users[3], velocity, "", "")
+		if err != nil || slow.Review != models.ReviewNone {
+			t.Errorf("slow registration = %+v, err %v; want not flagged", slow, err)
+		}
+	})
+}
+
+func TestRegisterRequiresActiveVerifiedUser(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, f *fixture) {
+		ctx := context.Background()
+		event := f.createEvent(t, 5)
+		event.RequireVerifiedEmail = true
+		if err := f.eventRepo.Update(ctx, event); err != nil {
+			t.Fatalf("requiring verified attendees: %v", err)
+		}
+		users := f.createUsers(t, 2)
+		update := func(id uint, change func(user *models.User)) {
+			user, err := f.userRepo.FindByID(ctx, id)
+			if err != nil {
+				t.Fatalf("loading user: %v", err)
+			}
+			change(user)
+			if err := f.userRepo.Update(ctx, user); err != nil {
+				t.Fatalf("updating user: %v", err)
+			}
+		}
+
+		if _, err := f.registrations.RegisterForEvent(ctx, testActor, users[0], event.ID, models.RegistrationDetails{}); !errors.Is(err, models.ErrEmailNotVerified) {
+			t.Fatalf("unverified user: error = %v, want %v", err, models.ErrEmailNotVerified)
+		}
+		f.assertSeats(t, event.ID, 5, 0)
+
+		now := time.Now()
+		update(users[0], func(user *models.User) { user.EmailVerifiedAt = &now })
+		if _, err := f.registrations.RegisterForEvent(ctx, testActor, users[0], event.ID, models.RegistrationDetails{}); err != nil {
+			t.Fatalf("verified user: %v", err)
+		}
+
+		update(users[1], func(user *models.User) { user.EmailVerifiedAt, user.DeactivatedAt = &now, &now })
+		if _, err := f.registrations.RegisterForEvent(ctx, testActor, users[1], event.ID, models.RegistrationDetails{}); !errors.Is(err, models.ErrUserDeactivated) {
+			t.Fatalf("deactivated user: error = %v, want %v", err, models.ErrUserDeactivated)
+		}
+		f.assertSeats(t, event.ID, 4, 1)
+	})
+}
+
+func TestAuditTrail(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, f *fixture) {
+		event := f.createEvent(t, 1)
+		users := f.createUsers(t, 2)
+		actor := models.Actor{UserID: &users[0], RequestID: "req-1", IP: "192.0.2.1"}
+
+		registration, err := f.registrations.RegisterForEvent(context.Background(), actor, users[0], event.ID, models.RegistrationDetails{})
+		if err != nil {
+			t.Fatalf("registering: %v", err)
+		}
+		if _, err := f.registrations.CheckIn(context.Background(), actor, registration.ID); err != nil {
+			t.Fatalf("checking in: %v", err)
+		}
+		// A rolled back registration leaves no entry
+		if _, err := f.registrations.RegisterForEvent(context.Background(), actor, users[1], event.ID, models.RegistrationDetails{}); !errors.Is(err, models.ErrEventFull) {
+			t.Fatalf("error = %v, want %v", err, models.ErrEventFull)
+		}
+		if err := f.registrations.CancelRegistration(context.Background(), actor, users[0], event.ID); err != nil {
+			t.Fatalf("cancelling: %v", err)
+		}
+
+		entries, err := f.auditRepo.Find(context.Background(), models.AuditFilter{TargetType: models.AuditTargetRegistration, EventID: event.ID})
+		if err != nil {
+			t.Fatalf("loading audit events: %v", err)
+		}
+		want := []models.Action{models.ActionCancelRegistration, models.ActionCheckIn, models.ActionRegister}
+		if len(entries) != len(want) {
+			t.Fatalf("audit events = %d, want %d", len(entries), len(want))
+		}
+		for i, entry := range entries {
+			if entry.Action != want[i] || entry.TargetID != registration.ID {
+				t.Errorf("entry %d = %s on %d, want %s on %d", i, entry.Action, entry.TargetID, want[i], registration.ID)
+			}
+			if entry.ActorID == nil || *entry.ActorID != users[0] || entry.RequestID != "req-1" || entry.IP != "192.0.2.1" {
+				t.Errorf("entry %d actor = %v %q %q, want %d %q %q", i, entry.ActorID, entry.RequestID, entry.IP, users[0], "req-1", "192.0.2.1")
+			}
+		}
+		if entries[0].Before == nil || entries[0].After != nil {
+			t.Errorf("cancel snapshots = %s / %s, want the registration / none", entries[0].Before, entries[0].After)
+		}
+		if entries[2].Before != nil || entries[2].After == nil {
+			t.Errorf("register snapshots = %s / %s, want none / the registration", entries[2].Before, entries[2].After)
+		}
+
+		// Organizers only see their own events
+		entries, err = f.auditRepo.Find(context.Background(), models.AuditFilter{OrganizerID: event.OrganizerID + 1000})
+		if err != nil {
+			t.Fatalf("loading audit events: %v", err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("audit events of another organizer = %d, want 0", len(entries))
+		}
+	})
+}
+
+func TestRegisterForEventTrace(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, f *fixture) {
+		users := f.createUsers(t, 1)
+		event := f.createEvent(t, 1)
+
+		exporter := tracetest.NewInMemoryExporter()
+		provider := tracing.NewProvider(exporter, 1)
+		defer func() { _ = provider.Shutdown(context.Background()) }()
+		previous := otel.GetTracerProvider()
+		otel.SetTracerProvider(provider)
+		defer otel.SetTracerProvider(previous)
+
+		ctx, request := tracing.Start(context.Background(), "POST /api/v1/registrations")
+		registration, err := f.registrations.RegisterForEvent(ctx, testActor, users[0], event.ID, models.RegistrationDetails{})
+		request.End()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := provider.ForceFlush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		var register *tracetest.SpanStub
+		queries := 0
+		spans := exporter.GetSpans()
+		for i, span := range spans {
+			switch {
+			case span.Name == "RegistrationService.RegisterForEvent":
+				register = &spans[i]
+			case span.SpanContext.TraceID() == request.SpanContext().TraceID() && span.Name != "POST /api/v1/registrations":
+				queries++
+			}
+		}
+		if register == nil {
+			t.Fatal("no RegistrationService.RegisterForEvent span")
+		}
+		if register.Parent.SpanID() != request.SpanContext().SpanID() {
+			t.Error("service span is not a child of the request span")
+		}
+		want := map[string]int64{"user.id": int64(users[0]), "event.id": int64(event.ID), "registration.id": int64(registration.ID)}
+		for _, kv := range register.Attributes {
+			if id, ok := want[string(kv.Key)]; ok {
+				if kv.Value.AsInt64() != id {
+					t.Errorf("%s = %d, want %d", kv.Key, kv.Value.AsInt64(), id)
+				}
+				delete(want, string(kv.Key))
+			}
+		}
+		if len(want) > 0 {
+			t.Errorf("missing attributes %v", want)
+		}
+		// The transaction's statements join the trace
+		if f.events != nil && queries == 0 {
+			t.Error("no query spans in the registration's trace")
+		}
+	})
+}
+
+func TestShardedAvailabilityVersionIncreases(t *testing.T) {
+	f := newPostgresFixture(t, strategySharded)
+	ctx := context.Background()
+	event := f.createEvent(t, 4)
+	users := f.createUsers(t, 2)
+
+	last := int64(-1)
+	check := func(step string) {
+		t.Helper()
+		availability, err := f.events.GetAvailability(ctx, event.ID)
+		if err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+		if availability.Version <= last {
+			t.Errorf("%s: version %d, want more than %d", step, availability.Version, last)
+		}
+		last = availability.Version
+	}
+
+	check("created")
+	for _, user := range users {
+		if _, err := f.registrations.RegisterForEvent(ctx, testActor, user, event.ID, models.RegistrationDetails{}); err != nil {
+			t.Fatalf("registering: %v", err)
+		}
+		check("registered")
+	}
+	if err := f.registrations.CancelRegistration(ctx, testActor, users[0], event.ID); err != nil {
+		t.Fatalf("cancelling: %v", err)
+	}
+	check("cancelled")
+	loaded, err := f.events.GetEventByID(ctx, event.ID)
+	if err != nil {
+		t.Fatalf("loading event: %v", err)
+	}
+	loaded.Capacity = 6
+	if err := f.events.UpdateEvent(ctx, testActor, loaded); err != nil {
+		t.Fatalf("raising capacity: %v", err)
+	}
+	check("capacity raised")
+}
+
+func TestUpdateEventCapacity(t *testing.T) {
+	// Capacity is changed through the event service, which needs GORM
+	cases := []struct {
+		name     string
+		open     func(t *testing.T, strategy SeatStrategy) *fixture
+		strategy SeatStrategy
+	}{
+		{"sqlite", newSQLiteFixture, SeatStrategyPessimistic},
+		{"postgres", newPostgresFixture, SeatStrategyPessimistic},
+		{"postgres/sharded", newPostgresFixture, strategySharded},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := tc.open(t, tc.strategy)
+			event := f.createEvent(t, 5)
+			users := f.createUsers(t, 4)
+
+			for _, user := range users[:3] {
+				if _, err := f.registrations.RegisterForEvent(context.Background(), testActor, user, event.ID, models.RegistrationDetails{}); err != nil {
+					t.Fatalf("registering: %v", err)
+				}
+			}
+
+			update := func(capacity int) error {
+				event, err := f.events.GetEventByID(context.Background(), event.ID)
+				if err != nil {
+					t.Fatalf("loading event: %v", err)
+				}
+				event.Capacity = capacity
+				return f.events.UpdateEvent(context.Background(), testActor, event)
+			}
+
+			// Raising capacity frees the new seats
+			if err := update(10); err != nil {
+				t.Fatalf("raising capacity: %v", err)
+			}
+			f.assertSeats(t, event.ID, 7, 3)
+
+			// Capacity cannot drop below the seats already taken
+			if err := update(2); !errors.Is(err, models.ErrCapacityTooLow) {
+				t.Fatalf("lowering below registrations error = %v, want %v", err, models.ErrCapacityTooLow)
+			}
+			f.assertSeats(t, event.ID, 7, 3)
+
+			// Lowering it to exactly the registrations sells the event out
+			if err := update(3); err != nil {
+				t.Fatalf("lowering capacity: %v", err)
+			}
+			f.assertSeats(t, event.ID, 0, 3)
+
+			if _, err := f.registrations.RegisterForEvent(context.Background(), testActor, users[3], event.ID, models.RegistrationDetails{}); !errors.Is(err, models.ErrEventFull) {
+				t.Fatalf("registering after sell-out error = %v, want %v", err, models.ErrEventFull)
+			}
+		})
+	}
+}
+
+func TestRecountSeats(t *testing.T) {
+	cases := []struct {
+		name     string
+		open     func(t *testing.T, strategy SeatStrategy) *fixture
+		strategy SeatStrategy
+	}{
+		{"sqlite", newSQLiteFixture, SeatStrategyPessimistic},
+		{"postgres", newPostgresFixture, SeatStrategyPessimistic},
+		{"postgres/sharded", newPostgresFixture, strategySharded},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := tc.open(t, tc.strategy)
+			event := f.createEvent(t, 5)
+			var registrationIDs []uint
+			for _, user := range f.createUsers(t, 3) {
+				registration, err := f.registrations.RegisterForEvent(context.Background(), testActor, user, event.ID, models.RegistrationDetails{})
+				if err != nil {
+					t.Fatalf("registering: %v", err)
+				}
+				registrationIDs = append(registrationIDs, registration.ID)
+			}
+
+			// Deleting behind the service's back leaves the seat taken
+			if err := f.registrationRepo.Delete(context.Background(), registrationIDs[0]); err != nil {
+				t.Fatalf("deleting registration: %v", err)
+			}
+			f.assertSeats(t, event.ID, 2, 2)
+
+			recount, err := f.events.RecountSeats(context.Background(), event.ID)
+			if err != nil {
+				t.Fatalf("recounting: %v", err)
+			}
+			want := models.SeatRecount{EventID: event.ID, Capacity: 5, Registered: 2, Before: 2, After: 3}
+			if *recount != want {
+				t.Errorf("recount = %+v, want %+v", *recount, want)
+			}
+			f.assertSeats(t, event.ID, 3, 2)
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	cases := []struct {
+		name     string
+		open     func(t *testing.T, strategy SeatStrategy) *fixture
+		strategy SeatStrategy
+	}{
+		{"sqlite", newSQLiteFixture, SeatStrategyPessimistic},
+		{"postgres", newPostgresFixture, SeatStrategyPessimistic},
+		{"postgres/sharded", newPostgresFixture, strategySharded},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := tc.open(t, tc.strategy)
+			reconciler := NewReconciliationService(f.eventRepo, f.events, false)
+			healthy := f.createEvent(t, 5)
+			drifted := f.createEvent(t, 5)
+			users := f.createUsers(t, 2)
+			for _, eventID := range []uint{healthy.ID, drifted.ID} {
+				for _, user := range users {
+					if _, err := f.registrations.RegisterForEvent(context.Background(), testActor, user, eventID, models.RegistrationDetails{}); err != nil {
+						t.Fatalf("registering: %v", err)
+					}
+				}
+			}
+			registration, err := f.registrationRepo.FindByUserAndEventID(context.Background(), users[0], drifted.ID)
+			if err != nil {
+				t.Fatalf("loading registration: %v", err)
+			}
+			if err := f.registrationRepo.Delete(context.Background(), registration.ID); err != nil {
+				t.Fatalf("deleting registration: %v", err)
+			}
+
+			// Reporting leaves the counter alone
+			report, err := reconciler.Reconcile(context.Background(), false)
+			if err != nil {
+				t.Fatalf("reconciling: %v", err)
+			}
+			want := models.SeatDrift{
+				SeatCount: models.SeatCount{EventID: drifted.ID, Capacity: 5, Available: 3, Registered: 1},
+				Expected:  4,
+			}
+			if report.EventsChecked != 2 || len(report.Drifts) != 1 || report.Drifts[0] != want {
+				t.Fatalf("report = %d checked, drifts %+v; want 2 checked, drifts [%+v]", report.EventsChecked, report.Drifts, want)
+			}
+			f.assertSeats(t, drifted.ID, 3, 1)
+
+			report, err = reconciler.Reconcile(context.Background(), true)
+			if err != nil {
+				t.Fatalf("correcting: %v", err)
+			}
+			if len(report.Drifts) != 1 || !report.Drifts[0].Corrected {
+				t.Fatalf("correcting drifts = %+v, want one corrected", report.Drifts)
+			}
+			f.assertSeats(t, drifted.ID, 4, 1)
+			f.assertSeats(t, healthy.ID, 3, 2)
+
+			if report, err = reconciler.Reconcile(context.Background(), false); err != nil || len(report.Drifts) != 0 {
+				t.Fatalf("after correcting drifts = %+v, err %v; want none", report, err)
+			}
+			stats := reconciler.Stats()
+			if stats.Runs != 3 || stats.DriftsFound != 2 || stats.DriftsCorrected != 1 || stats.LastDriftedEvents != 0 {
+				t.Errorf("stats = %+v, want 3 runs, 2 drifts found, 1 corrected, 0 last drifted", stats)
+			}
+		})
+	}
+}
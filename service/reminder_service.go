@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"event-api/models"
+	"event-api/repository"
+
+	"gorm.io/gorm"
+)
+
+// reminderBatchSize is how many due reminders one scheduler pass claims
+const reminderBatchSize = 20
+
+// ReminderService schedules and sends reminders before events start
+type ReminderService interface {
+	ScheduleForEventWithTx(ctx context.Context, tx repository.Tx, event *models.Event) error
+	CancelForEventWithTx(ctx context.Context, tx repository.Tx, eventID uint) error
+	SendDueReminders(ctx context.Context, now time.Time) (int, error)
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type reminderService struct {
+	db                  *gorm.DB
+	reminderRepo        repository.ReminderRepository
+	notificationService NotificationService
+	offsets             []time.Duration
+}
+
+// NewReminderService creates a new ReminderService that reminds attendees
+// at each of the given offsets before an event starts
+func NewReminderService(
+	db *gorm.DB,
+	reminderRepo repository.ReminderRepository,
+	notificationService NotificationService,
+	offsets []time.Duration,
+) ReminderService {
+	return &reminderService{
+		db:                  db,
+		reminderRepo:        reminderRepo,
+		notificationService: notificationService,
+		offsets:             offsets,
+	}
+}
+
+// ScheduleForEventWithTx (re)creates the reminders for an event from its
+// start time, within the transaction that changed the event. Reminders whose
+// due time has already passed are not scheduled, so moving an event never
+// re-sends a reminder that went out for the old time.
+func (s *reminderService) ScheduleForEventWithTx(ctx context.Context, tx repository.Tx, event *models.Event) error {
+	if event.StartsAt == nil {
+		return s.reminderRepo.DeleteByEventIDWithTx(ctx, tx, event.ID)
+	}
+
+	now := time.Now()
+	var reminders []models.EventReminder
+	for _, offset := range s.offsets {
+		dueAt := event.StartsAt.Add(-offset)
+		if !dueAt.After(now) {
+			continue
+		}
+		reminders = append(reminders, models.EventReminder{
+			EventID:       event.ID,
+			OffsetSeconds: int64(offset / time.Second),
+			DueAt:         dueAt,
+		})
+	}
+	return s.reminderRepo.ReplaceForEventWithTx(ctx, tx, event.ID, reminders)
+}
+
+// CancelForEventWithTx removes all pending reminders for an event within
+// the transaction that deleted it
+func (s *reminderService) CancelForEventWithTx(ctx context.Context, tx repository.Tx, eventID uint) error {
+	return s.reminderRepo.DeleteByEventIDWithTx(ctx, tx, eventID)
+}
+
+// SendDueReminders claims due reminders and queues a notification for every
+// confirmed attendee. Claiming, queueing and marking the reminder sent all
+// happen in one transaction, so a crash part-way through sends nothing and
+// the reminder is retried on the next pass.
+func (s *reminderService) SendDueReminders(ctx context.Context, now time.Time) (int, error) {
+	sent := 0
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		reminders, err := s.reminderRepo.ClaimDueWithTx(ctx, tx, now, reminderBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, reminder := range reminders {
+			if err := s.sendReminder(ctx, tx, reminder, now); err != nil {
+				return err
+			}
+			if err := s.reminderRepo.MarkSentWithTx(ctx, tx, reminder.ID, now); err != nil {
+				return err
+			}
+			sent++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return sent, nil
+}
+
+// sendReminder queues the reminder notification for each confirmed attendee
+// of the event; registrations still awaiting review are left out
+func (s *reminderService) sendReminder(ctx context.Context, tx *gorm.DB, reminder models.EventReminder, now time.Time) error {
+	event, err := s.reminderRepo.FindEventWithTx(ctx, tx, reminder.EventID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Event was deleted; nothing to remind anyone about
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Skip reminders that fell due after the event already started,
+	// e.g. because the scheduler was down
+	if event.StartsAt == nil || !event.StartsAt.After(now) {
+		return nil
+	}
+
+	users, err := s.reminderRepo.FindRecipientsWithTx(ctx, tx, event.ID)
+	if err != nil {
+		return err
+	}
+
+	for i := range users {
+		err := s.notificationService.EnqueueWithTx(ctx, tx, models.NotificationEventReminder, &users[i], event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run sends due reminders every interval until ctx is cancelled
+func (s *reminderService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Drain everything that is due before waiting for the next tick
+		for {
+			n, err := s.SendDueReminders(ctx, time.Now())
+			if err != nil {
+				slog.ErrorContext(ctx, "reminder scheduler failed", "err", err)
+			}
+			if err != nil || n < reminderBatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
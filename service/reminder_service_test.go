@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"event-api/internal/testdb"
+	"event-api/models"
+	"event-api/repository"
+
+	"gorm.io/gorm"
+)
+
+func TestReminders(t *testing.T) {
+	for name, open := range map[string]func(t testing.TB) *gorm.DB{
+		"sqlite":   testdb.NewSQLite,
+		"postgres": testdb.New,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			db := open(t)
+			f := newGormFixture(t, db, SeatStrategyPessimistic)
+			// Every replica has its own service over the shared database
+			newReminders := func() ReminderService {
+				return NewReminderService(db, repository.NewReminderRepository(db),
+					NewNotificationService(repository.NewNotificationRepository(db)), []time.Duration{24 * time.Hour, time.Hour})
+			}
+			reminders := newReminders()
+			events := NewEventService(repository.NewTransactor(db), f.eventRepo, f.registrationRepo, f.outboxRepo,
+				repository.NewInventoryRepository(db), f.auditRepo, reminders)
+
+			scheduled := func() []models.EventReminder {
+				t.Helper()
+				var rows []models.EventReminder
+				if err := db.Order("offset_seconds DESC").Find(&rows).Error; err != nil {
+					t.Fatal(err)
+				}
+				return rows
+			}
+
+			users := f.createUsers(t, 4)
+			startsAt := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+			event := &models.Event{Title: "Reminded", Capacity: 10, OrganizerID: users[0], StartsAt: &startsAt}
+			if err := events.CreateEvent(ctx, testActor, event); err != nil {
+				t.Fatalf("creating event: %v", err)
+			}
+			if rows := scheduled(); len(rows) != 2 || !rows[0].DueAt.Equal(startsAt.Add(-24*time.Hour)) || !rows[1].DueAt.Equal(startsAt.Add(-time.Hour)) {
+				t.Fatalf("reminders after create = %+v, want one a day and one an hour before", rows)
+			}
+
+			// Moving the event closer drops the reminder that is already past
+			startsAt = time.Now().Add(2 * time.Hour).Truncate(time.Second)
+			event.StartsAt = &startsAt
+			if err := events.UpdateEvent(ctx, testActor, event); err != nil {
+				t.Fatalf("updating event: %v", err)
+			}
+			rows := scheduled()
+			if len(rows) != 1 || !rows[0].DueAt.Equal(startsAt.Add(-time.Hour)) {
+				t.Fatalf("reminders after moving the event = %+v, want one an hour before", rows)
+			}
+
+			// Two attendees are confirmed; the third awaits review
+			for _, userID := range users[1:] {
+				if _, err := f.registrations.RegisterForEvent(ctx, testActor, userID, event.ID, models.RegistrationDetails{}); err != nil {
+					t.Fatalf("registering: %v", err)
+				}
+			}
+			if err := db.Model(&models.Registration{}).Where("user_id = ?", users[3]).Update("review", models.ReviewPending).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			// Two replicas poll at once; the reminder goes out exactly once
+			now := startsAt.Add(-30 * time.Minute)
+			var (
+				wg    sync.WaitGroup
+				mu    sync.Mutex
+				total int
+			)
+			for _, replica := range []ReminderService{reminders, newReminders()} {
+				wg.Go(func() {
+					n, err := replica.SendDueReminders(ctx, now)
+					if err != nil {
+						t.Errorf("sending reminders: %v", err)
+					}
+					mu.Lock()
+					total += n
+					mu.Unlock()
+				})
+			}
+			wg.Wait()
+			if total != 1 {
+				t.Errorf("replicas sent %d reminders, want 1", total)
+			}
+			var recipients []uint
+			if err := db.Model(&models.Notification{}).Where("type = ?", models.NotificationEventReminder).
+				Order("user_id").Pluck("user_id", &recipients).Error; err != nil {
+				t.Fatal(err)
+			}
+			if len(recipients) != 2 || recipients[0] != users[1] || recipients[1] != users[2] {
+				t.Errorf("reminded users %v, want the confirmed %v", recipients, users[1:3])
+			}
+
+			if err := events.DeleteEvent(ctx, testActor, event.ID); err != nil {
+				t.Fatalf("deleting event: %v", err)
+			}
+			if rows := scheduled(); len(rows) != 0 {
+				t.Errorf("reminders after delete = %+v, want none", rows)
+			}
+		})
+	}
+}
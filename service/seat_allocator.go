@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"event-api/models"
+	"event-api/repository"
+
+	"gorm.io/gorm"
+)
+
+// SeatStrategy names a SeatAllocator for events with row inventory
+type SeatStrategy string
+
+const (
+	// SeatStrategyPessimistic locks the event row with SELECT ... FOR UPDATE
+	// before checking and decrementing the seat count
+	SeatStrategyPessimistic SeatStrategy = "pessimistic"
+	// SeatStrategyOptimistic reads without a lock and decrements only if
+	// seats_version is unchanged, retrying when it moved on
+	SeatStrategyOptimistic SeatStrategy = "optimistic"
+	// SeatStrategyAtomic decrements in one conditional UPDATE ... RETURNING
+	SeatStrategyAtomic SeatStrategy = "atomic"
+)
+
+// SeatStrategies lists every row inventory strategy
+var SeatStrategies = []SeatStrategy{SeatStrategyPessimistic, SeatStrategyOptimistic, SeatStrategyAtomic}
+
+// Optimistic retry limits; the backoff is jittered so retries spread out
+const (
+	optimisticMaxAttempts = 20
+	optimisticBackoff     = 2 * time.Millisecond
+)
+
+/*
+SeatAllocator takes and gives back seats within a registration transaction.
+
+ReserveWithTx takes one seat and returns the event as it is afterwards. It
+returns ErrEventFull when no seat is left and ErrEventNotFound for unknown
+events. ReleaseWithTx gives one seat back. Both queue an availability
+notification that Postgres delivers when tx commits.
+*/
+type SeatAllocator interface {
+	ReserveWithTx(ctx context.Context, tx repository.Tx, eventID uint) (*models.Event, error)
+	ReleaseWithTx(ctx context.Context, tx repository.Tx, eventID uint) error
+}
+
+// NewSeatAllocator returns the allocator for registrations: events with
+// sharded inventory always use their shards, all other events use strategy
+func NewSeatAllocator(
+	strategy SeatStrategy,
+	eventRepo repository.EventRepository,
+	inventoryRepo repository.InventoryRepository,
+) (SeatAllocator, error) {
+	row, err := NewRowSeatAllocator(strategy, eventRepo)
+	if err != nil {
+		return nil, err
+	}
+	return &inventoryRouter{
+		eventRepo: eventRepo,
+		row:       row,
+		sharded:   &shardedAllocator{eventRepo: eventRepo, inventoryRepo: inventoryRepo},
+	}, nil
+}
+
+// NewRowSeatAllocator returns the allocator for a row inventory strategy
+func NewRowSeatAllocator(strategy SeatStrategy, eventRepo repository.EventRepository) (SeatAllocator, error) {
+	switch strategy {
+	case SeatStrategyPessimistic:
+		return &pessimisticAllocator{eventRepo: eventRepo}, nil
+	case SeatStrategyOptimistic:
+		return &optimisticAllocator{eventRepo: eventRepo}, nil
+	case SeatStrategyAtomic:
+		return &atomicAllocator{eventRepo: eventRepo}, nil
+	default:
+		return nil, fmt.Errorf("unknown seat strategy %q", strategy)
+	}
+}
+
+// inventoryRouter sends each event to the allocator for its inventory mode
+type inventoryRouter struct {
+	eventRepo repository.EventRepository
+	row       SeatAllocator
+	sharded   SeatAllocator
+}
+
+func (r *inventoryRouter) ReserveWithTx(ctx context.Context, tx repository.Tx, eventID uint) (*models.Event, error) {
+	allocator, err := r.allocatorFor(ctx, tx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	return allocator.ReserveWithTx(ctx, tx, eventID)
+}
+
+func (r *inventoryRouter) ReleaseWithTx(ctx context.Context, tx repository.Tx, eventID uint) error {
+	allocator, err := r.allocatorFor(ctx, tx, eventID)
+	if err != nil {
+		return err
+	}
+	return allocator.ReleaseWithTx(ctx, tx, eventID)
+}
+
+// allocatorFor looks up an event's inventory mode without locking its row.
+// Unknown events go to the row allocator, which reports them.
+func (r *inventoryRouter) allocatorFor(ctx context.Context, tx repository.Tx, eventID uint) (SeatAllocator, error) {
+	event, err := r.eventRepo.FindByIDWithTx(ctx, tx, eventID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return r.row, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if event.Inventory == models.InventorySharded {
+		return r.sharded, nil
+	}
+	return r.row, nil
+}
+
+// pessimisticAllocator serializes every registration for an event on the
+// event row lock
+type pessimisticAllocator struct {
+	eventRepo repository.EventRepository
+}
+
+func (a *pessimisticAllocator) ReserveWithTx(ctx context.Context, tx repository.Tx, eventID uint) (*models.Event, error) {
+	// CRITICAL: Lock the event row using SELECT FOR UPDATE
+	// This prevents other transactions from modifying this row until we commit/rollback
+	event, err := a.eventRepo.FindByIDForUpdate(ctx, tx, eventID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrEventNotFound
+		}
+		return nil, err
+	}
+
+	// CRITICAL: Check if seats are available
+	// This check happens AFTER acquiring the lock, so it's safe
+	if event.AvailableSeats <= 0 {
+		return nil, models.ErrEventFull
+	}
+
+	// The UPDATE's WHERE available_seats > 0 is an additional safety net
+	if err := a.eventRepo.DecreaseAvailableSeats(ctx, tx, eventID); err != nil {
+		return nil, err
+	}
+	event.AvailableSeats--
+
+	return event, a.eventRepo.NotifyAvailabilityWithTx(ctx, tx, eventID)
+}
+
+func (a *pessimisticAllocator) ReleaseWithTx(ctx context.Context, tx repository.Tx, eventID uint) error {
+	return releaseRowSeatWithTx(ctx, a.eventRepo, tx, eventID)
+}
+
+// optimisticAllocator reads without locking and retries when another
+// registration changed the seats in between
+type optimisticAllocator struct {
+	eventRepo repository.EventRepository
+}
+
+func (a *optimisticAllocator) ReserveWithTx(ctx context.Context, tx repository.Tx, eventID uint) (*models.Event, error) {
+	for attempt := 1; attempt <= optimisticMaxAttempts; attempt++ {
+		// Each statement sees the latest committed data, so a retry inside
+		// the same transaction reads the new version
+		event, err := a.eventRepo.FindByIDWithTx(ctx, tx, eventID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, models.ErrEventNotFound
+			}
+			return nil, err
+		}
+		if event.AvailableSeats <= 0 {
+			return nil, models.ErrEventFull
+		}
+
+		ok, err := a.eventRepo.DecreaseAvailableSeatsIfVersion(ctx, tx, eventID, event.SeatsVersion)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			event.AvailableSeats--
+			return event, a.eventRepo.NotifyAvailabilityWithTx(ctx, tx, eventID)
+		}
+
+		// Back off, but give up as soon as the registration times out
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Duration(rand.Int64N(int64(optimisticBackoff) * int64(attempt)))):
+		}
+	}
+	return nil, models.ErrSeatContention
+}
+
+func (a *optimisticAllocator) ReleaseWithTx(ctx context.Context, tx repository.Tx, eventID uint) error {
+	return releaseRowSeatWithTx(ctx, a.eventRepo, tx, eventID)
+}
+
+// atomicAllocator takes a seat with one conditional UPDATE and no prior read
+type atomicAllocator struct {
+	eventRepo repository.EventRepository
+}
+
+func (a *atomicAllocator) ReserveWithTx(ctx context.Context, tx repository.Tx, eventID uint) (*models.Event, error) {
+	event, err := a.eventRepo.DecreaseAvailableSeatsReturning(ctx, tx, eventID)
+	if errors.Is(err, models.ErrEventFull) {
+		// No row matched: either the event is full or it does not exist
+		if _, err := a.eventRepo.FindByIDWithTx(ctx, tx, eventID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, models.ErrEventNotFound
+			}
+			return nil, err
+		}
+		return nil, models.ErrEventFull
+	}
+	if err != nil {
+		return nil, err
+	}
+	return event, a.eventRepo.NotifyAvailabilityWithTx(ctx, tx, eventID)
+}
+
+func (a *atomicAllocator) ReleaseWithTx(ctx context.Context, tx repository.Tx, eventID uint) error {
+	return releaseRowSeatWithTx(ctx, a.eventRepo, tx, eventID)
+}
+
+// releaseRowSeatWithTx gives a seat back to a row inventory event
+func releaseRowSeatWithTx(ctx context.Context, eventRepo repository.EventRepository, tx repository.Tx, eventID uint) error {
+	if err := eventRepo.IncreaseAvailableSeats(ctx, tx, eventID); err != nil {
+		return err
+	}
+	return eventRepo.NotifyAvailabilityWithTx(ctx, tx, eventID)
+}
+
+// shardedAllocator takes seats from event_inventory_shards and never locks
+// the event row
+type shardedAllocator struct {
+	eventRepo     repository.EventRepository
+	inventoryRepo repository.InventoryRepository
+}
+
+func (a *shardedAllocator) ReserveWithTx(ctx context.Context, tx repository.Tx, eventID uint) (*models.Event, error) {
+	event, err := a.eventRepo.FindByIDWithTx(ctx, tx, eventID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrEventNotFound
+		}
+		return nil, err
+	}
+
+	// CRITICAL: Lock and decrement one non-empty shard. The decrement only
+	// applies while the shard has seats, so the shards can never oversell.
+	if err := a.inventoryRepo.TakeSeatWithTx(ctx, tx, eventID); err != nil {
+		return nil, err
+	}
+
+	totals, err := a.inventoryRepo.TotalsWithTx(ctx, tx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	event.AvailableSeats = totals.Available
+	event.SeatsVersion = totals.Version
+
+	return event, a.inventoryRepo.NotifyAvailabilityWithTx(ctx, tx, eventID)
+}
+
+func (a *shardedAllocator) ReleaseWithTx(ctx context.Context, tx repository.Tx, eventID uint) error {
+	if err := a.inventoryRepo.ReturnSeatWithTx(ctx, tx, eventID); err != nil {
+		return err
+	}
+	return a.inventoryRepo.NotifyAvailabilityWithTx(ctx, tx, eventID)
+}
@@ -1,55 +1,163 @@
 package service
 
 import (
+	"context"
+	"fmt"
+	"time"
+
+	"event-api/credential"
 	"event-api/models"
 	"event-api/repository"
+	"event-api/tracing"
 )
 
 // UserService handles user business logic
 type UserService interface {
-	CreateUser(user *models.User) error
-	GetUserByID(id uint) (*models.User, error)
-	GetUserByEmail(email string) (*models.User, error)
-	GetAllUsers() ([]models.User, error)
-	UpdateUser(user *models.User) error
-	DeleteUser(id uint) error
+	CreateUser(ctx context.Context, actor models.Actor, user *models.User) error
+	GetUserByID(ctx context.Context, id uint) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetAllUsers(ctx context.Context) ([]models.User, error)
+	UpdateUser(ctx context.Context, actor models.Actor, user *models.User) error
+	DeleteUser(ctx context.Context, actor models.Actor, id uint) error
 }
 
 type userService struct {
-	userRepo repository.UserRepository
+	transactor repository.Transactor
+	userRepo   repository.UserRepository
+	tokenRepo  repository.TokenRepository
+	auditRepo  repository.AuditRepository
 }
 
 // NewUserService creates a new UserService
-func NewUserService(userRepo repository.UserRepository) UserService {
-	return &userService{userRepo: userRepo}
+func NewUserService(transactor repository.Transactor, userRepo repository.UserRepository, tokenRepo repository.TokenRepository, auditRepo repository.AuditRepository) UserService {
+	return &userService{
+		transactor: transactor,
+		userRepo:   userRepo,
+		tokenRepo:  tokenRepo,
+		auditRepo:  auditRepo,
+	}
 }
 
-// CreateUser creates a new user
-func (s *userService) CreateUser(user *models.User) error {
-	return s.userRepo.Create(user)
+// CreateUser creates a new user and audits it. The email address must be
+// valid and starts out unverified; a password is optional and only its hash
+// is stored.
+func (s *userService) CreateUser(ctx context.Context, actor models.Actor, user *models.User) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.CreateUser")
+	defer func() { tracing.End(span, err) }()
+
+	if err := models.ValidateEmail(user.Email); err != nil {
+		return err
+	}
+	if user.Password != "" {
+		if err := models.ValidatePassword(user.Password); err != nil {
+			return err
+		}
+		if user.PasswordHash, err = credential.HashPassword(user.Password); err != nil {
+			return err
+		}
+	}
+	user.Password = ""
+	user.EmailVerifiedAt, user.DeactivatedAt, user.ErasedAt = nil, nil, nil
+
+	err = s.transactor.Transaction(ctx, func(tx repository.Tx) error {
+		if err := s.userRepo.CreateWithTx(ctx, tx, user); err != nil {
+			return err
+		}
+		return s.auditWithTx(ctx, tx, actor, models.ActionCreateUser, user.ID, nil, user)
+	})
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(tracing.UserID(user.ID))
+	return nil
 }
 
 // GetUserByID gets a user by ID
-func (s *userService) GetUserByID(id uint) (*models.User, error) {
-	return s.userRepo.FindByID(id)
+func (s *userService) GetUserByID(ctx context.Context, id uint) (_ *models.User, err error) {
+	_, span := tracing.Start(ctx, "UserService.GetUserByID", tracing.UserID(id))
+	defer func() { tracing.End(span, err) }()
+	return s.userRepo.FindByID(ctx, id)
 }
 
 // GetUserByEmail gets a user by email
-func (s *userService) GetUserByEmail(email string) (*models.User, error) {
-	return s.userRepo.FindByEmail(email)
+func (s *userService) GetUserByEmail(ctx context.Context, email string) (_ *models.User, err error) {
+	_, span := tracing.Start(ctx, "UserService.GetUserByEmail")
+	defer func() { tracing.End(span, err) }()
+	return s.userRepo.FindByEmail(ctx, email)
 }
 
 // GetAllUsers gets all users
-func (s *userService) GetAllUsers() ([]models.User, error) {
-	return s.userRepo.FindAll()
+func (s *userService) GetAllUsers(ctx context.Context) (_ []models.User, err error) {
+	_, span := tracing.Start(ctx, "UserService.GetAllUsers")
+	defer func() { tracing.End(span, err) }()
+	return s.userRepo.FindAll(ctx)
+}
+
+// UpdateUser updates a user and audits the change. It returns
+// gorm.ErrRecordNotFound when the user does not exist and ErrUserErased when
+// their data has been erased. Changing the email address makes it unverified
+// again and revokes the tokens mailed to the old one; the password and account state are changed through AccountService
+// only.
+func (s *userService) UpdateUser(ctx context.Context, actor models.Actor, user *models.User) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUser", tracing.UserID(user.ID))
+	defer func() { tracing.End(span, err) }()
+
+	if err := models.ValidateEmail(user.Email); err != nil {
+		return err
+	}
+	if user.Password != "" {
+		return fmt.Errorf("%w: the password can only be changed with a reset token", models.ErrInvalidInput)
+	}
+
+	return s.transactor.Transaction(ctx, func(tx repository.Tx) error {
+		existing, err := s.userRepo.FindByIDWithTx(ctx, tx, user.ID)
+		if err != nil {
+			return err
+		}
+		if existing.ErasedAt != nil {
+			return models.ErrUserErased
+		}
+		user.CreatedAt = existing.CreatedAt
+		user.PasswordHash = existing.PasswordHash
+		user.EmailVerifiedAt, user.DeactivatedAt = existing.EmailVerifiedAt, existing.DeactivatedAt
+		user.ErasedAt = nil
+		if user.Email != existing.Email {
+			// Tokens mailed to the old address stop working
+			user.EmailVerifiedAt = nil
+			if err := s.tokenRepo.RevokeAllWithTx(ctx, tx, user.ID, time.Now()); err != nil {
+				return err
+			}
+		}
+		if err := s.userRepo.UpdateWithTx(ctx, tx, user); err != nil {
+			return err
+		}
+		return s.auditWithTx(ctx, tx, actor, models.ActionUpdateUser, user.ID, existing, user)
+	})
 }
 
-// UpdateUser updates a user
-func (s *userService) UpdateUser(user *models.User) error {
-	return s.userRepo.Update(user)
+// DeleteUser deletes a user and audits it. It returns
+// gorm.ErrRecordNotFound when the user does not exist.
+func (s *userService) DeleteUser(ctx context.Context, actor models.Actor, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.DeleteUser", tracing.UserID(id))
+	defer func() { tracing.End(span, err) }()
+
+	return s.transactor.Transaction(ctx, func(tx repository.Tx) error {
+		existing, err := s.userRepo.FindByIDWithTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.userRepo.DeleteWithTx(ctx, tx, id); err != nil {
+			return err
+		}
+		return s.auditWithTx(ctx, tx, actor, models.ActionDeleteUser, id, existing, nil)
+	})
 }
 
-// DeleteUser deletes a user
-func (s *userService) DeleteUser(id uint) error {
-	return s.userRepo.Delete(id)
+// auditWithTx writes the audit entry of a change to a user within tx
+func (s *userService) auditWithTx(ctx context.Context, tx repository.Tx, actor models.Actor, action models.Action, id uint, before, after *models.User) error {
+	entry, err := models.NewAuditEvent(actor, action, models.AuditTargetUser, id, before, after)
+	if err != nil {
+		return err
+	}
+	return s.auditRepo.CreateWithTx(ctx, tx, entry)
 }
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"event-api/models"
+	"event-api/repository"
+	"event-api/waitingroom"
+
+	"gorm.io/gorm"
+)
+
+// admitChecksPerInterval is how many times per AdmitInterval each replica
+// looks for waiting rooms that are due, which bounds how late a batch is
+const admitChecksPerInterval = 10
+
+// WaitingRoomConfig controls how fast users are let out of waiting rooms
+type WaitingRoomConfig struct {
+	Secret        []byte        // signs queue tokens; must match on every replica
+	AdmitBatch    int           // users admitted per event per interval
+	AdmitInterval time.Duration // time between admission batches
+	AdmissionTTL  time.Duration // how long an admitted user has to register
+}
+
+// WaitingRoomService queues users in front of registration for high-demand
+// events, so only admitted users reach the seat lock
+type WaitingRoomService interface {
+	Join(ctx context.Context, eventID, userID uint) (*models.QueueStatus, error)
+	GetStatus(ctx context.Context, eventID uint, token string) (*models.QueueStatus, error)
+	Authorize(ctx context.Context, eventID, userID uint, token string) error
+	AdmitNext(ctx context.Context, now time.Time) (int64, error)
+	Run(ctx context.Context)
+}
+
+type waitingRoomService struct {
+	db              *gorm.DB
+	eventRepo       repository.EventRepository
+	userRepo        repository.UserRepository
+	waitingRoomRepo repository.WaitingRoomRepository
+	cfg             WaitingRoomConfig
+}
+
+// NewWaitingRoomService creates a new WaitingRoomService
+func NewWaitingRoomService(
+	db *gorm.DB,
+	eventRepo repository.EventRepository,
+	userRepo repository.UserRepository,
+	waitingRoomRepo repository.WaitingRoomRepository,
+	cfg WaitingRoomConfig,
+) WaitingRoomService {
+	return &waitingRoomService{
+		db:              db,
+		eventRepo:       eventRepo,
+		userRepo:        userRepo,
+		waitingRoomRepo: waitingRoomRepo,
+		cfg:             cfg,
+	}
+}
+
+// Join puts a user at the back of an event's waiting room and returns their
+// token. Joining again returns the existing place, unless the user's
+// admission expired, in which case they rejoin at the back.
+func (s *waitingRoomService) Join(ctx context.Context, eventID, userID uint) (*models.QueueStatus, error) {
+	event, err := s.eventRepo.FindByID(ctx, eventID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrEventNotFound
+		}
+		return nil, err
+	}
+	if !event.WaitingRoom {
+		return nil, models.ErrWaitingRoomDisabled
+	}
+	if _, err := s.userRepo.FindByID(ctx, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	entry, err := s.waitingRoomRepo.FindByEventAndUser(ctx, eventID, userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if entry != nil && s.expired(entry, time.Now()) {
+		if err := s.waitingRoomRepo.Delete(ctx, entry.ID); err != nil {
+			return nil, err
+		}
+		entry = nil
+	}
+	if entry == nil {
+		if err := s.waitingRoomRepo.Create(ctx, &models.WaitingRoomEntry{EventID: eventID, UserID: userID}); err != nil {
+			return nil, err
+		}
+		// Re-read in case a concurrent join won the unique constraint
+		if entry, err = s.waitingRoomRepo.FindByEventAndUser(ctx, eventID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	status, err := s.status(ctx, entry, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	status.Token = waitingroom.Sign(s.cfg.Secret, waitingroom.Claims{
+		EntryID: entry.ID,
+		EventID: entry.EventID,
+		UserID:  entry.UserID,
+	})
+	return status, nil
+}
+
+// GetStatus reports a token holder's position and estimated wait
+func (s *waitingRoomService) GetStatus(ctx context.Context, eventID uint, token string) (*models.QueueStatus, error) {
+	entry, err := s.entryForToken(ctx, eventID, token)
+	if err != nil {
+		return nil, err
+	}
+	return s.status(ctx, entry, time.Now())
+}
+
+// Authorize checks that a user may register for an event. Events without a
+// waiting room are always open; otherwise the user needs the token of an
+// unexpired admission. Unknown events are let through so registration can
+// report them.
+func (s *waitingRoomService) Authorize(ctx context.Context, eventID, userID uint, token string) error {
+	event, err := s.eventRepo.FindByID(ctx, eventID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !event.WaitingRoom {
+		return nil
+	}
+
+	entry, err := s.entryForToken(ctx, eventID, token)
+	if err != nil {
+		return err
+	}
+	if entry.UserID != userID {
+		return models.ErrInvalidQueueToken
+	}
+	if entry.AdmittedAt == nil {
+		return models.ErrNotAdmitted
+	}
+	if s.expired(entry, time.Now()) {
+		return models.ErrAdmissionExpired
+	}
+	return nil
+}
+
+// AdmitNext admits the next batch from every waiting room that has not
+// admitted one within the last AdmitInterval, and schedules its next batch.
+// The schedules are shared through the database, so each waiting room
+// admits one batch per interval however many replicas call AdmitNext; a
+// replica that finds nothing due, or the admitter lock taken, returns 0.
+func (s *waitingRoomService) AdmitNext(ctx context.Context, now time.Time) (int64, error) {
+	var admitted int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		locked, err := s.waitingRoomRepo.TryLockAdmitterWithTx(ctx, tx)
+		if err != nil || !locked {
+			return err
+		}
+		due, err := s.waitingRoomRepo.FindDueEventIDsWithTx(ctx, tx, now)
+		if err != nil || len(due) == 0 {
+			return err
+		}
+		admitted, err = s.waitingRoomRepo.AdmitNextWithTx(ctx, tx, due, s.cfg.AdmitBatch, now, now.Add(s.cfg.AdmissionTTL))
+		if err != nil {
+			return err
+		}
+		return s.waitingRoomRepo.ScheduleWithTx(ctx, tx, due, now.Add(s.cfg.AdmitInterval))
+	})
+	return admitted, err
+}
+
+// Run checks for due waiting rooms several times per AdmitInterval until
+// ctx is cancelled
+func (s *waitingRoomService) Run(ctx context.Context) {
+	ticker := time.NewTicker(max(s.cfg.AdmitInterval/admitChecksPerInterval, time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := s.AdmitNext(ctx, time.Now()); err != nil {
+			slog.ErrorContext(ctx, "waiting room admitter failed", "err", err)
+		}
+	}
+}
+
+// entryForToken verifies a token and loads its entry. Tokens for entries
+// that were replaced by a rejoin no longer resolve.
+func (s *waitingRoomService) entryForToken(ctx context.Context, eventID uint, token string) (*models.WaitingRoomEntry, error) {
+	claims, err := waitingroom.Parse(s.cfg.Secret, token)
+	if err != nil || claims.EventID != eventID {
+		return nil, models.ErrInvalidQueueToken
+	}
+	entry, err := s.waitingRoomRepo.FindByID(ctx, claims.EntryID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrInvalidQueueToken
+		}
+		return nil, err
+	}
+	if entry.EventID != claims.EventID || entry.UserID != claims.UserID {
+		return nil, models.ErrInvalidQueueToken
+	}
+	return entry, nil
+}
+
+// status describes an entry as of now
+func (s *waitingRoomService) status(ctx context.Context, entry *models.WaitingRoomEntry, now time.Time) (*models.QueueStatus, error) {
+	status := &models.QueueStatus{EventID: entry.EventID}
+	switch {
+	case s.expired(entry, now):
+		status.Status = models.QueueExpired
+	case entry.AdmittedAt != nil:
+		status.Status = models.QueueAdmitted
+		status.AdmittedUntil = entry.ExpiresAt
+	default:
+		position, err := s.waitingRoomRepo.CountWaitingUpTo(ctx, entry.EventID, entry.ID)
+		if err != nil {
+			return nil, err
+		}
+		status.Status = models.QueueWaiting
+		status.Position = position
+		status.EstimatedWaitSeconds = s.estimateWait(position)
+	}
+	return status, nil
+}
+
+// estimateWait returns roughly how long until the given position is admitted
+func (s *waitingRoomService) estimateWait(position int64) int64 {
+	batch := int64(max(s.cfg.AdmitBatch, 1))
+	batches := (position + batch - 1) / batch
+	return int64((time.Duration(batches) * s.cfg.AdmitInterval).Round(time.Second) / time.Second)
+}
+
+// expired reports whether an admitted entry's registration window has passed
+func (s *waitingRoomService) expired(entry *models.WaitingRoomEntry, now time.Time) bool {
+	return entry.ExpiresAt != nil && now.After(*entry.ExpiresAt)
+}
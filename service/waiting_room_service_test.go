@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"event-api/internal/testdb"
+	"event-api/models"
+	"event-api/repository"
+
+	"gorm.io/gorm"
+)
+
+func TestWaitingRoomAdmitsOneBatchPerIntervalAcrossReplicas(t *testing.T) {
+	for name, open := range map[string]func(t testing.TB) *gorm.DB{
+		"sqlite":   testdb.NewSQLite,
+		"postgres": testdb.New,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			db := open(t)
+			f := newGormFixture(t, db, SeatStrategyPessimistic)
+			cfg := WaitingRoomConfig{
+				Secret:        []byte("test"),
+				AdmitBatch:    2,
+				AdmitInterval: 10 * time.Second,
+				AdmissionTTL:  time.Minute,
+			}
+			// Two replicas share the database
+			replicas := make([]WaitingRoomService, 2)
+			for i := range replicas {
+				replicas[i] = NewWaitingRoomService(db, f.eventRepo, f.userRepo, repository.NewWaitingRoomRepository(db), cfg)
+			}
+
+			newWaitingRoom := func(users []uint) *models.Event {
+				t.Helper()
+				event := f.createEvent(t, 100)
+				if err := db.Model(event).Update("waiting_room", true).Error; err != nil {
+					t.Fatal(err)
+				}
+				for _, userID := range users {
+					if _, err := replicas[0].Join(ctx, event.ID, userID); err != nil {
+						t.Fatalf("joining: %v", err)
+					}
+				}
+				return event
+			}
+			admitted := func(event *models.Event) int64 {
+				t.Helper()
+				var n int64
+				if err := db.Model(&models.WaitingRoomEntry{}).Where("event_id = ? AND admitted_at IS NOT NULL", event.ID).Count(&n).Error; err != nil {
+					t.Fatal(err)
+				}
+				return n
+			}
+
+			users := f.createUsers(t, 10)
+			first := newWaitingRoom(users)
+			start := time.Now()
+
+			// Both replicas check often; each interval admits one batch
+			for _, tick := range []struct {
+				replica int
+				after   time.Duration
+				want    int64
+			}{
+				{0, 0, 2},
+				{1, time.Second, 0},
+				{0, 9 * time.Second, 0},
+				{1, 10 * time.Second, 2},
+				{0, 10*time.Second + time.Millisecond, 0},
+				{1, 15 * time.Second, 0},
+				{0, 20 * time.Second, 2},
+			} {
+				n, err := replicas[tick.replica].AdmitNext(ctx, start.Add(tick.after))
+				if err != nil {
+					t.Fatalf("admitting: %v", err)
+				}
+				if n != tick.want {
+					t.Fatalf("replica %d admitted %d at +%v, want %d", tick.replica, n, tick.after, tick.want)
+				}
+			}
+			if got := admitted(first); got != 6 {
+				t.Fatalf("%d users admitted after 3 intervals, want 6", got)
+			}
+
+			// Another waiting room keeps its own schedule
+			second := newWaitingRoom(users[:3])
+			if n, err := replicas[1].AdmitNext(ctx, start.Add(25*time.Second)); err != nil || n != 2 {
+				t.Fatalf("admitting the second waiting room = %d, %v; want 2", n, err)
+			}
+			if got := admitted(second); got != 2 {
+				t.Fatalf("%d users admitted from the second waiting room, want 2", got)
+			}
+		})
+	}
+}
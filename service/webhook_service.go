@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"event-api/models"
+	"event-api/repository"
+	"event-api/webhook"
+
+	"gorm.io/gorm"
+)
+
+// deliveryLogLimit caps how many deliveries are returned for a subscription
+const deliveryLogLimit = 100
+
+// WebhookService manages webhook subscriptions and queues deliveries
+type WebhookService interface {
+	CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	GetSubscription(ctx context.Context, organizerID, id uint) (*models.WebhookSubscription, error)
+	GetSubscriptions(ctx context.Context, organizerID uint) ([]models.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	RotateSecret(ctx context.Context, organizerID, id uint) (*models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, organizerID, id uint) error
+	GetDeliveries(ctx context.Context, organizerID, subscriptionID uint) ([]models.WebhookDelivery, error)
+
+	DispatchWithTx(ctx context.Context, tx *gorm.DB, eventID string, organizerID uint, eventType models.WebhookEventType, data interface{}) error
+	HandleOutboxEvent(ctx context.Context, tx *gorm.DB, event models.OutboxEvent) error
+}
+
+type webhookService struct {
+	webhookRepo repository.WebhookRepository
+}
+
+// NewWebhookService creates a new WebhookService
+func NewWebhookService(webhookRepo repository.WebhookRepository) WebhookService {
+	return &webhookService{webhookRepo: webhookRepo}
+}
+
+// CreateSubscription validates and stores a subscription, generating a secret if none was given
+func (s *webhookService) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	if err := validateSubscription(subscription); err != nil {
+		return err
+	}
+	if subscription.Secret == "" {
+		subscription.Secret = webhook.NewSecret()
+	}
+	return s.webhookRepo.CreateSubscription(ctx, subscription)
+}
+
+// GetSubscription gets one of an organizer's subscriptions
+func (s *webhookService) GetSubscription(ctx context.Context, organizerID, id uint) (*models.WebhookSubscription, error) {
+	subscription, err := s.webhookRepo.FindSubscriptionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if subscription.OrganizerID != organizerID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return subscription, nil
+}
+
+// GetSubscriptions gets all of an organizer's subscriptions
+func (s *webhookService) GetSubscriptions(ctx context.Context, organizerID uint) ([]models.WebhookSubscription, error) {
+	return s.webhookRepo.FindSubscriptionsByOrganizerID(ctx, organizerID)
+}
+
+// UpdateSubscription replaces a subscription's settings, keeping its secret
+func (s *webhookService) UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	existing, err := s.GetSubscription(ctx, subscription.OrganizerID, subscription.ID)
+	if err != nil {
+		return err
+	}
+	if err := validateSubscription(subscription); err != nil {
+		return err
+	}
+	subscription.Secret = existing.Secret
+	subscription.CreatedAt = existing.CreatedAt
+	return s.webhookRepo.UpdateSubscription(ctx, subscription)
+}
+
+// RotateSecret replaces a subscription's signing secret with a new one.
+// Deliveries already claimed may still be signed with the old secret.
+func (s *webhookService) RotateSecret(ctx context.Context, organizerID, id uint) (*models.WebhookSubscription, error) {
+	subscription, err := s.GetSubscription(ctx, organizerID, id)
+	if err != nil {
+		return nil, err
+	}
+	subscription.Secret = webhook.NewSecret()
+	if err := s.webhookRepo.UpdateSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// DeleteSubscription deletes one of an organizer's subscriptions
+func (s *webhookService) DeleteSubscription(ctx context.Context, organizerID, id uint) error {
+	if _, err := s.GetSubscription(ctx, organizerID, id); err != nil {
+		return err
+	}
+	return s.webhookRepo.DeleteSubscription(ctx, id)
+}
+
+// GetDeliveries gets the most recent delivery log entries for a subscription
+func (s *webhookService) GetDeliveries(ctx context.Context, organizerID, subscriptionID uint) ([]models.WebhookDelivery, error) {
+	if _, err := s.GetSubscription(ctx, organizerID, subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.FindDeliveriesBySubscriptionID(ctx, subscriptionID, deliveryLogLimit)
+}
+
+// HandleOutboxEvent queues webhook deliveries for a domain event.
+// It is registered as an outbox relay subscriber.
+func (s *webhookService) HandleOutboxEvent(ctx context.Context, tx *gorm.DB, event models.OutboxEvent) error {
+	payload, err := event.DecodePayload()
+	if err != nil {
+		return err
+	}
+
+	// Registration events carry the registration with its user and event;
+	// event lifecycle events carry the event itself
+	var data interface{} = payload.Event
+	if payload.Registration != nil {
+		registration := *payload.Registration
+		registration.Event = payload.Event
+		data = &registration
+	}
+
+	// Derive the envelope ID from the outbox row so a redelivered domain
+	// event reaches subscribers with the same ID
+	eventID := fmt.Sprintf("evt_%d", event.ID)
+	return s.DispatchWithTx(ctx, tx, eventID, payload.Event.OrganizerID, models.WebhookEventType(event.Type), data)
+}
+
+// DispatchWithTx queues a delivery inside tx for every subscription that wants eventType
+func (s *webhookService) DispatchWithTx(ctx context.Context, tx *gorm.DB, eventID string, organizerID uint, eventType models.WebhookEventType, data interface{}) error {
+	subscriptions, err := s.webhookRepo.FindActiveSubscriptionsWithTx(ctx, tx, organizerID)
+	if err != nil {
+		return err
+	}
+
+	var deliveries []models.WebhookDelivery
+	var payload string
+	for _, sub := range subscriptions {
+		if !sub.Wants(eventType) {
+			continue
+		}
+		// Encode lazily so organizers without webhooks pay nothing
+		if payload == "" {
+			body, err := webhook.NewPayload(eventID, eventType, data)
+			if err != nil {
+				return err
+			}
+			payload = string(body)
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventID:        eventID,
+			EventType:      eventType,
+			Payload:        payload,
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+		})
+	}
+	return s.webhookRepo.CreateDeliveriesWithTx(ctx, tx, deliveries)
+}
+
+// validateSubscription checks the endpoint URL and requested event types
+func validateSubscription(subscription *models.WebhookSubscription) error {
+	u, err := url.Parse(subscription.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", models.ErrInvalidInput)
+	}
+	if len(subscription.EventTypes) == 0 {
+		return fmt.Errorf("%w: at least one event type is required", models.ErrInvalidInput)
+	}
+	for _, t := range subscription.EventTypes {
+		if !t.Valid() {
+			return fmt.Errorf("%w: unknown event type %q", models.ErrInvalidInput, t)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey holds a statement's span on the GORM instance
+const spanKey = "tracing:span"
+
+// InstrumentDB traces every statement db runs within a traced context, such
+// as a request's transaction. Statements of background workers and other
+// untraced contexts are left alone, so they cannot start traces of their own.
+func InstrumentDB(db *gorm.DB) error {
+	return db.Use(&gormPlugin{system: db.Dialector.Name()})
+}
+
+// gormPlugin starts and ends statement spans through GORM callbacks
+type gormPlugin struct {
+	system string
+}
+
+// Name identifies the plugin to GORM
+func (p *gormPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize registers a callback before and after each kind of statement
+func (p *gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", p.start("create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", p.end),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", p.start("query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", p.end),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", p.start("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", p.end),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", p.start("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", p.end),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", p.start("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", p.end),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", p.start("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", p.end),
+	)
+}
+
+// start returns a callback starting the span of a statement, named like
+// "gorm.query events"
+func (p *gormPlugin) start(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+			return
+		}
+		name := "gorm." + operation
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+		_, span := Start(ctx, name,
+			attribute.String("db.system", p.system),
+			attribute.String("db.operation", operation),
+			attribute.String("db.sql.table", db.Statement.Table),
+		)
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+// end ends the span of a statement with its SQL, rows and error
+func (p *gormPlugin) end(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	span.SetAttributes(
+		attribute.String("db.statement", db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	End(span, db.Error)
+}
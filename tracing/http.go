@@ -0,0 +1,25 @@
+package tracing
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// untracedRoutes are polled by health checks and scrapers, whose traces
+// would drown out the requests worth looking at
+var untracedRoutes = map[string]bool{
+	"/health":     true,
+	"/livez":      true,
+	"/readyz":     true,
+	"/metrics":    true,
+	"/debug/vars": true,
+}
+
+// Middleware starts a server span for every request, continuing the trace
+// of the caller's W3C traceparent header when there is one. The span rides
+// in the request context, from which the services start theirs.
+func Middleware() gin.HandlerFunc {
+	return otelgin.Middleware(ServiceName, otelgin.WithGinFilter(func(c *gin.Context) bool {
+		return !untracedRoutes[c.FullPath()]
+	}))
+}
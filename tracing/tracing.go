@@ -0,0 +1,124 @@
+/*
+Package tracing sets up OpenTelemetry tracing: the tracer provider and its
+exporter, W3C trace-context propagation, spans around GORM statements, and
+helpers for the spans of the service layer.
+
+Spans are started through the global tracer provider at the time of the call,
+so tests can install a provider with an in-memory exporter and see every span.
+*/
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// ServiceName names the service on every span and the tracer creating them
+const ServiceName = "event-api"
+
+// Exporters selectable with TRACING_EXPORTER
+const (
+	// ExporterNone records no spans
+	ExporterNone = "none"
+	// ExporterStdout writes spans to standard output as JSON
+	ExporterStdout = "stdout"
+	// ExporterOTLP sends spans over OTLP/HTTP to the collector set by the
+	// standard OTEL_EXPORTER_OTLP_* variables
+	ExporterOTLP = "otlp"
+)
+
+// Span attribute keys for the domain IDs of a request
+const (
+	eventIDKey        = attribute.Key("event.id")
+	userIDKey         = attribute.Key("user.id")
+	registrationIDKey = attribute.Key("registration.id")
+)
+
+// Setup installs the global tracer provider exporting to exporter, sampling
+// sampleRatio of the traces that do not arrive already sampled, and the W3C
+// trace-context and baggage propagators. The returned function flushes and
+// stops the provider.
+func Setup(ctx context.Context, exporter string, sampleRatio float64) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var spanExporter sdktrace.SpanExporter
+	switch exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		e, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, err
+		}
+		spanExporter = e
+	case ExporterOTLP:
+		e, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+		spanExporter = e
+	default:
+		return nil, fmt.Errorf("unknown TRACING_EXPORTER %q", exporter)
+	}
+
+	provider := NewProvider(spanExporter, sampleRatio)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewProvider creates a tracer provider that batches spans into exporter,
+// such as a tracetest.InMemoryExporter in tests. Child spans follow their
+// parent's sampling decision, so a trace is either kept whole or dropped.
+func NewProvider(exporter sdktrace.SpanExporter, sampleRatio float64) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+}
+
+// Start starts a span named name as a child of the span in ctx, if any
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(ServiceName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends span, recording err on it first. A missing record is an answer
+// rather than a failure, so gorm.ErrRecordNotFound is not recorded.
+func End(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// EventID is the span attribute of an event ID
+func EventID(id uint) attribute.KeyValue {
+	return eventIDKey.Int64(int64(id))
+}
+
+// UserID is the span attribute of a user ID
+func UserID(id uint) attribute.KeyValue {
+	return userIDKey.Int64(int64(id))
+}
+
+// RegistrationID is the span attribute of a registration ID
+func RegistrationID(id uint) attribute.KeyValue {
+	return registrationIDKey.Int64(int64(id))
+}
@@ -0,0 +1,124 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// record installs a provider exporting to memory for the rest of the test
+// and returns a function that flushes and returns the ended spans
+func record(t *testing.T) func() tracetest.SpanStubs {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewProvider(exporter, 1)
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+	return func() tracetest.SpanStubs {
+		if err := provider.ForceFlush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return exporter.GetSpans()
+	}
+}
+
+// attr returns the value of the attribute key of span
+func attr(span tracetest.SpanStub, key string) attribute.Value {
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddlewareContinuesCallerTrace(t *testing.T) {
+	spans := record(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/events/:id", func(c *gin.Context) {
+		_, span := Start(c.Request.Context(), "EventService.GetEventByID", EventID(7))
+		span.End()
+		c.Status(http.StatusOK)
+	})
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/events/7", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	got := spans()
+	if len(got) != 2 {
+		t.Fatalf("got %d spans, want the service and server spans of /events/7", len(got))
+	}
+	service, server := got[0], got[1]
+	if server.SpanContext.TraceID().String() != traceID {
+		t.Errorf("server span trace = %s, want the caller's %s", server.SpanContext.TraceID(), traceID)
+	}
+	if service.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Error("service span is not a child of the server span")
+	}
+	if got := attr(service, "event.id").AsInt64(); got != 7 {
+		t.Errorf("event.id = %d, want 7", got)
+	}
+}
+
+func TestInstrumentDB(t *testing.T) {
+	spans := record(t)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := InstrumentDB(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("CREATE TABLE users (id integer PRIMARY KEY, name text)").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, parent := Start(context.Background(), "UserService.GetUserByID")
+	var user struct{ ID uint }
+	err = db.WithContext(ctx).Table("users").First(&user, 1).Error
+	if err != gorm.ErrRecordNotFound {
+		t.Fatalf("error = %v, want gorm.ErrRecordNotFound", err)
+	}
+	End(parent, err)
+
+	got := spans()
+	if len(got) != 2 {
+		t.Fatalf("got %d spans, want the query and its parent; untraced statements make none", len(got))
+	}
+	query := got[0]
+	if query.Name != "gorm.query users" {
+		t.Errorf("name = %q, want %q", query.Name, "gorm.query users")
+	}
+	if query.Parent.SpanID() != got[1].SpanContext.SpanID() {
+		t.Error("query span is not a child of the service span")
+	}
+	if got := attr(query, "db.statement").AsString(); got == "" {
+		t.Error("db.statement not recorded")
+	}
+	for _, span := range got {
+		if span.Status.Code == codes.Error {
+			t.Errorf("%s recorded a missing record as an error", span.Name)
+		}
+	}
+}
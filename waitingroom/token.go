@@ -0,0 +1,70 @@
+package waitingroom
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidToken is returned for tokens that are malformed or were not
+// signed with the current secret
+var ErrInvalidToken = errors.New("invalid queue token")
+
+// Claims identify a waiting room entry and who it belongs to
+type Claims struct {
+	EntryID uint
+	EventID uint
+	UserID  uint
+}
+
+// Sign returns a token of the form "<entry>.<event>.<user>.<hex hmac>".
+// The claims are not secret, only tamper-proof.
+func Sign(secret []byte, c Claims) string {
+	payload := fmt.Sprintf("%d.%d.%d", c.EntryID, c.EventID, c.UserID)
+	return payload + "." + signature(secret, payload)
+}
+
+// Parse verifies a token and returns its claims
+func Parse(secret []byte, token string) (Claims, error) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return Claims{}, ErrInvalidToken
+	}
+	payload, sig := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, payload))) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrInvalidToken
+	}
+	var ids [3]uint
+	for n, part := range parts {
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return Claims{}, ErrInvalidToken
+		}
+		ids[n] = uint(id)
+	}
+	return Claims{EntryID: ids[0], EventID: ids[1], UserID: ids[2]}, nil
+}
+
+// NewSecret returns a random signing secret, for when none is configured
+func NewSecret() []byte {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return b
+}
+
+// signature returns the hex HMAC-SHA256 of payload
+func signature(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
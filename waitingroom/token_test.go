@@ -0,0 +1,38 @@
+package waitingroom
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSignParseRoundTrip(t *testing.T) {
+	secret := []byte("s3cret")
+	want := Claims{EntryID: 1234, EventID: 7, UserID: 42}
+
+	got, err := Parse(secret, Sign(secret, want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestParseRejectsTampering(t *testing.T) {
+	secret := []byte("s3cret")
+	token := Sign(secret, Claims{EntryID: 1234, EventID: 7, UserID: 42})
+
+	cases := map[string]string{
+		"other user":   strings.Replace(token, ".42.", ".43.", 1),
+		"other secret": Sign([]byte("other"), Claims{EntryID: 1234, EventID: 7, UserID: 42}),
+		"no signature": "1234.7.42",
+		"empty":        "",
+		"garbage":      "a.b.c.d",
+	}
+	for name, tc := range cases {
+		if _, err := Parse(secret, tc); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: err = %v, want ErrInvalidToken", name, err)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"event-api/models"
+)
+
+// Envelope is the JSON body POSTed to subscribers
+type Envelope struct {
+	ID        string                  `json:"id"`
+	Type      models.WebhookEventType `json:"type"`
+	CreatedAt time.Time               `json:"created_at"`
+	Data      interface{}             `json:"data"`
+}
+
+// NewPayload wraps data in an Envelope and encodes it. id identifies the
+// lifecycle event and is shared by every delivery and retry of it, so
+// receivers can de-duplicate.
+func NewPayload(id string, eventType models.WebhookEventType, data interface{}) ([]byte, error) {
+	return json.Marshal(Envelope{
+		ID:        id,
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+}
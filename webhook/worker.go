@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"event-api/models"
+)
+
+// maxErrorBody bounds how much of a failed response is kept in the delivery log
+const maxErrorBody = 512
+
+// Store is the subset of the webhook repository the worker needs
+type Store interface {
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, id uint, responseStatus int, deliveredAt time.Time) error
+	MarkRetry(ctx context.Context, id uint, attempts, responseStatus int, nextAttemptAt time.Time, lastError string) error
+	MarkFailed(ctx context.Context, id uint, attempts, responseStatus int, lastError string) error
+}
+
+// WorkerConfig controls how the worker polls and retries
+type WorkerConfig struct {
+	PollInterval   time.Duration // how often to look for due deliveries
+	BatchSize      int           // how many deliveries to claim per poll
+	MaxAttempts    int           // attempts before a delivery is marked failed
+	BaseBackoff    time.Duration // delay after the first failure, doubled on each retry
+	MaxBackoff     time.Duration // upper bound on the retry delay
+	RequestTimeout time.Duration // deadline for a single HTTP request
+	// AllowPrivateTargets permits loopback and private endpoints, e.g. in development
+	AllowPrivateTargets bool
+}
+
+// DefaultWorkerConfig returns sensible defaults for production use
+func DefaultWorkerConfig() WorkerConfig {
+	return WorkerConfig{
+		PollInterval:   2 * time.Second,
+		BatchSize:      50,
+		MaxAttempts:    10,
+		BaseBackoff:    10 * time.Second,
+		MaxBackoff:     6 * time.Hour,
+		RequestTimeout: 10 * time.Second,
+	}
+}
+
+// Worker POSTs pending deliveries to subscriber endpoints with retries
+type Worker struct {
+	store  Store
+	client *http.Client
+	cfg    WorkerConfig
+	now    func() time.Time
+}
+
+// NewWorker creates a new Worker
+func NewWorker(store Store, cfg WorkerConfig) *Worker {
+	return &Worker{
+		store:  store,
+		client: newClient(cfg.RequestTimeout, cfg.AllowPrivateTargets),
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
+
+// Run polls for due deliveries until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.ProcessBatch(ctx); err != nil {
+			slog.ErrorContext(ctx, "webhook worker: batch failed", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch claims one batch of due deliveries and attempts each of them.
+// It returns the number of deliveries claimed.
+func (w *Worker) ProcessBatch(ctx context.Context) (int, error) {
+	lease := w.cfg.RequestTimeout * time.Duration(w.cfg.BatchSize)
+	deliveries, err := w.store.ClaimDueDeliveries(ctx, w.now(), lease, w.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range deliveries {
+		if ctx.Err() != nil {
+			break
+		}
+		w.deliver(ctx, d)
+	}
+	return len(deliveries), nil
+}
+
+// deliver sends a single delivery and records the outcome
+func (w *Worker) deliver(ctx context.Context, d models.WebhookDelivery) {
+	attempts := d.Attempts + 1
+
+	// The subscription was removed or disabled after the delivery was queued
+	if d.Subscription == nil || !d.Subscription.Active {
+		if err := w.store.MarkFailed(ctx, d.ID, attempts, 0, "subscription inactive"); err != nil {
+			slog.ErrorContext(ctx, "webhook worker: mark failed", "delivery_id", d.ID, "err", err)
+		}
+		return
+	}
+
+	status, err := w.post(ctx, d)
+	if err == nil {
+		if err := w.store.MarkDelivered(ctx, d.ID, status, w.now()); err != nil {
+			slog.ErrorContext(ctx, "webhook worker: mark delivered", "delivery_id", d.ID, "err", err)
+		}
+		return
+	}
+
+	if attempts >= w.cfg.MaxAttempts {
+		slog.WarnContext(ctx, "webhook worker: giving up", "delivery_id", d.ID, "attempts", attempts, "err", err)
+		if err := w.store.MarkFailed(ctx, d.ID, attempts, status, err.Error()); err != nil {
+			slog.ErrorContext(ctx, "webhook worker: mark failed", "delivery_id", d.ID, "err", err)
+		}
+		return
+	}
+
+	next := w.now().Add(w.backoff(attempts))
+	if err := w.store.MarkRetry(ctx, d.ID, attempts, status, next, err.Error()); err != nil {
+		slog.ErrorContext(ctx, "webhook worker: reschedule", "delivery_id", d.ID, "err", err)
+	}
+}
+
+// post signs and sends the payload, treating any non-2xx response, redirects
+// included, as a failure
+func (w *Worker) post(ctx context.Context, d models.WebhookDelivery) (int, error) {
+	payload := []byte(d.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Subscription.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	now := w.now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "event-api-webhooks/1.0")
+	req.Header.Set(HeaderID, d.EventID)
+	req.Header.Set(HeaderEvent, string(d.EventType))
+	req.Header.Set(HeaderTimestamp, fmt.Sprint(now.Unix()))
+	req.Header.Set(HeaderSignature, Sign(d.Subscription.Secret, now, payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return resp.StatusCode, fmt.Errorf("endpoint responded %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the given attempt number is retried
+func (w *Worker) backoff(attempts int) time.Duration {
+	d := w.cfg.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= w.cfg.MaxBackoff {
+			return w.cfg.MaxBackoff
+		}
+	}
+	return d
+}
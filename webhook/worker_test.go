@@ -0,0 +1,289 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"event-api/models"
+)
+
+// memoryStore is an in-memory Store used to exercise the worker
+type memoryStore struct {
+	mu         sync.Mutex
+	deliveries map[uint]*models.WebhookDelivery
+}
+
+func newMemoryStore(ds ...models.WebhookDelivery) *memoryStore {
+	s := &memoryStore{deliveries: map[uint]*models.WebhookDelivery{}}
+	for i := range ds {
+		d := ds[i]
+		d.Status = models.WebhookDeliveryPending
+		s.deliveries[d.ID] = &d
+	}
+	return s
+}
+
+func (s *memoryStore) ClaimDueDeliveries(_ context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []models.WebhookDelivery
+	for _, d := range s.deliveries {
+		if len(due) == limit {
+			break
+		}
+		if d.Status == models.WebhookDeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, *d)
+			d.NextAttemptAt = now.Add(lease)
+		}
+	}
+	return due, nil
+}
+
+func (s *memoryStore) MarkDelivered(_ context.Context, id uint, responseStatus int, deliveredAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.deliveries[id]
+	d.Status = models.WebhookDeliveryDelivered
+	d.Attempts++
+	d.ResponseStatus = responseStatus
+	d.DeliveredAt = &deliveredAt
+	return nil
+}
+
+func (s *memoryStore) MarkRetry(_ context.Context, id uint, attempts, responseStatus int, next time.Time, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.deliveries[id]
+	d.Attempts = attempts
+	d.ResponseStatus = responseStatus
+	d.NextAttemptAt = next
+	d.LastError = lastError
+	return nil
+}
+
+func (s *memoryStore) MarkFailed(_ context.Context, id uint, attempts, responseStatus int, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.deliveries[id]
+	d.Status = models.WebhookDeliveryFailed
+	d.Attempts = attempts
+	d.ResponseStatus = responseStatus
+	d.LastError = lastError
+	return nil
+}
+
+func (s *memoryStore) get(id uint) models.WebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.deliveries[id]
+}
+
+func testWorkerConfig() WorkerConfig {
+	return WorkerConfig{
+		PollInterval:   time.Millisecond,
+		BatchSize:      10,
+		MaxAttempts:    3,
+		BaseBackoff:    time.Second,
+		MaxBackoff:     time.Minute,
+		RequestTimeout: time.Second,
+		// Test receivers listen on loopback
+		AllowPrivateTargets: true,
+	}
+}
+
+func testDelivery(t *testing.T, url string) models.WebhookDelivery {
+	t.Helper()
+	id := "evt_42"
+	payload, err := NewPayload(id, models.WebhookRegistrationCreated, map[string]uint{"user_id": 2, "event_id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return models.WebhookDelivery{
+		ID:             1,
+		SubscriptionID: 9,
+		Subscription:   &models.WebhookSubscription{ID: 9, URL: url, Secret: "s3cret", Active: true},
+		EventID:        id,
+		EventType:      models.WebhookRegistrationCreated,
+		Payload:        string(payload),
+	}
+}
+
+func TestWorkerDeliversSignedPayload(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	delivery := testDelivery(t, receiver.URL)
+	store := newMemoryStore(delivery)
+	w := NewWorker(store, testWorkerConfig())
+
+	if n, err := w.ProcessBatch(context.Background()); err != nil || n != 1 {
+		t.Fatalf("ProcessBatch = %d, %v", n, err)
+	}
+
+	req, body := <-received, <-bodies
+	if got := req.Header.Get(HeaderEvent); got != string(models.WebhookRegistrationCreated) {
+		t.Errorf("%s = %q", HeaderEvent, got)
+	}
+	if got := req.Header.Get(HeaderID); got != delivery.EventID {
+		t.Errorf("%s = %q, want %q", HeaderID, got, delivery.EventID)
+	}
+	err := Verify("s3cret", req.Header.Get(HeaderTimestamp), req.Header.Get(HeaderSignature), body, time.Minute)
+	if err != nil {
+		t.Fatalf("signature did not verify: %v", err)
+	}
+	if err := Verify("wrong", req.Header.Get(HeaderTimestamp), req.Header.Get(HeaderSignature), body, time.Minute); err == nil {
+		t.Fatal("signature verified with the wrong secret")
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.ID != delivery.EventID || env.Type != models.WebhookRegistrationCreated {
+		t.Errorf("unexpected envelope: %+v", env)
+	}
+
+	got := store.get(1)
+	if got.Status != models.WebhookDeliveryDelivered || got.ResponseStatus != http.StatusNoContent || got.DeliveredAt == nil {
+		t.Fatalf("delivery not recorded: %+v", got)
+	}
+}
+
+func TestWorkerRetriesWithExponentialBackoff(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls < 3 {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	store := newMemoryStore(testDelivery(t, receiver.URL))
+	now := time.Now()
+	w := NewWorker(store, testWorkerConfig())
+	w.now = func() time.Time { return now }
+
+	w.ProcessBatch(context.Background())
+	got := store.get(1)
+	if got.Status != models.WebhookDeliveryPending || got.Attempts != 1 || got.ResponseStatus != http.StatusServiceUnavailable {
+		t.Fatalf("after first failure: %+v", got)
+	}
+	if !got.NextAttemptAt.Equal(now.Add(time.Second)) {
+		t.Fatalf("first retry at %v, want +1s", got.NextAttemptAt.Sub(now))
+	}
+
+	now = now.Add(time.Second)
+	w.ProcessBatch(context.Background())
+	if got := store.get(1); got.Attempts != 2 || !got.NextAttemptAt.Equal(now.Add(2*time.Second)) {
+		t.Fatalf("after second failure: %+v", got)
+	}
+
+	now = now.Add(2 * time.Second)
+	w.ProcessBatch(context.Background())
+	if got := store.get(1); got.Status != models.WebhookDeliveryDelivered || got.Attempts != 3 {
+		t.Fatalf("after success: %+v", got)
+	}
+}
+
+func TestWorkerFailsInactiveSubscription(t *testing.T) {
+	delivery := testDelivery(t, "http://127.0.0.1:1")
+	delivery.Subscription.Active = false
+	store := newMemoryStore(delivery)
+
+	NewWorker(store, testWorkerConfig()).ProcessBatch(context.Background())
+	if got := store.get(1); got.Status != models.WebhookDeliveryFailed {
+		t.Fatalf("delivery to inactive subscription: %+v", got)
+	}
+}
+
+func TestWorkerRefusesPrivateTargets(t *testing.T) {
+	var called atomic.Bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called.Store(true)
+	}))
+	defer receiver.Close()
+
+	store := newMemoryStore(testDelivery(t, receiver.URL))
+	cfg := testWorkerConfig()
+	cfg.AllowPrivateTargets = false
+	NewWorker(store, cfg).ProcessBatch(context.Background())
+	if got := store.get(1); called.Load() || got.Status != models.WebhookDeliveryPending || !strings.Contains(got.LastError, "non-public address: 127.0.0.1") {
+		t.Fatalf("delivery to loopback: called = %v, %+v", called.Load(), got)
+	}
+}
+
+func TestWorkerDoesNotFollowRedirects(t *testing.T) {
+	var followed atomic.Bool
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		followed.Store(true)
+	}))
+	defer internal.Close()
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusTemporaryRedirect)
+	}))
+	defer receiver.Close()
+
+	store := newMemoryStore(testDelivery(t, receiver.URL))
+	NewWorker(store, testWorkerConfig()).ProcessBatch(context.Background())
+	if got := store.get(1); followed.Load() || got.ResponseStatus != http.StatusTemporaryRedirect || got.Status != models.WebhookDeliveryPending {
+		t.Fatalf("redirected delivery: followed = %v, %+v", followed.Load(), got)
+	}
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":        true,
+		"2606:4700::1111":      true,
+		"127.0.0.1":            false,
+		"::1":                  false,
+		"10.1.2.3":             false,
+		"172.16.0.1":           false,
+		"192.168.1.1":          false,
+		"169.254.169.254":      false,
+		"fe80::1":              false,
+		"fd00::1":              false,
+		"100.64.0.1":           false,
+		"0.0.0.0":              false,
+		"224.0.0.1":            false,
+		"::ffff:127.0.0.1":     false,
+		"::ffff:93.184.216.34": true,
+	} {
+		if got := IsPublicAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("IsPublicAddr(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestVerifyRejectsStaleTimestamp(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+	payload := []byte(`{}`)
+	sig := Sign("s3cret", old, payload)
+
+	err := Verify("s3cret", strconv.FormatInt(old.Unix(), 10), sig, payload, 5*time.Minute)
+	if err != ErrStaleTimestamp {
+		t.Fatalf("Verify = %v, want ErrStaleTimestamp", err)
+	}
+}